@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/app"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/env"
+)
+
+// projection-check replays challenge.user_event and reports users whose
+// projected state does not match their challenge.user row
+func main() {
+	userID := flag.String("user", "", "only check the user with this ID")
+	flag.Parse()
+
+	options := []app.Option{
+		app.WithDBHost(env.LoadOrPanic("DB_HOST")),
+		app.WithDBPort(env.LoadOrPanic("DB_PORT")),
+		app.WithDBUser(env.LoadOrPanic("DB_USER")),
+		app.WithDBPassword(env.LoadOrPanic("DB_PASSWORD")),
+		app.WithDBName(env.LoadOrPanic("DB_NAME")),
+		app.WithSSLMode(env.LoadOrDefault("DB_SSL", "disable")),
+	}
+
+	drifted, err := app.VerifyProjections(context.Background(), os.Stdout, *userID, options...)
+	if err != nil {
+		log.Fatal(context.Background(), fmt.Sprintf("could not verify projections: %s", err.Error()))
+	}
+
+	if drifted > 0 {
+		fmt.Printf("%d user(s) drifted from their event log\n", drifted)
+		os.Exit(1)
+	}
+	fmt.Println("all users match their event log")
+}
@@ -20,4 +20,6 @@ func init() {
 	os.Setenv("DB_NAME", "user_challenge_svc")
 	os.Setenv("DB_MAX_CONNECTIONS", "100")
 	os.Setenv("DB_SSL", "disable")
+	os.Setenv("JWT_SECRET", "dev-only-secret-change-me-0123456789abcdef")
+	os.Setenv("IDEMPOTENCY_SECRET", "dev-only-idempotency-secret-change-me")
 }
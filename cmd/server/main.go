@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/app"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/env"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
 )
 
 func main() {
@@ -16,11 +19,40 @@ func main() {
 		log.Fatal(context.Background(), fmt.Sprintf("could not start application: %s", err.Error()))
 	}
 
+	relayPollInterval, err := time.ParseDuration(env.LoadOrDefault("RELAY_POLL_INTERVAL", "1s"))
+	if err != nil {
+		log.Fatal(context.Background(), fmt.Sprintf("could not start application: %s", err.Error()))
+	}
+
+	idempotencyTTL, err := time.ParseDuration(env.LoadOrDefault("IDEMPOTENCY_KEY_TTL", "24h"))
+	if err != nil {
+		log.Fatal(context.Background(), fmt.Sprintf("could not start application: %s", err.Error()))
+	}
+
+	grpcDefaultTimeout, err := time.ParseDuration(env.LoadOrDefault("GRPC_DEFAULT_TIMEOUT", "30s"))
+	if err != nil {
+		log.Fatal(context.Background(), fmt.Sprintf("could not start application: %s", err.Error()))
+	}
+
+	drainDelay, err := time.ParseDuration(env.LoadOrDefault("SHUTDOWN_DRAIN_DELAY", "5s"))
+	if err != nil {
+		log.Fatal(context.Background(), fmt.Sprintf("could not start application: %s", err.Error()))
+	}
+
+	accessTokenTTL, err := time.ParseDuration(env.LoadOrDefault("JWT_ACCESS_TTL", "15m"))
+	if err != nil {
+		log.Fatal(context.Background(), fmt.Sprintf("could not start application: %s", err.Error()))
+	}
+
+	refreshTokenTTL, err := time.ParseDuration(env.LoadOrDefault("JWT_REFRESH_TTL", "720h"))
+	if err != nil {
+		log.Fatal(context.Background(), fmt.Sprintf("could not start application: %s", err.Error()))
+	}
+
 	// We set options for the app
 	options := []app.Option{
-		// HTTP Options
-		app.WithHTTPPort(env.LoadOrPanic("HTTP_PORT")),
-		app.WithGRPCPort(env.LoadOrPanic("GRPC_PORT")),
+		// gRPC Options
+		app.WithGRPCDefaultTimeout(grpcDefaultTimeout),
 		// DB Options
 		app.WithDBHost(env.LoadOrPanic("DB_HOST")),
 		app.WithDBPort(env.LoadOrPanic("DB_PORT")),
@@ -29,6 +61,54 @@ func main() {
 		app.WithDBName(env.LoadOrPanic("DB_NAME")),
 		app.WithDBMaxConnections(maxDBConn),
 		app.WithSSLMode(env.LoadOrDefault("DB_SSL", "disable")),
+		// Auth Options
+		app.WithJWTIssuer(env.LoadOrDefault("JWT_ISSUER", "user_challenge_svc")),
+		app.WithAccessTokenTTL(accessTokenTTL),
+		app.WithRefreshTokenTTL(refreshTokenTTL),
+		// PubSub Options
+		app.WithPubSub(env.LoadOrDefault("PUBSUB", app.PubSubLocal)),
+		// Outbox relay Options
+		app.WithRelayPollInterval(relayPollInterval),
+		// Idempotency keys Options
+		app.WithIdempotencyKeyTTL(idempotencyTTL),
+		app.WithIdempotencySecret(env.LoadOrPanic("IDEMPOTENCY_SECRET")),
+		// Shutdown Options
+		app.WithDrainDelay(drainDelay),
+	}
+
+	// Tokens are signed with Ed25519 when a key file is given, HS256 otherwise
+	if keyFile := env.LoadOrDefault("JWT_ED25519_KEY_FILE", ""); keyFile != "" {
+		options = append(options, app.WithJWTEd25519KeyFile(keyFile))
+	} else {
+		options = append(options, app.WithJWTSecret(env.LoadOrPanic("JWT_SECRET")))
+	}
+
+	// SINGLE_PORT serves gRPC, gRPC-Web and HTTP on one port, HTTP_PORT and GRPC_PORT are not needed then
+	if port := env.LoadOrDefault("SINGLE_PORT", ""); port != "" {
+		options = append(options, app.WithSinglePort(port))
+	} else {
+		options = append(options, app.WithHTTPPort(env.LoadOrPanic("HTTP_PORT")), app.WithGRPCPort(env.LoadOrPanic("GRPC_PORT")))
+	}
+
+	// Spans are written as JSON lines to the standard output with TRACE_EXPORTER=stdout
+	switch exporter := env.LoadOrDefault("TRACE_EXPORTER", ""); exporter {
+	case "":
+	case "stdout":
+		options = append(options, app.WithTraceExporter(tracing.NewStdoutExporter()))
+	default:
+		log.Fatal(context.Background(), fmt.Sprintf("could not start application: unknown TRACE_EXPORTER %q", exporter))
+	}
+
+	// Service accounts are given as "id:secret:scope,scope;id:secret:scope"
+	for _, account := range strings.Split(env.LoadOrDefault("SERVICE_ACCOUNTS", ""), ";") {
+		if strings.TrimSpace(account) == "" {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSpace(account), ":", 3)
+		if len(parts) != 3 {
+			log.Fatal(context.Background(), "could not start application: SERVICE_ACCOUNTS entries must be id:secret:scopes")
+		}
+		options = append(options, app.WithServiceAccount(parts[0], parts[1], strings.Split(parts[2], ",")...))
 	}
 
 	err = app.New(options...)
@@ -2,6 +2,9 @@ echo "🔄 Generating mocks..."
 
 mockgen --source=pkg/challenge/internal/aggregate/user/user.go --destination=pkg/challenge/internal/mocks/mock_user_aggregate.go --package=mocks --mock_names=Aggregate=MockUserAggregate
 mockgen --source=pkg/challenge/internal/service/user/service.go --destination=pkg/challenge/internal/mocks/mock_user_service.go --package=mocks --mock_names=Service=MockUserService
+mockgen --source=pkg/challenge/internal/aggregate/webhook/webhook.go --destination=pkg/challenge/internal/mocks/mock_webhook_aggregate.go --package=mocks --mock_names=Aggregate=MockWebhookAggregate
+mockgen --source=pkg/challenge/internal/service/webhook/service.go --destination=pkg/challenge/internal/mocks/mock_webhook_service.go --package=mocks --mock_names=Service=MockWebhookService
+mockgen --source=pkg/challenge/internal/service/auth/service.go --destination=pkg/challenge/internal/mocks/mock_auth_service.go --package=mocks --mock_names=Service=MockAuthService
 mockgen --source=pkg/challenge/pubsub/publisher.go --destination=pkg/challenge/internal/mocks/mock_publisher.go --package=mocks --mock_names=Publisher=MockPublisher
 
 echo "✅ Mocks generated!"
\ No newline at end of file
@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX IF EXISTS challenge.user_event_unpublished_idx;
+
+ALTER TABLE challenge.user_event
+  DROP COLUMN IF EXISTS attempts,
+  DROP COLUMN IF EXISTS next_attempt_at,
+  DROP COLUMN IF EXISTS last_error;
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+ALTER TABLE challenge.user_event
+  ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+  ADD COLUMN next_attempt_at TIMESTAMPTZ,
+  ADD COLUMN last_error TEXT NOT NULL DEFAULT '';
+
+-- Relay lookups only care about pending events
+CREATE INDEX user_event_unpublished_idx
+  ON challenge.user_event (created_at)
+  WHERE published = FALSE;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS challenge.user_event_user_id_created_at_idx;
+
+COMMIT;
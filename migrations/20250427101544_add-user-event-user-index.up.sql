@@ -0,0 +1,6 @@
+BEGIN;
+
+CREATE INDEX user_event_user_id_created_at_idx
+  ON challenge.user_event (user_id, created_at);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS challenge.webhook_delivery CASCADE;
+DROP TABLE IF EXISTS challenge.webhook CASCADE;
+
+COMMIT;
@@ -0,0 +1,42 @@
+BEGIN;
+
+CREATE TABLE challenge.webhook (
+  id UUID PRIMARY KEY,
+  url TEXT NOT NULL,
+  event_types JSONB NOT NULL,
+  secret TEXT NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  consecutive_failures INT NOT NULL DEFAULT 0,
+  disabled_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ NOT NULL,
+  deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX webhook_event_types_idx
+  ON challenge.webhook USING GIN (event_types)
+  WHERE active AND deleted_at IS NULL;
+
+-- Update triggers
+CREATE TRIGGER set_updated_at
+  BEFORE INSERT OR UPDATE ON challenge.webhook
+  FOR EACH ROW
+  EXECUTE PROCEDURE challenge.set_updated_at ();
+
+CREATE TABLE challenge.webhook_delivery (
+  id UUID PRIMARY KEY,
+  webhook_id UUID NOT NULL REFERENCES challenge.webhook (id) ON DELETE CASCADE,
+  event_id UUID NOT NULL,
+  event_type TEXT NOT NULL,
+  attempt INT NOT NULL,
+  success BOOLEAN NOT NULL,
+  status_code INT NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  duration_ms BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX webhook_delivery_webhook_id_created_at_idx
+  ON challenge.webhook_delivery (webhook_id, created_at);
+
+COMMIT;
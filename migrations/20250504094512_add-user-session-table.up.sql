@@ -0,0 +1,27 @@
+BEGIN;
+
+-- One row per refresh token. Rotating a token inserts a new row with the same
+-- session_id and marks the previous one as used
+CREATE TABLE challenge.user_session (
+  id UUID PRIMARY KEY,
+  session_id UUID NOT NULL,
+  user_id UUID NOT NULL REFERENCES challenge.user (id) ON DELETE CASCADE,
+  token_hash TEXT NOT NULL UNIQUE,
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip_address TEXT NOT NULL DEFAULT '',
+  started_at TIMESTAMPTZ NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ,
+  revoke_reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX user_session_user_id_idx
+  ON challenge.user_session (user_id)
+  WHERE revoked_at IS NULL;
+
+CREATE INDEX user_session_session_id_idx
+  ON challenge.user_session (session_id);
+
+COMMIT;
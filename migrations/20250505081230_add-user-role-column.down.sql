@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE challenge.user DROP COLUMN IF EXISTS role;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Admins can manage every user. There is no endpoint to grant it, it is set directly in the DB
+ALTER TABLE challenge.user
+  ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user'
+  CONSTRAINT user_role_check CHECK (role IN ('user', 'admin'));
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Incremented by every write, used for optimistic concurrency (ETag / If-Match)
+ALTER TABLE challenge.user
+  ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+COMMIT;
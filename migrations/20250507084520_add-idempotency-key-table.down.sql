@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS challenge.idempotency_key;
+
+COMMIT;
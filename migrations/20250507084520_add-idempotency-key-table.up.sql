@@ -0,0 +1,17 @@
+BEGIN;
+
+-- Idempotency keys sent by the clients. The response of the first request is
+-- stored so retries with the same key get it back instead of repeating the write
+CREATE TABLE challenge.idempotency_key (
+  scope TEXT NOT NULL,
+  key TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  response JSONB,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idempotency_key_created_at_idx
+  ON challenge.idempotency_key (created_at);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS challenge.user_email_prefix_idx;
+DROP INDEX IF EXISTS challenge.user_nickname_prefix_idx;
+DROP INDEX IF EXISTS challenge.user_country_created_at_id_idx;
+DROP INDEX IF EXISTS challenge.user_nickname_id_idx;
+DROP INDEX IF EXISTS challenge.user_created_at_id_idx;
+
+COMMIT;
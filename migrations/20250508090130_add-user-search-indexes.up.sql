@@ -0,0 +1,25 @@
+BEGIN;
+
+-- Keyset pagination, one index per sort. Only not deleted users are ever listed
+CREATE INDEX user_created_at_id_idx
+  ON challenge.user (created_at, id)
+  WHERE deleted_at IS NULL;
+
+CREATE INDEX user_nickname_id_idx
+  ON challenge.user (nickname, id)
+  WHERE deleted_at IS NULL;
+
+CREATE INDEX user_country_created_at_id_idx
+  ON challenge.user (country, created_at, id)
+  WHERE deleted_at IS NULL;
+
+-- Case insensitive prefix searches: lower(column) LIKE 'prefix%'
+CREATE INDEX user_nickname_prefix_idx
+  ON challenge.user (lower(nickname) text_pattern_ops)
+  WHERE deleted_at IS NULL;
+
+CREATE INDEX user_email_prefix_idx
+  ON challenge.user (lower(email) text_pattern_ops)
+  WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS challenge.user_full_name_trgm_idx;
+DROP INDEX IF EXISTS challenge.user_nickname_trgm_idx;
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+-- Fuzzy search by nickname and full name with trigram similarity
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX user_nickname_trgm_idx
+  ON challenge.user USING GIN (nickname gin_trgm_ops)
+  WHERE deleted_at IS NULL;
+
+CREATE INDEX user_full_name_trgm_idx
+  ON challenge.user USING GIN ((first_name || ' ' || last_name) gin_trgm_ops)
+  WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+-- Renamed nicknames are kept
+DROP INDEX IF EXISTS challenge.user_nickname_key;
+
+COMMIT;
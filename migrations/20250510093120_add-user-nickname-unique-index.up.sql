@@ -0,0 +1,54 @@
+BEGIN;
+
+-- Nicknames differing only in case were allowed until now. The oldest user keeps
+-- the nickname, the others get their ID appended
+CREATE TEMPORARY TABLE renamed_nickname ON COMMIT DROP AS
+SELECT id, nickname AS old_nickname, nickname || '_' || id::text AS new_nickname
+FROM (
+  SELECT id, nickname,
+    row_number() OVER (PARTITION BY lower(nickname) ORDER BY created_at, id) AS position
+  FROM challenge.user
+  WHERE deleted_at IS NULL
+) ranked
+WHERE position > 1;
+
+-- IDs are unique so renames don't clash with each other, only with a nickname taken already
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT 1
+    FROM renamed_nickname r
+    JOIN challenge.user u ON lower(u.nickname) = lower(r.new_nickname)
+    WHERE u.deleted_at IS NULL
+  ) THEN
+    RAISE EXCEPTION 'renamed nicknames are already taken, rename those users first';
+  END IF;
+END
+$$;
+
+UPDATE challenge.user u
+SET nickname = r.new_nickname, version = u.version + 1, updated_at = now()
+FROM renamed_nickname r
+WHERE u.id = r.id;
+
+-- Renames are stored as regular updates, so projections and subscribers see them
+INSERT INTO challenge.user_event (id, user_id, event_type, payload)
+SELECT gen_random_uuid(), u.id, 'USER_UPDATED', jsonb_build_object(
+  'user_id', u.id::text,
+  'nickname', u.nickname,
+  'country', u.country,
+  'trace_id', '',
+  'version', u.version,
+  'changes', jsonb_build_array(jsonb_build_object(
+    'field', 'nickname', 'old', r.old_nickname, 'new', r.new_nickname
+  ))
+)
+FROM challenge.user u
+JOIN renamed_nickname r ON r.id = u.id;
+
+-- Named <table>_<column>_key so conflicts are reported on the nickname field
+CREATE UNIQUE INDEX user_nickname_key
+  ON challenge.user (lower(nickname))
+  WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS challenge.user_event_trace_id_idx;
+
+ALTER TABLE challenge.user_event
+  DROP COLUMN IF EXISTS trace_id;
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+-- Trace ID of the request that caused the event, so events can be correlated with logs
+ALTER TABLE challenge.user_event
+  ADD COLUMN trace_id TEXT NOT NULL DEFAULT '';
+
+UPDATE challenge.user_event
+SET trace_id = payload->>'trace_id'
+WHERE COALESCE(payload->>'trace_id', '') <> '';
+
+CREATE INDEX user_event_trace_id_idx
+  ON challenge.user_event (trace_id)
+  WHERE trace_id <> '';
+
+COMMIT;
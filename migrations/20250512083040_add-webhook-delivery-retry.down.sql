@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS challenge.webhook_delivery_next_attempt_at_idx;
+
+ALTER TABLE challenge.webhook_delivery
+  DROP COLUMN IF EXISTS next_attempt_at,
+  DROP COLUMN IF EXISTS payload;
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+-- Attempts are stored with the body to send and when it is due before they are sent,
+-- so deliveries survive restarts. next_attempt_at is cleared once the attempt is sent
+ALTER TABLE challenge.webhook_delivery
+  ADD COLUMN payload JSONB,
+  ADD COLUMN next_attempt_at TIMESTAMPTZ;
+
+CREATE INDEX webhook_delivery_next_attempt_at_idx
+  ON challenge.webhook_delivery (next_attempt_at)
+  WHERE next_attempt_at IS NOT NULL;
+
+COMMIT;
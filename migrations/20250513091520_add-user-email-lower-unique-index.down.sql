@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS challenge.user_email_key;
+
+ALTER TABLE challenge.user
+  ADD CONSTRAINT user_email_key UNIQUE (email);
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+-- Emails differing only in case belong to the same person, they can not be renamed
+-- like nicknames and have to be merged by hand before running this migration
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT 1 FROM challenge.user GROUP BY lower(email) HAVING count(*) > 1
+  ) THEN
+    RAISE EXCEPTION 'challenge.user has emails differing only in case, merge them first';
+  END IF;
+END
+$$;
+
+ALTER TABLE challenge.user
+  DROP CONSTRAINT IF EXISTS user_email_key;
+
+-- Named <table>_<column>_key so conflicts are reported on the email field
+CREATE UNIQUE INDEX user_email_key
+  ON challenge.user (lower(email));
+
+COMMIT;
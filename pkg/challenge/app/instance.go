@@ -12,16 +12,37 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/health"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/idempotency"
 	userAggregate "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/user"
+	webhookAggregate "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/webhook"
+	grpcAuthCtrl "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/grpc/auth"
 	grpcUserCtrl "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/grpc/user"
+	httpAuthCtrl "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/auth"
 	httpUserCtrl "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/user"
+	httpWebhookCtrl "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/webhook"
 	pubsubUserCtrl "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/pubsub/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	authService "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/auth"
 	userService "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/user"
+	webhookService "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+	authProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/auth"
 	userProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
 	simplePubSub "github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub/local"
+	postgresPubSub "github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub/postgres"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub/relay"
 	grpcServer "github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc/interceptor"
 	httpServer "github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/http"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/http/middleware"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/mux"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/webhook"
 )
 
 const (
@@ -36,6 +57,10 @@ type Instance struct {
 	timeout int
 	state   int
 	mu      sync.Mutex
+	// health reports the instance as not serving as soon as it is stopping
+	health *health.Checker
+	// drainDelay is how long the servers keep running once the instance is stopping
+	drainDelay time.Duration
 }
 
 type server interface {
@@ -49,49 +74,183 @@ func New(opts ...Option) error {
 		o(&options)
 	}
 
+	// Log lines written with the request context carry its trace ID
+	log.Logger = log.Logger.Hook(tracectx.LogHook{})
+
 	// DB connection
 	dbConn, err := db.New(options.dbOptions...)
 	if err != nil {
 		return err
 	}
 
-	// Initialize Bus
-	bus := simplePubSub.NewBus(dbConn)
+	// Spans of the requests, services, statements and events
+	tracing.SetExporter(options.traceExporter)
+	err = tracing.InstrumentDB(dbConn)
+	if err != nil {
+		return err
+	}
+
+	// Metrics, served on /metrics
+	appMetrics := metrics.New()
+	err = appMetrics.InstrumentDB(dbConn)
+	if err != nil {
+		return err
+	}
+	err = appMetrics.WatchUnpublishedEvents(func(ctx context.Context) (int64, error) {
+		return repo.CountUnpublishedEvents(dbConn.WithContext(ctx))
+	})
+	if err != nil {
+		return err
+	}
+
+	// Initialize Bus. The local bus only reaches this process, the postgres one
+	// reaches every instance sharing the database
+	localBus := simplePubSub.NewBus(simplePubSub.WithMetrics(appMetrics))
+	var publisher pubsub.Publisher = localBus
+	var subscriber pubsub.Subscriber = localBus
+	var backgroundServers []server
+	// Buses with a background listener, readiness checks they are running
+	var runningBuses []*postgresPubSub.Bus
+
+	switch options.pubSub {
+	case "", PubSubLocal:
+	case PubSubPostgres:
+		pgBus, err := postgresPubSub.New(dbConn, postgresPubSub.WithMetrics(appMetrics))
+		if err != nil {
+			return err
+		}
+		// Webhooks stay on the local bus so only the instance that wrote the event delivers it
+		publisher = pubsub.NewFanout(localBus, pgBus)
+		subscriber = pgBus
+		backgroundServers = append(backgroundServers, pgBus)
+		runningBuses = append(runningBuses, pgBus)
+	default:
+		return fmt.Errorf("unknown pubsub %q", options.pubSub)
+	}
 
 	// Register Subscribers
-	err = pubsubUserCtrl.RegisterUserSubscribers(bus)
+	err = pubsubUserCtrl.RegisterUserSubscribers(subscriber)
+	if err != nil {
+		return err
+	}
+
+	// User changes feed for gRPC streams
+	userHub := grpcUserCtrl.NewHub()
+	err = userHub.Register(subscriber)
+	if err != nil {
+		return err
+	}
+
+	// Webhook deliveries
+	webhookDispatcher, err := webhook.New(dbConn, "nontest", options.webhookOptions...)
+	if err != nil {
+		return err
+	}
+	err = webhookDispatcher.Register(localBus)
 	if err != nil {
 		return err
 	}
 
 	// Aggregate
-	userAgg, err := userAggregate.New(dbConn, "nontest", bus)
+	userAgg, err := userAggregate.New(dbConn, "nontest", publisher)
+	if err != nil {
+		return err
+	}
+	webhookAgg, err := webhookAggregate.New(dbConn, "nontest")
+	if err != nil {
+		return err
+	}
+
+	// Outbox relay
+	outboxRelay, err := relay.New(dbConn, "nontest", publisher, options.relayOptions...)
+	if err != nil {
+		return err
+	}
+
+	// Expired idempotency keys cleanup
+	idempotencyCleaner, err := idempotency.New(dbConn, "nontest", options.idempotencyOptions...)
+	if err != nil {
+		return err
+	}
+
+	// Readiness checks. The gRPC services need the database, the user one also
+	// needs the events to be delivered
+	checker := health.New(options.healthOptions...)
+	checker.AddCheck("database", func(ctx context.Context) error {
+		return db.Ping(ctx, dbConn)
+	})
+	checker.AddCheck("migrations", func(ctx context.Context) error {
+		return db.CheckSchemaVersion(ctx, dbConn)
+	})
+	checker.AddCheck("relay", health.Running("relay", outboxRelay), userProto.UserService_ServiceDesc.ServiceName)
+	for _, bus := range runningBuses {
+		checker.AddCheck("bus", health.Running("bus", bus), userProto.UserService_ServiceDesc.ServiceName)
+	}
+
+	// Access tokens
+	tokens, err := auth.New(options.authOptions...)
 	if err != nil {
 		return err
 	}
 
 	// Service
-	userSvc := userService.New(userAgg)
+	userSvc := userService.New(userAgg, options.userServiceOptions...)
+	authSvc := authService.New(userAgg, tokens)
+	webhookSvc := webhookService.New(webhookAgg)
 
 	// Controller
 	httpCtrl := httpUserCtrl.NewController(userSvc)
-	grpcCtrl := grpcUserCtrl.NewController(userSvc)
+	httpWebhookController := httpWebhookCtrl.NewController(webhookSvc)
+	httpAuthController := httpAuthCtrl.NewController(authSvc)
+	grpcCtrl := grpcUserCtrl.NewController(userSvc, grpcUserCtrl.WithHub(userHub))
 
 	// HTTP Server
-	httpSrv, err := httpServer.New(httpServer.WithAddress(fmt.Sprintf(":%s", options.httpPort)))
+	httpSrv, err := httpServer.New(
+		httpServer.WithAddress(fmt.Sprintf(":%s", options.httpPort)),
+		httpServer.WithMetrics(appMetrics),
+	)
 	if err != nil {
 		return err
 	}
 	httpRouter := httpServer.InitHTTPRouter(httpSrv)
+	httpRouter.Use(middleware.Authenticate(tokens))
 	httpServer.InitUserRoutes(httpRouter, httpCtrl)
+	httpServer.InitWebhookRoutes(httpRouter, httpWebhookController)
+	httpServer.InitAuthRoutes(httpRouter, httpAuthController)
+	httpServer.InitHealthRoutes(httpRouter, checker)
 
 	// gRPC Server
-	grpcSrv := grpcServer.New(options.gRPCPort)
+	grpcRules := grpcServer.Rules()
+	grpcSrv, err := grpcServer.New(append([]grpcServer.Option{
+		grpcServer.WithAddress(fmt.Sprintf(":%s", options.gRPCPort)),
+		grpcServer.WithMetrics(appMetrics),
+		grpcServer.WithUnaryInterceptors(interceptor.UnaryAuth(tokens, grpcRules)),
+		grpcServer.WithStreamInterceptors(interceptor.StreamAuth(tokens, grpcRules)),
+	}, options.grpcOptions...)...)
+	if err != nil {
+		return err
+	}
 	userProto.RegisterUserServiceServer(grpcSrv.Server(), grpcCtrl)
+	authProto.RegisterAuthServiceServer(grpcSrv.Server(), grpcAuthCtrl.NewController(authSvc))
+	checker.Register(grpcSrv.Server(), userProto.UserService_ServiceDesc.ServiceName, authProto.AuthService_ServiceDesc.ServiceName)
+
+	// The hub goes first so the WatchUsers streams end before the gRPC server stops.
+	// The gRPC server is served by the single port one when enabled, it can not
+	// be stopped on its own then
+	servers := []server{userHub, httpSrv, grpcSrv}
+	if options.singlePort != "" {
+		singlePortSrv, err := mux.New(grpcSrv.Server(), httpRouter, mux.WithAddress(fmt.Sprintf(":%s", options.singlePort)))
+		if err != nil {
+			return err
+		}
+		servers = []server{userHub, singlePortSrv}
+	}
 
 	i := Instance{
-		timeout: 20,
-		servers: []server{httpSrv, grpcSrv},
+		timeout:    20,
+		servers:    append(append(servers, outboxRelay, webhookDispatcher, idempotencyCleaner, checker), backgroundServers...),
+		health:     checker,
+		drainDelay: options.drainDelay,
 	}
 
 	quitCh := make(chan os.Signal, 1)
@@ -124,11 +283,17 @@ func (s *Instance) Run(quit chan os.Signal) error {
 			s.mu.Lock()
 			s.state = serviceSTOPPING
 			s.mu.Unlock()
+			// Probes report not serving right away, so load balancers drain
+			// the traffic while the servers keep running for drainDelay
+			s.health.Drain()
 
 			log.Info().Msgf("Application: stopping in %.0fs...", s.Timeout().Seconds())
 			ctx, cancel := context.WithTimeout(context.Background(), s.Timeout())
 			defer cancel()
-			go s.stopServers(ctx)
+			go func() {
+				s.drain(ctx)
+				s.stopServers(ctx)
+			}()
 			<-ctx.Done()
 
 			s.mu.Lock()
@@ -150,6 +315,18 @@ func (s *Instance) runServers(errCh chan error) {
 	}
 }
 
+// drain waits for drainDelay, or until ctx is done
+func (s *Instance) drain(ctx context.Context) {
+	if s.drainDelay <= 0 {
+		return
+	}
+	log.Info().Msgf("Application: draining for %s...", s.drainDelay)
+	select {
+	case <-time.After(s.drainDelay):
+	case <-ctx.Done():
+	}
+}
+
 func (s *Instance) stopServers(ctx context.Context) {
 	for _, srv := range s.servers {
 		_ = srv.Stop(ctx)
@@ -1,6 +1,25 @@
 package app
 
-import "github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+import (
+	"time"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/health"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/idempotency"
+	userService "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub/relay"
+	grpcServer "github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/webhook"
+)
+
+const (
+	// PubSubLocal dispatches the events inside the process. It is the default
+	PubSubLocal = "local"
+	// PubSubPostgres dispatches the events to every instance with Postgres LISTEN/NOTIFY
+	PubSubPostgres = "postgres"
+)
 
 // Options holds the configuration of the instance
 type Options struct {
@@ -8,7 +27,28 @@ type Options struct {
 	// HTTP server configuration
 	httpPort string
 	// gRPC server configuration
-	gRPCPort string
+	gRPCPort    string
+	grpcOptions []grpcServer.Option
+	// Port serving gRPC, gRPC-Web and HTTP together, instead of httpPort and gRPCPort
+	singlePort string
+	// Access tokens configuration
+	authOptions []auth.Option
+	// PubSub implementation, PubSubLocal or PubSubPostgres
+	pubSub string
+	// Outbox relay configuration
+	relayOptions []relay.Option
+	// Webhook dispatcher configuration
+	webhookOptions []webhook.Option
+	// Idempotency keys cleaner configuration
+	idempotencyOptions []idempotency.Option
+	// User service configuration
+	userServiceOptions []userService.Option
+	// Readiness checks configuration
+	healthOptions []health.Option
+	// How long the instance keeps serving, reported as not ready, before stopping the servers
+	drainDelay time.Duration
+	// Where the finished spans go, dropped when nil
+	traceExporter tracing.Exporter
 }
 
 // Option type to add dependencies to the given Options
@@ -28,6 +68,28 @@ func WithGRPCPort(p string) Option {
 	}
 }
 
+// WithSinglePort serves gRPC (h2c), gRPC-Web and the HTTP API on a single port, picked by
+// content type, instead of on the HTTP and gRPC ports
+func WithSinglePort(p string) Option {
+	return func(o *Options) {
+		o.singlePort = p
+	}
+}
+
+// WithGRPCDefaultTimeout sets the deadline of the unary gRPC calls arriving without one, 0 disables it
+func WithGRPCDefaultTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.grpcOptions = append(o.grpcOptions, grpcServer.WithDefaultTimeout(d))
+	}
+}
+
+// WithPubSub selects the publisher/subscriber implementation: PubSubLocal or PubSubPostgres
+func WithPubSub(p string) Option {
+	return func(o *Options) {
+		o.pubSub = p
+	}
+}
+
 func (b *Options) appendDBOption(o db.Option) {
 	if b.dbOptions == nil {
 		b.dbOptions = []db.Option{}
@@ -84,3 +146,112 @@ func WithSSLMode(s string) Option {
 		o.appendDBOption(db.WithSSLMode(s))
 	}
 }
+
+// WithRelayPollInterval sets how often the outbox relay looks for unpublished events
+func WithRelayPollInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.relayOptions = append(o.relayOptions, relay.WithPollInterval(d))
+	}
+}
+
+// WithRelayBatchSize sets the max number of events the outbox relay handles per poll
+func WithRelayBatchSize(n int) Option {
+	return func(o *Options) {
+		o.relayOptions = append(o.relayOptions, relay.WithBatchSize(n))
+	}
+}
+
+// WithWebhookMaxAttempts sets how many times a webhook delivery is tried before giving up
+func WithWebhookMaxAttempts(n int) Option {
+	return func(o *Options) {
+		o.webhookOptions = append(o.webhookOptions, webhook.WithMaxAttempts(n))
+	}
+}
+
+// WithWebhookDisableAfter sets after how many consecutive failed deliveries a webhook is disabled
+func WithWebhookDisableAfter(n int) Option {
+	return func(o *Options) {
+		o.webhookOptions = append(o.webhookOptions, webhook.WithDisableAfter(n))
+	}
+}
+
+// WithIdempotencyKeyTTL sets how long idempotency keys are honored before being removed
+func WithIdempotencyKeyTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.idempotencyOptions = append(o.idempotencyOptions, idempotency.WithTTL(d))
+		o.userServiceOptions = append(o.userServiceOptions, userService.WithIdempotencyKeyTTL(d))
+	}
+}
+
+// WithIdempotencySecret sets the secret keying the hash of the requests stored with
+// their idempotency key. It must be the same on every instance
+func WithIdempotencySecret(secret string) Option {
+	return func(o *Options) {
+		o.userServiceOptions = append(o.userServiceOptions, userService.WithIdempotencySecret(secret))
+	}
+}
+
+// WithHealthCheckTimeout sets how long the readiness checks can take
+func WithHealthCheckTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.healthOptions = append(o.healthOptions, health.WithTimeout(d))
+	}
+}
+
+// WithDrainDelay sets how long the instance keeps serving once stopping, with /readyz and
+// the gRPC health reporting it as not serving, so load balancers stop sending traffic first
+func WithDrainDelay(d time.Duration) Option {
+	return func(o *Options) {
+		o.drainDelay = d
+	}
+}
+
+// WithTraceExporter sends the spans of the instance to the given exporter,
+// e.g. tracing.NewStdoutExporter(). Without one the spans are dropped
+func WithTraceExporter(e tracing.Exporter) Option {
+	return func(o *Options) {
+		o.traceExporter = e
+	}
+}
+
+// WithJWTSecret signs the access tokens with HS256 and the given secret
+func WithJWTSecret(secret string) Option {
+	return func(o *Options) {
+		o.authOptions = append(o.authOptions, auth.WithHMACSecret(secret))
+	}
+}
+
+// WithJWTEd25519KeyFile signs the access tokens with the Ed25519 key stored in the given PEM file
+func WithJWTEd25519KeyFile(path string) Option {
+	return func(o *Options) {
+		o.authOptions = append(o.authOptions, auth.WithEd25519KeyFile(path))
+	}
+}
+
+// WithJWTIssuer sets the iss and aud claims of the access tokens
+func WithJWTIssuer(iss string) Option {
+	return func(o *Options) {
+		o.authOptions = append(o.authOptions, auth.WithIssuer(iss), auth.WithAudience(iss))
+	}
+}
+
+// WithAccessTokenTTL sets how long the access tokens are valid
+func WithAccessTokenTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.authOptions = append(o.authOptions, auth.WithAccessTTL(d))
+	}
+}
+
+// WithRefreshTokenTTL sets how long the refresh tokens are valid
+func WithRefreshTokenTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.authOptions = append(o.authOptions, auth.WithRefreshTTL(d))
+	}
+}
+
+// WithServiceAccount lets a service get access tokens with the given scopes using its ID and secret
+func WithServiceAccount(id, secret string, scopes ...string) Option {
+	return func(o *Options) {
+		o.authOptions = append(o.authOptions, auth.WithServiceAccount(id, secret, scopes...))
+	}
+}
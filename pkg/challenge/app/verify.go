@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+	userAggregate "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/user"
+	projector "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/projector/user"
+)
+
+// VerifyProjections replays the event log of the given user, or of every user when userID
+// is empty, and writes to w every field that differs from the challenge.user row.
+// It returns how many users drifted
+func VerifyProjections(ctx context.Context, w io.Writer, userID string, opts ...Option) (int, error) {
+	options := Options{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	dbConn, err := db.New(options.dbOptions...)
+	if err != nil {
+		return 0, err
+	}
+	if sqlDB, err := dbConn.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	userAgg, err := userAggregate.New(dbConn, "nontest", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	drifted := 0
+	report := func(id uuid.UUID, drifts []projector.Drift) {
+		drifted++
+		for _, d := range drifts {
+			fmt.Fprintf(w, "%s\t%s\tprojected=%q\tstored=%q\n", id, d.Field, d.Projected, d.Stored)
+		}
+	}
+
+	if userID == "" {
+		err = userAgg.VerifyProjections(ctx, report)
+		return drifted, err
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, err
+	}
+	drifts, err := userAgg.VerifyProjection(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if len(drifts) > 0 {
+		report(id, drifts)
+	}
+	return drifted, nil
+}
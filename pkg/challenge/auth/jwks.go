@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+)
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// JWKS is the set of keys other services can use to verify the tokens offline
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys. It is empty when tokens are signed
+// with HS256, since a symmetric secret can not be published
+func (t *TokenIssuer) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+
+	pub, ok := t.verifyKey.(ed25519.PublicKey)
+	if !ok {
+		return set
+	}
+
+	set.Keys = append(set.Keys, JWK{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(pub),
+		KeyID:     t.keyID,
+		Algorithm: t.method.Alg(),
+		Use:       "sig",
+	})
+	return set
+}
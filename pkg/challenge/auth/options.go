@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"time"
+)
+
+// Retrieve the default options
+func defaultOptions() Options {
+	return Options{
+		Issuer:     "user_challenge_svc",
+		Audience:   "user_challenge_svc",
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: 30 * 24 * time.Hour,
+	}
+}
+
+type Options struct {
+	// Issuer is the iss claim of the issued tokens
+	Issuer string
+	// Audience is the aud claim of the issued tokens
+	Audience string
+	// AccessTTL is how long an access token is valid
+	AccessTTL time.Duration
+	// RefreshTTL is how long a refresh token is valid. Every rotation extends the session by RefreshTTL
+	RefreshTTL time.Duration
+	// HMACSecret signs the tokens with HS256. It can not be published in the JWKS
+	HMACSecret []byte
+	// Ed25519Key signs the tokens with EdDSA. Its public key is published in the JWKS
+	Ed25519Key ed25519.PrivateKey
+	// Ed25519KeyFile is a PEM (PKCS#8) file with the Ed25519 private key
+	Ed25519KeyFile string
+	// ServiceAccounts can get access tokens with their client credentials
+	ServiceAccounts []ServiceAccount
+}
+
+// WithIssuer sets the iss claim of the issued tokens
+func WithIssuer(iss string) Option {
+	return func(o *Options) {
+		o.Issuer = iss
+	}
+}
+
+// WithAudience sets the aud claim of the issued tokens
+func WithAudience(aud string) Option {
+	return func(o *Options) {
+		o.Audience = aud
+	}
+}
+
+// WithAccessTTL sets how long an access token is valid
+func WithAccessTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.AccessTTL = d
+	}
+}
+
+// WithRefreshTTL sets how long a refresh token is valid
+func WithRefreshTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.RefreshTTL = d
+	}
+}
+
+// WithHMACSecret signs the tokens with HS256 and the given secret
+func WithHMACSecret(secret string) Option {
+	return func(o *Options) {
+		o.HMACSecret = []byte(secret)
+	}
+}
+
+// WithEd25519Key signs the tokens with EdDSA and the given key
+func WithEd25519Key(key ed25519.PrivateKey) Option {
+	return func(o *Options) {
+		o.Ed25519Key = key
+	}
+}
+
+// WithEd25519KeyFile signs the tokens with EdDSA and the key stored in the given PEM file
+func WithEd25519KeyFile(path string) Option {
+	return func(o *Options) {
+		o.Ed25519KeyFile = path
+	}
+}
+
+// WithServiceAccount lets a service get access tokens with the given scopes using its ID and secret
+func WithServiceAccount(id, secret string, scopes ...string) Option {
+	return func(o *Options) {
+		o.ServiceAccounts = append(o.ServiceAccounts, ServiceAccount{
+			ID:     id,
+			Secret: secret,
+			Scopes: scopes,
+		})
+	}
+}
+
+type Option func(*Options)
@@ -0,0 +1,43 @@
+package auth
+
+import "errors"
+
+var (
+	// ErrUnauthenticated used when a protected action is called without a valid token
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrPermissionDenied used when the principal is not allowed to perform the action
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Policy decides who can perform an action. Admins can always perform it
+type Policy struct {
+	// Self lets users perform the action on themselves
+	Self bool
+	// Scope is what a service account needs to perform the action. Empty means services can not
+	Scope string
+}
+
+// AdminOnly is the policy of the actions only admins, and no service account, can perform
+var AdminOnly = Policy{}
+
+// Authorize checks if the principal can perform the action on the resources of the owner user.
+// ownerID is empty for actions not tied to an user
+func (p Policy) Authorize(principal *Principal, ownerID string) error {
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+
+	switch principal.Role {
+	case RoleAdmin:
+		return nil
+	case RoleUser:
+		if p.Self && ownerID != "" && principal.Subject == ownerID {
+			return nil
+		}
+	case RoleService:
+		if p.Scope != "" && principal.HasScope(p.Scope) {
+			return nil
+		}
+	}
+	return ErrPermissionDenied
+}
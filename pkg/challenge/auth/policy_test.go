@@ -0,0 +1,49 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+)
+
+func TestPolicy_Authorize(t *testing.T) {
+	policy := auth.Policy{Self: true, Scope: auth.ScopeUsersWrite}
+
+	user := &auth.Principal{Subject: "user-id", Role: auth.RoleUser}
+	admin := &auth.Principal{Subject: "admin-id", Role: auth.RoleAdmin}
+	service := &auth.Principal{Subject: "billing", Role: auth.RoleService, Scopes: []string{auth.ScopeUsersWrite}}
+	readOnly := &auth.Principal{Subject: "reporting", Role: auth.RoleService, Scopes: []string{auth.ScopeUsersRead}}
+
+	tests := []struct {
+		name      string
+		policy    auth.Policy
+		principal *auth.Principal
+		ownerID   string
+		expected  error
+	}{
+		{"anonymous callers are unauthenticated", policy, nil, "user-id", auth.ErrUnauthenticated},
+		{"users can act on themselves", policy, user, "user-id", nil},
+		{"users can not act on others", policy, user, "other-id", auth.ErrPermissionDenied},
+		{"users can not act on themselves when the policy says so", auth.Policy{Scope: auth.ScopeUsersRead}, user, "user-id", auth.ErrPermissionDenied},
+		{"users can not call actions without owner", policy, user, "", auth.ErrPermissionDenied},
+		{"admins can act on anyone", policy, admin, "other-id", nil},
+		{"admins can call admin only actions", auth.AdminOnly, admin, "", nil},
+		{"services need the scope", policy, service, "other-id", nil},
+		{"services without the scope are denied", policy, readOnly, "other-id", auth.ErrPermissionDenied},
+		{"services can not call admin only actions", auth.AdminOnly, service, "", auth.ErrPermissionDenied},
+		{"services are not users even with the same subject", policy, &auth.Principal{Subject: "user-id", Role: auth.RoleService}, "user-id", auth.ErrPermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Authorize(tt.principal, tt.ownerID)
+			if tt.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
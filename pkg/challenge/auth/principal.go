@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+const (
+	// RoleUser is the role of the regular users. They can only act on themselves
+	RoleUser = "user"
+	// RoleAdmin is the role of the users that can act on anyone
+	RoleAdmin = "admin"
+	// RoleService is the role of the service accounts. They can only do what their scopes allow
+	RoleService = "service"
+)
+
+// Scopes granted to the service accounts
+const (
+	ScopeUsersRead      = "users:read"
+	ScopeUsersWrite     = "users:write"
+	ScopeUsersDelete    = "users:delete"
+	ScopeSessionsRead   = "sessions:read"
+	ScopeSessionsWrite  = "sessions:write"
+	ScopeWebhooksManage = "webhooks:manage"
+)
+
+type principalKey struct{}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject is the user ID, or the service account ID for services
+	Subject  string
+	Nickname string
+	Role     string
+	Scopes   []string
+}
+
+// HasScope reports whether the principal was granted the given scope
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Principal returns who the token was issued to. Tokens without role belong to regular users
+func (c *Claims) Principal() *Principal {
+	p := &Principal{
+		Subject:  c.Subject,
+		Nickname: c.Nickname,
+		Role:     c.Role,
+		Scopes:   strings.Fields(c.Scope),
+	}
+	if p.Role == "" {
+		p.Role = RoleUser
+	}
+	return p
+}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal of the request, if it was authenticated
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMissingKey     = errors.New("a HMAC secret or an Ed25519 key must be configured")
+	ErrTooManyKeys    = errors.New("only one of HMAC secret or Ed25519 key can be configured")
+	ErrWeakHMACSecret = errors.New("HMAC secret must be at least 32 bytes")
+	ErrInvalidToken   = errors.New("token is not valid")
+	ErrMissingSubject = errors.New("token subject cannot be empty")
+	// ErrInvalidClient used for unknown service accounts and wrong secrets alike
+	ErrInvalidClient = errors.New("invalid client credentials")
+	// ErrWeakClientSecret used when a service account is configured with a short secret
+	ErrWeakClientSecret = errors.New("service account secret must be at least 32 bytes")
+)
+
+// minHMACSecretBytes is the min length of the HS256 secret, as long as the hash output
+const minHMACSecretBytes = 32
+
+// Claims of the access tokens
+type Claims struct {
+	jwt.RegisteredClaims
+	Nickname string `json:"nickname,omitempty"`
+	Role     string `json:"role,omitempty"`
+	// Scope is the space separated list of scopes of a service account
+	Scope string `json:"scope,omitempty"`
+}
+
+// ServiceAccount is a client of other services, authenticated with an ID and a secret
+type ServiceAccount struct {
+	ID     string
+	Secret string
+	Scopes []string
+}
+
+// TokenIssuer signs and verifies the access tokens
+type TokenIssuer struct {
+	opts      Options
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	keyID     string
+}
+
+// New returns a TokenIssuer using either HS256 or EdDSA, depending on the configured key
+func New(opts ...Option) (*TokenIssuer, error) {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Ed25519KeyFile != "" {
+		data, err := os.ReadFile(options.Ed25519KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read Ed25519 key: %w", err)
+		}
+		key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse Ed25519 key: %w", err)
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("could not parse Ed25519 key: unexpected key type %T", key)
+		}
+		options.Ed25519Key = edKey
+	}
+
+	for _, sa := range options.ServiceAccounts {
+		if len(sa.Secret) < minHMACSecretBytes {
+			return nil, fmt.Errorf("%w: %s", ErrWeakClientSecret, sa.ID)
+		}
+	}
+
+	t := &TokenIssuer{opts: options}
+	switch {
+	case len(options.HMACSecret) > 0 && options.Ed25519Key != nil:
+		return nil, ErrTooManyKeys
+	case options.Ed25519Key != nil:
+		pub := options.Ed25519Key.Public().(ed25519.PublicKey)
+		t.method = jwt.SigningMethodEdDSA
+		t.signKey = options.Ed25519Key
+		t.verifyKey = pub
+		t.keyID = thumbprint(pub)
+	case len(options.HMACSecret) >= minHMACSecretBytes:
+		t.method = jwt.SigningMethodHS256
+		t.signKey = options.HMACSecret
+		t.verifyKey = options.HMACSecret
+	case len(options.HMACSecret) > 0:
+		return nil, ErrWeakHMACSecret
+	default:
+		return nil, ErrMissingKey
+	}
+
+	return t, nil
+}
+
+// Issue returns a signed access token for the given regular user and when it expires
+func (t *TokenIssuer) Issue(subject, nickname string) (string, time.Time, error) {
+	return t.IssueFor(&Principal{Subject: subject, Nickname: nickname, Role: RoleUser})
+}
+
+// IssueFor returns a signed access token for the given principal and when it expires
+func (t *TokenIssuer) IssueFor(p *Principal) (string, time.Time, error) {
+	if p.Subject == "" {
+		return "", time.Time{}, ErrMissingSubject
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(t.opts.AccessTTL)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    t.opts.Issuer,
+			Subject:   p.Subject,
+			Audience:  jwt.ClaimStrings{t.opts.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Nickname: p.Nickname,
+		Role:     p.Role,
+		Scope:    strings.Join(p.Scopes, " "),
+	}
+
+	token := jwt.NewWithClaims(t.method, claims)
+	if t.keyID != "" {
+		token.Header["kid"] = t.keyID
+	}
+
+	signed, err := token.SignedString(t.signKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// Verify checks the signature, issuer, audience and expiration of a token and returns its claims
+func (t *TokenIssuer) Verify(token string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return t.verifyKey, nil
+	},
+		jwt.WithValidMethods([]string{t.method.Alg()}),
+		jwt.WithIssuer(t.opts.Issuer),
+		jwt.WithAudience(t.opts.Audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return &claims, nil
+}
+
+// AuthenticateService checks the credentials of a service account and returns it as principal
+func (t *TokenIssuer) AuthenticateService(id, secret string) (*Principal, error) {
+	for _, sa := range t.opts.ServiceAccounts {
+		if sa.ID != id {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(sa.Secret), []byte(secret)) != 1 {
+			return nil, ErrInvalidClient
+		}
+		return &Principal{Subject: sa.ID, Role: RoleService, Scopes: sa.Scopes}, nil
+	}
+	return nil, ErrInvalidClient
+}
+
+// AccessTTL returns how long the access tokens are valid
+func (t *TokenIssuer) AccessTTL() time.Duration {
+	return t.opts.AccessTTL
+}
+
+// RefreshTTL returns how long the refresh tokens are valid
+func (t *TokenIssuer) RefreshTTL() time.Duration {
+	return t.opts.RefreshTTL
+}
+
+// thumbprint returns the RFC 7638 thumbprint of an Ed25519 public key, used as key ID
+func thumbprint(pub ed25519.PublicKey) string {
+	canonical := fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, base64.RawURLEncoding.EncodeToString(pub))
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,172 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+)
+
+const hmacSecret = "0123456789abcdef0123456789abcdef"
+
+func TestTokenIssuer_HS256(t *testing.T) {
+	tokens, err := auth.New(auth.WithHMACSecret(hmacSecret))
+	assert.NoError(t, err)
+
+	t.Run("should issue and verify a token with standard claims", func(t *testing.T) {
+		token, expiresAt, err := tokens.Issue("user-id", "bandido")
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), expiresAt, time.Second)
+
+		claims, err := tokens.Verify(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-id", claims.Subject)
+		assert.Equal(t, "bandido", claims.Nickname)
+		assert.Equal(t, "user_challenge_svc", claims.Issuer)
+		assert.NotEmpty(t, claims.ID)
+		assert.NotNil(t, claims.IssuedAt)
+	})
+
+	t.Run("should not publish the HMAC secret", func(t *testing.T) {
+		assert.Empty(t, tokens.JWKS().Keys)
+	})
+
+	t.Run("should reject expired tokens", func(t *testing.T) {
+		expired, err := auth.New(auth.WithHMACSecret(hmacSecret), auth.WithAccessTTL(-time.Minute))
+		assert.NoError(t, err)
+		token, _, err := expired.Issue("user-id", "")
+		assert.NoError(t, err)
+
+		_, err = tokens.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+
+	t.Run("should reject tokens of another issuer or secret", func(t *testing.T) {
+		other, err := auth.New(auth.WithHMACSecret(hmacSecret), auth.WithIssuer("someone-else"))
+		assert.NoError(t, err)
+		token, _, err := other.Issue("user-id", "")
+		assert.NoError(t, err)
+		_, err = tokens.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+
+		other, err = auth.New(auth.WithHMACSecret("another-secret-another-secret-123"))
+		assert.NoError(t, err)
+		token, _, err = other.Issue("user-id", "")
+		assert.NoError(t, err)
+		_, err = tokens.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("should refuse a bad key configuration", func(t *testing.T) {
+		_, err := auth.New()
+		assert.ErrorIs(t, err, auth.ErrMissingKey)
+
+		_, err = auth.New(auth.WithHMACSecret("short"))
+		assert.ErrorIs(t, err, auth.ErrWeakHMACSecret)
+
+		_, priv, _ := ed25519.GenerateKey(rand.Reader)
+		_, err = auth.New(auth.WithHMACSecret(hmacSecret), auth.WithEd25519Key(priv))
+		assert.ErrorIs(t, err, auth.ErrTooManyKeys)
+	})
+}
+
+func TestTokenIssuer_Ed25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	assert.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	tokens, err := auth.New(auth.WithEd25519KeyFile(keyFile))
+	assert.NoError(t, err)
+
+	t.Run("should publish the public key and sign with it", func(t *testing.T) {
+		jwks := tokens.JWKS()
+		assert.Len(t, jwks.Keys, 1)
+		key := jwks.Keys[0]
+		assert.Equal(t, "OKP", key.KeyType)
+		assert.Equal(t, "Ed25519", key.Curve)
+		assert.Equal(t, "EdDSA", key.Algorithm)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(pub), key.X)
+
+		token, _, err := tokens.Issue("user-id", "bandido")
+		assert.NoError(t, err)
+
+		// What another service would do with the JWKS
+		parsed, err := jwt.Parse(token, func(tk *jwt.Token) (interface{}, error) {
+			assert.Equal(t, key.KeyID, tk.Header["kid"])
+			x, err := base64.RawURLEncoding.DecodeString(key.X)
+			return ed25519.PublicKey(x), err
+		}, jwt.WithValidMethods([]string{"EdDSA"}))
+		assert.NoError(t, err)
+		sub, _ := parsed.Claims.GetSubject()
+		assert.Equal(t, "user-id", sub)
+	})
+
+	t.Run("should fail with a missing key file", func(t *testing.T) {
+		_, err := auth.New(auth.WithEd25519KeyFile(filepath.Join(t.TempDir(), "missing.pem")))
+		assert.Error(t, err)
+	})
+}
+
+func TestTokenIssuer_ServiceAccounts(t *testing.T) {
+	const clientSecret = "billing-secret-0123456789abcdef01"
+
+	tokens, err := auth.New(
+		auth.WithHMACSecret(hmacSecret),
+		auth.WithServiceAccount("billing", clientSecret, auth.ScopeUsersRead, auth.ScopeUsersWrite),
+	)
+	assert.NoError(t, err)
+
+	t.Run("should issue a token with the scopes of the service account", func(t *testing.T) {
+		principal, err := tokens.AuthenticateService("billing", clientSecret)
+		assert.NoError(t, err)
+
+		token, _, err := tokens.IssueFor(principal)
+		assert.NoError(t, err)
+
+		claims, err := tokens.Verify(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "users:read users:write", claims.Scope)
+
+		verified := claims.Principal()
+		assert.Equal(t, auth.RoleService, verified.Role)
+		assert.True(t, verified.HasScope(auth.ScopeUsersWrite))
+		assert.False(t, verified.HasScope(auth.ScopeUsersDelete))
+	})
+
+	t.Run("should fail the same way for unknown clients and wrong secrets", func(t *testing.T) {
+		_, err := tokens.AuthenticateService("billing", "wrong")
+		assert.ErrorIs(t, err, auth.ErrInvalidClient)
+
+		_, err = tokens.AuthenticateService("unknown", clientSecret)
+		assert.ErrorIs(t, err, auth.ErrInvalidClient)
+	})
+
+	t.Run("should default tokens without role to regular users", func(t *testing.T) {
+		token, _, err := tokens.Issue("user-id", "bandido")
+		assert.NoError(t, err)
+
+		claims, err := tokens.Verify(token)
+		assert.NoError(t, err)
+		assert.Equal(t, auth.RoleUser, claims.Principal().Role)
+	})
+
+	t.Run("should refuse weak client secrets", func(t *testing.T) {
+		_, err := auth.New(auth.WithHMACSecret(hmacSecret), auth.WithServiceAccount("billing", "short"))
+		assert.ErrorIs(t, err, auth.ErrWeakClientSecret)
+	})
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SchemaVersion is the version of the latest migration in ./migrations.
+// Bump it with every new migration, instances are not ready until the database has it
+const SchemaVersion uint64 = 20250513091520
+
+var (
+	// ErrSchemaDirty used when the last migration failed half way
+	ErrSchemaDirty = errors.New("database schema is dirty")
+	// ErrSchemaOutdated used when the database misses migrations this version needs
+	ErrSchemaOutdated = errors.New("database schema is outdated")
+)
+
+// Ping checks that the database can be reached
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckSchemaVersion checks the version recorded by golang-migrate in schema_migrations.
+// Newer versions are accepted, so the instances still running during a rolling
+// deploy stay ready once the new one has migrated the database
+func CheckSchemaVersion(ctx context.Context, db *gorm.DB) error {
+	var migration struct {
+		Version uint64
+		Dirty   bool
+	}
+	err := db.WithContext(ctx).
+		Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").
+		Scan(&migration).Error
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case migration.Dirty:
+		return fmt.Errorf("%w at version %d", ErrSchemaDirty, migration.Version)
+	case migration.Version < SchemaVersion:
+		return fmt.Errorf("%w: version %d, expected %d", ErrSchemaOutdated, migration.Version, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	entries, err := os.ReadDir("../../../migrations")
+	assert.NoError(t, err)
+
+	var versions []uint64
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok || !strings.HasSuffix(e.Name(), ".up.sql") {
+			continue
+		}
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		assert.NoError(t, err, e.Name())
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	assert.NotEmpty(t, versions)
+	assert.Equal(t, versions[len(versions)-1], db.SchemaVersion, "db.SchemaVersion must be the latest migration")
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	tx, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+	ctx := context.Background()
+
+	t.Run("should reach the test database", func(t *testing.T) {
+		assert.NoError(t, db.Ping(ctx, tx))
+	})
+
+	t.Run("should accept the migrated test database", func(t *testing.T) {
+		assert.NoError(t, db.CheckSchemaVersion(ctx, tx))
+	})
+
+	t.Run("should accept newer versions", func(t *testing.T) {
+		assert.NoError(t, tx.Exec("UPDATE schema_migrations SET version = ?", db.SchemaVersion+1).Error)
+		assert.NoError(t, db.CheckSchemaVersion(ctx, tx))
+	})
+
+	t.Run("should fail with older versions", func(t *testing.T) {
+		assert.NoError(t, tx.Exec("UPDATE schema_migrations SET version = ?", db.SchemaVersion-1).Error)
+		assert.ErrorIs(t, db.CheckSchemaVersion(ctx, tx), db.ErrSchemaOutdated)
+	})
+
+	t.Run("should fail when dirty", func(t *testing.T) {
+		assert.NoError(t, tx.Exec("UPDATE schema_migrations SET version = ?, dirty = true", db.SchemaVersion).Error)
+		assert.ErrorIs(t, db.CheckSchemaVersion(ctx, tx), db.ErrSchemaDirty)
+	})
+}
@@ -0,0 +1,273 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	grpcHealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// StatusOK means the instance can take traffic
+	StatusOK = "ok"
+	// StatusUnavailable means a readiness check failed
+	StatusUnavailable = "unavailable"
+	// StatusStopping means the instance is shutting down and should not get new traffic
+	StatusStopping = "stopping"
+)
+
+// Check reports whether a dependency works, a nil error means healthy
+type Check func(ctx context.Context) error
+
+// Report is the result of the readiness checks, served by /readyz
+type Report struct {
+	Status string `json:"status"`
+	// Checks holds StatusOK or the error of every check
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// OK reports whether the instance can take traffic
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name     string
+	check    Check
+	services []string
+}
+
+// Checker runs the readiness checks of the instance for /readyz and keeps the status of
+// the grpc.health.v1.Health service up to date. Once Drain is called both report the
+// instance as not serving for good, so load balancers stop sending traffic before the
+// servers stop
+type Checker struct {
+	opts Options
+	grpc *grpcHealth.Server
+
+	mu       sync.RWMutex
+	checks   []namedCheck
+	services []string
+	draining atomic.Bool
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns a checker without checks, ready until some are added
+func New(opts ...Option) *Checker {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Checker{
+		opts:   options,
+		grpc:   grpcHealth.NewServer(),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// AddCheck adds a readiness check. services are the gRPC services depending on it,
+// none means all of them. /readyz and the overall gRPC status ("") use every check
+func (c *Checker) AddCheck(name string, check Check, services ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check, services: services})
+}
+
+// Register registers grpc.health.v1.Health on the server with a status per given service.
+// Every service is NOT_SERVING until Run checks them
+func (c *Checker) Register(s *grpc.Server, services ...string) {
+	c.mu.Lock()
+	c.services = append(c.services, services...)
+	c.mu.Unlock()
+
+	c.grpc.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	for _, service := range services {
+		c.grpc.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	healthpb.RegisterHealthServer(s, c.grpc)
+}
+
+// Ready runs every check concurrently, each of them bounded by the configured timeout
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.draining.Load() {
+		return Report{Status: StatusStopping}
+	}
+
+	results := c.run(ctx)
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(results))}
+	for name, err := range results {
+		report.Checks[name] = StatusOK
+		if err != nil {
+			report.Status = StatusUnavailable
+			report.Checks[name] = err.Error()
+		}
+	}
+	return report
+}
+
+// Drain reports the instance as not serving from now on. It can be called on a nil *Checker
+func (c *Checker) Drain() {
+	if c == nil || c.draining.Swap(true) {
+		return
+	}
+	log.Info().Msg("Health: draining, probes report not serving")
+	c.grpc.Shutdown()
+}
+
+// LiveHandler serves /livez. It only tells the process is up and able to answer, a
+// failing dependency must not get it restarted
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, Report{Status: StatusOK})
+	})
+}
+
+// ReadyHandler serves /readyz: 200 when every check passes, 503 with the failing ones otherwise
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, c.Ready(r.Context()))
+	})
+}
+
+// Run refreshes the gRPC serving status until Stop is called.
+// This method will block the calling go routine
+func (c *Checker) Run() error {
+	defer close(c.done)
+	log.Info().Msgf("Health: checking every %s", c.opts.Interval)
+
+	c.refresh()
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-ticker.C:
+			c.refresh()
+		}
+	}
+}
+
+// Stop stops refreshing the gRPC serving status, which stays NOT_SERVING
+func (c *Checker) Stop(ctx context.Context) error {
+	c.Drain()
+	c.stopOnce.Do(c.cancel)
+
+	select {
+	case <-c.done:
+		log.Info().Msg("Health: stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refresh sets the gRPC status of every service from the checks it depends on.
+// A drained checker ignores it, the health server keeps everything NOT_SERVING
+func (c *Checker) refresh() {
+	if c.draining.Load() {
+		return
+	}
+
+	results := c.run(c.ctx)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	c.grpc.SetServingStatus("", servingStatus(results, nil))
+	for _, service := range c.services {
+		var names []string
+		for _, nc := range c.checks {
+			if len(nc.services) == 0 || slices.Contains(nc.services, service) {
+				names = append(names, nc.name)
+			}
+		}
+		c.grpc.SetServingStatus(service, servingStatus(results, names))
+	}
+
+	for _, nc := range c.checks {
+		if err := results[nc.name]; err != nil {
+			log.Error().Err(err).Str("check", nc.name).Msg("Health: readiness check failed")
+		}
+	}
+}
+
+// run runs every check concurrently and returns their errors by name
+func (c *Checker) run(ctx context.Context) map[string]error {
+	c.mu.RLock()
+	checks := slices.Clone(c.checks)
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]error, len(checks))
+	)
+	for _, nc := range checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+			err := nc.check(ctx)
+			if err == nil && ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			mu.Lock()
+			results[nc.name] = err
+			mu.Unlock()
+		}(nc)
+	}
+	wg.Wait()
+	return results
+}
+
+// servingStatus is SERVING when none of the named checks failed, nil names means all of them
+func servingStatus(results map[string]error, names []string) healthpb.HealthCheckResponse_ServingStatus {
+	for name, err := range results {
+		if err != nil && (names == nil || slices.Contains(names, name)) {
+			return healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if report.OK() {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Error().Err(err).Str("health", "writeReport").Msg("failed to write the health report")
+	}
+}
+
+// Running turns a component reporting whether it runs, such as the outbox relay, into a Check
+func Running(name string, r interface{ Running() bool }) Check {
+	return func(context.Context) error {
+		if !r.Running() {
+			return errors.New(name + " is not running")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,158 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/health"
+)
+
+const (
+	userService = "user.UserService"
+	authService = "auth.AuthService"
+)
+
+type fakeComponent struct {
+	running atomic.Bool
+}
+
+func (f *fakeComponent) Running() bool {
+	return f.running.Load()
+}
+
+func get(t *testing.T, h http.Handler) (int, health.Report) {
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	var report health.Report
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+	return res.StatusCode, report
+}
+
+func TestChecker_HTTP(t *testing.T) {
+	relay := &fakeComponent{}
+	checker := health.New(health.WithTimeout(50 * time.Millisecond))
+	checker.AddCheck("database", func(context.Context) error { return nil })
+	checker.AddCheck("relay", health.Running("relay", relay))
+
+	t.Run("should always be live", func(t *testing.T) {
+		code, report := get(t, checker.LiveHandler())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusOK, report.Status)
+	})
+
+	t.Run("should not be ready while a check fails", func(t *testing.T) {
+		code, report := get(t, checker.ReadyHandler())
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusUnavailable, report.Status)
+		assert.Equal(t, map[string]string{"database": health.StatusOK, "relay": "relay is not running"}, report.Checks)
+	})
+
+	t.Run("should be ready when every check passes", func(t *testing.T) {
+		relay.running.Store(true)
+		code, report := get(t, checker.ReadyHandler())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusOK, report.Status)
+	})
+
+	t.Run("should fail the checks running longer than the timeout", func(t *testing.T) {
+		slow := health.New(health.WithTimeout(10 * time.Millisecond))
+		slow.AddCheck("database", func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		report := slow.Ready(context.Background())
+		assert.False(t, report.OK())
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"])
+	})
+
+	t.Run("should not be ready once draining but stay live", func(t *testing.T) {
+		checker.Drain()
+		code, report := get(t, checker.ReadyHandler())
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusStopping, report.Status)
+
+		code, _ = get(t, checker.LiveHandler())
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
+
+func TestChecker_GRPC(t *testing.T) {
+	relay := &fakeComponent{}
+	checker := health.New(health.WithInterval(10 * time.Millisecond))
+	checker.AddCheck("database", func(context.Context) error { return nil })
+	checker.AddCheck("relay", health.Running("relay", relay), userService)
+
+	srv := grpc.NewServer()
+	checker.Register(srv, userService, authService)
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	status := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return healthpb.HealthCheckResponse_UNKNOWN
+		}
+		return res.GetStatus()
+	}
+
+	t.Run("should not serve before the first check", func(t *testing.T) {
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(""))
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(authService))
+	})
+
+	go func() { _ = checker.Run() }()
+	defer func() { _ = checker.Stop(context.Background()) }()
+
+	t.Run("should only fail the services depending on the failing check", func(t *testing.T) {
+		assert.Eventually(t, func() bool {
+			return status(authService) == healthpb.HealthCheckResponse_SERVING
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(userService))
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(""))
+	})
+
+	t.Run("should serve once the checks pass", func(t *testing.T) {
+		relay.running.Store(true)
+		assert.Eventually(t, func() bool {
+			return status("") == healthpb.HealthCheckResponse_SERVING &&
+				status(userService) == healthpb.HealthCheckResponse_SERVING
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should stop serving for good once draining", func(t *testing.T) {
+		checker.Drain()
+		for _, service := range []string{"", userService, authService} {
+			assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(service), service)
+		}
+
+		// Refreshes after draining do not bring it back
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(""))
+	})
+}
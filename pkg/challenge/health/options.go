@@ -0,0 +1,34 @@
+package health
+
+import "time"
+
+// Retrieve the default options
+func defaultOptions() Options {
+	return Options{
+		Timeout:  2 * time.Second,
+		Interval: 5 * time.Second,
+	}
+}
+
+type Options struct {
+	// Timeout bounds every run of the readiness checks
+	Timeout time.Duration
+	// Interval is how often the gRPC serving status is refreshed
+	Interval time.Duration
+}
+
+// WithTimeout sets how long the readiness checks can take
+func WithTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// WithInterval sets how often the gRPC serving status is refreshed
+func WithInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = d
+	}
+}
+
+type Option func(*Options)
@@ -12,19 +12,9 @@ type Teardown func()
 
 // NewTestDB creates a test transaction and teardown logic for cleanup
 func NewTestDB() (*gorm.DB, Teardown, error) {
-	connStr := fmt.Sprintf(
-		"host=%s port=%s dbname=%s user=%s password=%s sslmode=%s",
-		"127.0.0.1",
-		"5435",
-		"user_challenge_svc",
-		"user_challenge_svc",
-		"user_challenge_svc",
-		"prefer",
-	)
-
-	db, err := gorm.Open(postgres.Open(connStr), &gorm.Config{})
+	db, err := openTestDB()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to open db connection: %w", err)
+		return nil, nil, err
 	}
 
 	tx := db.Begin()
@@ -40,3 +30,37 @@ func NewTestDB() (*gorm.DB, Teardown, error) {
 
 	return tx, teardown, nil
 }
+
+// NewTestConn creates a test connection without a transaction, for code that needs
+// its changes committed (e.g. LISTEN/NOTIFY). Callers must clean up what they write
+func NewTestConn() (*gorm.DB, Teardown, error) {
+	db, err := openTestDB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	teardown := func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	}
+
+	return db, teardown, nil
+}
+
+func openTestDB() (*gorm.DB, error) {
+	connStr := fmt.Sprintf(
+		"host=%s port=%s dbname=%s user=%s password=%s sslmode=%s",
+		"127.0.0.1",
+		"5435",
+		"user_challenge_svc",
+		"user_challenge_svc",
+		"user_challenge_svc",
+		"prefer",
+	)
+
+	db, err := gorm.Open(postgres.Open(connStr), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db connection: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,31 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+)
+
+// ScrapeMetrics serves the metrics on a test server and returns what a Prometheus scrape gets
+func ScrapeMetrics(m *metrics.Metrics) (string, error) {
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("scrape failed with status %d: %s", res.StatusCode, body)
+	}
+	return string(body), nil
+}
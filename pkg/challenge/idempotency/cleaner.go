@@ -0,0 +1,104 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	dbInstance "github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/env"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+const (
+	// ErrMissingDB used when DB is nil
+	ErrMissingDB = "Cleaner is missing DB connection"
+	// ErrMissingTestEnv when test env is missing
+	ErrMissingTestEnv = "DB connection can only be a TX when ENV == env.Test"
+)
+
+// Cleaner removes the idempotency keys of challenge.idempotency_key once they expire
+type Cleaner struct {
+	db   *gorm.DB
+	opts Options
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns a new idempotency keys cleaner
+func New(db *gorm.DB, e string, opts ...Option) (*Cleaner, error) {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
+	switch {
+	case db == nil:
+		return nil, errors.New(ErrMissingDB)
+	case dbInstance.IsTransaction(db) && !env.IsTest(e):
+		return nil, errors.New(ErrMissingTestEnv)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Cleaner{
+		db:     db,
+		opts:   options,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Run removes the expired keys every interval until Stop is called.
+// This method will block the calling go routine
+func (c *Cleaner) Run() error {
+	defer close(c.done)
+	log.Info().Msgf("Idempotency cleaner: removing keys older than %s every %s", c.opts.TTL, c.opts.Interval)
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := c.Process(c.ctx); err != nil {
+				log.Error().Err(err).Msg("Idempotency cleaner: could not remove expired keys")
+			}
+		}
+	}
+}
+
+// Stop stops the cleaner and waits for the removal in flight to finish
+func (c *Cleaner) Stop(ctx context.Context) error {
+	log.Info().Msg("Idempotency cleaner: stopping...")
+	c.stopOnce.Do(c.cancel)
+
+	select {
+	case <-c.done:
+		log.Info().Msg("Idempotency cleaner: stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Process removes the expired keys and returns how many were removed
+func (c *Cleaner) Process(ctx context.Context) (int64, error) {
+	deleted, err := repo.DeleteIdempotencyKeysBefore(time.Now().Add(-c.opts.TTL), c.db.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Info().Int64("deleted", deleted).Msg("Idempotency cleaner: expired keys removed")
+	}
+	return deleted, nil
+}
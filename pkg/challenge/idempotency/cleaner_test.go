@@ -0,0 +1,49 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/idempotency"
+	entity "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
+)
+
+func TestCleaner_Process(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	c, err := idempotency.New(db, "test", idempotency.WithTTL(time.Hour))
+	assert.NoError(t, err)
+
+	now := time.Now()
+	keys := []entity.Entity{
+		{Scope: entity.ScopeCreateUser, Key: "expired", RequestHash: "hash", CreatedAt: now.Add(-2 * time.Hour)},
+		{Scope: entity.ScopeCreateUser, Key: "fresh", RequestHash: "hash", CreatedAt: now},
+	}
+	for _, k := range keys {
+		assert.NoError(t, db.Create(&k).Error)
+	}
+
+	t.Run("should remove the expired keys only", func(t *testing.T) {
+		deleted, err := c.Process(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		var remaining []entity.Entity
+		assert.NoError(t, db.Where("key IN ?", []string{"expired", "fresh"}).Find(&remaining).Error)
+		assert.Len(t, remaining, 1)
+		assert.Equal(t, "fresh", remaining[0].Key)
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should fail without DB", func(t *testing.T) {
+		_, err := idempotency.New(nil, "test")
+		assert.EqualError(t, err, idempotency.ErrMissingDB)
+	})
+}
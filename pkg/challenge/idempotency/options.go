@@ -0,0 +1,34 @@
+package idempotency
+
+import "time"
+
+// Retrieve the default options
+func defaultOptions() Options {
+	return Options{
+		TTL:      24 * time.Hour,
+		Interval: time.Hour,
+	}
+}
+
+type Options struct {
+	// TTL is how long an idempotency key is kept. Retries after it are handled as new requests
+	TTL time.Duration
+	// Interval is how often the expired keys are removed
+	Interval time.Duration
+}
+
+// WithTTL sets how long idempotency keys are kept
+func WithTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = d
+	}
+}
+
+// WithInterval sets how often the expired keys are removed
+func WithInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = d
+	}
+}
+
+type Option func(*Options)
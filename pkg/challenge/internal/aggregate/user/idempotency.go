@@ -0,0 +1,82 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+// ErrMissingIdempotentResponse used when a claimed idempotency key has no response stored
+var ErrMissingIdempotentResponse = errors.New("idempotency key has no response stored")
+
+// CreateIdempotent creates a new user like Create and stores it under the given idempotency
+// key in the same transaction. Retrying with the same key and request returns the user as it
+// was first created, without storing or emitting anything. Reusing the key with a different
+// request fails with idempotency.ErrKeyReused. Keys older than ttl are used as new ones
+func (a aggregate) CreateIdempotent(ctx context.Context, u *user.Entity, key, requestHash string, ttl time.Duration) (*user.Entity, error) {
+	if err := idempotency.ValidKey(key); err != nil {
+		return nil, err
+	}
+	if err := u.Valid(); err != nil {
+		return nil, err
+	}
+
+	tx := a.begin(ctx)
+	defer a.rollback(tx)
+
+	claim, claimed, err := repo.ClaimIdempotencyKey(&idempotency.Entity{
+		Scope:       idempotency.ScopeCreateUser,
+		Key:         key,
+		RequestHash: requestHash,
+	}, ttl, tx)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return replayCreate(claim, requestHash)
+	}
+
+	res, e, payload, err := a.create(ctx, tx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// The password hash is not kept with the response
+	snapshot := *res
+	snapshot.Password = ""
+	response, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.SaveIdempotencyResponse(claim, response, tx); err != nil {
+		return nil, err
+	}
+
+	if err := a.commit(tx); err != nil {
+		return nil, err
+	}
+
+	a.publish(ctx, e, payload)
+	return res, nil
+}
+
+// replayCreate returns the user stored under an already used idempotency key
+func replayCreate(claim *idempotency.Entity, requestHash string) (*user.Entity, error) {
+	if !claim.Matches(requestHash) {
+		return nil, idempotency.ErrKeyReused
+	}
+	if len(claim.Response) == 0 {
+		return nil, ErrMissingIdempotentResponse
+	}
+
+	var u user.Entity
+	if err := json.Unmarshal(claim.Response, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
@@ -0,0 +1,80 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	agg "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	eventUser "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+)
+
+func TestUserAggregate_CreateIdempotent(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPublisher := mocks.NewMockPublisher(ctrl)
+	aggregate, err := agg.New(db, "test", mockPublisher)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	newInput := func() *user.Entity {
+		return &user.Entity{
+			FirstName: "Rick",
+			LastName:  "Sanchez",
+			Nickname:  "picklerick",
+			Password:  "wubbalubba",
+			Email:     "rick@citadel.com",
+			Country:   "US",
+		}
+	}
+
+	mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), eventUser.UserCreated, gomock.Any()).Return(nil).Times(2)
+
+	created, err := aggregate.CreateIdempotent(ctx, newInput(), "key-1", "hash-1", time.Hour)
+	assert.NoError(t, err)
+
+	t.Run("should return the first response when retried with the same key", func(t *testing.T) {
+		replayed, err := aggregate.CreateIdempotent(ctx, newInput(), "key-1", "hash-1", time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, created.ID, replayed.ID)
+		assert.Equal(t, created.Email, replayed.Email)
+		assert.Equal(t, created.Version, replayed.Version)
+		assert.Empty(t, replayed.Password)
+
+		var count int64
+		assert.NoError(t, db.Model(&eventUser.User{}).Where("user_id = ?", created.ID).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("should fail when the key is reused with a different request", func(t *testing.T) {
+		_, err := aggregate.CreateIdempotent(ctx, newInput(), "key-1", "hash-2", time.Hour)
+		assert.ErrorIs(t, err, idempotency.ErrKeyReused)
+	})
+
+	t.Run("should create a new user when the key is expired", func(t *testing.T) {
+		in := newInput()
+		in.Nickname = "morty"
+		in.Email = "morty@citadel.com"
+		res, err := aggregate.CreateIdempotent(ctx, in, "key-1", "hash-2", 0)
+		assert.NoError(t, err)
+		assert.NotEqual(t, created.ID, res.ID)
+		assert.Equal(t, "morty", res.Nickname)
+	})
+
+	t.Run("should fail when the key is not valid", func(t *testing.T) {
+		_, err := aggregate.CreateIdempotent(ctx, newInput(), "", "hash-1", time.Hour)
+		assert.ErrorIs(t, err, idempotency.ErrInvalidKey)
+	})
+}
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+const (
+	// MaxNicknameSuggestions is how many alternatives are suggested for a taken nickname
+	MaxNicknameSuggestions = 5
+	// nicknameCandidates is how many alternatives are checked against the DB at once
+	nicknameCandidates = 10
+	// nicknameSuggestionRounds bounds the lookups when most candidates are taken too
+	nicknameSuggestionRounds = 3
+)
+
+// NicknameAvailability tells whether a nickname is free, ignoring case. When it is
+// taken, up to MaxNicknameSuggestions free alternatives are returned. They are not
+// reserved, creating the user may still conflict
+func (a aggregate) NicknameAvailability(ctx context.Context, nickname string) (bool, []string, error) {
+	if err := user.ValidNickname(nickname); err != nil {
+		return false, nil, err
+	}
+	nickname = strings.TrimSpace(nickname)
+
+	taken, err := repo.TakenNicknames([]string{nickname}, a.db(ctx))
+	if err != nil {
+		return false, nil, err
+	}
+	if len(taken) == 0 {
+		return true, nil, nil
+	}
+
+	suggestions := make([]string, 0, MaxNicknameSuggestions)
+	for round := 0; round < nicknameSuggestionRounds && len(suggestions) < MaxNicknameSuggestions; round++ {
+		candidates := user.NicknameCandidates(nickname, nicknameCandidates)
+		taken, err := repo.TakenNicknames(candidates, a.db(ctx))
+		if err != nil {
+			return false, nil, err
+		}
+
+		for _, c := range candidates {
+			normalized := user.NormalizeNickname(c)
+			if slices.Contains(taken, normalized) || slices.ContainsFunc(suggestions, func(s string) bool {
+				return user.NormalizeNickname(s) == normalized
+			}) {
+				continue
+			}
+			suggestions = append(suggestions, c)
+			if len(suggestions) == MaxNicknameSuggestions {
+				break
+			}
+		}
+	}
+
+	return false, suggestions, nil
+}
@@ -0,0 +1,133 @@
+package user_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	agg "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+)
+
+// newNicknameAggregate returns an aggregate on its own test DB with the users "Rush" and "eco".
+// A conflict aborts the test transaction, so every test expecting one needs a new DB
+func newNicknameAggregate(t *testing.T) (agg.Aggregate, *user.Entity, *user.Entity, func()) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	mockPublisher := mocks.NewMockPublisher(ctrl)
+	mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	aggregate, err := agg.New(db, "test", mockPublisher)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	rush, err := aggregate.Create(ctx, &user.Entity{
+		FirstName: "Nacho",
+		LastName:  "Calcagno",
+		Nickname:  "Rush",
+		Password:  "123123123",
+		Email:     "rush@gmail.com",
+		Country:   "VE",
+	})
+	assert.NoError(t, err)
+	eco, err := aggregate.Create(ctx, &user.Entity{
+		FirstName: "Juan",
+		LastName:  "Calcagno",
+		Nickname:  "eco",
+		Password:  "123123123",
+		Email:     "eco@gmail.com",
+		Country:   "ES",
+	})
+	assert.NoError(t, err)
+
+	return aggregate, rush, eco, teardown
+}
+
+func TestUserAggregate_CreateTakenNickname(t *testing.T) {
+	aggregate, _, _, teardown := newNicknameAggregate(t)
+	defer teardown()
+
+	_, err := aggregate.Create(context.Background(), &user.Entity{
+		FirstName: "Other",
+		LastName:  "Player",
+		Nickname:  "RUSH",
+		Password:  "123123123",
+		Email:     "other@gmail.com",
+		Country:   "UK",
+	})
+	assert.ErrorIs(t, err, domainerr.ErrConflict)
+
+	e, ok := domainerr.As(err)
+	assert.True(t, ok)
+	assert.Equal(t, "nickname", e.Field)
+}
+
+func TestUserAggregate_UpdateTakenNickname(t *testing.T) {
+	aggregate, rush, eco, teardown := newNicknameAggregate(t)
+	defer teardown()
+	ctx := context.Background()
+
+	t.Run("should let an user change the case of its own nickname", func(t *testing.T) {
+		nickname := "RUSH"
+		updated, err := aggregate.Update(ctx, rush.ID, model.UpdateUserInput{Nickname: &nickname}, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "RUSH", updated.Nickname)
+	})
+
+	t.Run("should fail to update to a taken nickname in another case", func(t *testing.T) {
+		nickname := "rush"
+		_, err := aggregate.Update(ctx, eco.ID, model.UpdateUserInput{Nickname: &nickname}, 0)
+		assert.ErrorIs(t, err, domainerr.ErrConflict)
+
+		e, ok := domainerr.As(err)
+		assert.True(t, ok)
+		assert.Equal(t, "nickname", e.Field)
+	})
+}
+
+func TestUserAggregate_NicknameAvailability(t *testing.T) {
+	aggregate, _, eco, teardown := newNicknameAggregate(t)
+	defer teardown()
+	ctx := context.Background()
+
+	t.Run("should return a free nickname as available", func(t *testing.T) {
+		available, suggestions, err := aggregate.NicknameAvailability(ctx, "ropz")
+		assert.NoError(t, err)
+		assert.True(t, available)
+		assert.Empty(t, suggestions)
+	})
+
+	t.Run("should suggest free alternatives to a taken nickname", func(t *testing.T) {
+		available, suggestions, err := aggregate.NicknameAvailability(ctx, "rush")
+		assert.NoError(t, err)
+		assert.False(t, available)
+		assert.Len(t, suggestions, agg.MaxNicknameSuggestions)
+		for _, s := range suggestions {
+			assert.True(t, strings.HasPrefix(s, "rush"), s)
+			free, _, err := aggregate.NicknameAvailability(ctx, s)
+			assert.NoError(t, err)
+			assert.True(t, free, s)
+		}
+	})
+
+	t.Run("should release the nickname of deleted users", func(t *testing.T) {
+		assert.NoError(t, aggregate.Delete(ctx, eco.ID, 0))
+
+		available, _, err := aggregate.NicknameAvailability(ctx, "ECO")
+		assert.NoError(t, err)
+		assert.True(t, available)
+	})
+
+	t.Run("should fail with an empty nickname", func(t *testing.T) {
+		_, _, err := aggregate.NicknameAvailability(ctx, "  ")
+		assert.ErrorIs(t, err, user.ErrInvalidNickname)
+	})
+}
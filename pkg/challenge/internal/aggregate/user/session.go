@@ -0,0 +1,146 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+// CreateSession starts a new session for an user and returns its first refresh token
+func (a aggregate) CreateSession(ctx context.Context, userID uuid.UUID, meta session.Metadata, ttl time.Duration) (*session.Entity, error) {
+	s, err := session.New(userID, uuid.Nil, meta, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return repo.CreateSession(s, a.db(ctx))
+}
+
+// RotateSession exchanges a refresh token for a new one of the same session.
+// Presenting an already rotated token revokes the whole session, as it means the
+// token was leaked, and fails with session.ErrRefreshTokenReused
+func (a aggregate) RotateSession(ctx context.Context, token string, meta session.Metadata, ttl time.Duration) (*user.Entity, *session.Entity, error) {
+	tx := a.begin(ctx)
+	defer a.rollback(tx)
+
+	current, err := repo.GetSessionByTokenHashForUpdate(session.HashToken(token), tx)
+	if err != nil {
+		if errors.Is(err, repo.ErrRecordNotFound) {
+			return nil, nil, session.ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+	if current.RevokedAt != nil || current.Expired(time.Now()) {
+		return nil, nil, session.ErrInvalidRefreshToken
+	}
+
+	if current.UsedAt != nil {
+		if _, err := a.revokeSessions(ctx, tx, current.UserID, current.SessionID, session.RevokedOnReuse); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, session.ErrRefreshTokenReused
+	}
+
+	u, err := repo.Get(current.UserID, tx)
+	if err != nil {
+		if errors.Is(err, repo.ErrRecordNotFound) {
+			return nil, nil, session.ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	if err := repo.MarkSessionUsed(current.ID, tx); err != nil {
+		return nil, nil, err
+	}
+
+	next, err := session.New(current.UserID, current.SessionID, meta, ttl)
+	if err != nil {
+		return nil, nil, err
+	}
+	next.StartedAt = current.StartedAt
+	if _, err := repo.CreateSession(next, tx); err != nil {
+		return nil, nil, err
+	}
+
+	if err := a.commit(tx); err != nil {
+		return nil, nil, err
+	}
+	return u, next, nil
+}
+
+// ListSessions returns the active sessions of an user, newest first
+func (a aggregate) ListSessions(ctx context.Context, userID uuid.UUID) ([]session.Entity, error) {
+	return repo.FindActiveSessions(userID, a.db(ctx))
+}
+
+// RevokeSession revokes one session of an user and emits event
+func (a aggregate) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if sessionID == uuid.Nil {
+		return repo.ErrIDShouldNotBeEmpty
+	}
+
+	tx := a.begin(ctx)
+	defer a.rollback(tx)
+
+	ids, err := a.revokeSessions(ctx, tx, userID, sessionID, session.RevokedByUser)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return repo.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeSessions revokes every session of an user, emits event and returns how many were revoked
+func (a aggregate) RevokeSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	tx := a.begin(ctx)
+	defer a.rollback(tx)
+
+	ids, err := a.revokeSessions(ctx, tx, userID, uuid.Nil, session.RevokedByUser)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// revokeSessions revokes the sessions, then stores the USER_SESSION_REVOKED event, commits
+// and publishes it. Nothing is stored when there was nothing to revoke
+func (a aggregate) revokeSessions(ctx context.Context, tx *gorm.DB, userID, sessionID uuid.UUID, reason string) ([]uuid.UUID, error) {
+	ids, err := repo.RevokeSessions(userID, sessionID, reason, tx)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	payload := event.SessionRevokedPayload{
+		UserID:     userID.String(),
+		SessionIDs: make([]string, 0, len(ids)),
+		Reason:     reason,
+		TraceID:    tracectx.TraceIDFromContext(ctx),
+		SpanID:     tracing.SpanIDFromContext(ctx),
+	}
+	for _, id := range ids {
+		payload.SessionIDs = append(payload.SessionIDs, id.String())
+	}
+
+	e, err := a.saveEvent(ctx, tx, userID, event.UserSessionRevoked, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.commit(tx); err != nil {
+		return nil, err
+	}
+
+	a.publish(ctx, e, payload)
+	return ids, nil
+}
@@ -0,0 +1,132 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	agg "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	eventUser "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+func TestUserAggregate_RotateSession(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPublisher := mocks.NewMockPublisher(ctrl)
+	aggregate, err := agg.New(db, "test", mockPublisher)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), eventUser.UserCreated, gomock.Any()).Return(nil)
+	created, err := aggregate.Create(ctx, &user.Entity{
+		FirstName: "Ana",
+		LastName:  "Lopez",
+		Nickname:  "alopez",
+		Password:  "12345678",
+		Email:     "ana@correo.com",
+		Country:   "ES",
+	})
+	assert.NoError(t, err)
+
+	meta := session.Metadata{UserAgent: "curl/8.0", IPAddress: "127.0.0.1"}
+	first, err := aggregate.CreateSession(ctx, created.ID, meta, time.Hour)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, first.Token)
+
+	t.Run("should rotate the refresh token within the same session", func(t *testing.T) {
+		u, second, err := aggregate.RotateSession(ctx, first.Token, meta, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, created.ID, u.ID)
+		assert.Equal(t, first.SessionID, second.SessionID)
+		assert.NotEqual(t, first.Token, second.Token)
+
+		sessions, err := aggregate.ListSessions(ctx, created.ID)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, second.ID, sessions[0].ID)
+
+		t.Run("should revoke the session when a rotated token is reused", func(t *testing.T) {
+			mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), eventUser.UserSessionRevoked, gomock.Any()).Return(nil)
+
+			_, _, err := aggregate.RotateSession(ctx, first.Token, meta, time.Hour)
+			assert.ErrorIs(t, err, session.ErrRefreshTokenReused)
+
+			_, _, err = aggregate.RotateSession(ctx, second.Token, meta, time.Hour)
+			assert.ErrorIs(t, err, session.ErrInvalidRefreshToken)
+
+			var event eventUser.User
+			err = db.Where("user_id = ? AND event_type = ?", created.ID, eventUser.UserSessionRevoked).First(&event).Error
+			assert.NoError(t, err)
+		})
+	})
+
+	t.Run("should fail for unknown tokens", func(t *testing.T) {
+		_, _, err := aggregate.RotateSession(ctx, "unknown", meta, time.Hour)
+		assert.ErrorIs(t, err, session.ErrInvalidRefreshToken)
+	})
+}
+
+func TestUserAggregate_RevokeSessions(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPublisher := mocks.NewMockPublisher(ctrl)
+	aggregate, err := agg.New(db, "test", mockPublisher)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), eventUser.UserCreated, gomock.Any()).Return(nil)
+	created, err := aggregate.Create(ctx, &user.Entity{
+		FirstName: "Luis",
+		LastName:  "Diaz",
+		Nickname:  "ldiaz",
+		Password:  "12345678",
+		Email:     "luis@correo.com",
+		Country:   "CO",
+	})
+	assert.NoError(t, err)
+
+	first, err := aggregate.CreateSession(ctx, created.ID, session.Metadata{}, time.Hour)
+	assert.NoError(t, err)
+	_, err = aggregate.CreateSession(ctx, created.ID, session.Metadata{}, time.Hour)
+	assert.NoError(t, err)
+	_, err = aggregate.CreateSession(ctx, created.ID, session.Metadata{}, time.Hour)
+	assert.NoError(t, err)
+
+	mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), eventUser.UserSessionRevoked, gomock.Any()).Return(nil).Times(2)
+
+	err = aggregate.RevokeSession(ctx, created.ID, first.SessionID)
+	assert.NoError(t, err)
+
+	err = aggregate.RevokeSession(ctx, created.ID, first.SessionID)
+	assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+
+	revoked, err := aggregate.RevokeSessions(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, revoked)
+
+	revoked, err = aggregate.RevokeSessions(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, revoked)
+
+	sessions, err := aggregate.ListSessions(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -4,19 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 
 	dbInstance "github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/env"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	projector "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/projector/user"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
 )
 
-const traceID string = "trace_id"
+// verifyBatchSize is how many users are loaded at once when verifying projections
+const verifyBatchSize = 500
 
 type aggregate struct {
 	DB        *gorm.DB
@@ -26,9 +34,23 @@ type aggregate struct {
 
 type Aggregate interface {
 	Create(ctx context.Context, u *user.Entity) (*user.Entity, error)
-	Update(ctx context.Context, u *user.Entity) (*user.Entity, error)
-	Delete(ctx context.Context, id uuid.UUID) error
-	Find(ctx context.Context, country string, page, limit int) ([]user.Entity, error)
+	CreateIdempotent(ctx context.Context, u *user.Entity, key, requestHash string, ttl time.Duration) (*user.Entity, error)
+	Update(ctx context.Context, id uuid.UUID, in model.UpdateUserInput, expectedVersion int64) (*user.Entity, error)
+	Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error
+	Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (*user.Entity, error)
+	Find(ctx context.Context, filter model.UserFilter) ([]user.Entity, string, error)
+	Count(ctx context.Context, filter model.UserFilter) (int64, error)
+	Search(ctx context.Context, query string, limit int) ([]user.Match, error)
+	NicknameAvailability(ctx context.Context, nickname string) (bool, []string, error)
+	GetByLogin(ctx context.Context, login string) (*user.Entity, error)
+	ListEvents(ctx context.Context, filter model.UserEventFilter) ([]event.User, error)
+	Project(ctx context.Context, id uuid.UUID, asOf time.Time) (*user.Entity, error)
+	ListEventsAfter(ctx context.Context, eventID uuid.UUID, userIDs []uuid.UUID, limit int) ([]event.User, error)
+	CreateSession(ctx context.Context, userID uuid.UUID, meta session.Metadata, ttl time.Duration) (*session.Entity, error)
+	RotateSession(ctx context.Context, token string, meta session.Metadata, ttl time.Duration) (*user.Entity, *session.Entity, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]session.Entity, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeSessions(ctx context.Context, userID uuid.UUID) (int, error)
 }
 
 const (
@@ -61,15 +83,14 @@ func New(db *gorm.DB, e string, pub pubsub.Publisher) (aggregate, error) {
 
 // Create creates a new user and emits event after commit
 func (a aggregate) Create(ctx context.Context, u *user.Entity) (*user.Entity, error) {
-	tx := a.begin()
-	defer a.rollback(tx)
-
-	res, err := repo.Create(u, tx)
-	if err != nil {
+	if err := u.Valid(); err != nil {
 		return nil, err
 	}
 
-	eventID, err := a.saveEvent(ctx, tx, res.ID, event.UserCreated, res)
+	tx := a.begin(ctx)
+	defer a.rollback(tx)
+
+	res, e, payload, err := a.create(ctx, tx, u)
 	if err != nil {
 		return nil, err
 	}
@@ -78,34 +99,36 @@ func (a aggregate) Create(ctx context.Context, u *user.Entity) (*user.Entity, er
 		return nil, err
 	}
 
-	payload := event.CreatedPayload{
-		UserID:   res.ID.String(),
-		Email:    res.Email,
-		Nickname: res.Nickname,
-		TraceID:  traceIDFromContext(ctx),
-	}
-
-	_ = a.publisher.Publish(ctx, eventID.String(), event.UserCreated, payload)
+	a.publish(ctx, e, payload)
 	return res, nil
 }
 
-// Update only updates nickname and emits event
-func (a aggregate) Update(ctx context.Context, u *user.Entity) (*user.Entity, error) {
-	tx := a.begin()
+// Update changes the profile fields that are set in the input and emits an event listing
+// the changed fields. Missing and deleted users are not found, and users not at the expected
+// version (0 skips the check) fail with ErrVersionMismatch. Nothing is stored when no
+// field actually changes
+func (a aggregate) Update(ctx context.Context, id uuid.UUID, in model.UpdateUserInput, expectedVersion int64) (*user.Entity, error) {
+	tx := a.begin(ctx)
 	defer a.rollback(tx)
 
-	existing, err := repo.GetUserForUpdate(u.ID, tx)
+	existing, err := repo.GetUserForUpdate(id, tx)
 	if err != nil {
 		return nil, err
 	}
-	existing.Nickname = u.Nickname
+	if err := existing.CheckVersion(expectedVersion); err != nil {
+		return nil, err
+	}
 
-	updated, err := repo.Update(existing, tx)
-	if err != nil {
+	changes := applyUpdate(existing, in)
+	if len(changes) == 0 {
+		return existing, nil
+	}
+	if err := existing.Valid(); err != nil {
 		return nil, err
 	}
+	existing.Version++
 
-	eventID, err := a.saveEvent(ctx, tx, updated.ID, event.UserUpdated, updated)
+	updated, err := repo.Update(existing, tx)
 	if err != nil {
 		return nil, err
 	}
@@ -113,56 +136,178 @@ func (a aggregate) Update(ctx context.Context, u *user.Entity) (*user.Entity, er
 	payload := event.UpdatedPayload{
 		UserID:   updated.ID.String(),
 		Nickname: updated.Nickname,
-		TraceID:  traceIDFromContext(ctx),
+		Country:  updated.Country,
+		TraceID:  tracectx.TraceIDFromContext(ctx),
+		SpanID:   tracing.SpanIDFromContext(ctx),
+		Version:  updated.Version,
+		Changes:  changes,
+	}
+
+	e, err := a.saveEvent(ctx, tx, updated.ID, event.UserUpdated, payload)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := a.commit(tx); err != nil {
 		return nil, err
 	}
 
-	_ = a.publisher.Publish(ctx, eventID.String(), event.UserUpdated, payload)
+	a.publish(ctx, e, payload)
 	return updated, nil
 }
 
-// Delete performs a soft delete and emits event
-func (a aggregate) Delete(ctx context.Context, id uuid.UUID) error {
-	tx := a.begin()
+// Delete performs a soft delete and emits event. Missing and already deleted users
+// are not found and no event is stored, so repeating a delete changes nothing.
+// Users not at the expected version (0 skips the check) fail with ErrVersionMismatch
+func (a aggregate) Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
+	tx := a.begin(ctx)
 	defer a.rollback(tx)
 
-	eventID, err := a.saveEvent(ctx, tx, id, event.UserSoftDeleted, map[string]string{
-		"user_id": id.String(),
-	})
+	// Locks the row so concurrent deletes of the same user wait and then find it deleted
+	existing, err := repo.GetUserForUpdate(id, tx)
 	if err != nil {
 		return err
 	}
-
-	if err := repo.Delete(id, tx); err != nil {
+	if err := existing.CheckVersion(expectedVersion); err != nil {
 		return err
 	}
 
-	if err := a.commit(tx); err != nil {
+	if err := repo.Delete(id, tx); err != nil {
 		return err
 	}
 
 	payload := event.DeletedPayload{
 		UserID:  id.String(),
-		TraceID: traceIDFromContext(ctx),
+		Country: existing.Country,
+		TraceID: tracectx.TraceIDFromContext(ctx),
+		SpanID:  tracing.SpanIDFromContext(ctx),
+		Version: existing.Version + 1,
+	}
+	e, err := a.saveEvent(ctx, tx, id, event.UserSoftDeleted, payload)
+	if err != nil {
+		return err
+	}
+
+	if err := a.commit(tx); err != nil {
+		return err
 	}
 
-	_ = a.publisher.Publish(ctx, eventID.String(), event.UserSoftDeleted, payload)
+	a.publish(ctx, e, payload)
 	return nil
 }
 
-// Find returns a list of users with pagination and country filter
-func (a aggregate) Find(_ context.Context, country string, page, limit int) ([]user.Entity, error) {
-	return repo.Find(a.DB, country, page, limit)
+// Get returns an user by ID. Soft deleted users are only found with includeDeleted
+func (a aggregate) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (*user.Entity, error) {
+	if includeDeleted {
+		return repo.GetUnscoped(id, a.db(ctx))
+	}
+	return repo.Get(id, a.db(ctx))
+}
+
+// Find returns a page of users matching the filter and the cursor of the next page
+func (a aggregate) Find(ctx context.Context, filter model.UserFilter) ([]user.Entity, string, error) {
+	return repo.Find(a.db(ctx), filter)
+}
+
+// Count returns how many users match the filter
+func (a aggregate) Count(ctx context.Context, filter model.UserFilter) (int64, error) {
+	return repo.Count(a.db(ctx), filter)
+}
+
+// Search returns the not deleted users whose nickname or name are similar to the query, best first
+func (a aggregate) Search(ctx context.Context, query string, limit int) ([]user.Match, error) {
+	return repo.Search(a.db(ctx), query, limit)
+}
+
+// GetByLogin returns the not deleted user with the given email or nickname
+func (a aggregate) GetByLogin(ctx context.Context, login string) (*user.Entity, error) {
+	return repo.GetByLogin(login, a.db(ctx))
+}
+
+// ListEvents returns the stored events of an user
+func (a aggregate) ListEvents(ctx context.Context, filter model.UserEventFilter) ([]event.User, error) {
+	return repo.FindUserEvents(a.db(ctx), filter)
+}
+
+// ListEventsAfter returns the events stored after the given one, optionally only for some users
+func (a aggregate) ListEventsAfter(ctx context.Context, eventID uuid.UUID, userIDs []uuid.UUID, limit int) ([]event.User, error) {
+	return repo.FindEventsAfter(a.db(ctx), eventID, userIDs, limit)
+}
+
+// Project rebuilds the user state as of the given time by replaying its events.
+// Users that did not exist yet, or were already deleted, are not found
+func (a aggregate) Project(ctx context.Context, id uuid.UUID, asOf time.Time) (*user.Entity, error) {
+	events, err := repo.FindUserEventsUntil(a.db(ctx), id, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := projector.Project(events)
+	if err != nil {
+		return nil, err
+	}
+	if u.DeletedAt.Valid {
+		return nil, projector.ErrUserNotFound
+	}
+	return u, nil
+}
+
+// VerifyProjection replays the whole event log of an user and compares it with the stored row
+func (a aggregate) VerifyProjection(ctx context.Context, id uuid.UUID) ([]projector.Drift, error) {
+	stored, err := repo.GetUnscoped(id, a.db(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := repo.FindUserEventsUntil(a.db(ctx), id, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	projected, err := projector.Project(events)
+	if err != nil {
+		return []projector.Drift{{Field: "event_log", Projected: err.Error(), Stored: "row exists"}}, nil
+	}
+	return projector.Diff(projected, stored), nil
+}
+
+// VerifyProjections runs VerifyProjection for every user, including soft deleted ones,
+// and calls report for each user that drifted
+func (a aggregate) VerifyProjections(ctx context.Context, report func(id uuid.UUID, drifts []projector.Drift)) error {
+	after := uuid.Nil
+	for {
+		ids, err := repo.FindIDs(a.db(ctx), after, verifyBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		for _, id := range ids {
+			drifts, err := a.VerifyProjection(ctx, id)
+			if err != nil {
+				return err
+			}
+			if len(drifts) > 0 {
+				report(id, drifts)
+			}
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+// db returns the connection bound to ctx, so the statements are traced and
+// cancelled with the request
+func (a aggregate) db(ctx context.Context) *gorm.DB {
+	return a.DB.WithContext(ctx)
 }
 
-func (a aggregate) begin() *gorm.DB {
+func (a aggregate) begin(ctx context.Context) *gorm.DB {
 	if a.TestTx {
-		return a.DB
+		return a.db(ctx)
 	}
-	return a.DB.Begin()
+	return a.db(ctx).Begin()
 }
 
 func (a aggregate) commit(tx *gorm.DB) error {
@@ -178,30 +323,83 @@ func (a aggregate) rollback(tx *gorm.DB) {
 	}
 }
 
-func (a *aggregate) saveEvent(ctx context.Context, tx *gorm.DB, userID uuid.UUID, eventType string, payload interface{}) (uuid.UUID, error) {
-	if m, ok := payload.(map[string]string); ok {
-		if id, ok := ctx.Value(traceID).(string); ok {
-			m[traceID] = id
-		}
-		payload = m
-	}
-
+// saveEvent stores an event of the user with the trace ID of the request that caused it.
+// The creation time is set at the precision Postgres stores it, so publishing it here or
+// from the stored row gives the same value
+func (a *aggregate) saveEvent(ctx context.Context, tx *gorm.DB, userID uuid.UUID, eventType string, payload interface{}) (*event.User, error) {
 	data, err := json.Marshal(payload)
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
-	eventID := uuid.New()
-	event := event.User{
-		ID:        eventID,
+	e := &event.User{
+		ID:        uuid.New(),
 		UserID:    userID,
 		EventType: eventType,
 		Payload:   data,
+		TraceID:   tracectx.TraceIDFromContext(ctx),
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := tx.Create(e).Error; err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// publish delivers a committed event and flags it as published. Failures are only logged,
+// the outbox relay will pick the event up and retry it later
+func (a aggregate) publish(ctx context.Context, e *event.User, payload interface{}) {
+	eventID := e.ID.String()
+	if err := a.publisher.Publish(pubsub.WithEventTime(ctx, e.CreatedAt), eventID, e.EventType, payload); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("event_id", eventID).Msg("could not publish event, relay will retry")
+		return
+	}
+	if err := repo.MarkEventPublished(e.ID, a.db(ctx)); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("event_id", eventID).Msg("could not mark event as published")
+	}
+}
+
+// create stores a new user and its created event in the given transaction
+func (a aggregate) create(ctx context.Context, tx *gorm.DB, u *user.Entity) (*user.Entity, *event.User, event.CreatedPayload, error) {
+	res, err := repo.Create(u, tx)
+	if err != nil {
+		return nil, nil, event.CreatedPayload{}, err
+	}
+
+	payload := event.CreatedPayload{
+		UserID:    res.ID.String(),
+		FirstName: res.FirstName,
+		LastName:  res.LastName,
+		Email:     res.Email,
+		Nickname:  res.Nickname,
+		Country:   res.Country,
+		TraceID:   tracectx.TraceIDFromContext(ctx),
+		SpanID:    tracing.SpanIDFromContext(ctx),
+		Version:   res.Version,
 	}
-	return eventID, tx.Create(&event).Error
+
+	e, err := a.saveEvent(ctx, tx, res.ID, event.UserCreated, payload)
+	if err != nil {
+		return nil, nil, event.CreatedPayload{}, err
+	}
+	return res, e, payload, nil
 }
 
-func traceIDFromContext(ctx context.Context) string {
-	traceID, _ := ctx.Value(traceID).(string)
-	return traceID
+// applyUpdate sets the fields of the input on the user and returns the ones that changed
+func applyUpdate(u *user.Entity, in model.UpdateUserInput) []event.FieldChange {
+	var changes []event.FieldChange
+	set := func(field string, current *string, value *string) {
+		if value == nil || *value == *current {
+			return
+		}
+		changes = append(changes, event.FieldChange{Field: field, Old: *current, New: *value})
+		*current = *value
+	}
+
+	set("first_name", &u.FirstName, in.FirstName)
+	set("last_name", &u.LastName, in.LastName)
+	set("nickname", &u.Nickname, in.Nickname)
+	set("email", &u.Email, in.Email)
+	set("country", &u.Country, in.Country)
+	return changes
 }
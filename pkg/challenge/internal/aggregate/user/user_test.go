@@ -3,16 +3,24 @@ package user_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
 	agg "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
 	eventUser "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	projector "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/projector/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
 )
 
 func TestUserAggregate_Create(t *testing.T) {
@@ -27,7 +35,7 @@ func TestUserAggregate_Create(t *testing.T) {
 	aggregate, err := agg.New(db, "test", mockPublisher)
 	assert.NoError(t, err)
 
-	ctx := context.Background()
+	ctx := tracectx.WithTraceID(context.Background(), "checkout-123")
 	input := &user.Entity{
 		FirstName: "Nacho",
 		LastName:  "Calcagno",
@@ -51,6 +59,11 @@ func TestUserAggregate_Create(t *testing.T) {
 	err = json.Unmarshal(event.Payload, &payload)
 	assert.NoError(t, err)
 	assert.Equal(t, created.Email, payload.Email)
+	assert.Equal(t, "checkout-123", event.TraceID)
+
+	var createdPayload eventUser.CreatedPayload
+	assert.NoError(t, json.Unmarshal(event.Payload, &createdPayload))
+	assert.Equal(t, "checkout-123", createdPayload.TraceID)
 }
 
 func TestUserAggregate_Update(t *testing.T) {
@@ -79,16 +92,57 @@ func TestUserAggregate_Update(t *testing.T) {
 	created, err := agg.Create(ctx, user)
 	assert.NoError(t, err)
 
-	created.Nickname = "csgooo"
+	nickname, country := "csgooo", "UY"
 	mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), eventUser.UserUpdated, gomock.Any()).Return(nil)
 
-	updated, err := agg.Update(ctx, created)
+	updated, err := agg.Update(ctx, created.ID, model.UpdateUserInput{Nickname: &nickname, Country: &country}, 0)
 	assert.NoError(t, err)
 	assert.Equal(t, "csgooo", updated.Nickname)
+	assert.Equal(t, "UY", updated.Country)
+	assert.Equal(t, "Juan", updated.FirstName)
 
 	var event eventUser.User
 	err = db.Where("user_id = ? AND event_type = ?", updated.ID, eventUser.UserUpdated).First(&event).Error
 	assert.NoError(t, err)
+
+	var payload eventUser.UpdatedPayload
+	assert.NoError(t, json.Unmarshal(event.Payload, &payload))
+	assert.Equal(t, []eventUser.FieldChange{
+		{Field: "nickname", Old: "jperez", New: "csgooo"},
+		{Field: "country", Old: "AR", New: "UY"},
+	}, payload.Changes)
+
+	t.Run("should increment the version", func(t *testing.T) {
+		assert.Equal(t, int64(1), created.Version)
+		assert.Equal(t, int64(2), updated.Version)
+		assert.Equal(t, int64(2), payload.Version)
+	})
+
+	t.Run("should fail when the expected version does not match", func(t *testing.T) {
+		stale := "stale"
+		_, err := agg.Update(ctx, created.ID, model.UpdateUserInput{Nickname: &stale}, 1)
+		assert.ErrorIs(t, err, domainerr.ErrPreconditionFailed)
+	})
+
+	t.Run("should not emit events when nothing changes", func(t *testing.T) {
+		_, err := agg.Update(ctx, created.ID, model.UpdateUserInput{Nickname: &nickname}, 0)
+		assert.NoError(t, err)
+
+		var count int64
+		assert.NoError(t, db.Model(&eventUser.User{}).
+			Where("user_id = ? AND event_type = ?", created.ID, eventUser.UserUpdated).
+			Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("should validate the fields", func(t *testing.T) {
+		email := "not-an-email"
+		_, err := agg.Update(ctx, created.ID, model.UpdateUserInput{Email: &email}, 0)
+		assert.ErrorIs(t, err, domainerr.ErrValidation)
+		e, ok := domainerr.As(err)
+		assert.True(t, ok)
+		assert.Equal(t, "email", e.Field)
+	})
 }
 
 func TestUserAggregate_Delete(t *testing.T) {
@@ -119,10 +173,126 @@ func TestUserAggregate_Delete(t *testing.T) {
 
 	mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), eventUser.UserSoftDeleted, gomock.Any()).Return(nil)
 
-	err = agg.Delete(ctx, created.ID)
+	err = agg.Delete(ctx, created.ID, 0)
 	assert.NoError(t, err)
 
 	var event eventUser.User
 	err = db.Where("user_id = ? AND event_type = ?", created.ID, eventUser.UserSoftDeleted).First(&event).Error
 	assert.NoError(t, err)
+
+	t.Run("repeating the delete should not emit another event", func(t *testing.T) {
+		err := agg.Delete(ctx, created.ID, 0)
+		assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+
+		var count int64
+		assert.NoError(t, db.Model(&eventUser.User{}).
+			Where("user_id = ? AND event_type = ?", created.ID, eventUser.UserSoftDeleted).
+			Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("updating a deleted user should fail", func(t *testing.T) {
+		nickname := "ghost"
+		_, err := agg.Update(ctx, created.ID, model.UpdateUserInput{Nickname: &nickname}, 0)
+		assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+	})
+
+	t.Run("deleting a missing user should fail without events", func(t *testing.T) {
+		missingID := uuid.New()
+		err := agg.Delete(ctx, missingID, 0)
+		assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+
+		var count int64
+		assert.NoError(t, db.Model(&eventUser.User{}).Where("user_id = ?", missingID).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+}
+
+func TestUserAggregate_PublishFailure(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPublisher := mocks.NewMockPublisher(ctrl)
+	agg, err := agg.New(db, "test", mockPublisher)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	u := &user.Entity{
+		FirstName: "Juan",
+		LastName:  "calcagno",
+		Nickname:  "outbox",
+		Password:  "12345678",
+		Email:     "outbox@gmail.com",
+		Country:   "ES",
+	}
+
+	mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), eventUser.UserCreated, gomock.Any()).Return(errors.New("bus down"))
+
+	created, err := agg.Create(ctx, u)
+	assert.NoError(t, err, "user is committed even if publishing fails")
+
+	var event eventUser.User
+	err = db.Where("user_id = ? AND event_type = ?", created.ID, eventUser.UserCreated).First(&event).Error
+	assert.NoError(t, err)
+	assert.False(t, event.Published, "event must stay in the outbox for the relay")
+}
+
+func TestUserAggregate_Project(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPublisher := mocks.NewMockPublisher(ctrl)
+	mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	agg, err := agg.New(db, "test", mockPublisher)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	beforeCreate := time.Now().Add(-time.Minute)
+	created, err := agg.Create(ctx, &user.Entity{
+		FirstName: "Time",
+		LastName:  "Traveler",
+		Nickname:  "before",
+		Password:  "12345678",
+		Email:     "timetraveler@gmail.com",
+		Country:   "UK",
+	})
+	assert.NoError(t, err)
+
+	afterCreate := time.Now()
+	nickname, lastName := "after", "Returner"
+	_, err = agg.Update(ctx, created.ID, model.UpdateUserInput{Nickname: &nickname, LastName: &lastName}, 0)
+	assert.NoError(t, err)
+
+	t.Run("should return the user as it was before the update", func(t *testing.T) {
+		u, err := agg.Project(ctx, created.ID, afterCreate)
+		assert.NoError(t, err)
+		assert.Equal(t, "before", u.Nickname)
+		assert.Equal(t, "Time", u.FirstName)
+	})
+
+	t.Run("should return the current user", func(t *testing.T) {
+		u, err := agg.Project(ctx, created.ID, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, "after", u.Nickname)
+		assert.Equal(t, "Returner", u.LastName)
+	})
+
+	t.Run("should not find the user before it was created", func(t *testing.T) {
+		_, err := agg.Project(ctx, created.ID, beforeCreate)
+		assert.ErrorIs(t, err, projector.ErrUserNotFound)
+	})
+
+	t.Run("should not report drift for an user managed by the aggregate", func(t *testing.T) {
+		drifts, err := agg.VerifyProjection(ctx, created.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, drifts)
+	})
 }
@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	dbInstance "github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/env"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+type aggregate struct {
+	DB     *gorm.DB
+	TestTx bool
+}
+
+type Aggregate interface {
+	Create(ctx context.Context, w *webhook.Entity) (*webhook.Entity, error)
+	Get(ctx context.Context, id uuid.UUID) (*webhook.Entity, error)
+	Find(ctx context.Context, page, limit int) ([]webhook.Entity, error)
+	Update(ctx context.Context, id uuid.UUID, in model.UpdateWebhookInput) (*webhook.Entity, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, id uuid.UUID, page, limit int) ([]webhook.Delivery, error)
+}
+
+const (
+	// ErrMissingDB used when DB is nil
+	ErrMissingDB = "Aggregate is missing DB connection"
+	// ErrMissingTestEnv when test env is missing
+	ErrMissingTestEnv = "DB connection can only be a TX when ENV == env.Test"
+)
+
+// New returns a new Webhook aggregate
+//
+//nolint:revive // no need to return agg because we need is the struct not the interface
+func New(db *gorm.DB, e string) (aggregate, error) {
+	a := aggregate{
+		DB: db,
+	}
+
+	switch {
+	case db == nil:
+		return a, errors.New(ErrMissingDB)
+	case dbInstance.IsTransaction(db) && !env.IsTest(e):
+		return a, errors.New(ErrMissingTestEnv)
+	case dbInstance.IsTransaction(db) && env.IsTest(e):
+		a.TestTx = true
+	}
+
+	return a, nil
+}
+
+// Create validates and stores a new webhook. A secret is generated when none is given
+func (a aggregate) Create(ctx context.Context, w *webhook.Entity) (*webhook.Entity, error) {
+	if w.Secret == "" {
+		if err := w.GenerateSecret(); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Valid(); err != nil {
+		return nil, err
+	}
+
+	return repo.CreateWebhook(w, a.db(ctx))
+}
+
+// Get returns a webhook by ID
+func (a aggregate) Get(ctx context.Context, id uuid.UUID) (*webhook.Entity, error) {
+	return repo.GetWebhook(id, a.db(ctx))
+}
+
+// Find returns a paginated list of webhooks
+func (a aggregate) Find(ctx context.Context, page, limit int) ([]webhook.Entity, error) {
+	return repo.FindWebhooks(a.db(ctx), page, limit)
+}
+
+// Update changes the given fields of a webhook. Enabling it again resets its failures
+func (a aggregate) Update(ctx context.Context, id uuid.UUID, in model.UpdateWebhookInput) (*webhook.Entity, error) {
+	tx := a.begin(ctx)
+	defer a.rollback(tx)
+
+	existing, err := repo.GetWebhookForUpdate(id, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.URL != nil {
+		existing.URL = *in.URL
+	}
+	if in.EventTypes != nil {
+		existing.EventTypes = in.EventTypes
+	}
+	if in.Active != nil {
+		existing.Active = *in.Active
+	}
+	if err := existing.Valid(); err != nil {
+		return nil, err
+	}
+
+	updated, err := repo.UpdateWebhook(existing, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.commit(tx); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Delete soft deletes a webhook
+func (a aggregate) Delete(ctx context.Context, id uuid.UUID) error {
+	return repo.DeleteWebhook(id, a.db(ctx))
+}
+
+// ListDeliveries returns the latest delivery attempts of a webhook
+func (a aggregate) ListDeliveries(ctx context.Context, id uuid.UUID, page, limit int) ([]webhook.Delivery, error) {
+	if _, err := repo.GetWebhook(id, a.db(ctx)); err != nil {
+		return nil, err
+	}
+	return repo.FindWebhookDeliveries(a.db(ctx), id, page, limit)
+}
+
+// db returns the connection bound to ctx, so the statements are traced and
+// cancelled with the request
+func (a aggregate) db(ctx context.Context) *gorm.DB {
+	return a.DB.WithContext(ctx)
+}
+
+func (a aggregate) begin(ctx context.Context) *gorm.DB {
+	if a.TestTx {
+		return a.db(ctx)
+	}
+	return a.db(ctx).Begin()
+}
+
+func (a aggregate) commit(tx *gorm.DB) error {
+	if !a.TestTx {
+		return tx.Commit().Error
+	}
+	return nil
+}
+
+func (a aggregate) rollback(tx *gorm.DB) {
+	if !a.TestTx {
+		tx.Rollback()
+	}
+}
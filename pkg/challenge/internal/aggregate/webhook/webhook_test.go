@@ -0,0 +1,110 @@
+package webhook_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	agg "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+func TestWebhookAggregate(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	aggregate, err := agg.New(db, "test")
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	var created *webhook.Entity
+	t.Run("should create a webhook with a generated secret", func(t *testing.T) {
+		created, err = aggregate.Create(ctx, &webhook.Entity{
+			URL:        "https://partner.example.com/hooks",
+			EventTypes: []string{event.UserCreated},
+		})
+		assert.NoError(t, err)
+		assert.True(t, created.Active)
+		assert.NotEmpty(t, created.Secret)
+
+		stored, err := aggregate.Get(ctx, created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, created.Secret, stored.Secret)
+	})
+
+	t.Run("should refuse invalid webhooks", func(t *testing.T) {
+		_, err := aggregate.Create(ctx, &webhook.Entity{
+			URL:        "http://169.254.169.254/latest/meta-data",
+			EventTypes: []string{event.UserCreated},
+		})
+		assert.ErrorIs(t, err, domainerr.ErrValidation)
+		assert.ErrorIs(t, err, webhook.ErrPrivateURL)
+	})
+
+	t.Run("should reset the failures when the webhook is enabled again", func(t *testing.T) {
+		disabled, err := repo.MarkWebhookFailed(created.ID, 1, db)
+		assert.NoError(t, err)
+		assert.True(t, disabled)
+
+		active := true
+		updated, err := aggregate.Update(ctx, created.ID, model.UpdateWebhookInput{Active: &active})
+		assert.NoError(t, err)
+		assert.True(t, updated.Active)
+
+		stored, err := aggregate.Get(ctx, created.ID)
+		assert.NoError(t, err)
+		assert.True(t, stored.Active)
+		assert.Equal(t, 0, stored.ConsecutiveFailures)
+		assert.Nil(t, stored.DisabledAt)
+	})
+
+	t.Run("should not update a webhook into an invalid one", func(t *testing.T) {
+		url := "ftp://partner.example.com"
+		_, err := aggregate.Update(ctx, created.ID, model.UpdateWebhookInput{URL: &url})
+		assert.ErrorIs(t, err, domainerr.ErrValidation)
+
+		stored, err := aggregate.Get(ctx, created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://partner.example.com/hooks", stored.URL)
+	})
+
+	t.Run("should list the deliveries sent to a webhook", func(t *testing.T) {
+		d := &webhook.Delivery{
+			WebhookID: created.ID,
+			EventID:   uuid.New(),
+			EventType: event.UserCreated,
+			Attempt:   1,
+			Success:   true,
+		}
+		assert.NoError(t, repo.CreateWebhookDelivery(d, db))
+
+		deliveries, err := aggregate.ListDeliveries(ctx, created.ID, 1, 10)
+		assert.NoError(t, err)
+		if assert.Len(t, deliveries, 1) {
+			assert.Equal(t, d.ID, deliveries[0].ID)
+		}
+	})
+
+	t.Run("should not find deleted webhooks nor their deliveries", func(t *testing.T) {
+		assert.NoError(t, aggregate.Delete(ctx, created.ID))
+
+		_, err := aggregate.Get(ctx, created.ID)
+		assert.ErrorIs(t, err, domainerr.ErrNotFound)
+		_, err = aggregate.ListDeliveries(ctx, created.ID, 1, 10)
+		assert.ErrorIs(t, err, domainerr.ErrNotFound)
+		assert.ErrorIs(t, aggregate.Delete(ctx, created.ID), domainerr.ErrNotFound)
+	})
+
+	t.Run("should fail without DB", func(t *testing.T) {
+		_, err := agg.New(nil, "test")
+		assert.EqualError(t, err, agg.ErrMissingDB)
+	})
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	service "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/auth"
+	authProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/auth"
+)
+
+type Controller struct {
+	svc service.Service
+	authProto.UnimplementedAuthServiceServer
+}
+
+// NewController returns a gRPC Auth controller
+func NewController(s service.Service) *Controller {
+	return &Controller{svc: s}
+}
+
+// Login returns an access token and a refresh token for a valid email or nickname and password
+func (c *Controller) Login(ctx context.Context, req *authProto.LoginRequest) (*authProto.TokenResponse, error) {
+	if strings.TrimSpace(req.Login) == "" || req.Password == "" {
+		log.Error().Ctx(ctx).Str("authController", "Login").Msg("not valid data")
+		return nil, status.Error(codes.InvalidArgument, "login and password are required")
+	}
+
+	userAgent, ipAddress := clientMetadata(ctx)
+	token, err := c.svc.Login(ctx, &model.LoginInput{
+		Login:     req.Login,
+		Password:  req.Password,
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		}
+		log.Error().Ctx(ctx).Err(err).Str("authController", "Login").Msg("could not login")
+		return nil, status.Error(codes.Internal, "could not login")
+	}
+
+	return mapTokenToResponse(token), nil
+}
+
+// Token returns an access token for a service account
+func (c *Controller) Token(ctx context.Context, req *authProto.ClientCredentialsRequest) (*authProto.TokenResponse, error) {
+	if req.ClientId == "" || req.ClientSecret == "" {
+		log.Error().Ctx(ctx).Str("authController", "Token").Msg("not valid data")
+		return nil, status.Error(codes.InvalidArgument, "client ID and secret are required")
+	}
+
+	token, err := c.svc.Token(ctx, &model.ClientCredentialsInput{
+		ClientID:     req.ClientId,
+		ClientSecret: req.ClientSecret,
+	})
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidClient) {
+			return nil, status.Error(codes.Unauthenticated, "invalid client credentials")
+		}
+		log.Error().Ctx(ctx).Err(err).Str("authController", "Token").Msg("could not issue token")
+		return nil, status.Error(codes.Internal, "could not issue token")
+	}
+
+	return mapTokenToResponse(token), nil
+}
+
+// Refresh exchanges a refresh token for a new access token and refresh token
+func (c *Controller) Refresh(ctx context.Context, req *authProto.RefreshRequest) (*authProto.TokenResponse, error) {
+	if req.RefreshToken == "" {
+		log.Error().Ctx(ctx).Str("authController", "Refresh").Msg("not valid data")
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+
+	userAgent, ipAddress := clientMetadata(ctx)
+	token, err := c.svc.Refresh(ctx, &model.RefreshInput{
+		RefreshToken: req.RefreshToken,
+		UserAgent:    userAgent,
+		IPAddress:    ipAddress,
+	})
+	if err != nil {
+		if errors.Is(err, session.ErrInvalidRefreshToken) || errors.Is(err, session.ErrRefreshTokenReused) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		log.Error().Ctx(ctx).Err(err).Str("authController", "Refresh").Msg("could not refresh token")
+		return nil, status.Error(codes.Internal, "could not refresh token")
+	}
+
+	return mapTokenToResponse(token), nil
+}
+
+// ListSessions returns the active sessions of an user
+func (c *Controller) ListSessions(ctx context.Context, req *authProto.ListSessionsRequest) (*authProto.ListSessionsResponse, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "ListSessions").Msg("invalid user ID")
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	sessions, err := c.svc.ListSessions(ctx, userID)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "ListSessions").Msg("could not list sessions")
+		return nil, status.Error(codes.Internal, "could not list sessions")
+	}
+
+	res := &authProto.ListSessionsResponse{Sessions: make([]*authProto.Session, 0, len(sessions))}
+	for _, s := range sessions {
+		res.Sessions = append(res.Sessions, &authProto.Session{
+			Id:              s.ID,
+			UserAgent:       s.UserAgent,
+			IpAddress:       s.IPAddress,
+			StartedAt:       timestamppb.New(s.StartedAt),
+			LastRefreshedAt: timestamppb.New(s.LastRefreshedAt),
+			ExpiresAt:       timestamppb.New(s.ExpiresAt),
+		})
+	}
+	return res, nil
+}
+
+// RevokeSession revokes one session of an user
+func (c *Controller) RevokeSession(ctx context.Context, req *authProto.RevokeSessionRequest) (*authProto.RevokeSessionResponse, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSession").Msg("invalid user ID")
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+	sessionID, err := uuid.Parse(req.SessionId)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSession").Msg("invalid session ID")
+		return nil, status.Error(codes.InvalidArgument, "invalid session ID")
+	}
+
+	if err := c.svc.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, repo.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "session not found")
+		}
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSession").Msg("could not revoke session")
+		return nil, status.Error(codes.Internal, "could not revoke session")
+	}
+	return &authProto.RevokeSessionResponse{}, nil
+}
+
+// RevokeSessions revokes every session of an user
+func (c *Controller) RevokeSessions(ctx context.Context, req *authProto.RevokeSessionsRequest) (*authProto.RevokeSessionsResponse, error) {
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSessions").Msg("invalid user ID")
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	revoked, err := c.svc.RevokeSessions(ctx, userID)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSessions").Msg("could not revoke sessions")
+		return nil, status.Error(codes.Internal, "could not revoke sessions")
+	}
+	return &authProto.RevokeSessionsResponse{Revoked: int32(revoked)}, nil
+}
+
+func mapTokenToResponse(token *model.TokenOutput) *authProto.TokenResponse {
+	return &authProto.TokenResponse{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.TokenType,
+		ExpiresIn:    token.ExpiresIn,
+		ExpiresAt:    timestamppb.New(token.ExpiresAt),
+		RefreshToken: token.RefreshToken,
+		SessionId:    token.SessionID,
+		Scope:        token.Scope,
+	}
+}
+
+// clientMetadata returns the user agent and the IP address of the caller
+func clientMetadata(ctx context.Context) (string, string) {
+	var userAgent, ipAddress string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			userAgent = ua[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ipAddress = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ipAddress); err == nil {
+			ipAddress = host
+		}
+	}
+	return userAgent, ipAddress
+}
@@ -0,0 +1,113 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	controller "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/grpc/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	authProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/auth"
+)
+
+func TestLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockAuthService(ctrl)
+	c := controller.NewController(mockSvc)
+
+	t.Run("should return a token", func(t *testing.T) {
+		expiresAt := time.Now().Add(15 * time.Minute)
+		mockSvc.EXPECT().
+			Login(gomock.Any(), &model.LoginInput{Login: "alice@bob.com", Password: "supersecure"}).
+			Return(&model.TokenOutput{AccessToken: "token", TokenType: "Bearer", ExpiresIn: 900, ExpiresAt: expiresAt}, nil)
+
+		res, err := c.Login(context.Background(), &authProto.LoginRequest{Login: "alice@bob.com", Password: "supersecure"})
+		assert.NoError(t, err)
+		assert.Equal(t, "token", res.AccessToken)
+		assert.Equal(t, expiresAt.Unix(), res.ExpiresAt.AsTime().Unix())
+	})
+
+	t.Run("should be unauthenticated on invalid credentials", func(t *testing.T) {
+		mockSvc.EXPECT().Login(gomock.Any(), gomock.Any()).Return(nil, user.ErrInvalidCredentials)
+
+		_, err := c.Login(context.Background(), &authProto.LoginRequest{Login: "alice@bob.com", Password: "wrong"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("should fail on missing fields", func(t *testing.T) {
+		_, err := c.Login(context.Background(), &authProto.LoginRequest{Login: " "})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should be internal when the service fails", func(t *testing.T) {
+		mockSvc.EXPECT().Login(gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
+
+		_, err := c.Login(context.Background(), &authProto.LoginRequest{Login: "alice@bob.com", Password: "supersecure"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockAuthService(ctrl)
+	c := controller.NewController(mockSvc)
+
+	t.Run("should return new tokens", func(t *testing.T) {
+		mockSvc.EXPECT().
+			Refresh(gomock.Any(), &model.RefreshInput{RefreshToken: "refresh-token"}).
+			Return(&model.TokenOutput{AccessToken: "token", RefreshToken: "new-refresh-token", SessionID: "session"}, nil)
+
+		res, err := c.Refresh(context.Background(), &authProto.RefreshRequest{RefreshToken: "refresh-token"})
+		assert.NoError(t, err)
+		assert.Equal(t, "new-refresh-token", res.RefreshToken)
+		assert.Equal(t, "session", res.SessionId)
+	})
+
+	t.Run("should be unauthenticated when the refresh token was reused", func(t *testing.T) {
+		mockSvc.EXPECT().Refresh(gomock.Any(), gomock.Any()).Return(nil, session.ErrRefreshTokenReused)
+
+		_, err := c.Refresh(context.Background(), &authProto.RefreshRequest{RefreshToken: "refresh-token"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("should fail without refresh token", func(t *testing.T) {
+		_, err := c.Refresh(context.Background(), &authProto.RefreshRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockAuthService(ctrl)
+	c := controller.NewController(mockSvc)
+	userID, sessionID := uuid.New(), uuid.New()
+
+	t.Run("should be not found for unknown sessions", func(t *testing.T) {
+		mockSvc.EXPECT().RevokeSession(gomock.Any(), userID, sessionID).Return(repo.ErrRecordNotFound)
+
+		_, err := c.RevokeSession(context.Background(), &authProto.RevokeSessionRequest{UserId: userID.String(), SessionId: sessionID.String()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("should fail on invalid session ID", func(t *testing.T) {
+		_, err := c.RevokeSession(context.Background(), &authProto.RevokeSessionRequest{UserId: userID.String(), SessionId: "nope"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -0,0 +1,100 @@
+// Package grpcerror translates domain errors to gRPC statuses
+package grpcerror
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+)
+
+// Code returns the gRPC code of an error, Internal for anything not a domain error
+func Code(err error) codes.Code {
+	switch {
+	case errors.Is(err, domainerr.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, domainerr.ErrConflict):
+		return codes.AlreadyExists
+	case errors.Is(err, domainerr.ErrValidation):
+		return codes.InvalidArgument
+	case errors.Is(err, domainerr.ErrPreconditionFailed):
+		return codes.FailedPrecondition
+	case errors.Is(err, domainerr.ErrUnauthenticated):
+		return codes.Unauthenticated
+	case errors.Is(err, domainerr.ErrPermissionDenied):
+		return codes.PermissionDenied
+	default:
+		return codes.Internal
+	}
+}
+
+// Status returns the gRPC status of an error. Domain errors keep their text in the
+// message and their field as a BadRequest violation, internal errors only show message
+func Status(err error, message string) *status.Status {
+	code := Code(err)
+	if code == codes.Internal {
+		return status.New(code, message)
+	}
+
+	st := status.New(code, fmt.Sprintf("%s: %s", message, err.Error()))
+	e, ok := domainerr.As(err)
+	if !ok {
+		return st
+	}
+
+	var detail protoadapt.MessageV1
+	switch {
+	case code == codes.FailedPrecondition:
+		detail = &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{
+				Type:        string(e.Kind),
+				Subject:     e.Field,
+				Description: e.Description(),
+			}},
+		}
+	case e.Field != "":
+		detail = &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+				Field:       e.Field,
+				Description: e.Description(),
+			}},
+		}
+	default:
+		return st
+	}
+
+	withDetails, err := st.WithDetails(detail)
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// Error returns err as a gRPC status error. The original error is kept in the chain
+// so it can still be matched with errors.Is
+func Error(err error, message string) error {
+	return &statusError{err: err, status: Status(err, message)}
+}
+
+type statusError struct {
+	err    error
+	status *status.Status
+}
+
+func (e *statusError) Error() string {
+	return e.status.Err().Error()
+}
+
+// GRPCStatus is used by the gRPC server to answer with the status of the error
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,75 @@
+package grpcerror_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/grpc/grpcerror"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"not found", domainerr.NotFound("user not found"), codes.NotFound},
+		{"conflict", domainerr.Conflict("email", "already taken", nil), codes.AlreadyExists},
+		{"validation", domainerr.Validation("email", errors.New("invalid")), codes.InvalidArgument},
+		{"precondition failed", domainerr.PreconditionFailed("version mismatch"), codes.FailedPrecondition},
+		{"unauthenticated", domainerr.Unauthenticated("invalid access token"), codes.Unauthenticated},
+		{"permission denied", domainerr.PermissionDenied("permission denied"), codes.PermissionDenied},
+		{"unknown", errors.New("boom"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, grpcerror.Code(tt.err))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	t.Run("should attach the field violation and keep the original error", func(t *testing.T) {
+		errInvalid := errors.New("invalid email format")
+		err := grpcerror.Error(domainerr.Validation("email", errInvalid), "failed to create user")
+
+		assert.ErrorIs(t, err, errInvalid)
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		assert.Equal(t, "failed to create user: email: invalid email format", st.Message())
+
+		require.Len(t, st.Details(), 1)
+		badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+		require.True(t, ok)
+		require.Len(t, badRequest.FieldViolations, 1)
+		assert.Equal(t, "email", badRequest.FieldViolations[0].Field)
+		assert.Equal(t, "invalid email format", badRequest.FieldViolations[0].Description)
+	})
+
+	t.Run("should attach the precondition failure", func(t *testing.T) {
+		err := grpcerror.Error(domainerr.PreconditionFailed("version mismatch"), "failed to update user")
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.FailedPrecondition, st.Code())
+		require.Len(t, st.Details(), 1)
+		_, ok := st.Details()[0].(*errdetails.PreconditionFailure)
+		assert.True(t, ok)
+	})
+
+	t.Run("should hide internal errors", func(t *testing.T) {
+		err := grpcerror.Error(errors.New("connection refused"), "failed to create user")
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.Internal, st.Code())
+		assert.Equal(t, "failed to create user", st.Message())
+		assert.Empty(t, st.Details())
+	})
+}
@@ -8,7 +8,14 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/grpc/grpcerror"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
 	service "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/user"
 	userProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/user"
@@ -16,19 +23,51 @@ import (
 
 var (
 	// ErrMissingFields used user request is missing fields
-	ErrMissingFields = errors.New("missing fields")
+	ErrMissingFields = domainerr.Validation("", errors.New("missing fields"))
 	// ErrIDnotValid used when entity ID is not valid
-	ErrIDnotValid = errors.New("ID is not valid")
+	ErrIDnotValid = domainerr.Validation("", errors.New("ID is not valid"))
+	// ErrInvalidEventType used when an unknown event type is requested
+	ErrInvalidEventType = domainerr.Validation("", errors.New("event type is not valid"))
+	// ErrFieldNotUpdatable used when the update mask has a field that cannot be updated
+	ErrFieldNotUpdatable = domainerr.Validation("", errors.New("field cannot be updated"))
+	// ErrInvalidTimeRange used when from is after to
+	ErrInvalidTimeRange = domainerr.Validation("", errors.New("from must be before to"))
+	// ErrInvalidSearchQuery used when a search query is empty or too long
+	ErrInvalidSearchQuery = domainerr.Validation("", fmt.Errorf("must have 1 to %d characters", maxSearchQueryLength))
+)
+
+const (
+	// maxEventsLimit is the max page size allowed when listing user events
+	maxEventsLimit = 100
+	// maxUsersLimit is the max page size allowed when finding users
+	maxUsersLimit = 100
+	// maxSearchQueryLength is the max length of a fuzzy search query
+	maxSearchQueryLength = 100
 )
 
 type Controller struct {
 	svc service.Service
+	hub *Hub
 	userProto.UnimplementedUserServiceServer
 }
 
+// Option type to add dependencies to the Controller
+type Option func(*Controller)
+
+// WithHub enables WatchUsers streams fed by the given Hub
+func WithHub(h *Hub) Option {
+	return func(c *Controller) {
+		c.hub = h
+	}
+}
+
 // NewController returns a gRPC User controller
-func NewController(s service.Service) *Controller {
-	return &Controller{svc: s}
+func NewController(s service.Service, opts ...Option) *Controller {
+	c := &Controller{svc: s}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
 }
 
 // CreateUser returns a created user
@@ -39,85 +78,274 @@ func (c *Controller) CreateUser(ctx context.Context, req *userProto.CreateUserRe
 		strings.TrimSpace(req.Password) == "" ||
 		strings.TrimSpace(req.Email) == "" ||
 		strings.TrimSpace(req.Country) == "" {
-		log.Error().Err(ErrMissingFields).Str("userController", "CreateUser").Msg("not valid data")
-		return nil, ErrMissingFields
+		log.Error().Ctx(ctx).Err(ErrMissingFields).Str("userController", "CreateUser").Msg("not valid data")
+		return nil, grpcerror.Error(ErrMissingFields, "invalid input")
 	}
 
 	in := &model.CreateUserInput{
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Nickname:  req.Nickname,
-		Password:  req.Password,
-		Email:     req.Email,
-		Country:   req.Country,
+		FirstName:      req.FirstName,
+		LastName:       req.LastName,
+		Nickname:       req.Nickname,
+		Password:       req.Password,
+		Email:          req.Email,
+		Country:        req.Country,
+		IdempotencyKey: req.IdempotencyKey,
 	}
 	user, err := c.svc.Create(ctx, in)
 	if err != nil {
-		log.Error().Err(ErrMissingFields).Str("userController", "CreateUser").Msg("failed to create user")
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		log.Error().Ctx(ctx).Err(err).Str("userController", "CreateUser").Msg("failed to create user")
+		return nil, grpcerror.Error(err, "failed to create user")
+	}
+	return mapToProto(user), nil
+}
+
+// GetUser returns an user by ID. Admins can also get soft deleted users with include_deleted
+func (c *Controller) GetUser(ctx context.Context, req *userProto.GetUserRequest) (*userProto.UserResponse, error) {
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, grpcerror.Error(domainerr.Validation("id", ErrIDnotValid), "invalid user ID")
+	}
+	if req.IncludeDeleted {
+		if principal, ok := auth.PrincipalFromContext(ctx); !ok || principal.Role != auth.RoleAdmin {
+			return nil, status.Error(codes.PermissionDenied, "include_deleted is only allowed to admins")
+		}
+	}
+
+	user, err := c.svc.Get(ctx, id, req.IncludeDeleted)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "GetUser").Msg("failed to get user")
+		return nil, grpcerror.Error(err, "failed to get user")
 	}
 	return mapToProto(user), nil
 }
 
-// UpdateUser updates an user nickname
+// UpdateUser changes the fields of an user listed in update_mask. Without a mask every
+// non empty field of the request is changed. With expected_version it fails if the user
+// changed since that version
 func (c *Controller) UpdateUser(ctx context.Context, req *userProto.UpdateUserRequest) (*userProto.UserResponse, error) {
 	id, err := uuid.Parse(req.Id)
 	if err != nil {
-		return nil, ErrIDnotValid
+		return nil, grpcerror.Error(domainerr.Validation("id", ErrIDnotValid), "invalid user ID")
 	}
-	if strings.TrimSpace(req.Nickname) == "" {
-		return nil, ErrMissingFields
+
+	in, err := updateInput(req)
+	if err != nil {
+		return nil, grpcerror.Error(err, "invalid input")
 	}
-	user, err := c.svc.Update(ctx, id, req.Nickname)
+
+	user, err := c.svc.Update(ctx, id, in, req.ExpectedVersion)
 	if err != nil {
-		log.Error().Err(ErrMissingFields).Str("userController", "UpdateUser").Msg("failed to update user")
-		return nil, fmt.Errorf("failed to update user: %w", err)
+		log.Error().Ctx(ctx).Err(err).Str("userController", "UpdateUser").Msg("failed to update user")
+		return nil, grpcerror.Error(err, "failed to update user")
 	}
 	return mapToProto(user), nil
 }
 
-// DeleteUser soft deletes an user based on its ID
+// DeleteUser soft deletes an user based on its ID. With expected_version it fails if
+// the user changed since that version
 func (c *Controller) DeleteUser(ctx context.Context, req *userProto.DeleteUserRequest) (*userProto.Empty, error) {
 	id, err := uuid.Parse(req.Id)
 	if err != nil {
-		return nil, errors.New("invalid user ID")
+		return nil, grpcerror.Error(domainerr.Validation("id", ErrIDnotValid), "invalid user ID")
 	}
-	if err := c.svc.Delete(ctx, id); err != nil {
-		log.Error().Err(ErrMissingFields).Str("userController", "DeleteUser").Msg("failed to delete user")
-		return nil, fmt.Errorf("failed to delete user: %w", err)
+	if err := c.svc.Delete(ctx, id, req.ExpectedVersion); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "DeleteUser").Msg("failed to delete user")
+		return nil, grpcerror.Error(err, "failed to delete user")
 	}
 	return &userProto.Empty{}, nil
 }
 
-// FindUsers returns a list of users. It is paginated and also can be filtered by country
+// FindUsers returns a list of users. It can be filtered by countries, nickname and email prefixes
+// and a creation time range, sorted by creation time or nickname, and paginated with page tokens,
+// or page and limit
 func (c *Controller) FindUsers(ctx context.Context, req *userProto.FindUsersRequest) (*userProto.UsersResponse, error) {
-	if req.Page < 1 {
-		req.Page = 1
+	filter := model.UserFilter{
+		Countries:      req.Countries,
+		NicknamePrefix: req.NicknamePrefix,
+		EmailPrefix:    req.EmailPrefix,
+		SortBy:         req.SortBy,
+		Descending:     req.Descending,
+		Cursor:         req.PageToken,
+		Page:           int(req.Page),
+		Limit:          int(req.Limit),
+		IncludeTotal:   req.IncludeTotal,
+	}
+	if req.Country != "" {
+		filter.Countries = append([]string{req.Country}, filter.Countries...)
+	}
+	if req.CreatedFrom != nil {
+		filter.CreatedFrom = req.CreatedFrom.AsTime()
+	}
+	if req.CreatedTo != nil {
+		filter.CreatedTo = req.CreatedTo.AsTime()
+	}
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && filter.CreatedFrom.After(filter.CreatedTo) {
+		return nil, grpcerror.Error(domainerr.Validation("created_from", ErrInvalidTimeRange), "invalid time range")
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 10
 	}
-	if req.Limit < 1 {
-		req.Limit = 10
+	if filter.Limit > maxUsersLimit {
+		filter.Limit = maxUsersLimit
 	}
 
-	users, err := c.svc.Find(ctx, req.Country, int(req.Page), int(req.Limit))
+	page, err := c.svc.Find(ctx, filter)
 	if err != nil {
-		log.Error().Err(ErrMissingFields).Str("userController", "FindUsers").Msg("failed to find users")
-		return nil, fmt.Errorf("failed to find users: %w", err)
+		log.Error().Ctx(ctx).Err(err).Str("userController", "FindUsers").Msg("failed to find users")
+		return nil, grpcerror.Error(err, "failed to find users")
 	}
 
-	res := &userProto.UsersResponse{}
-	for _, u := range users {
+	res := &userProto.UsersResponse{
+		NextPageToken: page.NextPageToken,
+		TotalCount:    page.Total,
+	}
+	for _, u := range page.Users {
 		res.Users = append(res.Users, mapToProto(&u))
 	}
 	return res, nil
 }
 
+// SearchUsers returns the users whose nickname or name are similar to the query, best matches
+// first, so misspelled nicknames are found too. Soft deleted users are never returned
+func (c *Controller) SearchUsers(ctx context.Context, req *userProto.SearchUsersRequest) (*userProto.SearchUsersResponse, error) {
+	query := strings.TrimSpace(req.Query)
+	if query == "" || len(query) > maxSearchQueryLength {
+		return nil, grpcerror.Error(domainerr.Validation("query", ErrInvalidSearchQuery), "invalid search query")
+	}
+
+	limit := int(req.Limit)
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > maxUsersLimit {
+		limit = maxUsersLimit
+	}
+
+	matches, err := c.svc.Search(ctx, query, limit)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "SearchUsers").Msg("failed to search users")
+		return nil, grpcerror.Error(err, "failed to search users")
+	}
+
+	res := &userProto.SearchUsersResponse{}
+	for _, m := range matches {
+		res.Matches = append(res.Matches, &userProto.UserMatch{
+			User:  mapToProto(&m.UserOutput),
+			Score: m.Score,
+		})
+	}
+	return res, nil
+}
+
+// ListUserEvents returns the change history of an user. It is paginated and can be
+// filtered by event type and by a creation time range
+func (c *Controller) ListUserEvents(ctx context.Context, req *userProto.ListUserEventsRequest) (*userProto.UserEventsResponse, error) {
+	id, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, grpcerror.Error(domainerr.Validation("user_id", ErrIDnotValid), "invalid user ID")
+	}
+	for _, t := range req.EventTypes {
+		if !event.IsValidType(t) {
+			return nil, grpcerror.Error(domainerr.Validation("event_types", ErrInvalidEventType), "invalid event type")
+		}
+	}
+
+	filter := model.UserEventFilter{
+		UserID:     id,
+		EventTypes: req.EventTypes,
+		Page:       int(req.Page),
+		Limit:      int(req.Limit),
+	}
+	if req.From != nil {
+		filter.From = req.From.AsTime()
+	}
+	if req.To != nil {
+		filter.To = req.To.AsTime()
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return nil, grpcerror.Error(domainerr.Validation("from", ErrInvalidTimeRange), "invalid time range")
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 10
+	}
+	if filter.Limit > maxEventsLimit {
+		filter.Limit = maxEventsLimit
+	}
+
+	events, err := c.svc.ListEvents(ctx, filter)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "ListUserEvents").Msg("failed to list user events")
+		return nil, grpcerror.Error(err, "failed to list user events")
+	}
+
+	res := &userProto.UserEventsResponse{}
+	for _, e := range events {
+		res.Events = append(res.Events, &userProto.UserEventResponse{
+			Id:        e.ID,
+			UserId:    e.UserID,
+			EventType: e.EventType,
+			Payload:   string(e.Payload),
+			CreatedAt: timestamppb.New(e.CreatedAt),
+		})
+	}
+	return res, nil
+}
+
+// updateInput returns the fields of the request selected by its update mask
+func updateInput(req *userProto.UpdateUserRequest) (model.UpdateUserInput, error) {
+	var in model.UpdateUserInput
+	fields := map[string]struct {
+		target **string
+		value  string
+	}{
+		"first_name": {&in.FirstName, req.FirstName},
+		"last_name":  {&in.LastName, req.LastName},
+		"nickname":   {&in.Nickname, req.Nickname},
+		"email":      {&in.Email, req.Email},
+		"country":    {&in.Country, req.Country},
+	}
+
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		for path, f := range fields {
+			if strings.TrimSpace(f.value) != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return in, ErrMissingFields
+	}
+
+	for _, path := range paths {
+		f, ok := fields[path]
+		if !ok {
+			return in, domainerr.Validation("update_mask", fmt.Errorf("%q: %w", path, ErrFieldNotUpdatable))
+		}
+		value := f.value
+		*f.target = &value
+	}
+	return in, nil
+}
+
 func mapToProto(u *model.UserOutput) *userProto.UserResponse {
-	return &userProto.UserResponse{
+	res := &userProto.UserResponse{
 		Id:        u.ID,
 		FirstName: u.FirstName,
 		LastName:  u.LastName,
 		Nickname:  u.Nickname,
 		Email:     u.Email,
 		Country:   u.Country,
+		Version:   u.Version,
+	}
+	if u.DeletedAt != nil {
+		res.DeletedAt = timestamppb.New(*u.DeletedAt)
 	}
+	return res
 }
@@ -4,16 +4,71 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
 	controller "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/grpc/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
+	entityUser "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
 	userProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/user"
 )
 
+func TestGetUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	c := controller.NewController(mockSvc)
+	id := uuid.New()
+
+	t.Run("should return the user", func(t *testing.T) {
+		mockSvc.EXPECT().Get(gomock.Any(), id, false).Return(&model.UserOutput{ID: id.String(), Nickname: "AB123"}, nil)
+
+		res, err := c.GetUser(context.Background(), &userProto.GetUserRequest{Id: id.String()})
+		assert.NoError(t, err)
+		assert.Equal(t, "AB123", res.Nickname)
+		assert.Nil(t, res.DeletedAt)
+	})
+
+	t.Run("should be not found for missing or deleted users", func(t *testing.T) {
+		mockSvc.EXPECT().Get(gomock.Any(), id, false).Return(nil, repo.ErrRecordNotFound)
+
+		_, err := c.GetUser(context.Background(), &userProto.GetUserRequest{Id: id.String()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("should only let admins include deleted users", func(t *testing.T) {
+		userCtx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: id.String(), Role: auth.RoleUser})
+		_, err := c.GetUser(userCtx, &userProto.GetUserRequest{Id: id.String(), IncludeDeleted: true})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		deletedAt := time.Now()
+		mockSvc.EXPECT().Get(gomock.Any(), id, true).Return(&model.UserOutput{ID: id.String(), DeletedAt: &deletedAt}, nil)
+		adminCtx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "admin-id", Role: auth.RoleAdmin})
+		res, err := c.GetUser(adminCtx, &userProto.GetUserRequest{Id: id.String(), IncludeDeleted: true})
+		assert.NoError(t, err)
+		assert.Equal(t, deletedAt.Unix(), res.DeletedAt.AsTime().Unix())
+	})
+
+	t.Run("should fail on invalid ID", func(t *testing.T) {
+		_, err := c.GetUser(context.Background(), &userProto.GetUserRequest{Id: "nope"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
 func TestCreateUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -52,6 +107,53 @@ func TestCreateUser(t *testing.T) {
 
 		_, err := c.CreateUser(context.Background(), req)
 		assert.Error(t, err)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should be already exists when the email is taken", func(t *testing.T) {
+		req := &userProto.CreateUserRequest{
+			FirstName: "Alice",
+			LastName:  "Bob",
+			Nickname:  "AB123",
+			Password:  "secret123",
+			Email:     "alice@bob.com",
+			Country:   "UK",
+		}
+		mockSvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil, domainerr.Conflict("email", "already taken", nil))
+
+		_, err := c.CreateUser(context.Background(), req)
+		st := status.Convert(err)
+		assert.Equal(t, codes.AlreadyExists, st.Code())
+		if assert.Len(t, st.Details(), 1) {
+			badRequest := st.Details()[0].(*errdetails.BadRequest)
+			assert.Equal(t, "email", badRequest.FieldViolations[0].Field)
+		}
+	})
+
+	t.Run("should pass the idempotency key and reject reused keys", func(t *testing.T) {
+		req := &userProto.CreateUserRequest{
+			FirstName:      "Alice",
+			LastName:       "Bob",
+			Nickname:       "AB123",
+			Password:       "secret123",
+			Email:          "alice@bob.com",
+			Country:        "UK",
+			IdempotencyKey: "key-1",
+		}
+		mockSvc.EXPECT().
+			Create(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, in *model.CreateUserInput) (*model.UserOutput, error) {
+				assert.Equal(t, "key-1", in.IdempotencyKey)
+				return nil, idempotency.ErrKeyReused
+			})
+
+		_, err := c.CreateUser(context.Background(), req)
+		st := status.Convert(err)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		if assert.Len(t, st.Details(), 1) {
+			badRequest := st.Details()[0].(*errdetails.BadRequest)
+			assert.Equal(t, "idempotency_key", badRequest.FieldViolations[0].Field)
+		}
 	})
 }
 
@@ -69,7 +171,7 @@ func TestUpdateUser(t *testing.T) {
 		}
 
 		mockSvc.EXPECT().
-			Update(gomock.Any(), gomock.Any(), gomock.Any()).
+			Update(gomock.Any(), gomock.Any(), gomock.Any(), int64(0)).
 			Return(&model.UserOutput{Nickname: "newcsgoplayer"}, nil)
 
 		res, err := c.UpdateUser(context.Background(), req)
@@ -77,6 +179,56 @@ func TestUpdateUser(t *testing.T) {
 		assert.Equal(t, "newcsgoplayer", res.Nickname)
 	})
 
+	t.Run("should only update the fields in the mask", func(t *testing.T) {
+		req := &userProto.UpdateUserRequest{
+			Id:         "d4e3e4ea-6a0b-4c2e-9e5c-cd6fdf2de771",
+			Nickname:   "ignored",
+			FirstName:  "Ignacio",
+			Country:    "UY",
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"first_name", "country"}},
+		}
+
+		firstName, country := "Ignacio", "UY"
+		mockSvc.EXPECT().
+			Update(gomock.Any(), gomock.Any(), model.UpdateUserInput{FirstName: &firstName, Country: &country}, int64(0)).
+			Return(&model.UserOutput{FirstName: firstName, Country: country}, nil)
+
+		res, err := c.UpdateUser(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, "Ignacio", res.FirstName)
+	})
+
+	t.Run("should fail when the expected version does not match", func(t *testing.T) {
+		req := &userProto.UpdateUserRequest{
+			Id:              "d4e3e4ea-6a0b-4c2e-9e5c-cd6fdf2de771",
+			Nickname:        "newcsgoplayer",
+			ExpectedVersion: 2,
+		}
+
+		mockSvc.EXPECT().
+			Update(gomock.Any(), gomock.Any(), gomock.Any(), int64(2)).
+			Return(nil, entityUser.ErrVersionMismatch)
+
+		_, err := c.UpdateUser(context.Background(), req)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("should fail on fields that cannot be updated", func(t *testing.T) {
+		req := &userProto.UpdateUserRequest{
+			Id:         "d4e3e4ea-6a0b-4c2e-9e5c-cd6fdf2de771",
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"password"}},
+		}
+
+		_, err := c.UpdateUser(context.Background(), req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.ErrorIs(t, err, controller.ErrFieldNotUpdatable)
+	})
+
+	t.Run("should fail without fields to update", func(t *testing.T) {
+		_, err := c.UpdateUser(context.Background(), &userProto.UpdateUserRequest{Id: "d4e3e4ea-6a0b-4c2e-9e5c-cd6fdf2de771"})
+		assert.ErrorIs(t, err, controller.ErrMissingFields)
+	})
+
 	t.Run("should fail on invalid UUID", func(t *testing.T) {
 		req := &userProto.UpdateUserRequest{
 			Id:       "not-a-uuid",
@@ -98,7 +250,7 @@ func TestDeleteUser(t *testing.T) {
 		req := &userProto.DeleteUserRequest{Id: "d4e3e4ea-6a0b-4c2e-9e5c-cd6fdf2de771"}
 
 		mockSvc.EXPECT().
-			Delete(gomock.Any(), gomock.Any()).
+			Delete(gomock.Any(), gomock.Any(), int64(0)).
 			Return(nil)
 
 		res, err := c.DeleteUser(context.Background(), req)
@@ -106,6 +258,17 @@ func TestDeleteUser(t *testing.T) {
 		assert.NotNil(t, res)
 	})
 
+	t.Run("should be not found for missing or deleted users", func(t *testing.T) {
+		req := &userProto.DeleteUserRequest{Id: "d4e3e4ea-6a0b-4c2e-9e5c-cd6fdf2de771"}
+
+		mockSvc.EXPECT().
+			Delete(gomock.Any(), gomock.Any(), int64(0)).
+			Return(repo.ErrRecordNotFound)
+
+		_, err := c.DeleteUser(context.Background(), req)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
 	t.Run("should fail on invalid ID", func(t *testing.T) {
 		req := &userProto.DeleteUserRequest{Id: "invalid-id"}
 
@@ -128,18 +291,80 @@ func TestFindUsers(t *testing.T) {
 			Limit:   2,
 		}
 
-		expected := []model.UserOutput{
-			{ID: "1", FirstName: "nachooo"},
-			{ID: "2", FirstName: "faceit"},
+		expected := &model.UsersPage{
+			Users: []model.UserOutput{
+				{ID: "1", FirstName: "nachooo"},
+				{ID: "2", FirstName: "faceit"},
+			},
+			NextPageToken: "next",
 		}
 
 		mockSvc.EXPECT().
-			Find(gomock.Any(), "UK", 1, 2).
+			Find(gomock.Any(), model.UserFilter{Countries: []string{"UK"}, Page: 1, Limit: 2}).
 			Return(expected, nil)
 
 		res, err := c.FindUsers(context.Background(), req)
 		assert.NoError(t, err)
 		assert.Len(t, res.Users, 2)
+		assert.Equal(t, "next", res.NextPageToken)
+		assert.Nil(t, res.TotalCount)
+	})
+
+	t.Run("should pass the page token and return the total", func(t *testing.T) {
+		req := &userProto.FindUsersRequest{
+			PageToken:    "token",
+			Limit:        500,
+			IncludeTotal: true,
+		}
+
+		total := int64(42)
+		mockSvc.EXPECT().
+			Find(gomock.Any(), model.UserFilter{Cursor: "token", Page: 1, Limit: 100, IncludeTotal: true}).
+			Return(&model.UsersPage{Total: &total}, nil)
+
+		res, err := c.FindUsers(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Empty(t, res.NextPageToken)
+		assert.Equal(t, int64(42), res.GetTotalCount())
+	})
+
+	t.Run("should pass the filters and the sort", func(t *testing.T) {
+		from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+		req := &userProto.FindUsersRequest{
+			Country:        "UK",
+			Countries:      []string{"ES"},
+			NicknamePrefix: "nac",
+			EmailPrefix:    "nacho@",
+			CreatedFrom:    timestamppb.New(from),
+			SortBy:         model.UserSortNickname,
+			Descending:     true,
+		}
+
+		mockSvc.EXPECT().
+			Find(gomock.Any(), model.UserFilter{
+				Countries:      []string{"UK", "ES"},
+				NicknamePrefix: "nac",
+				EmailPrefix:    "nacho@",
+				CreatedFrom:    from,
+				SortBy:         model.UserSortNickname,
+				Descending:     true,
+				Page:           1,
+				Limit:          10,
+			}).
+			Return(&model.UsersPage{}, nil)
+
+		_, err := c.FindUsers(context.Background(), req)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject inverted time ranges", func(t *testing.T) {
+		req := &userProto.FindUsersRequest{
+			CreatedFrom: timestamppb.New(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)),
+			CreatedTo:   timestamppb.New(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)),
+		}
+
+		_, err := c.FindUsers(context.Background(), req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
 	})
 
 	t.Run("should handle service error", func(t *testing.T) {
@@ -150,10 +375,75 @@ func TestFindUsers(t *testing.T) {
 		}
 
 		mockSvc.EXPECT().
-			Find(gomock.Any(), "UK", 1, 2).
+			Find(gomock.Any(), gomock.Any()).
 			Return(nil, errors.New("errtest"))
 
 		_, err := c.FindUsers(context.Background(), req)
 		assert.Error(t, err)
 	})
 }
+
+func TestSearchUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	c := controller.NewController(mockSvc)
+
+	t.Run("should return the matches", func(t *testing.T) {
+		mockSvc.EXPECT().
+			Search(gomock.Any(), "bandid", 10).
+			Return([]model.UserMatch{{UserOutput: model.UserOutput{Nickname: "bandido"}, Score: 0.7}}, nil)
+
+		res, err := c.SearchUsers(context.Background(), &userProto.SearchUsersRequest{Query: "bandid"})
+		assert.NoError(t, err)
+		if assert.Len(t, res.Matches, 1) {
+			assert.Equal(t, "bandido", res.Matches[0].User.Nickname)
+			assert.Equal(t, 0.7, res.Matches[0].Score)
+		}
+	})
+
+	t.Run("should reject empty queries", func(t *testing.T) {
+		_, err := c.SearchUsers(context.Background(), &userProto.SearchUsersRequest{Query: "  "})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestListUserEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	c := controller.NewController(mockSvc)
+
+	t.Run("should list user events", func(t *testing.T) {
+		req := &userProto.ListUserEventsRequest{
+			UserId:     "d4e3e4ea-6a0b-4c2e-9e5c-cd6fdf2de771",
+			EventTypes: []string{"USER_CREATED"},
+		}
+
+		mockSvc.EXPECT().
+			ListEvents(gomock.Any(), gomock.Any()).
+			Return([]model.UserEventOutput{{ID: "1", EventType: "USER_CREATED", Payload: []byte(`{}`)}}, nil)
+
+		res, err := c.ListUserEvents(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Len(t, res.Events, 1)
+		assert.Equal(t, "USER_CREATED", res.Events[0].EventType)
+	})
+
+	t.Run("should fail on invalid event type", func(t *testing.T) {
+		req := &userProto.ListUserEventsRequest{
+			UserId:     "d4e3e4ea-6a0b-4c2e-9e5c-cd6fdf2de771",
+			EventTypes: []string{"USER_HACKED"},
+		}
+
+		_, err := c.ListUserEvents(context.Background(), req)
+		assert.ErrorIs(t, err, controller.ErrInvalidEventType)
+	})
+
+	t.Run("should fail on invalid user ID", func(t *testing.T) {
+		_, err := c.ListUserEvents(context.Background(), &userProto.ListUserEventsRequest{UserId: "nope"})
+		assert.ErrorIs(t, err, controller.ErrIDnotValid)
+	})
+}
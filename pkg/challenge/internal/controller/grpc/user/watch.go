@@ -0,0 +1,323 @@
+package user
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/grpc/grpcerror"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	userProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+)
+
+const (
+	// watcherBuffer is how many changes a stream can lag behind before being dropped
+	watcherBuffer = 256
+	// backfillBatchSize is how many stored events are loaded at once when resuming
+	backfillBatchSize = 100
+)
+
+// Hub fans out the user events received from the bus to every WatchUsers stream.
+// It subscribes once to the bus, streams only register and unregister themselves.
+// Stopping the Hub ends every stream, so the gRPC server can stop gracefully
+type Hub struct {
+	mu       sync.RWMutex
+	watchers map[*watcher]struct{}
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+type watcher struct {
+	userIDs   []string
+	countries []string
+	changes   chan liveChange
+	// dropped is closed when the watcher could not keep up with the changes
+	dropped  chan struct{}
+	dropOnce sync.Once
+}
+
+// liveChange is a change received from the bus and when its event was stored
+type liveChange struct {
+	change    *userProto.UserChange
+	createdAt time.Time
+}
+
+// position is the place of a stored event in the order the backfill sends them
+type position struct {
+	createdAt time.Time
+	eventID   string
+}
+
+// covers reports whether the change is at or before the position, so it was already
+// sent by the backfill. Changes without event time are never covered
+func (p position) covers(c liveChange) bool {
+	if p.eventID == "" || c.createdAt.IsZero() {
+		return false
+	}
+	if !c.createdAt.Equal(p.createdAt) {
+		return c.createdAt.Before(p.createdAt)
+	}
+	return c.change.EventId <= p.eventID
+}
+
+// NewHub returns an empty Hub
+func NewHub() *Hub {
+	return &Hub{
+		watchers: make(map[*watcher]struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run waits until the Hub is stopped.
+// This method will block the calling go routine
+func (h *Hub) Run() error {
+	<-h.done
+	return nil
+}
+
+// Stop ends every WatchUsers stream, clients are told to resume from the last
+// event they received. Streams started afterwards end right away
+func (h *Hub) Stop(_ context.Context) error {
+	log.Info().Msg("User watch hub: stopping...")
+	h.stopOnce.Do(func() { close(h.done) })
+	return nil
+}
+
+// Register subscribes the Hub to the event types that change the user profile
+func (h *Hub) Register(sub pubsub.Subscriber) error {
+	for _, eventType := range []string{event.UserCreated, event.UserUpdated, event.UserSoftDeleted} {
+		if err := sub.Subscribe(eventType, h.onEvent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hub) onEvent(ctx context.Context, payload interface{}) {
+	change, country, ok := toChange(pubsub.EventIDFromContext(ctx), payload)
+	if !ok {
+		log.Error().Ctx(ctx).Msg("invalid payload type for user watch")
+		return
+	}
+
+	live := liveChange{change: change, createdAt: pubsub.EventTimeFromContext(ctx)}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for w := range h.watchers {
+		if !w.matches(change.UserId, country) {
+			continue
+		}
+		select {
+		case w.changes <- live:
+		default:
+			w.dropOnce.Do(func() { close(w.dropped) })
+		}
+	}
+}
+
+func newWatcher(userIDs, countries []string) *watcher {
+	return &watcher{
+		userIDs:   userIDs,
+		countries: countries,
+		changes:   make(chan liveChange, watcherBuffer),
+		dropped:   make(chan struct{}),
+	}
+}
+
+func (h *Hub) watch(w *watcher) {
+	h.mu.Lock()
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+}
+
+func (h *Hub) unwatch(w *watcher) {
+	h.mu.Lock()
+	delete(h.watchers, w)
+	h.mu.Unlock()
+}
+
+func (w *watcher) matches(userID, country string) bool {
+	if len(w.userIDs) > 0 && !slices.Contains(w.userIDs, userID) {
+		return false
+	}
+	if len(w.countries) > 0 && !slices.Contains(w.countries, country) {
+		return false
+	}
+	return true
+}
+
+// WatchUsers streams user changes as they happen. When resume_after_event_id is set,
+// the stored events after that one are sent first so clients do not miss changes
+func (c *Controller) WatchUsers(req *userProto.WatchUsersRequest, stream grpc.ServerStreamingServer[userProto.UserChange]) error {
+	if c.hub == nil {
+		return status.Error(codes.Unavailable, "user watch is not enabled")
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(req.UserIds))
+	for _, raw := range req.UserIds {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, ErrIDnotValid.Error())
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	w := newWatcher(req.UserIds, req.Countries)
+	var resumeAfter uuid.UUID
+	if req.ResumeAfterEventId != "" {
+		after, err := uuid.Parse(req.ResumeAfterEventId)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "resume_after_event_id is not valid")
+		}
+		resumeAfter = after
+	}
+
+	// The stored events are sent before subscribing, so a long backfill does not fill
+	// the buffer of live changes. The ones stored until the subscription are sent after
+	// it, and the live changes at or before the last of them are skipped
+	var last position
+	if resumeAfter != uuid.Nil {
+		var err error
+		if last, err = c.backfill(stream, w, resumeAfter, userIDs, last); err != nil {
+			return err
+		}
+	}
+
+	c.hub.watch(w)
+	defer c.hub.unwatch(w)
+
+	if resumeAfter != uuid.Nil {
+		if last.eventID != "" {
+			resumeAfter = uuid.MustParse(last.eventID)
+		}
+		var err error
+		if last, err = c.backfill(stream, w, resumeAfter, userIDs, last); err != nil {
+			return err
+		}
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.hub.done:
+			return status.Error(codes.Unavailable, "server is stopping, resume from the last received event")
+		case <-w.dropped:
+			return status.Error(codes.ResourceExhausted, "watcher fell behind, resume from the last received event")
+		case live := <-w.changes:
+			if last.covers(live) {
+				continue
+			}
+			if err := stream.Send(live.change); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// backfill sends the stored events after the given one, page by page. It returns the
+// position of the last event read, or last when there was none
+func (c *Controller) backfill(
+	stream grpc.ServerStreamingServer[userProto.UserChange],
+	w *watcher,
+	after uuid.UUID,
+	userIDs []uuid.UUID,
+	last position,
+) (position, error) {
+	for {
+		events, err := c.svc.ListEventsAfter(stream.Context(), after, userIDs, backfillBatchSize)
+		if err != nil {
+			log.Error().Ctx(stream.Context()).Err(err).Str("userController", "WatchUsers").Msg("failed to backfill user changes")
+			return last, grpcerror.Error(err, "could not resume from the given event")
+		}
+
+		for _, e := range events {
+			last = position{createdAt: e.CreatedAt, eventID: e.ID}
+
+			payload, err := event.Decode(e.EventType, e.Payload)
+			if err != nil {
+				log.Error().Ctx(stream.Context()).Err(err).Str("event_id", e.ID).Msg("could not decode stored event")
+				continue
+			}
+			change, country, ok := toChange(e.ID, payload)
+			if !ok {
+				continue
+			}
+			if change.UserId == "" {
+				change.UserId = e.UserID
+			}
+			if !w.matches(change.UserId, country) {
+				continue
+			}
+			if err := stream.Send(change); err != nil {
+				return last, err
+			}
+		}
+
+		if len(events) < backfillBatchSize {
+			return last, nil
+		}
+		after = uuid.MustParse(events[len(events)-1].ID)
+	}
+}
+
+// toChange maps a typed event payload into a stream message and returns the user country
+func toChange(eventID string, payload interface{}) (*userProto.UserChange, string, bool) {
+	switch p := payload.(type) {
+	case event.CreatedPayload:
+		return &userProto.UserChange{
+			EventId: eventID,
+			UserId:  p.UserID,
+			TraceId: p.TraceID,
+			Version: p.Version,
+			Change: &userProto.UserChange_Created{Created: &userProto.UserCreated{
+				FirstName: p.FirstName,
+				LastName:  p.LastName,
+				Nickname:  p.Nickname,
+				Email:     p.Email,
+				Country:   p.Country,
+			}},
+		}, p.Country, true
+	case event.UpdatedPayload:
+		return &userProto.UserChange{
+			EventId: eventID,
+			UserId:  p.UserID,
+			TraceId: p.TraceID,
+			Version: p.Version,
+			Change: &userProto.UserChange_Updated{Updated: &userProto.UserUpdated{
+				Nickname: p.Nickname,
+				Country:  p.Country,
+				Changes:  mapChangesToProto(p.Changes),
+			}},
+		}, p.Country, true
+	case event.DeletedPayload:
+		return &userProto.UserChange{
+			EventId: eventID,
+			UserId:  p.UserID,
+			TraceId: p.TraceID,
+			Version: p.Version,
+			Change:  &userProto.UserChange_Deleted{Deleted: &userProto.UserDeleted{Country: p.Country}},
+		}, p.Country, true
+	default:
+		return nil, "", false
+	}
+}
+
+func mapChangesToProto(changes []event.FieldChange) []*userProto.FieldChange {
+	res := make([]*userProto.FieldChange, 0, len(changes))
+	for _, c := range changes {
+		res = append(res, &userProto.FieldChange{Field: c.Field, Old: c.Old, New: c.New})
+	}
+	return res
+}
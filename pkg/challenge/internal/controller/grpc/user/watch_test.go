@@ -0,0 +1,226 @@
+package user_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	controller "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/grpc/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	userProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub/local"
+)
+
+type fakeWatchStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	mu   sync.Mutex
+	sent []*userProto.UserChange
+}
+
+func (s *fakeWatchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeWatchStream) Send(c *userProto.UserChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, c)
+	return nil
+}
+
+func (s *fakeWatchStream) received() []*userProto.UserChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*userProto.UserChange(nil), s.sent...)
+}
+
+func TestWatchUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockUserService(ctrl)
+	bus := local.NewBus()
+	hub := controller.NewHub()
+	assert.NoError(t, hub.Register(bus))
+	c := controller.NewController(mockSvc, controller.WithHub(hub))
+
+	t.Run("should backfill and then stream live changes matching the filter", func(t *testing.T) {
+		userID := uuid.New().String()
+		resumeFrom := uuid.New()
+		createdAt := time.Date(2025, 5, 12, 8, 30, 0, 0, time.UTC)
+		stored := model.UserEventOutput{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			EventType: event.UserCreated,
+			Payload:   mustJSON(t, event.CreatedPayload{UserID: userID, Nickname: "backfilled", Country: "ES"}),
+			CreatedAt: createdAt,
+		}
+		// Stored while the first backfill ran, before the stream subscribed
+		storedMeanwhile := model.UserEventOutput{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			EventType: event.UserUpdated,
+			Payload:   mustJSON(t, event.UpdatedPayload{UserID: userID, Nickname: "meanwhile", Country: "ES"}),
+			CreatedAt: createdAt.Add(time.Second),
+		}
+
+		backfilled := make(chan struct{})
+		gomock.InOrder(
+			mockSvc.EXPECT().
+				ListEventsAfter(gomock.Any(), resumeFrom, gomock.Any(), gomock.Any()).
+				Return([]model.UserEventOutput{stored}, nil),
+			mockSvc.EXPECT().
+				ListEventsAfter(gomock.Any(), uuid.MustParse(stored.ID), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(context.Context, uuid.UUID, []uuid.UUID, int) ([]model.UserEventOutput, error) {
+					close(backfilled)
+					return []model.UserEventOutput{storedMeanwhile}, nil
+				}),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := &fakeWatchStream{ctx: ctx}
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- c.WatchUsers(&userProto.WatchUsersRequest{
+				Countries:          []string{"ES"},
+				ResumeAfterEventId: resumeFrom.String(),
+			}, stream)
+		}()
+		<-backfilled
+
+		// Events already backfilled and delivered live must not be sent twice
+		_ = bus.Publish(pubsub.WithEventTime(ctx, stored.CreatedAt), stored.ID, event.UserCreated, event.CreatedPayload{UserID: userID, Nickname: "backfilled", Country: "ES"})
+		_ = bus.Publish(pubsub.WithEventTime(ctx, storedMeanwhile.CreatedAt), storedMeanwhile.ID, event.UserUpdated, event.UpdatedPayload{UserID: userID, Nickname: "meanwhile", Country: "ES"})
+		_ = bus.Publish(ctx, uuid.NewString(), event.UserCreated, event.CreatedPayload{UserID: uuid.NewString(), Country: "IT"})
+		_ = bus.Publish(pubsub.WithEventTime(ctx, createdAt.Add(2*time.Second)), uuid.NewString(), event.UserUpdated, event.UpdatedPayload{UserID: userID, Nickname: "live", Country: "ES"})
+
+		assert.Eventually(t, func() bool {
+			return len(stream.received()) == 3
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		assert.NoError(t, <-errCh)
+
+		sent := stream.received()
+		assert.Len(t, sent, 3)
+		assert.Equal(t, "backfilled", sent[0].GetCreated().GetNickname())
+		assert.Equal(t, "meanwhile", sent[1].GetUpdated().GetNickname())
+		assert.Equal(t, "live", sent[2].GetUpdated().GetNickname())
+		assert.Equal(t, userID, sent[2].UserId)
+	})
+
+	t.Run("should send the live changes without event time", func(t *testing.T) {
+		resumeFrom := uuid.New()
+		stored := model.UserEventOutput{
+			ID:        uuid.New().String(),
+			UserID:    uuid.NewString(),
+			EventType: event.UserCreated,
+			Payload:   mustJSON(t, event.CreatedPayload{Nickname: "backfilled"}),
+			CreatedAt: time.Now(),
+		}
+
+		backfilled := make(chan struct{})
+		gomock.InOrder(
+			mockSvc.EXPECT().ListEventsAfter(gomock.Any(), resumeFrom, gomock.Any(), gomock.Any()).Return([]model.UserEventOutput{stored}, nil),
+			mockSvc.EXPECT().ListEventsAfter(gomock.Any(), uuid.MustParse(stored.ID), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(context.Context, uuid.UUID, []uuid.UUID, int) ([]model.UserEventOutput, error) {
+					close(backfilled)
+					return nil, nil
+				}),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stream := &fakeWatchStream{ctx: ctx}
+		go func() {
+			_ = c.WatchUsers(&userProto.WatchUsersRequest{ResumeAfterEventId: resumeFrom.String()}, stream)
+		}()
+		<-backfilled
+
+		_ = bus.Publish(ctx, uuid.NewString(), event.UserUpdated, event.UpdatedPayload{UserID: stored.UserID, Nickname: "live"})
+
+		assert.Eventually(t, func() bool {
+			sent := stream.received()
+			return len(sent) == 2 && sent[1].GetUpdated().GetNickname() == "live"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should fail on invalid user ID", func(t *testing.T) {
+		stream := &fakeWatchStream{ctx: context.Background()}
+		err := c.WatchUsers(&userProto.WatchUsersRequest{UserIds: []string{"nope"}}, stream)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("should fail when the event to resume from is not found", func(t *testing.T) {
+		resumeFrom := uuid.New()
+		mockSvc.EXPECT().ListEventsAfter(gomock.Any(), resumeFrom, gomock.Any(), gomock.Any()).Return(nil, repo.ErrRecordNotFound)
+
+		stream := &fakeWatchStream{ctx: context.Background()}
+		err := c.WatchUsers(&userProto.WatchUsersRequest{ResumeAfterEventId: resumeFrom.String()}, stream)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("should fail with an internal error when the events can not be loaded", func(t *testing.T) {
+		resumeFrom := uuid.New()
+		mockSvc.EXPECT().ListEventsAfter(gomock.Any(), resumeFrom, gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused"))
+
+		stream := &fakeWatchStream{ctx: context.Background()}
+		err := c.WatchUsers(&userProto.WatchUsersRequest{ResumeAfterEventId: resumeFrom.String()}, stream)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.NotContains(t, status.Convert(err).Message(), "connection refused")
+	})
+
+	t.Run("should be unavailable without a hub", func(t *testing.T) {
+		stream := &fakeWatchStream{ctx: context.Background()}
+		err := controller.NewController(mockSvc).WatchUsers(&userProto.WatchUsersRequest{}, stream)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func TestWatchUsers_HubStop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hub := controller.NewHub()
+	c := controller.NewController(mocks.NewMockUserService(ctrl), controller.WithHub(hub))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.WatchUsers(&userProto.WatchUsersRequest{}, &fakeWatchStream{ctx: context.Background()})
+	}()
+
+	assert.NoError(t, hub.Stop(context.Background()))
+	select {
+	case err := <-errCh:
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	case <-time.After(time.Second):
+		t.Fatal("stream did not end when the hub stopped")
+	}
+	assert.NoError(t, hub.Run())
+
+	t.Run("should end streams started after the stop", func(t *testing.T) {
+		err := c.WatchUsers(&userProto.WatchUsersRequest{}, &fakeWatchStream{ctx: context.Background()})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return data
+}
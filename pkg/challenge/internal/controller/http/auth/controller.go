@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/httperror"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	service "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/auth"
+)
+
+type Controller struct {
+	svc service.Service
+}
+
+// NewController returns a HTTP Auth controller
+func NewController(s service.Service) *Controller {
+	return &Controller{svc: s}
+}
+
+// Login returns an access token and a refresh token for a valid email or nickname and password
+func (c *Controller) Login(ctx *gin.Context) {
+	var input model.LoginInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "Login").Msg("not valid data")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid input", err.Error())
+		return
+	}
+
+	input.UserAgent = ctx.Request.UserAgent()
+	input.IPAddress = ctx.ClientIP()
+
+	token, err := c.svc.Login(ctx, &input)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			httperror.Write(ctx, domainerr.Unauthenticated(err.Error()), "could not login")
+			return
+		}
+		log.Error().Ctx(ctx).Err(err).Str("authController", "Login").Msg("could not login")
+		httperror.Write(ctx, err, "could not login")
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, token)
+}
+
+// Token returns an access token for a service account (OAuth2 client credentials)
+func (c *Controller) Token(ctx *gin.Context) {
+	var input model.ClientCredentialsInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "Token").Msg("not valid data")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid input", err.Error())
+		return
+	}
+
+	token, err := c.svc.Token(ctx, &input)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidClient) {
+			httperror.Write(ctx, domainerr.Unauthenticated(err.Error()), "could not issue token")
+			return
+		}
+		log.Error().Ctx(ctx).Err(err).Str("authController", "Token").Msg("could not issue token")
+		httperror.Write(ctx, err, "could not issue token")
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, token)
+}
+
+// Refresh exchanges a refresh token for a new access token and refresh token.
+// Unknown, expired, revoked and reused refresh tokens are unauthorized
+func (c *Controller) Refresh(ctx *gin.Context) {
+	var input model.RefreshInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "Refresh").Msg("not valid data")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid input", err.Error())
+		return
+	}
+	input.UserAgent = ctx.Request.UserAgent()
+	input.IPAddress = ctx.ClientIP()
+
+	token, err := c.svc.Refresh(ctx, &input)
+	if err != nil {
+		if errors.Is(err, session.ErrInvalidRefreshToken) || errors.Is(err, session.ErrRefreshTokenReused) {
+			httperror.Write(ctx, domainerr.Unauthenticated(err.Error()), "could not refresh token")
+			return
+		}
+		log.Error().Ctx(ctx).Err(err).Str("authController", "Refresh").Msg("could not refresh token")
+		httperror.Write(ctx, err, "could not refresh token")
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, token)
+}
+
+// ListSessions returns the active sessions of an user
+func (c *Controller) ListSessions(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "ListSessions").Msg("invalid user ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid user ID", err.Error())
+		return
+	}
+
+	sessions, err := c.svc.ListSessions(ctx, userID)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "ListSessions").Msg("could not list sessions")
+		httperror.Write(ctx, err, "could not list sessions")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession revokes one session of an user
+func (c *Controller) RevokeSession(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSession").Msg("invalid user ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid user ID", err.Error())
+		return
+	}
+
+	sessionID, err := uuid.Parse(ctx.Param("session_id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSession").Msg("invalid session ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid session ID", err.Error())
+		return
+	}
+
+	if err := c.svc.RevokeSession(ctx, userID, sessionID); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSession").Msg("could not revoke session")
+		httperror.Write(ctx, err, "could not revoke session")
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RevokeSessions revokes every session of an user, signing it out of all its devices
+func (c *Controller) RevokeSessions(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSessions").Msg("invalid user ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid user ID", err.Error())
+		return
+	}
+
+	revoked, err := c.svc.RevokeSessions(ctx, userID)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("authController", "RevokeSessions").Msg("could not revoke sessions")
+		httperror.Write(ctx, err, "could not revoke sessions")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.RevokeSessionsOutput{Revoked: revoked})
+}
+
+// JWKS returns the public keys other services use to verify the access tokens
+func (c *Controller) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.svc.JWKS())
+}
+
+func returnsWithError(ctx *gin.Context, code int, message string, details ...string) {
+	res := model.ErrorResponse{Error: message}
+	if len(details) > 0 {
+		res.Details = details[0]
+	}
+	ctx.JSON(code, res)
+}
@@ -0,0 +1,204 @@
+package auth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	authKeys "github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+func TestController_Login(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocks.NewMockAuthService(ctrl)
+	handler := auth.NewController(mockService)
+
+	login := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader([]byte(body)))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		handler.Login(ctx)
+		return w
+	}
+
+	t.Run("should return a token", func(t *testing.T) {
+		mockService.EXPECT().
+			Login(gomock.Any(), &model.LoginInput{Login: "bandido", Password: "supersecure"}).
+			Return(&model.TokenOutput{AccessToken: "token", TokenType: "Bearer", ExpiresIn: 900}, nil)
+
+		w := login(`{"login":"bandido","password":"supersecure"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+		var res model.TokenOutput
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "token", res.AccessToken)
+	})
+
+	t.Run("should return 401 on invalid credentials", func(t *testing.T) {
+		mockService.EXPECT().
+			Login(gomock.Any(), gomock.Any()).
+			Return(nil, user.ErrInvalidCredentials)
+
+		w := login(`{"login":"bandido","password":"wrong-password"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should return 400 without password", func(t *testing.T) {
+		w := login(`{"login":"bandido"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestController_JWKS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocks.NewMockAuthService(ctrl)
+	handler := auth.NewController(mockService)
+
+	mockService.EXPECT().JWKS().Return(authKeys.JWKS{Keys: []authKeys.JWK{{KeyType: "OKP", KeyID: "kid"}}})
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request, _ = http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	handler.JWKS(ctx)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res authKeys.JWKS
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "kid", res.Keys[0].KeyID)
+}
+
+func TestController_Token(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocks.NewMockAuthService(ctrl)
+	handler := auth.NewController(mockService)
+
+	token := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader([]byte(body)))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		handler.Token(ctx)
+		return w
+	}
+
+	t.Run("should return a token", func(t *testing.T) {
+		mockService.EXPECT().
+			Token(gomock.Any(), &model.ClientCredentialsInput{ClientID: "reporting", ClientSecret: "secret"}).
+			Return(&model.TokenOutput{AccessToken: "token", Scope: "users:read"}, nil)
+
+		w := token(`{"client_id":"reporting","client_secret":"secret"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should return 401 on invalid client credentials", func(t *testing.T) {
+		mockService.EXPECT().Token(gomock.Any(), gomock.Any()).Return(nil, authKeys.ErrInvalidClient)
+
+		w := token(`{"client_id":"reporting","client_secret":"wrong"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestController_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocks.NewMockAuthService(ctrl)
+	handler := auth.NewController(mockService)
+
+	refresh := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewReader([]byte(body)))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Request.Header.Set("User-Agent", "curl/8.0")
+		handler.Refresh(ctx)
+		return w
+	}
+
+	t.Run("should return new tokens", func(t *testing.T) {
+		mockService.EXPECT().
+			Refresh(gomock.Any(), &model.RefreshInput{RefreshToken: "refresh-token", UserAgent: "curl/8.0"}).
+			Return(&model.TokenOutput{AccessToken: "token", RefreshToken: "new-refresh-token"}, nil)
+
+		w := refresh(`{"refresh_token":"refresh-token"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+		var res model.TokenOutput
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "new-refresh-token", res.RefreshToken)
+	})
+
+	t.Run("should return 401 when the refresh token was reused", func(t *testing.T) {
+		mockService.EXPECT().Refresh(gomock.Any(), gomock.Any()).Return(nil, session.ErrRefreshTokenReused)
+
+		w := refresh(`{"refresh_token":"refresh-token"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should return 400 without refresh token", func(t *testing.T) {
+		w := refresh(`{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestController_RevokeSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocks.NewMockAuthService(ctrl)
+	handler := auth.NewController(mockService)
+
+	router := gin.New()
+	router.DELETE("/users/:id/sessions/:session_id", handler.RevokeSession)
+	router.DELETE("/users/:id/sessions", handler.RevokeSessions)
+
+	userID, sessionID := uuid.New(), uuid.New()
+
+	t.Run("should revoke one session", func(t *testing.T) {
+		mockService.EXPECT().RevokeSession(gomock.Any(), userID, sessionID).Return(nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/users/"+userID.String()+"/sessions/"+sessionID.String(), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("should return 404 for unknown sessions", func(t *testing.T) {
+		mockService.EXPECT().RevokeSession(gomock.Any(), userID, sessionID).Return(repo.ErrRecordNotFound)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/users/"+userID.String()+"/sessions/"+sessionID.String(), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should revoke every session", func(t *testing.T) {
+		mockService.EXPECT().RevokeSessions(gomock.Any(), userID).Return(2, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/users/"+userID.String()+"/sessions", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res model.RevokeSessionsOutput
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, 2, res.Revoked)
+	})
+}
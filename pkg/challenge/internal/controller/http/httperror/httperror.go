@@ -0,0 +1,51 @@
+// Package httperror translates domain errors to HTTP responses
+package httperror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+)
+
+// Status returns the HTTP status code of an error, 500 for anything not a domain error
+func Status(err error) int {
+	switch {
+	case errors.Is(err, domainerr.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domainerr.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domainerr.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domainerr.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, domainerr.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, domainerr.ErrPermissionDenied):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Response returns the body answered for an error. Domain errors keep their text in
+// Details and the field they are about, if any, in Fields. Internal errors only show message
+func Response(err error, message string) model.ErrorResponse {
+	if Status(err) == http.StatusInternalServerError {
+		return model.ErrorResponse{Error: message}
+	}
+
+	res := model.ErrorResponse{Error: message, Details: err.Error()}
+	if e, ok := domainerr.As(err); ok && e.Field != "" {
+		res.Fields = []model.FieldError{{Field: e.Field, Description: e.Description()}}
+	}
+	return res
+}
+
+// Write answers the request with the status and body of the error
+func Write(ctx *gin.Context, err error, message string) {
+	ctx.JSON(Status(err), Response(err, message))
+}
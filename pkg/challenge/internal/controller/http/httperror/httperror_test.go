@@ -0,0 +1,60 @@
+package httperror_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/httperror"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", domainerr.NotFound("user not found"), http.StatusNotFound},
+		{"conflict", domainerr.Conflict("email", "already taken", nil), http.StatusConflict},
+		{"validation", fmt.Errorf("create: %w", domainerr.Validation("email", errors.New("invalid"))), http.StatusUnprocessableEntity},
+		{"precondition failed", domainerr.PreconditionFailed("version mismatch"), http.StatusPreconditionFailed},
+		{"unauthenticated", domainerr.Unauthenticated("invalid access token"), http.StatusUnauthorized},
+		{"permission denied", domainerr.PermissionDenied("permission denied"), http.StatusForbidden},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, httperror.Status(tt.err))
+		})
+	}
+}
+
+func TestResponse(t *testing.T) {
+	t.Run("should list the field of the error", func(t *testing.T) {
+		err := domainerr.Conflict("email", "already taken", nil)
+
+		res := httperror.Response(err, "could not create user")
+		assert.Equal(t, model.ErrorResponse{
+			Error:   "could not create user",
+			Details: "email: already taken",
+			Fields:  []model.FieldError{{Field: "email", Description: "already taken"}},
+		}, res)
+	})
+
+	t.Run("should not list fields of errors without one", func(t *testing.T) {
+		res := httperror.Response(domainerr.NotFound("user not found"), "could not get user")
+		assert.Equal(t, model.ErrorResponse{Error: "could not get user", Details: "user not found"}, res)
+	})
+
+	t.Run("should only show the message of internal errors", func(t *testing.T) {
+		err := fmt.Errorf("could not insert: %w", errors.New(`duplicate key value violates unique constraint "user_pkey"`))
+
+		res := httperror.Response(err, "could not create user")
+		assert.Equal(t, model.ErrorResponse{Error: "could not create user"}, res)
+	})
+}
@@ -1,18 +1,48 @@
 package user
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/httperror"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	entityUser "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
 	service "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/user"
 )
 
+var (
+	// ErrFieldNotUpdatable used when a merge patch has a field that cannot be updated
+	ErrFieldNotUpdatable = domainerr.Validation("", errors.New("field cannot be updated"))
+	// ErrNotAString used when a merge patch field is not a string
+	ErrNotAString = domainerr.Validation("", errors.New("must be a string"))
+	// ErrInvalidIfMatch used when If-Match is not a single strong ETag returned by this API
+	ErrInvalidIfMatch = errors.New("header must be a single strong ETag or *")
+)
+
+const (
+	// maxEventsLimit is the max page size allowed when listing user events
+	maxEventsLimit = 100
+	// maxUsersLimit is the max page size allowed when finding users
+	maxUsersLimit = 100
+	// maxSearchQueryLength is the max length of a fuzzy search query
+	maxSearchQueryLength = 100
+	// HeaderIdempotencyKey makes retries of a create return the user first created
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
 type Controller struct {
 	svc service.Service
 }
@@ -22,111 +52,431 @@ func NewController(s service.Service) *Controller {
 	return &Controller{svc: s}
 }
 
-// Create returns a created user
+// Create returns a created user. Requests with an Idempotency-Key header can be retried
+// safely, the same key and body always get the user first created back
 func (c *Controller) Create(ctx *gin.Context) {
 	var input model.CreateUserInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		log.Error().Err(err).Str("userController", "Create").Msg("not valid data")
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Create").Msg("not valid data")
 		returnsWithError(ctx, http.StatusBadRequest, "invalid input", err.Error())
 		return
 	}
+	input.IdempotencyKey = ctx.GetHeader(HeaderIdempotencyKey)
 
 	user, err := c.svc.Create(ctx, &input)
 	if err != nil {
-		log.Error().Err(err).Str("userController", "Create").Msg("could not create user")
-		returnsWithError(ctx, http.StatusInternalServerError, "could not create user", err.Error())
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Create").Msg("could not create user")
+		httperror.Write(ctx, err, "could not create user")
 		return
 	}
 
+	ctx.Header("ETag", etag(user.Version))
 	ctx.JSON(http.StatusCreated, user)
 }
 
-// Find returns a list of users. It is paginated and also can be filtered by country
+// Find returns a list of users. It can be filtered by countries, nickname and email prefixes and
+// a creation time range (RFC3339), sorted by created_at or nickname (descending with a leading -),
+// and paginated with cursors, or page and limit. The next page is linked in the Link header and,
+// with include_total=true, every matching user is counted in X-Total-Count
 func (c *Controller) Find(ctx *gin.Context) {
-	country := ctx.DefaultQuery("country", "")
-	pageStr := ctx.DefaultQuery("page", "1")
-	limitStr := ctx.DefaultQuery("limit", "10")
+	filter := model.UserFilter{
+		NicknamePrefix: ctx.Query("nickname_prefix"),
+		EmailPrefix:    ctx.Query("email_prefix"),
+		Cursor:         ctx.Query("cursor"),
+	}
+
+	// Countries can be repeated or comma separated
+	for _, countries := range ctx.QueryArray("country") {
+		for _, country := range strings.Split(countries, ",") {
+			if country = strings.TrimSpace(country); country != "" {
+				filter.Countries = append(filter.Countries, country)
+			}
+		}
+	}
+
+	if sort := ctx.Query("sort"); sort != "" {
+		filter.SortBy = strings.TrimPrefix(sort, "-")
+		filter.Descending = strings.HasPrefix(sort, "-")
+	}
+
+	var err error
+	if from := ctx.Query("created_from"); from != "" {
+		filter.CreatedFrom, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			log.Error().Ctx(ctx).Err(err).Str("userController", "Find").Msg("invalid created_from param")
+			returnsWithError(ctx, http.StatusBadRequest, "invalid created_from parameter", err.Error())
+			return
+		}
+	}
+
+	if to := ctx.Query("created_to"); to != "" {
+		filter.CreatedTo, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			log.Error().Ctx(ctx).Err(err).Str("userController", "Find").Msg("invalid created_to param")
+			returnsWithError(ctx, http.StatusBadRequest, "invalid created_to parameter", err.Error())
+			return
+		}
+	}
+
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && filter.CreatedFrom.After(filter.CreatedTo) {
+		log.Error().Ctx(ctx).Str("userController", "Find").Msg("created_from is after created_to")
+		returnsWithError(ctx, http.StatusBadRequest, "created_from must be before created_to")
+		return
+	}
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		log.Error().Err(err).Str("userController", "Find").Msg("invalid pagination page param")
+	filter.Page, err = strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || filter.Page < 1 {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Find").Msg("invalid pagination page param")
 		returnsWithError(ctx, http.StatusBadRequest, "invalid page parameter")
 		return
 	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 {
-		log.Error().Err(err).Str("userController", "Find").Msg("invalid pagination limit param")
+	filter.Limit, err = strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || filter.Limit < 1 || filter.Limit > maxUsersLimit {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Find").Msg("invalid pagination limit param")
 		returnsWithError(ctx, http.StatusBadRequest, "invalid limit parameter")
 		return
 	}
 
-	users, err := c.svc.Find(ctx, country, page, limit)
+	if includeTotal := ctx.Query("include_total"); includeTotal != "" {
+		filter.IncludeTotal, err = strconv.ParseBool(includeTotal)
+		if err != nil {
+			log.Error().Ctx(ctx).Err(err).Str("userController", "Find").Msg("invalid include_total param")
+			returnsWithError(ctx, http.StatusBadRequest, "invalid include_total parameter")
+			return
+		}
+	}
+
+	page, err := c.svc.Find(ctx, filter)
 	if err != nil {
-		log.Error().Err(err).Str("userController", "Find").Msg("could not find users")
-		returnsWithError(ctx, http.StatusInternalServerError, "could not find users", err.Error())
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Find").Msg("could not find users")
+		httperror.Write(ctx, err, "could not find users")
 		return
 	}
 
-	returnsWithSuccess(ctx, users)
+	if page.NextPageToken != "" {
+		ctx.Header("Link", pageLinks(ctx.Request.URL, page.NextPageToken))
+	}
+	if page.Total != nil {
+		ctx.Header("X-Total-Count", strconv.FormatInt(*page.Total, 10))
+	}
+	returnsWithSuccess(ctx, page.Users)
 }
 
-// Update updates an user nickname
-func (c *Controller) Update(ctx *gin.Context) {
-	var input struct {
-		Nickname string `json:"nickname" binding:"required"`
+// Search returns the users whose nickname or name are similar to the q query param, best
+// matches first, so misspelled nicknames are found too. Soft deleted users are never returned
+func (c *Controller) Search(ctx *gin.Context) {
+	query := strings.TrimSpace(ctx.Query("q"))
+	if query == "" || len(query) > maxSearchQueryLength {
+		log.Error().Ctx(ctx).Str("userController", "Search").Msg("invalid q param")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid q parameter")
+		return
 	}
 
-	if err := ctx.ShouldBindJSON(&input); err != nil {
-		log.Error().Err(err).Str("userController", "Update").Msg("invalid update data")
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > maxUsersLimit {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Search").Msg("invalid limit param")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid limit parameter")
+		return
+	}
+
+	matches, err := c.svc.Search(ctx, query, limit)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Search").Msg("could not search users")
+		httperror.Write(ctx, err, "could not search users")
+		return
+	}
+
+	returnsWithSuccess(ctx, matches)
+}
+
+// NicknameAvailability tells whether a nickname is free, ignoring case, and suggests
+// free alternatives when it is taken
+func (c *Controller) NicknameAvailability(ctx *gin.Context) {
+	nickname := strings.TrimSpace(ctx.Param("nick"))
+	if nickname == "" || len(nickname) > entityUser.MaxNicknameLength {
+		log.Error().Ctx(ctx).Str("userController", "NicknameAvailability").Msg("invalid nickname param")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid nickname")
+		return
+	}
+
+	availability, err := c.svc.NicknameAvailability(ctx, nickname)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "NicknameAvailability").Msg("could not check nickname availability")
+		httperror.Write(ctx, err, "could not check nickname availability")
+		return
+	}
+
+	returnsWithSuccess(ctx, availability)
+}
+
+// pageLinks returns the RFC 8288 links to the first and the next page of the request
+func pageLinks(u *url.URL, nextPageToken string) string {
+	first := *u
+	query := first.Query()
+	query.Del("page")
+	query.Del("cursor")
+	first.RawQuery = query.Encode()
+
+	next := first
+	query.Set("cursor", nextPageToken)
+	next.RawQuery = query.Encode()
+
+	return fmt.Sprintf(`<%s>; rel="first", <%s>; rel="next"`, first.RequestURI(), next.RequestURI())
+}
+
+// Get returns an user by ID. With the as_of query param (RFC3339) the user is rebuilt
+// from its events as it was at that time. Admins can also get soft deleted users
+// with include_deleted=true
+func (c *Controller) Get(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Get").Msg("invalid user ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid user ID", err.Error())
+		return
+	}
+
+	if asOfStr := ctx.Query("as_of"); asOfStr != "" {
+		c.getAsOf(ctx, id, asOfStr)
+		return
+	}
+
+	includeDeleted, err := strconv.ParseBool(ctx.DefaultQuery("include_deleted", "false"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Get").Msg("invalid include_deleted param")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid include_deleted parameter", err.Error())
+		return
+	}
+	if includeDeleted {
+		if principal, ok := auth.PrincipalFromContext(ctx.Request.Context()); !ok || principal.Role != auth.RoleAdmin {
+			log.Error().Ctx(ctx).Str("userController", "Get").Msg("include_deleted is only for admins")
+			httperror.Write(ctx, domainerr.PermissionDenied("include_deleted is only allowed to admins"), "could not get user")
+			return
+		}
+	}
+
+	user, err := c.svc.Get(ctx, id, includeDeleted)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Get").Msg("could not get user")
+		httperror.Write(ctx, err, "could not get user")
+		return
+	}
+
+	ctx.Header("ETag", etag(user.Version))
+	returnsWithSuccess(ctx, user)
+}
+
+func (c *Controller) getAsOf(ctx *gin.Context, id uuid.UUID, asOfStr string) {
+	asOf, err := time.Parse(time.RFC3339, asOfStr)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Get").Msg("invalid as_of param")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid as_of parameter", err.Error())
+		return
+	}
+
+	user, err := c.svc.GetAsOf(ctx, id, asOf)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Get").Msg("could not get user")
+		httperror.Write(ctx, err, "could not get user")
+		return
+	}
+
+	returnsWithSuccess(ctx, user)
+}
+
+// Update applies a JSON merge patch (RFC 7396) to an user. Only first_name, last_name,
+// nickname, email and country can be changed and none of them can be removed.
+// With If-Match it fails with 412 if the user changed since that version
+func (c *Controller) Update(ctx *gin.Context) {
+	var patch map[string]json.RawMessage
+	if err := ctx.ShouldBindJSON(&patch); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Update").Msg("invalid update data")
 		returnsWithError(ctx, http.StatusBadRequest, "invalid update data", err.Error())
 		return
 	}
 
-	if strings.TrimSpace(input.Nickname) == "" {
-		log.Error().Str("userController", "Update").Msg("nickname cannot be empty or whitespace")
-		returnsWithError(ctx, http.StatusUnprocessableEntity, "nickname cannot be empty or whitespace")
+	input, err := mergePatchInput(patch)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Update").Msg("invalid update data")
+		httperror.Write(ctx, err, "invalid update data")
 		return
 	}
 
 	paramID := ctx.Param("id")
 	id, err := uuid.Parse(paramID)
 	if err != nil {
-		log.Error().Err(err).Str("userController", "Update").Msg("invalid ID formatt")
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Update").Msg("invalid ID formatt")
 		returnsWithError(ctx, http.StatusBadRequest, "invalid ID format", err.Error())
 		return
 	}
 
-	updatedUser, err := c.svc.Update(ctx, id, input.Nickname)
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Update").Msg("invalid If-Match header")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid If-Match header", err.Error())
+		return
+	}
+
+	updatedUser, err := c.svc.Update(ctx, id, input, version)
 	if err != nil {
-		log.Error().Err(err).Str("userController", "Update").Msg("could not update user")
-		returnsWithError(ctx, http.StatusInternalServerError, "could not update user", err.Error())
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Update").Msg("could not update user")
+		httperror.Write(ctx, err, "could not update user")
 		return
 	}
 
+	ctx.Header("ETag", etag(updatedUser.Version))
 	returnsWithSuccess(ctx, updatedUser)
 }
 
-// Delete soft deletes an user based on its ID
+// Delete soft deletes an user based on its ID. With If-Match it fails with 412 if the
+// user changed since that version
 func (c *Controller) Delete(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		log.Error().Err(err).Str("userController", "Delete").Msg("invalid user ID")
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Delete").Msg("invalid user ID")
 		returnsWithError(ctx, http.StatusBadRequest, "invalid user ID", err.Error())
 		return
 	}
 
-	err = c.svc.Delete(ctx, id)
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Delete").Msg("invalid If-Match header")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid If-Match header", err.Error())
+		return
+	}
+
+	err = c.svc.Delete(ctx, id, version)
 	if err != nil {
-		log.Error().Err(err).Str("userController", "Delete").Msg("could not delete user")
-		returnsWithError(ctx, http.StatusInternalServerError, "could not delete user", err.Error())
+		log.Error().Ctx(ctx).Err(err).Str("userController", "Delete").Msg("could not delete user")
+		httperror.Write(ctx, err, "could not delete user")
 		return
 	}
 
 	ctx.Status(http.StatusOK)
 }
 
+// ListEvents returns the change history of an user. It is paginated and can be
+// filtered by event type and by a creation time range (RFC3339)
+func (c *Controller) ListEvents(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "ListEvents").Msg("invalid user ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid user ID", err.Error())
+		return
+	}
+
+	filter := model.UserEventFilter{UserID: id}
+
+	for _, t := range ctx.QueryArray("event_type") {
+		if !event.IsValidType(t) {
+			log.Error().Ctx(ctx).Str("userController", "ListEvents").Msg("invalid event_type param")
+			returnsWithError(ctx, http.StatusBadRequest, "invalid event_type parameter", t)
+			return
+		}
+		filter.EventTypes = append(filter.EventTypes, t)
+	}
+
+	if from := ctx.Query("from"); from != "" {
+		filter.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			log.Error().Ctx(ctx).Err(err).Str("userController", "ListEvents").Msg("invalid from param")
+			returnsWithError(ctx, http.StatusBadRequest, "invalid from parameter", err.Error())
+			return
+		}
+	}
+
+	if to := ctx.Query("to"); to != "" {
+		filter.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			log.Error().Ctx(ctx).Err(err).Str("userController", "ListEvents").Msg("invalid to param")
+			returnsWithError(ctx, http.StatusBadRequest, "invalid to parameter", err.Error())
+			return
+		}
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		log.Error().Ctx(ctx).Str("userController", "ListEvents").Msg("from is after to")
+		returnsWithError(ctx, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	filter.Page, err = strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || filter.Page < 1 {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "ListEvents").Msg("invalid pagination page param")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid page parameter")
+		return
+	}
+
+	filter.Limit, err = strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || filter.Limit < 1 || filter.Limit > maxEventsLimit {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "ListEvents").Msg("invalid pagination limit param")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid limit parameter")
+		return
+	}
+
+	events, err := c.svc.ListEvents(ctx, filter)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("userController", "ListEvents").Msg("could not list user events")
+		httperror.Write(ctx, err, "could not list user events")
+		return
+	}
+
+	returnsWithSuccess(ctx, events)
+}
+
+// mergePatchInput maps a merge patch to the fields to update. Every updatable field
+// is required, so they cannot be null or blank
+func mergePatchInput(patch map[string]json.RawMessage) (model.UpdateUserInput, error) {
+	var in model.UpdateUserInput
+	fields := map[string]**string{
+		"first_name": &in.FirstName,
+		"last_name":  &in.LastName,
+		"nickname":   &in.Nickname,
+		"email":      &in.Email,
+		"country":    &in.Country,
+	}
+
+	for name, raw := range patch {
+		target, ok := fields[name]
+		if !ok {
+			return in, domainerr.Validation(name, ErrFieldNotUpdatable)
+		}
+
+		var value *string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return in, domainerr.Validation(name, ErrNotAString)
+		}
+		if value == nil || strings.TrimSpace(*value) == "" {
+			return in, domainerr.Validation(name, entityUser.ErrEmptyField)
+		}
+		*target = value
+	}
+	return in, nil
+}
+
+// etag returns the strong ETag of an user version
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion returns the user version in the If-Match header, 0 when there is none or it is *
+func ifMatchVersion(ctx *gin.Context) (int64, error) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	if !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) || len(header) < 2 {
+		return 0, ErrInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, ErrInvalidIfMatch
+	}
+	return version, nil
+}
+
 func returnsWithError(ctx *gin.Context, code int, message string, details ...string) {
 	res := model.ErrorResponse{Error: message}
 	if len(details) > 0 {
@@ -3,18 +3,27 @@ package user_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
+	entityUser "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	projector "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/projector/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
 )
 
 func TestController_Create_Success(t *testing.T) {
@@ -77,6 +86,91 @@ func TestController_Create_InvalidData(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestController_Create_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	input := model.CreateUserInput{
+		FirstName: "Nacho",
+		LastName:  "Calcagno",
+		Nickname:  "bandido",
+		Password:  "111123123",
+		Email:     "nacho@bandidoclub.com",
+		Country:   "VE",
+	}
+	mockService.EXPECT().
+		Create(gomock.Any(), &input).
+		Return(nil, domainerr.Conflict("email", "already taken", nil))
+
+	body, _ := json.Marshal(input)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request, _ = http.NewRequest(http.MethodPost, "/users", bytes.NewReader(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Create(ctx)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var res model.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, []model.FieldError{{Field: "email", Description: "already taken"}}, res.Fields)
+}
+
+func TestController_Create_IdempotencyKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	input := model.CreateUserInput{
+		FirstName: "Nacho",
+		LastName:  "Calcagno",
+		Nickname:  "bandido",
+		Password:  "111123123",
+		Email:     "nacho@bandidoclub.com",
+		Country:   "VE",
+	}
+	body, _ := json.Marshal(input)
+
+	create := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/users", bytes.NewReader(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Request.Header.Set(user.HeaderIdempotencyKey, "key-1")
+		handler.Create(ctx)
+		return w
+	}
+
+	t.Run("should pass the key to the service", func(t *testing.T) {
+		withKey := input
+		withKey.IdempotencyKey = "key-1"
+		mockService.EXPECT().
+			Create(gomock.Any(), &withKey).
+			Return(&model.UserOutput{ID: uuid.New().String(), Version: 1}, nil)
+
+		w := create()
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("should answer 422 when the key was used with another body", func(t *testing.T) {
+		mockService.EXPECT().
+			Create(gomock.Any(), gomock.Any()).
+			Return(nil, idempotency.ErrKeyReused)
+
+		w := create()
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var res model.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "idempotency_key", res.Fields[0].Field)
+	})
+}
+
 func TestController_Find_InvalidParams(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -94,6 +188,150 @@ func TestController_Find_InvalidParams(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestController_Find_Cursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	find := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, target, nil)
+		handler.Find(ctx)
+		return w
+	}
+
+	t.Run("should link the next page and send the total", func(t *testing.T) {
+		total := int64(3)
+		mockService.EXPECT().
+			Find(gomock.Any(), model.UserFilter{Countries: []string{"UK"}, Cursor: "abc", Page: 1, Limit: 2, IncludeTotal: true}).
+			Return(&model.UsersPage{
+				Users:         []model.UserOutput{{ID: "1"}, {ID: "2"}},
+				NextPageToken: "def",
+				Total:         &total,
+			}, nil)
+
+		w := find("/users?country=UK&cursor=abc&limit=2&include_total=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("X-Total-Count"))
+		assert.Equal(t,
+			`</users?country=UK&include_total=true&limit=2>; rel="first", </users?country=UK&cursor=def&include_total=true&limit=2>; rel="next"`,
+			w.Header().Get("Link"))
+
+		var res []model.UserOutput
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Len(t, res, 2)
+	})
+
+	t.Run("should not link a next page on the last one", func(t *testing.T) {
+		mockService.EXPECT().
+			Find(gomock.Any(), gomock.Any()).
+			Return(&model.UsersPage{Users: []model.UserOutput{{ID: "1"}}}, nil)
+
+		w := find("/users?page=2")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Link"))
+		assert.Empty(t, w.Header().Get("X-Total-Count"))
+	})
+
+	t.Run("should reject limits over the max", func(t *testing.T) {
+		w := find("/users?limit=101")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should answer 422 for invalid cursors", func(t *testing.T) {
+		mockService.EXPECT().
+			Find(gomock.Any(), gomock.Any()).
+			Return(nil, domainerr.Validation("cursor", errors.New("cursor is not valid")))
+
+		w := find("/users?cursor=bad")
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
+
+func TestController_Find_Filters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	find := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, target, nil)
+		handler.Find(ctx)
+		return w
+	}
+
+	t.Run("should pass the filters and the sort to the service", func(t *testing.T) {
+		mockService.EXPECT().
+			Find(gomock.Any(), model.UserFilter{
+				Countries:      []string{"ES", "IT", "UK"},
+				NicknamePrefix: "nac",
+				EmailPrefix:    "nacho@",
+				CreatedFrom:    time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+				CreatedTo:      time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+				SortBy:         model.UserSortNickname,
+				Descending:     true,
+				Page:           1,
+				Limit:          10,
+			}).
+			Return(&model.UsersPage{}, nil)
+
+		w := find("/users?country=ES,IT&country=UK&nickname_prefix=nac&email_prefix=nacho@" +
+			"&created_from=2025-04-01T00:00:00Z&created_to=2025-05-01T00:00:00Z&sort=-nickname")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should reject invalid dates and ranges", func(t *testing.T) {
+		w := find("/users?created_from=yesterday")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = find("/users?created_from=2025-05-01T00:00:00Z&created_to=2025-04-01T00:00:00Z")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestController_Search(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	search := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, target, nil)
+		handler.Search(ctx)
+		return w
+	}
+
+	t.Run("should return the matches", func(t *testing.T) {
+		mockService.EXPECT().
+			Search(gomock.Any(), "bandid", 10).
+			Return([]model.UserMatch{{UserOutput: model.UserOutput{Nickname: "bandido"}, Score: 0.7}}, nil)
+
+		w := search("/users/search?q=+bandid+")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res []model.UserMatch
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		if assert.Len(t, res, 1) {
+			assert.Equal(t, "bandido", res[0].Nickname)
+			assert.Equal(t, 0.7, res[0].Score)
+		}
+	})
+
+	t.Run("should reject missing queries and invalid limits", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, search("/users/search").Code)
+		assert.Equal(t, http.StatusBadRequest, search("/users/search?q=bandid&limit=0").Code)
+	})
+}
+
 func TestController_Update_EmptyNickname(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -115,6 +353,172 @@ func TestController_Update_EmptyNickname(t *testing.T) {
 	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 }
 
+func TestController_Update_NicknameConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	id := uuid.New()
+	mockService.EXPECT().
+		Update(gomock.Any(), id, gomock.Any(), int64(0)).
+		Return(nil, domainerr.Conflict("nickname", "already taken", nil))
+
+	body := []byte(`{"nickname":"Bandido"}`)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request, _ = http.NewRequest(http.MethodPatch, "/users/"+id.String(), bytes.NewReader(body))
+	ctx.Params = gin.Params{{Key: "id", Value: id.String()}}
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Update(ctx)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var res model.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, []model.FieldError{{Field: "nickname", Description: "already taken"}}, res.Fields)
+}
+
+func TestController_NicknameAvailability(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	check := func(nick string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/nicknames/"+nick+"/availability", nil)
+		ctx.Params = gin.Params{{Key: "nick", Value: nick}}
+		handler.NicknameAvailability(ctx)
+		return w
+	}
+
+	t.Run("should return the suggestions of a taken nickname", func(t *testing.T) {
+		expected := &model.NicknameAvailability{
+			Nickname:    "Bandido",
+			Available:   false,
+			Suggestions: []string{"Bandido7", "Bandido_42"},
+		}
+		mockService.EXPECT().NicknameAvailability(gomock.Any(), "Bandido").Return(expected, nil)
+
+		w := check("Bandido")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res model.NicknameAvailability
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, *expected, res)
+	})
+
+	t.Run("should reject too long nicknames", func(t *testing.T) {
+		w := check(strings.Repeat("a", entityUser.MaxNicknameLength+1))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestController_Update_MergePatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	id := uuid.New()
+	update := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodPatch, "/users/"+id.String(), bytes.NewReader([]byte(body)))
+		ctx.Params = gin.Params{{Key: "id", Value: id.String()}}
+		ctx.Request.Header.Set("Content-Type", "application/merge-patch+json")
+		handler.Update(ctx)
+		return w
+	}
+
+	t.Run("should only update the fields in the patch", func(t *testing.T) {
+		firstName, country := "Ignacio", "UY"
+		mockService.EXPECT().
+			Update(gomock.Any(), id, model.UpdateUserInput{FirstName: &firstName, Country: &country}, int64(0)).
+			Return(&model.UserOutput{ID: id.String(), FirstName: firstName, Country: country}, nil)
+
+		w := update(`{"first_name":"Ignacio","country":"UY"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should not remove required fields", func(t *testing.T) {
+		w := update(`{"last_name":null}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		var res model.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "last_name", res.Fields[0].Field)
+	})
+
+	t.Run("should not update other fields", func(t *testing.T) {
+		w := update(`{"password":"newpassword"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		var res model.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "password", res.Fields[0].Field)
+	})
+
+	t.Run("should answer the validation errors of the service", func(t *testing.T) {
+		mockService.EXPECT().
+			Update(gomock.Any(), id, gomock.Any(), int64(0)).
+			Return(nil, domainerr.Validation("email", errors.New("invalid email format")))
+
+		w := update(`{"email":"not-an-email"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
+
+func TestController_Update_IfMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	id := uuid.New()
+	nickname := "newnick"
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodPatch, "/users/"+id.String(), bytes.NewReader([]byte(`{"nickname":"newnick"}`)))
+		ctx.Params = gin.Params{{Key: "id", Value: id.String()}}
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Request.Header.Set("If-Match", ifMatch)
+		handler.Update(ctx)
+		return w
+	}
+
+	t.Run("should pass the version and answer the new ETag", func(t *testing.T) {
+		mockService.EXPECT().
+			Update(gomock.Any(), id, model.UpdateUserInput{Nickname: &nickname}, int64(3)).
+			Return(&model.UserOutput{ID: id.String(), Nickname: nickname, Version: 4}, nil)
+
+		w := update(`"3"`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("should answer 412 when the version does not match", func(t *testing.T) {
+		mockService.EXPECT().
+			Update(gomock.Any(), id, gomock.Any(), int64(2)).
+			Return(nil, entityUser.ErrVersionMismatch)
+
+		w := update(`"2"`)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("should reject weak or malformed ETags", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, update(`W/"3"`).Code)
+		assert.Equal(t, http.StatusBadRequest, update(`3`).Code)
+	})
+}
+
 func TestController_Update_InvalidID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -143,7 +547,7 @@ func TestController_Delete_Success(t *testing.T) {
 
 	id := uuid.New()
 	mockService.EXPECT().
-		Delete(gomock.Any(), id).
+		Delete(gomock.Any(), id, int64(0)).
 		Return(nil)
 
 	w := httptest.NewRecorder()
@@ -156,6 +560,28 @@ func TestController_Delete_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestController_Delete_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	id := uuid.New()
+	mockService.EXPECT().
+		Delete(gomock.Any(), id, int64(0)).
+		Return(repo.ErrRecordNotFound)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request, _ = http.NewRequest(http.MethodDelete, "/users/"+id.String(), nil)
+	ctx.Params = gin.Params{{Key: "id", Value: id.String()}}
+
+	handler.Delete(ctx)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func TestController_Delete_InvalidID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -172,3 +598,180 @@ func TestController_Delete_InvalidID(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestController_ListEvents_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	id := uuid.New()
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	expectedFilter := model.UserEventFilter{
+		UserID:     id,
+		EventTypes: []string{"USER_UPDATED"},
+		From:       from,
+		Page:       1,
+		Limit:      5,
+	}
+
+	mockService.EXPECT().
+		ListEvents(gomock.Any(), expectedFilter).
+		Return([]model.UserEventOutput{{ID: uuid.New().String(), UserID: id.String(), EventType: "USER_UPDATED"}}, nil)
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request, _ = http.NewRequest(http.MethodGet, "/users/"+id.String()+"/events?event_type=USER_UPDATED&from=2025-04-01T00:00:00Z&limit=5", nil)
+	ctx.Params = gin.Params{{Key: "id", Value: id.String()}}
+
+	handler.ListEvents(ctx)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res []model.UserEventOutput
+	err := json.Unmarshal(w.Body.Bytes(), &res)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+}
+
+func TestController_ListEvents_InvalidParams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	id := uuid.New().String()
+	tests := map[string]string{
+		"invalid event type": "/users/" + id + "/events?event_type=USER_HACKED",
+		"invalid from":       "/users/" + id + "/events?from=yesterday",
+		"from after to":      "/users/" + id + "/events?from=2025-04-02T00:00:00Z&to=2025-04-01T00:00:00Z",
+		"limit too big":      "/users/" + id + "/events?limit=1000",
+	}
+
+	for name, url := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request, _ = http.NewRequest(http.MethodGet, url, nil)
+			ctx.Params = gin.Params{{Key: "id", Value: id}}
+
+			handler.ListEvents(ctx)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestController_Get(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	id := uuid.New()
+	get := func(query string, principal *auth.Principal) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/users/"+id.String()+query, nil)
+		if principal != nil {
+			ctx.Request = ctx.Request.WithContext(auth.WithPrincipal(ctx.Request.Context(), principal))
+		}
+		ctx.Params = gin.Params{{Key: "id", Value: id.String()}}
+		handler.Get(ctx)
+		return w
+	}
+
+	t.Run("should return the user", func(t *testing.T) {
+		mockService.EXPECT().
+			Get(gomock.Any(), id, false).
+			Return(&model.UserOutput{ID: id.String(), Nickname: "bandido"}, nil)
+
+		w := get("", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res model.UserOutput
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "bandido", res.Nickname)
+		assert.Nil(t, res.DeletedAt)
+	})
+
+	t.Run("should return not found for missing or deleted users", func(t *testing.T) {
+		mockService.EXPECT().Get(gomock.Any(), id, false).Return(nil, repo.ErrRecordNotFound)
+
+		w := get("", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should only let admins include deleted users", func(t *testing.T) {
+		w := get("?include_deleted=true", &auth.Principal{Subject: id.String(), Role: auth.RoleUser})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		mockService.EXPECT().
+			Get(gomock.Any(), id, true).
+			Return(&model.UserOutput{ID: id.String(), Nickname: "bandido"}, nil)
+		w = get("?include_deleted=true", &auth.Principal{Subject: "admin-id", Role: auth.RoleAdmin})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should fail on invalid include_deleted", func(t *testing.T) {
+		w := get("?include_deleted=maybe", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestController_Get_AsOf(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockUserService(ctrl)
+	handler := user.NewController(mockService)
+
+	id := uuid.New()
+	asOf := time.Date(2025, 4, 20, 10, 0, 0, 0, time.UTC)
+
+	t.Run("should return the projected user", func(t *testing.T) {
+		mockService.EXPECT().
+			GetAsOf(gomock.Any(), id, asOf).
+			Return(&model.UserOutput{ID: id.String(), Nickname: "oldnick"}, nil)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/users/"+id.String()+"?as_of=2025-04-20T10:00:00Z", nil)
+		ctx.Params = gin.Params{{Key: "id", Value: id.String()}}
+
+		handler.Get(ctx)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res model.UserOutput
+		err := json.Unmarshal(w.Body.Bytes(), &res)
+		assert.NoError(t, err)
+		assert.Equal(t, "oldnick", res.Nickname)
+	})
+
+	t.Run("should return not found when the user did not exist", func(t *testing.T) {
+		mockService.EXPECT().
+			GetAsOf(gomock.Any(), id, asOf).
+			Return(nil, projector.ErrUserNotFound)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/users/"+id.String()+"?as_of=2025-04-20T10:00:00Z", nil)
+		ctx.Params = gin.Params{{Key: "id", Value: id.String()}}
+
+		handler.Get(ctx)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should fail on invalid as_of", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/users/"+id.String()+"?as_of=last-tuesday", nil)
+		ctx.Params = gin.Params{{Key: "id", Value: id.String()}}
+
+		handler.Get(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/httperror"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	service "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/webhook"
+)
+
+// maxLimit is the max page size allowed when listing webhooks or deliveries
+const maxLimit = 100
+
+type Controller struct {
+	svc service.Service
+}
+
+// NewController returns a HTTP Webhook controller
+func NewController(s service.Service) *Controller {
+	return &Controller{svc: s}
+}
+
+// Create registers a new webhook. The response contains the signing secret
+func (c *Controller) Create(ctx *gin.Context) {
+	var input model.CreateWebhookInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Create").Msg("not valid data")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid input", err.Error())
+		return
+	}
+
+	created, err := c.svc.Create(ctx, &input)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Create").Msg("could not create webhook")
+		httperror.Write(ctx, err, "could not create webhook")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, created)
+}
+
+// Find returns a paginated list of webhooks
+func (c *Controller) Find(ctx *gin.Context) {
+	page, limit, ok := pagination(ctx, "Find")
+	if !ok {
+		return
+	}
+
+	webhooks, err := c.svc.Find(ctx, page, limit)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Find").Msg("could not find webhooks")
+		httperror.Write(ctx, err, "could not find webhooks")
+		return
+	}
+
+	returnsWithSuccess(ctx, webhooks)
+}
+
+// Get returns a webhook by ID
+func (c *Controller) Get(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Get").Msg("invalid webhook ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid webhook ID", err.Error())
+		return
+	}
+
+	w, err := c.svc.Get(ctx, id)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Get").Msg("could not get webhook")
+		httperror.Write(ctx, err, "could not get webhook")
+		return
+	}
+
+	returnsWithSuccess(ctx, w)
+}
+
+// Update changes the url, event types or active flag of a webhook
+func (c *Controller) Update(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Update").Msg("invalid webhook ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid webhook ID", err.Error())
+		return
+	}
+
+	var input model.UpdateWebhookInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Update").Msg("invalid update data")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid update data", err.Error())
+		return
+	}
+
+	updated, err := c.svc.Update(ctx, id, input)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Update").Msg("could not update webhook")
+		httperror.Write(ctx, err, "could not update webhook")
+		return
+	}
+
+	returnsWithSuccess(ctx, updated)
+}
+
+// Delete removes a webhook
+func (c *Controller) Delete(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Delete").Msg("invalid webhook ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid webhook ID", err.Error())
+		return
+	}
+
+	if err := c.svc.Delete(ctx, id); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "Delete").Msg("could not delete webhook")
+		httperror.Write(ctx, err, "could not delete webhook")
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListDeliveries returns the latest delivery attempts of a webhook, newest first
+func (c *Controller) ListDeliveries(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "ListDeliveries").Msg("invalid webhook ID")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid webhook ID", err.Error())
+		return
+	}
+
+	page, limit, ok := pagination(ctx, "ListDeliveries")
+	if !ok {
+		return
+	}
+
+	deliveries, err := c.svc.ListDeliveries(ctx, id, page, limit)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", "ListDeliveries").Msg("could not list webhook deliveries")
+		httperror.Write(ctx, err, "could not list webhook deliveries")
+		return
+	}
+
+	returnsWithSuccess(ctx, deliveries)
+}
+
+func pagination(ctx *gin.Context, handler string) (int, int, bool) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", handler).Msg("invalid pagination page param")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid page parameter")
+		return 0, 0, false
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > maxLimit {
+		log.Error().Ctx(ctx).Err(err).Str("webhookController", handler).Msg("invalid pagination limit param")
+		returnsWithError(ctx, http.StatusBadRequest, "invalid limit parameter")
+		return 0, 0, false
+	}
+
+	return page, limit, true
+}
+
+func returnsWithError(ctx *gin.Context, code int, message string, details ...string) {
+	res := model.ErrorResponse{Error: message}
+	if len(details) > 0 {
+		res.Details = details[0]
+	}
+	ctx.JSON(code, res)
+}
+
+func returnsWithSuccess(ctx *gin.Context, payload interface{}) {
+	ctx.JSON(http.StatusOK, payload)
+}
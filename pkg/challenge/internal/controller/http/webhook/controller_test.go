@@ -0,0 +1,136 @@
+package webhook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	entity "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+func TestController_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocks.NewMockWebhookService(ctrl)
+	handler := webhook.NewController(mockService)
+
+	input := model.CreateWebhookInput{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{event.UserCreated},
+	}
+
+	post := func(body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		handler.Create(ctx)
+		return w
+	}
+
+	t.Run("should create a webhook and return its secret", func(t *testing.T) {
+		mockService.EXPECT().
+			Create(gomock.Any(), &input).
+			Return(&model.WebhookOutput{ID: uuid.NewString(), URL: input.URL, Secret: "generated-secret-value"}, nil)
+
+		body, _ := json.Marshal(input)
+		w := post(body)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var res model.WebhookOutput
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "generated-secret-value", res.Secret)
+	})
+
+	t.Run("should return 400 without event types", func(t *testing.T) {
+		w := post([]byte(`{"url":"https://partner.example.com/hooks"}`))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should return 422 on validation errors", func(t *testing.T) {
+		mockService.EXPECT().
+			Create(gomock.Any(), gomock.Any()).
+			Return(nil, entity.ErrInvalidEventType)
+
+		body, _ := json.Marshal(input)
+		w := post(body)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
+
+func TestController_Get(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocks.NewMockWebhookService(ctrl)
+	handler := webhook.NewController(mockService)
+
+	get := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Params = gin.Params{{Key: "id", Value: id}}
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/webhooks/"+id, nil)
+		handler.Get(ctx)
+		return w
+	}
+
+	t.Run("should return 404 when the webhook does not exist", func(t *testing.T) {
+		id := uuid.New()
+		mockService.EXPECT().
+			Get(gomock.Any(), id).
+			Return(nil, repo.ErrRecordNotFound)
+
+		w := get(id.String())
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should return 400 on invalid ID", func(t *testing.T) {
+		w := get("nope")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestController_ListDeliveries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocks.NewMockWebhookService(ctrl)
+	handler := webhook.NewController(mockService)
+
+	list := func(id, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Params = gin.Params{{Key: "id", Value: id}}
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/webhooks/"+id+"/deliveries"+query, nil)
+		handler.ListDeliveries(ctx)
+		return w
+	}
+
+	t.Run("should list deliveries", func(t *testing.T) {
+		id := uuid.New()
+		mockService.EXPECT().
+			ListDeliveries(gomock.Any(), id, 2, 5).
+			Return([]model.WebhookDeliveryOutput{{ID: uuid.NewString(), Attempt: 1}}, nil)
+
+		w := list(id.String(), "?page=2&limit=5")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res []model.WebhookDeliveryOutput
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Len(t, res, 1)
+	})
+
+	t.Run("should return 400 when the limit is too big", func(t *testing.T) {
+		w := list(uuid.NewString(), "?limit=1000")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
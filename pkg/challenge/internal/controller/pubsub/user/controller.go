@@ -18,32 +18,44 @@ func RegisterUserSubscribers(sub pubsub.Subscriber) error {
 	if err := sub.Subscribe(event.UserSoftDeleted, onUserSoftDeleted); err != nil {
 		return err
 	}
+	if err := sub.Subscribe(event.UserSessionRevoked, onUserSessionRevoked); err != nil {
+		return err
+	}
 	return nil
 }
 
-func onUserCreated(_ context.Context, payload interface{}) {
+func onUserCreated(ctx context.Context, payload interface{}) {
 	data, ok := payload.(event.CreatedPayload)
 	if !ok {
-		log.Error().Msg("invalid payload type for USER_CREATED")
+		log.Error().Ctx(ctx).Msg("invalid payload type for USER_CREATED")
 		return
 	}
-	log.Info().Str("trace_id", data.TraceID).Str("userID", data.UserID).Msg("USER_CREATED")
+	log.Info().Ctx(ctx).Str("userID", data.UserID).Msg("USER_CREATED")
 }
 
-func onUserUpdated(_ context.Context, payload interface{}) {
+func onUserUpdated(ctx context.Context, payload interface{}) {
 	data, ok := payload.(event.UpdatedPayload)
 	if !ok {
-		log.Error().Msg("invalid payload type for USER_UPDATED")
+		log.Error().Ctx(ctx).Msg("invalid payload type for USER_UPDATED")
 		return
 	}
-	log.Info().Str("trace_id", data.TraceID).Str("nickname", data.Nickname).Msg("USER_UPDATED")
+	log.Info().Ctx(ctx).Str("nickname", data.Nickname).Msg("USER_UPDATED")
 }
 
-func onUserSoftDeleted(_ context.Context, payload interface{}) {
+func onUserSoftDeleted(ctx context.Context, payload interface{}) {
 	data, ok := payload.(event.DeletedPayload)
 	if !ok {
-		log.Error().Msg("invalid payload type for USER_SOFT_DELETED")
+		log.Error().Ctx(ctx).Msg("invalid payload type for USER_SOFT_DELETED")
+		return
+	}
+	log.Info().Ctx(ctx).Str("user_id", data.UserID).Msg("USER_SOFT_DELETED")
+}
+
+func onUserSessionRevoked(ctx context.Context, payload interface{}) {
+	data, ok := payload.(event.SessionRevokedPayload)
+	if !ok {
+		log.Error().Ctx(ctx).Msg("invalid payload type for USER_SESSION_REVOKED")
 		return
 	}
-	log.Info().Str("trace_id", data.TraceID).Str("user_id", data.UserID).Msg("USER_SOFT_DELETED")
+	log.Info().Ctx(ctx).Str("user_id", data.UserID).Strs("session_ids", data.SessionIDs).Msg("USER_SESSION_REVOKED")
 }
@@ -0,0 +1,119 @@
+// Package domainerr holds the kinds of failure the domain reports to the transports.
+// Repos, entities and aggregates return them, and the HTTP and gRPC controllers
+// translate them to status codes without knowing where they came from
+package domainerr
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind is the category of a domain error
+type Kind string
+
+const (
+	// KindNotFound used when the requested resource does not exist
+	KindNotFound Kind = "not_found"
+	// KindConflict used when the change clashes with the stored state, e.g. a unique value already taken
+	KindConflict Kind = "conflict"
+	// KindValidation used when the input is not valid
+	KindValidation Kind = "validation"
+	// KindPreconditionFailed used when the resource is not in the state the caller expected
+	KindPreconditionFailed Kind = "precondition_failed"
+	// KindUnauthenticated used when the caller could not be identified
+	KindUnauthenticated Kind = "unauthenticated"
+	// KindPermissionDenied used when the caller is not allowed to perform the action
+	KindPermissionDenied Kind = "permission_denied"
+)
+
+// Kind sentinels, match any domain error of their kind with errors.Is
+var (
+	ErrNotFound           = &Error{Kind: KindNotFound, Message: "not found", sentinel: true}
+	ErrConflict           = &Error{Kind: KindConflict, Message: "conflict", sentinel: true}
+	ErrValidation         = &Error{Kind: KindValidation, Message: "validation failed", sentinel: true}
+	ErrPreconditionFailed = &Error{Kind: KindPreconditionFailed, Message: "precondition failed", sentinel: true}
+	ErrUnauthenticated    = &Error{Kind: KindUnauthenticated, Message: "unauthenticated", sentinel: true}
+	ErrPermissionDenied   = &Error{Kind: KindPermissionDenied, Message: "permission denied", sentinel: true}
+)
+
+// Error is a domain error. Field is the input field it is about, if any
+type Error struct {
+	Kind    Kind
+	Field   string
+	Message string
+	Err     error
+
+	sentinel bool
+}
+
+// NotFound returns a not found error
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+// Conflict returns a conflict error on the given field, wrapping its cause
+func Conflict(field, message string, err error) *Error {
+	return &Error{Kind: KindConflict, Field: field, Message: message, Err: err}
+}
+
+// Validation returns a validation error on the given field, wrapping its cause
+func Validation(field string, err error) *Error {
+	return &Error{Kind: KindValidation, Field: field, Err: err}
+}
+
+// PreconditionFailed returns a precondition failed error
+func PreconditionFailed(message string) *Error {
+	return &Error{Kind: KindPreconditionFailed, Message: message}
+}
+
+// Unauthenticated returns an unauthenticated error
+func Unauthenticated(message string) *Error {
+	return &Error{Kind: KindUnauthenticated, Message: message}
+}
+
+// PermissionDenied returns a permission denied error
+func PermissionDenied(message string) *Error {
+	return &Error{Kind: KindPermissionDenied, Message: message}
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, 0, 3)
+	if e.Field != "" {
+		parts = append(parts, e.Field)
+	}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+	if e.Err != nil {
+		parts = append(parts, e.Err.Error())
+	}
+	return strings.Join(parts, ": ")
+}
+
+// Unwrap returns the cause of the error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the kind sentinels
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.sentinel && t.Kind == e.Kind
+}
+
+// Description returns the error without its field, as shown to the clients
+func (e *Error) Description() string {
+	if e.Field == "" {
+		return e.Error()
+	}
+	return strings.TrimPrefix(e.Error(), e.Field+": ")
+}
+
+// As returns the outermost domain error in the chain of err
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
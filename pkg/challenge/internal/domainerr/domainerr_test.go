@@ -0,0 +1,43 @@
+package domainerr_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+)
+
+func TestError(t *testing.T) {
+	errEmpty := errors.New("field cannot be empty")
+
+	t.Run("should match its kind and its cause", func(t *testing.T) {
+		err := fmt.Errorf("could not create: %w", domainerr.Validation("nickname", errEmpty))
+
+		assert.ErrorIs(t, err, domainerr.ErrValidation)
+		assert.ErrorIs(t, err, errEmpty)
+		assert.NotErrorIs(t, err, domainerr.ErrNotFound)
+		assert.Equal(t, "could not create: nickname: field cannot be empty", err.Error())
+	})
+
+	t.Run("should not match other errors of the same kind", func(t *testing.T) {
+		errUser := domainerr.NotFound("user not found")
+		errWebhook := domainerr.NotFound("webhook not found")
+
+		assert.ErrorIs(t, errUser, domainerr.ErrNotFound)
+		assert.NotErrorIs(t, errUser, errWebhook)
+	})
+
+	t.Run("should expose the field and its description", func(t *testing.T) {
+		e, ok := domainerr.As(fmt.Errorf("wrapped: %w", domainerr.Conflict("email", "already taken", nil)))
+		assert.True(t, ok)
+		assert.Equal(t, domainerr.KindConflict, e.Kind)
+		assert.Equal(t, "email", e.Field)
+		assert.Equal(t, "already taken", e.Description())
+
+		_, ok = domainerr.As(errEmpty)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,79 @@
+package idempotency
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/datatypes"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+)
+
+var (
+	// ErrInvalidKey used for keys that are too long or not printable
+	ErrInvalidKey = domainerr.Validation("idempotency_key", errors.New("must be 1 to 255 printable ASCII characters"))
+	// ErrKeyReused used when a key is sent again with a different request
+	ErrKeyReused = domainerr.Validation("idempotency_key", errors.New("key was already used with a different request"))
+)
+
+const (
+	// TableName define idempotency key table name for idempotency entity
+	TableName = "challenge.idempotency_key"
+
+	// ScopeCreateUser is the scope of the keys sent when creating users
+	ScopeCreateUser = "create_user"
+
+	// maxKeyLength is the max length of a key sent by a client
+	maxKeyLength = 255
+)
+
+// Entity represents an idempotency key in DB, with the hash of the request that
+// used it first and the response that was sent back
+type Entity struct {
+	Scope       string         `gorm:"primaryKey"`
+	Key         string         `gorm:"primaryKey"`
+	RequestHash string         `gorm:"not null"`
+	Response    datatypes.JSON `gorm:"type:jsonb"`
+	CreatedAt   time.Time
+}
+
+// TableName returns table name
+func (Entity) TableName() string {
+	return TableName
+}
+
+// ValidKey checks a key sent by a client
+func ValidKey(key string) error {
+	if key == "" || len(key) > maxKeyLength {
+		return ErrInvalidKey
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// HashRequest returns the hash stored along with a key, so a key sent again
+// with a different request can be told apart from a retry. Requests hold passwords,
+// the hash is an HMAC-SHA256 keyed with a server secret so the stored hashes can not
+// be brute forced without it
+func HashRequest(secret []byte, req any) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Matches reports whether the key was first used by a request with the given hash
+func (e *Entity) Matches(requestHash string) bool {
+	return e.RequestHash == requestHash
+}
@@ -0,0 +1,64 @@
+package idempotency_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
+)
+
+func TestValidKey(t *testing.T) {
+	t.Run("should accept printable keys", func(t *testing.T) {
+		assert.NoError(t, idempotency.ValidKey("8e3c1c58-5d5f-4bd4-9d59-1b8e8c9d0a11"))
+	})
+
+	t.Run("should reject empty, long and non printable keys", func(t *testing.T) {
+		for _, key := range []string{"", strings.Repeat("a", 256), "key\n", "clé"} {
+			err := idempotency.ValidKey(key)
+			assert.ErrorIs(t, err, idempotency.ErrInvalidKey)
+			assert.ErrorIs(t, err, domainerr.ErrValidation)
+		}
+	})
+}
+
+func TestHashRequest(t *testing.T) {
+	type request struct {
+		Name string `json:"name"`
+	}
+	secret := []byte("idempotency-secret")
+
+	t.Run("should hash equal requests the same", func(t *testing.T) {
+		first, err := idempotency.HashRequest(secret, request{Name: "Rick"})
+		assert.NoError(t, err)
+		second, err := idempotency.HashRequest(secret, request{Name: "Rick"})
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+
+		e := idempotency.Entity{RequestHash: first}
+		assert.True(t, e.Matches(second))
+	})
+
+	t.Run("should hash different requests differently", func(t *testing.T) {
+		first, err := idempotency.HashRequest(secret, request{Name: "Rick"})
+		assert.NoError(t, err)
+		second, err := idempotency.HashRequest(secret, request{Name: "Morty"})
+		assert.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("should not be a plain hash of the request", func(t *testing.T) {
+		hash, err := idempotency.HashRequest(secret, request{Name: "Rick"})
+		assert.NoError(t, err)
+		sum := sha256.Sum256([]byte(`{"name":"Rick"}`))
+		assert.NotEqual(t, hex.EncodeToString(sum[:]), hash)
+
+		other, err := idempotency.HashRequest([]byte("another-secret"), request{Name: "Rick"})
+		assert.NoError(t, err)
+		assert.NotEqual(t, hash, other)
+	})
+}
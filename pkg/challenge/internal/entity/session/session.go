@@ -0,0 +1,105 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidRefreshToken used for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("refresh token is not valid")
+	// ErrRefreshTokenReused used when an already rotated refresh token is presented again.
+	// The whole session is revoked when it happens
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+)
+
+const (
+	// TableName define user session table name for session entity
+	TableName = "challenge.user_session"
+
+	// RevokedByUser is the reason used when the user revokes the session
+	RevokedByUser = "revoked"
+	// RevokedOnReuse is the reason used when a rotated refresh token is used again
+	RevokedOnReuse = "refresh_token_reused"
+)
+
+// Metadata describes the device a session was started from
+type Metadata struct {
+	UserAgent string
+	IPAddress string
+}
+
+// Entity represents a refresh token of an user session in DB
+type Entity struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	SessionID    uuid.UUID `gorm:"type:uuid;not null"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null"`
+	TokenHash    string    `gorm:"not null;unique"`
+	UserAgent    string    `gorm:"not null;default:''"`
+	IPAddress    string    `gorm:"not null;default:''"`
+	StartedAt    time.Time `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	UsedAt       *time.Time
+	RevokedAt    *time.Time
+	RevokeReason string `gorm:"not null;default:''"`
+	CreatedAt    time.Time
+	// Token is the plain refresh token. It is only known right after being generated
+	Token string `gorm:"-"`
+}
+
+// TableName returns table name
+func (Entity) TableName() string {
+	return TableName
+}
+
+// New returns a new refresh token for the given session. A new session is
+// started when sessionID is uuid.Nil
+func New(userID, sessionID uuid.UUID, meta Metadata, ttl time.Duration) (*Entity, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s := &Entity{
+		ID:        uuid.New(),
+		SessionID: sessionID,
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		UserAgent: meta.UserAgent,
+		IPAddress: meta.IPAddress,
+		StartedAt: now,
+		ExpiresAt: now.Add(ttl),
+		Token:     token,
+	}
+	if s.SessionID == uuid.Nil {
+		s.SessionID = uuid.New()
+	}
+	return s, nil
+}
+
+// HashToken returns the value stored for a refresh token. Tokens are random
+// enough for a plain SHA-256 to be safe
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Expired reports whether the refresh token can no longer be used
+func (s *Entity) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,38 @@
+package session_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+)
+
+func TestNew(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("should start a new session", func(t *testing.T) {
+		s, err := session.New(userID, uuid.Nil, session.Metadata{UserAgent: "curl/8.0"}, time.Hour)
+		assert.NoError(t, err)
+		assert.NotEqual(t, uuid.Nil, s.SessionID)
+		assert.Equal(t, "curl/8.0", s.UserAgent)
+		assert.Equal(t, session.HashToken(s.Token), s.TokenHash)
+		assert.NotEqual(t, s.Token, s.TokenHash)
+		assert.False(t, s.Expired(time.Now()))
+		assert.True(t, s.Expired(time.Now().Add(time.Hour)))
+	})
+
+	t.Run("should keep the given session", func(t *testing.T) {
+		sessionID := uuid.New()
+		first, err := session.New(userID, sessionID, session.Metadata{}, time.Hour)
+		assert.NoError(t, err)
+		second, err := session.New(userID, sessionID, session.Metadata{}, time.Hour)
+		assert.NoError(t, err)
+
+		assert.Equal(t, sessionID, first.SessionID)
+		assert.Equal(t, sessionID, second.SessionID)
+		assert.NotEqual(t, first.Token, second.Token)
+	})
+}
@@ -1,6 +1,9 @@
 package event
 
 import (
+	"encoding/json"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,16 +17,30 @@ const (
 	UserUpdated string = "USER_UPDATED"
 	// UserSoftDeleted event type means that the user was soft deleted
 	UserSoftDeleted string = "USER_SOFT_DELETED"
+	// UserSessionRevoked event type means that one or more sessions of the user were revoked
+	UserSessionRevoked string = "USER_SESSION_REVOKED"
 )
 
+// Types lists every known user event type
+var Types = []string{UserCreated, UserUpdated, UserSoftDeleted, UserSessionRevoked}
+
+// IsValidType reports whether t is a known user event type
+func IsValidType(t string) bool {
+	return slices.Contains(Types, t)
+}
+
 // User event type represnt the entity that will be stored when user has any changes
 type User struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primaryKey"`
-	UserID    uuid.UUID      `gorm:"not null"`
-	EventType string         `gorm:"not null"`
-	Payload   datatypes.JSON `gorm:"type:jsonb;not null"`
-	Published bool           `gorm:"not null;default:false"`
-	CreatedAt time.Time
+	ID            uuid.UUID      `gorm:"type:uuid;primaryKey"`
+	UserID        uuid.UUID      `gorm:"not null"`
+	EventType     string         `gorm:"not null"`
+	Payload       datatypes.JSON `gorm:"type:jsonb;not null"`
+	TraceID       string         `gorm:"not null;default:''"`
+	Published     bool           `gorm:"not null;default:false"`
+	Attempts      int            `gorm:"not null;default:0"`
+	NextAttemptAt *time.Time
+	LastError     string `gorm:"not null;default:''"`
+	CreatedAt     time.Time
 }
 
 // TableName returns the user event table
@@ -31,20 +48,118 @@ func (User) TableName() string {
 	return "challenge.user_event"
 }
 
+// DecodePayload returns the typed payload stored in the event so it can
+// be published again exactly as the aggregate would have done
+func (u User) DecodePayload() (interface{}, error) {
+	payload, err := Decode(u.EventType, u.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older events did not always store the user ID in the payload
+	switch p := payload.(type) {
+	case CreatedPayload:
+		if p.UserID == "" {
+			p.UserID = u.UserID.String()
+		}
+		return p, nil
+	case UpdatedPayload:
+		if p.UserID == "" {
+			p.UserID = u.UserID.String()
+		}
+		return p, nil
+	case DeletedPayload:
+		if p.UserID == "" {
+			p.UserID = u.UserID.String()
+		}
+		return p, nil
+	case SessionRevokedPayload:
+		if p.UserID == "" {
+			p.UserID = u.UserID.String()
+		}
+		return p, nil
+	}
+	return payload, nil
+}
+
+// ParentSpanID returns the span that caused the event, stored in its payload.
+// Older events have none
+func (u User) ParentSpanID() string {
+	var p struct {
+		SpanID string `json:"span_id"`
+	}
+	if err := json.Unmarshal(u.Payload, &p); err != nil {
+		return ""
+	}
+	return p.SpanID
+}
+
+// Decode returns the typed payload of the given event type
+func Decode(eventType string, data []byte) (interface{}, error) {
+	switch eventType {
+	case UserCreated:
+		return decode[CreatedPayload](data)
+	case UserUpdated:
+		return decode[UpdatedPayload](data)
+	case UserSoftDeleted:
+		return decode[DeletedPayload](data)
+	case UserSessionRevoked:
+		return decode[SessionRevokedPayload](data)
+	default:
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+}
+
+func decode[T any](data []byte) (T, error) {
+	var p T
+	err := json.Unmarshal(data, &p)
+	return p, err
+}
+
 type CreatedPayload struct {
-	UserID   string `json:"user_id"`
-	Email    string `json:"email"`
-	Nickname string `json:"nickname"`
-	TraceID  string `json:"trace_id"`
+	UserID    string `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Nickname  string `json:"nickname"`
+	Country   string `json:"country"`
+	TraceID   string `json:"trace_id"`
+	// SpanID is the span that caused the event, parent of the spans of its handlers
+	SpanID string `json:"span_id,omitempty"`
+	// Version of the user after the event. Older events have none
+	Version int64 `json:"version,omitempty"`
 }
 
 type UpdatedPayload struct {
 	UserID   string `json:"user_id"`
 	Nickname string `json:"nickname"`
+	Country  string `json:"country"`
 	TraceID  string `json:"trace_id"`
+	SpanID   string `json:"span_id,omitempty"`
+	Version  int64  `json:"version,omitempty"`
+	// Changes lists the updated fields. Older events only changed the nickname and have none
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is the old and new value of an updated user field
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
 }
 
 type DeletedPayload struct {
 	UserID  string `json:"user_id"`
+	Country string `json:"country,omitempty"`
 	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id,omitempty"`
+	Version int64  `json:"version,omitempty"`
+}
+
+type SessionRevokedPayload struct {
+	UserID     string   `json:"user_id"`
+	SessionIDs []string `json:"session_ids"`
+	Reason     string   `json:"reason"`
+	TraceID    string   `json:"trace_id"`
+	SpanID     string   `json:"span_id,omitempty"`
 }
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+)
+
+// MaxNicknameLength is the longest nickname whose availability can be checked
+const MaxNicknameLength = 100
+
+var (
+	// ErrInvalidNickname used when checking the availability of an empty or too long nickname
+	ErrInvalidNickname = domainerr.Validation("nickname", errors.New("nickname must have between 1 and 100 characters"))
+)
+
+// NormalizeNickname returns the form nicknames are compared with, the same the
+// unique index on lower(nickname) uses among the not deleted users
+func NormalizeNickname(nickname string) string {
+	return strings.ToLower(nickname)
+}
+
+// ValidNickname checks that a nickname can be looked up
+func ValidNickname(nickname string) error {
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" || len(nickname) > MaxNicknameLength {
+		return ErrInvalidNickname
+	}
+	return nil
+}
+
+// NicknameCandidates returns n different alternatives to a taken nickname, made of the
+// nickname followed by a random number. They still have to be checked against the DB
+func NicknameCandidates(nickname string, n int) []string {
+	nickname = strings.TrimSpace(nickname)
+	candidates := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for len(candidates) < n {
+		var candidate string
+		// Half of them with a separator, so short and long numbers both show up
+		if len(candidates)%2 == 0 {
+			candidate = fmt.Sprintf("%s%d", nickname, rand.IntN(1000))
+		} else {
+			candidate = fmt.Sprintf("%s_%d", nickname, rand.IntN(10000))
+		}
+		if seen[NormalizeNickname(candidate)] {
+			continue
+		}
+		seen[NormalizeNickname(candidate)] = true
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
@@ -0,0 +1,33 @@
+package user_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+)
+
+func TestNormalizeNickname(t *testing.T) {
+	assert.Equal(t, "nacho", user.NormalizeNickname("NaCho"))
+}
+
+func TestValidNickname(t *testing.T) {
+	assert.NoError(t, user.ValidNickname("nacho"))
+	assert.ErrorIs(t, user.ValidNickname("   "), user.ErrInvalidNickname)
+	assert.ErrorIs(t, user.ValidNickname(strings.Repeat("a", user.MaxNicknameLength+1)), user.ErrInvalidNickname)
+}
+
+func TestNicknameCandidates(t *testing.T) {
+	candidates := user.NicknameCandidates(" nacho ", 10)
+	assert.Len(t, candidates, 10)
+
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		assert.True(t, strings.HasPrefix(c, "nacho"), c)
+		assert.NotEqual(t, "nacho", c)
+		assert.False(t, seen[c], "duplicated candidate %s", c)
+		seen[c] = true
+	}
+}
@@ -10,13 +10,19 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
 )
 
 var (
-	ErrEmptyField     = errors.New("field cannot be empty")
-	ErrInvalidEmail   = errors.New("invalid email format")
-	ErrWeakPassword   = errors.New("password must be at least 8 characters")
-	ErrInvalidCountry = errors.New("country must be specified")
+	ErrEmptyField     = domainerr.Validation("", errors.New("field cannot be empty"))
+	ErrInvalidEmail   = domainerr.Validation("", errors.New("invalid email format"))
+	ErrWeakPassword   = domainerr.Validation("", errors.New("password must be at least 8 characters"))
+	ErrInvalidCountry = domainerr.Validation("", errors.New("country must be specified"))
+	// ErrInvalidCredentials is used for both unknown users and wrong passwords
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrVersionMismatch used when the user changed since the version the caller read
+	ErrVersionMismatch = domainerr.PreconditionFailed("user version does not match")
 )
 
 const (
@@ -29,10 +35,12 @@ type Entity struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
 	FirstName string    `gorm:"not null"`
 	LastName  string    `gorm:"not null"`
-	Nickname  string    `gorm:"not null;unique"`
+	Nickname  string    `gorm:"not null"` // unique ignoring case among not deleted users
 	Password  string    `gorm:"not null"`
 	Email     string    `gorm:"not null;unique"`
 	Country   string    `gorm:"not null"`
+	Role      string    `gorm:"not null;default:user"`
+	Version   int64     `gorm:"not null;default:1"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt
@@ -43,6 +51,12 @@ func (Entity) TableName() string {
 	return TableName
 }
 
+// Match is an user found by a fuzzy search, with how similar it is to the query from 0 to 1
+type Match struct {
+	Entity
+	Score float64 `gorm:"->"`
+}
+
 func (u *Entity) HashPassword(password string) error {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -52,28 +66,45 @@ func (u *Entity) HashPassword(password string) error {
 	return nil
 }
 
+// CheckVersion fails with ErrVersionMismatch if the user is not at the expected version.
+// An expected version of 0 skips the check
+func (u *Entity) CheckVersion(expected int64) error {
+	if expected != 0 && expected != u.Version {
+		return fmt.Errorf("%w: expected %d, current %d", ErrVersionMismatch, expected, u.Version)
+	}
+	return nil
+}
+
+// CheckPassword compares the given password with the stored bcrypt hash
+func (u *Entity) CheckPassword(password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
 // Valid checks that the entity meets the criteria for being persisted
 func (u *Entity) Valid() error {
 	if strings.TrimSpace(u.FirstName) == "" {
-		return fmt.Errorf("first name: %w", ErrEmptyField)
+		return domainerr.Validation("first_name", ErrEmptyField)
 	}
 	if strings.TrimSpace(u.LastName) == "" {
-		return fmt.Errorf("last name: %w", ErrEmptyField)
+		return domainerr.Validation("last_name", ErrEmptyField)
 	}
 	if strings.TrimSpace(u.Nickname) == "" {
-		return fmt.Errorf("nickname: %w", ErrEmptyField)
+		return domainerr.Validation("nickname", ErrEmptyField)
 	}
 	if strings.TrimSpace(u.Password) == "" || len(u.Password) < 8 {
-		return fmt.Errorf("password: %w", ErrWeakPassword)
+		return domainerr.Validation("password", ErrWeakPassword)
 	}
 	if strings.TrimSpace(u.Email) == "" {
-		return fmt.Errorf("email: %w", ErrEmptyField)
+		return domainerr.Validation("email", ErrEmptyField)
 	}
 	if _, err := mail.ParseAddress(u.Email); err != nil {
-		return fmt.Errorf("email: %w", ErrInvalidEmail)
+		return domainerr.Validation("email", ErrInvalidEmail)
 	}
 	if strings.TrimSpace(u.Country) == "" {
-		return fmt.Errorf("country: %w", ErrInvalidCountry)
+		return domainerr.Validation("country", ErrInvalidCountry)
 	}
 	return nil
 }
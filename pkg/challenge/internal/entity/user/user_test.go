@@ -84,3 +84,19 @@ func TestEntity_HashPassword(t *testing.T) {
 	assert.NotEqual(t, "supersecure", user.Password)
 	assert.Greater(t, len(user.Password), 0)
 }
+
+func TestEntity_CheckPassword(t *testing.T) {
+	u := &user.Entity{}
+	assert.NoError(t, u.HashPassword("supersecure"))
+
+	assert.NoError(t, u.CheckPassword("supersecure"))
+	assert.ErrorIs(t, u.CheckPassword("notthesame"), user.ErrInvalidCredentials)
+}
+
+func TestEntity_CheckVersion(t *testing.T) {
+	u := &user.Entity{Version: 3}
+
+	assert.NoError(t, u.CheckVersion(3))
+	assert.NoError(t, u.CheckVersion(0), "0 skips the check")
+	assert.ErrorIs(t, u.CheckVersion(2), user.ErrVersionMismatch)
+}
@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+)
+
+var (
+	ErrInvalidURL        = domainerr.Validation("", errors.New("url must be an absolute http or https URL"))
+	ErrPrivateURL        = domainerr.Validation("", errors.New("url must not point to a loopback, link-local or private address"))
+	ErrMissingEventTypes = domainerr.Validation("", errors.New("at least one event type must be specified"))
+	ErrInvalidEventType  = domainerr.Validation("", errors.New("event type is not valid"))
+	ErrWeakSecret        = domainerr.Validation("", errors.New("secret must be at least 16 characters"))
+)
+
+const (
+	// TableName define webhook table name for webhook entity
+	TableName = "challenge.webhook"
+	// DeliveryTableName define webhook delivery table name for delivery entity
+	DeliveryTableName = "challenge.webhook_delivery"
+	// minSecretLength is the min length of a secret provided by the partner
+	minSecretLength = 16
+)
+
+// Entity represents a webhook subscription in DB
+type Entity struct {
+	ID                  uuid.UUID                   `gorm:"type:uuid;primary_key"`
+	URL                 string                      `gorm:"not null"`
+	EventTypes          datatypes.JSONSlice[string] `gorm:"type:jsonb;not null"`
+	Secret              string                      `gorm:"not null"`
+	Active              bool                        `gorm:"not null;default:true"`
+	ConsecutiveFailures int                         `gorm:"not null;default:0"`
+	DisabledAt          *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	DeletedAt           gorm.DeletedAt
+}
+
+// TableName returns table name
+func (Entity) TableName() string {
+	return TableName
+}
+
+// Delivery represents a single attempt to deliver an event to a webhook. Attempts are
+// stored before they are sent and updated with their outcome once sent
+type Delivery struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key"`
+	WebhookID  uuid.UUID `gorm:"type:uuid;not null"`
+	EventID    uuid.UUID `gorm:"type:uuid;not null"`
+	EventType  string    `gorm:"not null"`
+	Attempt    int       `gorm:"not null"`
+	Success    bool      `gorm:"not null"`
+	StatusCode int       `gorm:"not null;default:0"`
+	Error      string    `gorm:"not null;default:''"`
+	DurationMS int64     `gorm:"column:duration_ms;not null;default:0"`
+	// Payload is the body to send
+	Payload datatypes.JSON `gorm:"type:jsonb"`
+	// NextAttemptAt is when the attempt is due, nil once it is sent
+	NextAttemptAt *time.Time
+	CreatedAt     time.Time
+}
+
+// TableName returns table name
+func (Delivery) TableName() string {
+	return DeliveryTableName
+}
+
+// nonPublicPrefixes are the ranges not covered by the netip helpers that are not
+// reachable on the internet either
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// IsPublicAddr reports whether deliveries can be sent to the given address. Loopback,
+// link-local (e.g. the 169.254.169.254 metadata endpoint), private, unspecified and
+// multicast addresses are refused, so webhooks can not reach the internal network
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscribes reports whether the webhook wants to receive the given event type
+func (w *Entity) Subscribes(eventType string) bool {
+	return slices.Contains(w.EventTypes, eventType)
+}
+
+// GenerateSecret sets a random secret used to sign the deliveries
+func (w *Entity) GenerateSecret() error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	w.Secret = hex.EncodeToString(b)
+	return nil
+}
+
+// Valid checks that the entity meets the criteria for being persisted
+func (w *Entity) Valid() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return domainerr.Validation("url", ErrInvalidURL)
+	}
+	// Host names are checked again once resolved, when delivering
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return domainerr.Validation("url", ErrPrivateURL)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicAddr(addr) {
+		return domainerr.Validation("url", ErrPrivateURL)
+	}
+	if len(w.EventTypes) == 0 {
+		return domainerr.Validation("event_types", ErrMissingEventTypes)
+	}
+	for _, t := range w.EventTypes {
+		if !event.IsValidType(t) {
+			return domainerr.Validation("event_types", fmt.Errorf("%q: %w", t, ErrInvalidEventType))
+		}
+	}
+	if len(w.Secret) < minSecretLength {
+		return domainerr.Validation("secret", ErrWeakSecret)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package webhook_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+)
+
+func TestWebhookEntity_Valid(t *testing.T) {
+	valid := webhook.Entity{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{event.UserCreated, event.UserSoftDeleted},
+		Secret:     "0123456789abcdef",
+	}
+
+	t.Run("should be valid", func(t *testing.T) {
+		assert.NoError(t, valid.Valid())
+	})
+
+	t.Run("should fail with a relative or non http url", func(t *testing.T) {
+		for _, u := range []string{"", "/hooks", "ftp://partner.example.com", "https://"} {
+			w := valid
+			w.URL = u
+			assert.ErrorIs(t, w.Valid(), webhook.ErrInvalidURL, u)
+		}
+	})
+
+	t.Run("should fail with a loopback, link-local or private host", func(t *testing.T) {
+		for _, u := range []string{
+			"http://localhost:8080/hooks",
+			"http://api.localhost/hooks",
+			"http://127.0.0.1/hooks",
+			"http://169.254.169.254/latest/meta-data",
+			"http://10.0.0.5/hooks",
+			"https://192.168.1.1/hooks",
+			"http://100.64.0.1/hooks",
+			"http://0.0.0.0/hooks",
+			"http://[::1]/hooks",
+			"http://[::ffff:127.0.0.1]/hooks",
+			"http://[fd00::1]/hooks",
+		} {
+			w := valid
+			w.URL = u
+			assert.ErrorIs(t, w.Valid(), webhook.ErrPrivateURL, u)
+		}
+	})
+
+	t.Run("should accept public IP hosts", func(t *testing.T) {
+		w := valid
+		w.URL = "https://203.0.113.10:8443/hooks"
+		assert.NoError(t, w.Valid())
+	})
+
+	t.Run("should fail without event types", func(t *testing.T) {
+		w := valid
+		w.EventTypes = nil
+		assert.ErrorIs(t, w.Valid(), webhook.ErrMissingEventTypes)
+	})
+
+	t.Run("should fail with an unknown event type", func(t *testing.T) {
+		w := valid
+		w.EventTypes = []string{"USER_EXPLODED"}
+		assert.ErrorIs(t, w.Valid(), webhook.ErrInvalidEventType)
+	})
+
+	t.Run("should fail with a short secret", func(t *testing.T) {
+		w := valid
+		w.Secret = "short"
+		assert.ErrorIs(t, w.Valid(), webhook.ErrWeakSecret)
+	})
+
+	t.Run("should generate a secret that passes validation", func(t *testing.T) {
+		w := valid
+		w.Secret = ""
+		assert.NoError(t, w.GenerateSecret())
+		assert.NoError(t, w.Valid())
+		assert.True(t, w.Subscribes(event.UserCreated))
+		assert.False(t, w.Subscribes(event.UserUpdated))
+	})
+}
@@ -12,9 +12,13 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	uuid "github.com/google/uuid"
+	session "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
 	user "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	event "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	model "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -42,6 +46,21 @@ func (m *MockUserAggregate) EXPECT() *MockUserAggregateMockRecorder {
 	return m.recorder
 }
 
+// Count mocks base method.
+func (m *MockUserAggregate) Count(ctx context.Context, filter model.UserFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, filter)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockUserAggregateMockRecorder) Count(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockUserAggregate)(nil).Count), ctx, filter)
+}
+
 // Create mocks base method.
 func (m *MockUserAggregate) Create(ctx context.Context, u *user.Entity) (*user.Entity, error) {
 	m.ctrl.T.Helper()
@@ -57,46 +76,243 @@ func (mr *MockUserAggregateMockRecorder) Create(ctx, u any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserAggregate)(nil).Create), ctx, u)
 }
 
+// CreateIdempotent mocks base method.
+func (m *MockUserAggregate) CreateIdempotent(ctx context.Context, u *user.Entity, key, requestHash string, ttl time.Duration) (*user.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdempotent", ctx, u, key, requestHash, ttl)
+	ret0, _ := ret[0].(*user.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIdempotent indicates an expected call of CreateIdempotent.
+func (mr *MockUserAggregateMockRecorder) CreateIdempotent(ctx, u, key, requestHash, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdempotent", reflect.TypeOf((*MockUserAggregate)(nil).CreateIdempotent), ctx, u, key, requestHash, ttl)
+}
+
+// CreateSession mocks base method.
+func (m *MockUserAggregate) CreateSession(ctx context.Context, userID uuid.UUID, meta session.Metadata, ttl time.Duration) (*session.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, userID, meta, ttl)
+	ret0, _ := ret[0].(*session.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockUserAggregateMockRecorder) CreateSession(ctx, userID, meta, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockUserAggregate)(nil).CreateSession), ctx, userID, meta, ttl)
+}
+
 // Delete mocks base method.
-func (m *MockUserAggregate) Delete(ctx context.Context, id uuid.UUID) error {
+func (m *MockUserAggregate) Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, expectedVersion)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockUserAggregateMockRecorder) Delete(ctx, id any) *gomock.Call {
+func (mr *MockUserAggregateMockRecorder) Delete(ctx, id, expectedVersion any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserAggregate)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserAggregate)(nil).Delete), ctx, id, expectedVersion)
 }
 
 // Find mocks base method.
-func (m *MockUserAggregate) Find(ctx context.Context, country string, page, limit int) ([]user.Entity, error) {
+func (m *MockUserAggregate) Find(ctx context.Context, filter model.UserFilter) ([]user.Entity, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Find", ctx, country, page, limit)
+	ret := m.ctrl.Call(m, "Find", ctx, filter)
 	ret0, _ := ret[0].([]user.Entity)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockUserAggregateMockRecorder) Find(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockUserAggregate)(nil).Find), ctx, filter)
+}
+
+// Get mocks base method.
+func (m *MockUserAggregate) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (*user.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id, includeDeleted)
+	ret0, _ := ret[0].(*user.Entity)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Find indicates an expected call of Find.
-func (mr *MockUserAggregateMockRecorder) Find(ctx, country, page, limit any) *gomock.Call {
+// Get indicates an expected call of Get.
+func (mr *MockUserAggregateMockRecorder) Get(ctx, id, includeDeleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockUserAggregate)(nil).Get), ctx, id, includeDeleted)
+}
+
+// GetByLogin mocks base method.
+func (m *MockUserAggregate) GetByLogin(ctx context.Context, login string) (*user.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByLogin", ctx, login)
+	ret0, _ := ret[0].(*user.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByLogin indicates an expected call of GetByLogin.
+func (mr *MockUserAggregateMockRecorder) GetByLogin(ctx, login any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByLogin", reflect.TypeOf((*MockUserAggregate)(nil).GetByLogin), ctx, login)
+}
+
+// ListEvents mocks base method.
+func (m *MockUserAggregate) ListEvents(ctx context.Context, filter model.UserEventFilter) ([]event.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEvents", ctx, filter)
+	ret0, _ := ret[0].([]event.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEvents indicates an expected call of ListEvents.
+func (mr *MockUserAggregateMockRecorder) ListEvents(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEvents", reflect.TypeOf((*MockUserAggregate)(nil).ListEvents), ctx, filter)
+}
+
+// ListEventsAfter mocks base method.
+func (m *MockUserAggregate) ListEventsAfter(ctx context.Context, eventID uuid.UUID, userIDs []uuid.UUID, limit int) ([]event.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEventsAfter", ctx, eventID, userIDs, limit)
+	ret0, _ := ret[0].([]event.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEventsAfter indicates an expected call of ListEventsAfter.
+func (mr *MockUserAggregateMockRecorder) ListEventsAfter(ctx, eventID, userIDs, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEventsAfter", reflect.TypeOf((*MockUserAggregate)(nil).ListEventsAfter), ctx, eventID, userIDs, limit)
+}
+
+// ListSessions mocks base method.
+func (m *MockUserAggregate) ListSessions(ctx context.Context, userID uuid.UUID) ([]session.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, userID)
+	ret0, _ := ret[0].([]session.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockUserAggregateMockRecorder) ListSessions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockUserAggregate)(nil).ListSessions), ctx, userID)
+}
+
+// NicknameAvailability mocks base method.
+func (m *MockUserAggregate) NicknameAvailability(ctx context.Context, nickname string) (bool, []string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NicknameAvailability", ctx, nickname)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].([]string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// NicknameAvailability indicates an expected call of NicknameAvailability.
+func (mr *MockUserAggregateMockRecorder) NicknameAvailability(ctx, nickname any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NicknameAvailability", reflect.TypeOf((*MockUserAggregate)(nil).NicknameAvailability), ctx, nickname)
+}
+
+// Project mocks base method.
+func (m *MockUserAggregate) Project(ctx context.Context, id uuid.UUID, asOf time.Time) (*user.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Project", ctx, id, asOf)
+	ret0, _ := ret[0].(*user.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Project indicates an expected call of Project.
+func (mr *MockUserAggregateMockRecorder) Project(ctx, id, asOf any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Project", reflect.TypeOf((*MockUserAggregate)(nil).Project), ctx, id, asOf)
+}
+
+// RevokeSession mocks base method.
+func (m *MockUserAggregate) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockUserAggregateMockRecorder) RevokeSession(ctx, userID, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockUserAggregate)(nil).RevokeSession), ctx, userID, sessionID)
+}
+
+// RevokeSessions mocks base method.
+func (m *MockUserAggregate) RevokeSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessions", ctx, userID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeSessions indicates an expected call of RevokeSessions.
+func (mr *MockUserAggregateMockRecorder) RevokeSessions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessions", reflect.TypeOf((*MockUserAggregate)(nil).RevokeSessions), ctx, userID)
+}
+
+// RotateSession mocks base method.
+func (m *MockUserAggregate) RotateSession(ctx context.Context, token string, meta session.Metadata, ttl time.Duration) (*user.Entity, *session.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", ctx, token, meta, ttl)
+	ret0, _ := ret[0].(*user.Entity)
+	ret1, _ := ret[1].(*session.Entity)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockUserAggregateMockRecorder) RotateSession(ctx, token, meta, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockUserAggregate)(nil).RotateSession), ctx, token, meta, ttl)
+}
+
+// Search mocks base method.
+func (m *MockUserAggregate) Search(ctx context.Context, query string, limit int) ([]user.Match, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, query, limit)
+	ret0, _ := ret[0].([]user.Match)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockUserAggregateMockRecorder) Search(ctx, query, limit any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockUserAggregate)(nil).Find), ctx, country, page, limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockUserAggregate)(nil).Search), ctx, query, limit)
 }
 
 // Update mocks base method.
-func (m *MockUserAggregate) Update(ctx context.Context, u *user.Entity) (*user.Entity, error) {
+func (m *MockUserAggregate) Update(ctx context.Context, id uuid.UUID, in model.UpdateUserInput, expectedVersion int64) (*user.Entity, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", ctx, u)
+	ret := m.ctrl.Call(m, "Update", ctx, id, in, expectedVersion)
 	ret0, _ := ret[0].(*user.Entity)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockUserAggregateMockRecorder) Update(ctx, u any) *gomock.Call {
+func (mr *MockUserAggregateMockRecorder) Update(ctx, id, in, expectedVersion any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserAggregate)(nil).Update), ctx, u)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserAggregate)(nil).Update), ctx, id, in, expectedVersion)
 }
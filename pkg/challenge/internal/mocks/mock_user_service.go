@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	uuid "github.com/google/uuid"
 	model "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
@@ -58,45 +59,135 @@ func (mr *MockUserServiceMockRecorder) Create(ctx, input any) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockUserService) Delete(ctx context.Context, id uuid.UUID) error {
+func (m *MockUserService) Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, expectedVersion)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockUserServiceMockRecorder) Delete(ctx, id any) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) Delete(ctx, id, expectedVersion any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserService)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserService)(nil).Delete), ctx, id, expectedVersion)
 }
 
 // Find mocks base method.
-func (m *MockUserService) Find(ctx context.Context, country string, page, limit int) ([]model.UserOutput, error) {
+func (m *MockUserService) Find(ctx context.Context, filter model.UserFilter) (*model.UsersPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Find", ctx, country, page, limit)
-	ret0, _ := ret[0].([]model.UserOutput)
+	ret := m.ctrl.Call(m, "Find", ctx, filter)
+	ret0, _ := ret[0].(*model.UsersPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Find indicates an expected call of Find.
-func (mr *MockUserServiceMockRecorder) Find(ctx, country, page, limit any) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) Find(ctx, filter any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockUserService)(nil).Find), ctx, country, page, limit)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockUserService)(nil).Find), ctx, filter)
+}
+
+// Get mocks base method.
+func (m *MockUserService) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.UserOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id, includeDeleted)
+	ret0, _ := ret[0].(*model.UserOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockUserServiceMockRecorder) Get(ctx, id, includeDeleted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockUserService)(nil).Get), ctx, id, includeDeleted)
+}
+
+// GetAsOf mocks base method.
+func (m *MockUserService) GetAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*model.UserOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAsOf", ctx, id, asOf)
+	ret0, _ := ret[0].(*model.UserOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAsOf indicates an expected call of GetAsOf.
+func (mr *MockUserServiceMockRecorder) GetAsOf(ctx, id, asOf any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAsOf", reflect.TypeOf((*MockUserService)(nil).GetAsOf), ctx, id, asOf)
+}
+
+// ListEvents mocks base method.
+func (m *MockUserService) ListEvents(ctx context.Context, filter model.UserEventFilter) ([]model.UserEventOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEvents", ctx, filter)
+	ret0, _ := ret[0].([]model.UserEventOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEvents indicates an expected call of ListEvents.
+func (mr *MockUserServiceMockRecorder) ListEvents(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEvents", reflect.TypeOf((*MockUserService)(nil).ListEvents), ctx, filter)
+}
+
+// ListEventsAfter mocks base method.
+func (m *MockUserService) ListEventsAfter(ctx context.Context, eventID uuid.UUID, userIDs []uuid.UUID, limit int) ([]model.UserEventOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEventsAfter", ctx, eventID, userIDs, limit)
+	ret0, _ := ret[0].([]model.UserEventOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEventsAfter indicates an expected call of ListEventsAfter.
+func (mr *MockUserServiceMockRecorder) ListEventsAfter(ctx, eventID, userIDs, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEventsAfter", reflect.TypeOf((*MockUserService)(nil).ListEventsAfter), ctx, eventID, userIDs, limit)
+}
+
+// NicknameAvailability mocks base method.
+func (m *MockUserService) NicknameAvailability(ctx context.Context, nickname string) (*model.NicknameAvailability, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NicknameAvailability", ctx, nickname)
+	ret0, _ := ret[0].(*model.NicknameAvailability)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NicknameAvailability indicates an expected call of NicknameAvailability.
+func (mr *MockUserServiceMockRecorder) NicknameAvailability(ctx, nickname any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NicknameAvailability", reflect.TypeOf((*MockUserService)(nil).NicknameAvailability), ctx, nickname)
+}
+
+// Search mocks base method.
+func (m *MockUserService) Search(ctx context.Context, query string, limit int) ([]model.UserMatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, query, limit)
+	ret0, _ := ret[0].([]model.UserMatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockUserServiceMockRecorder) Search(ctx, query, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockUserService)(nil).Search), ctx, query, limit)
 }
 
 // Update mocks base method.
-func (m *MockUserService) Update(ctx context.Context, id uuid.UUID, nickname string) (*model.UserOutput, error) {
+func (m *MockUserService) Update(ctx context.Context, id uuid.UUID, input model.UpdateUserInput, expectedVersion int64) (*model.UserOutput, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", ctx, id, nickname)
+	ret := m.ctrl.Call(m, "Update", ctx, id, input, expectedVersion)
 	ret0, _ := ret[0].(*model.UserOutput)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockUserServiceMockRecorder) Update(ctx, id, nickname any) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) Update(ctx, id, input, expectedVersion any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserService)(nil).Update), ctx, id, nickname)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserService)(nil).Update), ctx, id, input, expectedVersion)
 }
@@ -0,0 +1,133 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pkg/challenge/internal/aggregate/webhook/webhook.go
+//
+// Generated by this command:
+//
+//	mockgen --source=pkg/challenge/internal/aggregate/webhook/webhook.go --destination=pkg/challenge/internal/mocks/mock_webhook_aggregate.go --package=mocks --mock_names=Aggregate=MockWebhookAggregate
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	uuid "github.com/google/uuid"
+	webhook "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+	model "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWebhookAggregate is a mock of Aggregate interface.
+type MockWebhookAggregate struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookAggregateMockRecorder
+	isgomock struct{}
+}
+
+// MockWebhookAggregateMockRecorder is the mock recorder for MockWebhookAggregate.
+type MockWebhookAggregateMockRecorder struct {
+	mock *MockWebhookAggregate
+}
+
+// NewMockWebhookAggregate creates a new mock instance.
+func NewMockWebhookAggregate(ctrl *gomock.Controller) *MockWebhookAggregate {
+	mock := &MockWebhookAggregate{ctrl: ctrl}
+	mock.recorder = &MockWebhookAggregateMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookAggregate) EXPECT() *MockWebhookAggregateMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockWebhookAggregate) Create(ctx context.Context, w *webhook.Entity) (*webhook.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, w)
+	ret0, _ := ret[0].(*webhook.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockWebhookAggregateMockRecorder) Create(ctx, w any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockWebhookAggregate)(nil).Create), ctx, w)
+}
+
+// Delete mocks base method.
+func (m *MockWebhookAggregate) Delete(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockWebhookAggregateMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWebhookAggregate)(nil).Delete), ctx, id)
+}
+
+// Find mocks base method.
+func (m *MockWebhookAggregate) Find(ctx context.Context, page, limit int) ([]webhook.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", ctx, page, limit)
+	ret0, _ := ret[0].([]webhook.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockWebhookAggregateMockRecorder) Find(ctx, page, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockWebhookAggregate)(nil).Find), ctx, page, limit)
+}
+
+// Get mocks base method.
+func (m *MockWebhookAggregate) Get(ctx context.Context, id uuid.UUID) (*webhook.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(*webhook.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockWebhookAggregateMockRecorder) Get(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockWebhookAggregate)(nil).Get), ctx, id)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockWebhookAggregate) ListDeliveries(ctx context.Context, id uuid.UUID, page, limit int) ([]webhook.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", ctx, id, page, limit)
+	ret0, _ := ret[0].([]webhook.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockWebhookAggregateMockRecorder) ListDeliveries(ctx, id, page, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockWebhookAggregate)(nil).ListDeliveries), ctx, id, page, limit)
+}
+
+// Update mocks base method.
+func (m *MockWebhookAggregate) Update(ctx context.Context, id uuid.UUID, in model.UpdateWebhookInput) (*webhook.Entity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, id, in)
+	ret0, _ := ret[0].(*webhook.Entity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockWebhookAggregateMockRecorder) Update(ctx, id, in any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockWebhookAggregate)(nil).Update), ctx, id, in)
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+type LoginInput struct {
+	// Login is the email or the nickname of the user
+	Login    string `json:"login" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	// UserAgent and IPAddress are taken from the request and stored with the session
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+// ClientCredentialsInput authenticates a service account
+type ClientCredentialsInput struct {
+	ClientID     string `json:"client_id" binding:"required"`
+	ClientSecret string `json:"client_secret" binding:"required"`
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	// UserAgent and IPAddress are taken from the request and stored with the session
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type TokenOutput struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresIn    int64     `json:"expires_in"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	SessionID    string    `json:"session_id,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+}
+
+type SessionOutput struct {
+	ID              string    `json:"id"`
+	UserAgent       string    `json:"user_agent"`
+	IPAddress       string    `json:"ip_address"`
+	StartedAt       time.Time `json:"started_at"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+type RevokeSessionsOutput struct {
+	Revoked int `json:"revoked"`
+}
@@ -1,6 +1,13 @@
 package model
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
+	Error   string       `json:"error"`
+	Details string       `json:"details,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes what is wrong with a single input field
+type FieldError struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
 }
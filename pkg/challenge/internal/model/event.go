@@ -0,0 +1,27 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserEventFilter narrows down the events returned for an user.
+// Zero values mean no filter
+type UserEventFilter struct {
+	UserID     uuid.UUID
+	EventTypes []string
+	From       time.Time
+	To         time.Time
+	Page       int
+	Limit      int
+}
+
+type UserEventOutput struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type CreateUserInput struct {
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
@@ -7,6 +9,52 @@ type CreateUserInput struct {
 	Password  string `json:"password" binding:"required,min=8"`
 	Email     string `json:"email" binding:"required,email"`
 	Country   string `json:"country" binding:"required"`
+	// IdempotencyKey makes retries of the same request return the user first created
+	IdempotencyKey string `json:"-"`
+}
+
+// UpdateUserInput only changes the fields that are set
+type UpdateUserInput struct {
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Nickname  *string `json:"nickname"`
+	Email     *string `json:"email"`
+	Country   *string `json:"country"`
+}
+
+// Sort fields of the users returned by Find
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortNickname  = "nickname"
+)
+
+// UserFilter narrows down, sorts and paginates the users returned by Find.
+// Zero values mean no filter
+type UserFilter struct {
+	// Countries matches any of the given countries
+	Countries []string
+	// NicknamePrefix and EmailPrefix are case insensitive
+	NicknamePrefix string
+	EmailPrefix    string
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	// SortBy is UserSortCreatedAt (default) or UserSortNickname. Ties are sorted by ID
+	SortBy     string
+	Descending bool
+	// Cursor is the next page token of a previous page. It takes precedence over Page
+	Cursor string
+	Page   int
+	Limit  int
+	// IncludeTotal also counts every user matching the filter
+	IncludeTotal bool
+}
+
+// UsersPage is a page of users. NextPageToken is empty on the last page and
+// Total is only set when it was requested
+type UsersPage struct {
+	Users         []UserOutput
+	NextPageToken string
+	Total         *int64
 }
 
 type UserOutput struct {
@@ -16,4 +64,21 @@ type UserOutput struct {
 	Nickname  string `json:"nickname"`
 	Email     string `json:"email"`
 	Country   string `json:"country"`
+	// Version is incremented by every write, it is also sent as ETag
+	Version int64 `json:"version"`
+	// DeletedAt is only set for soft deleted users, which are only returned to admins
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// UserMatch is an user found by a fuzzy search, with how similar it is to the query from 0 to 1
+type UserMatch struct {
+	UserOutput
+	Score float64 `json:"score"`
+}
+
+// NicknameAvailability tells whether a nickname is free and, when taken, some free alternatives
+type NicknameAvailability struct {
+	Nickname    string   `json:"nickname"`
+	Available   bool     `json:"available"`
+	Suggestions []string `json:"suggestions"`
 }
@@ -0,0 +1,169 @@
+package projector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+)
+
+var (
+	// ErrUserNotFound used when the user did not exist at the requested time
+	ErrUserNotFound = domainerr.NotFound("user not found at the given time")
+	// ErrMissingCreatedEvent used when the event log of an user does not start with USER_CREATED
+	ErrMissingCreatedEvent = errors.New("event log does not start with USER_CREATED")
+	// ErrDuplicatedCreatedEvent used when an user has more than one USER_CREATED event
+	ErrDuplicatedCreatedEvent = errors.New("event log has more than one USER_CREATED")
+)
+
+// Drift describes a field whose projected value differs from the stored one
+type Drift struct {
+	Field     string
+	Projected string
+	Stored    string
+}
+
+// Project folds the events of a single user, in chronological order, into its state.
+// A soft deleted user is returned with DeletedAt set. Passwords are never part of the events
+func Project(events []event.User) (*user.Entity, error) {
+	if len(events) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	var u *user.Entity
+	for _, e := range events {
+		// Sessions are not part of the user state
+		if e.EventType == event.UserSessionRevoked {
+			continue
+		}
+		if u == nil && e.EventType != event.UserCreated {
+			return nil, fmt.Errorf("event %s: %w", e.ID, ErrMissingCreatedEvent)
+		}
+
+		switch e.EventType {
+		case event.UserCreated:
+			if u != nil {
+				return nil, fmt.Errorf("event %s: %w", e.ID, ErrDuplicatedCreatedEvent)
+			}
+			created, err := applyCreated(e)
+			if err != nil {
+				return nil, fmt.Errorf("event %s: %w", e.ID, err)
+			}
+			u = created
+		case event.UserUpdated:
+			if err := applyUpdated(u, e); err != nil {
+				return nil, fmt.Errorf("event %s: %w", e.ID, err)
+			}
+		case event.UserSoftDeleted:
+			var p event.DeletedPayload
+			if err := json.Unmarshal(e.Payload, &p); err != nil {
+				return nil, fmt.Errorf("event %s: %w", e.ID, err)
+			}
+			u.DeletedAt = gorm.DeletedAt{Time: e.CreatedAt, Valid: true}
+			setVersion(u, p.Version)
+		default:
+			return nil, fmt.Errorf("event %s: unknown event type %q", e.ID, e.EventType)
+		}
+		u.UpdatedAt = e.CreatedAt
+	}
+
+	return u, nil
+}
+
+// Diff returns the fields where the projected user and the stored row disagree
+func Diff(projected, stored *user.Entity) []Drift {
+	var drifts []Drift
+	compare := func(field, p, s string) {
+		if p != s {
+			drifts = append(drifts, Drift{Field: field, Projected: p, Stored: s})
+		}
+	}
+
+	compare("first_name", projected.FirstName, stored.FirstName)
+	compare("last_name", projected.LastName, stored.LastName)
+	compare("nickname", projected.Nickname, stored.Nickname)
+	compare("email", projected.Email, stored.Email)
+	compare("country", projected.Country, stored.Country)
+	compare("deleted", strconv.FormatBool(projected.DeletedAt.Valid), strconv.FormatBool(stored.DeletedAt.Valid))
+	return drifts
+}
+
+func applyCreated(e event.User) (*user.Entity, error) {
+	var p event.CreatedPayload
+	if err := json.Unmarshal(e.Payload, &p); err != nil {
+		return nil, err
+	}
+
+	u := &user.Entity{
+		ID:        e.UserID,
+		FirstName: p.FirstName,
+		LastName:  p.LastName,
+		Nickname:  p.Nickname,
+		Email:     p.Email,
+		Country:   p.Country,
+		Version:   1,
+		CreatedAt: e.CreatedAt,
+	}
+
+	// Older events stored the whole entity instead of the typed payload
+	if p.FirstName == "" && p.LastName == "" {
+		var legacy user.Entity
+		if err := json.Unmarshal(e.Payload, &legacy); err != nil {
+			return nil, err
+		}
+		u.FirstName = legacy.FirstName
+		u.LastName = legacy.LastName
+		u.Country = legacy.Country
+	}
+
+	return u, nil
+}
+
+func applyUpdated(u *user.Entity, e event.User) error {
+	var p event.UpdatedPayload
+	if err := json.Unmarshal(e.Payload, &p); err != nil {
+		return err
+	}
+	setVersion(u, p.Version)
+	if len(p.Changes) == 0 {
+		// Older events only changed the nickname
+		if p.Nickname != "" {
+			u.Nickname = p.Nickname
+		}
+		return nil
+	}
+
+	for _, c := range p.Changes {
+		switch c.Field {
+		case "first_name":
+			u.FirstName = c.New
+		case "last_name":
+			u.LastName = c.New
+		case "nickname":
+			u.Nickname = c.New
+		case "email":
+			u.Email = c.New
+		case "country":
+			u.Country = c.New
+		default:
+			return fmt.Errorf("unknown updated field %q", c.Field)
+		}
+	}
+	return nil
+}
+
+// setVersion sets the version stored in the event. Older events have none, every
+// write incremented it
+func setVersion(u *user.Entity, version int64) {
+	if version == 0 {
+		u.Version++
+		return
+	}
+	u.Version = version
+}
@@ -0,0 +1,126 @@
+package projector_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	projector "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/projector/user"
+)
+
+func TestProject(t *testing.T) {
+	userID := uuid.New()
+	base := time.Date(2025, 4, 20, 10, 0, 0, 0, time.UTC)
+	created := event.User{
+		ID:        uuid.New(),
+		UserID:    userID,
+		EventType: event.UserCreated,
+		Payload:   []byte(`{"user_id":"` + userID.String() + `","first_name":"Nacho","last_name":"Calcagno","email":"nacho@gmail.com","nickname":"bandido","country":"VE"}`),
+		CreatedAt: base,
+	}
+	updated := event.User{
+		ID:        uuid.New(),
+		UserID:    userID,
+		EventType: event.UserUpdated,
+		Payload:   []byte(`{"user_id":"` + userID.String() + `","nickname":"csgolover"}`),
+		CreatedAt: base.Add(time.Hour),
+	}
+	deleted := event.User{
+		ID:        uuid.New(),
+		UserID:    userID,
+		EventType: event.UserSoftDeleted,
+		Payload:   []byte(`{"user_id":"` + userID.String() + `"}`),
+		CreatedAt: base.Add(2 * time.Hour),
+	}
+
+	t.Run("should fold created and updated events", func(t *testing.T) {
+		u, err := projector.Project([]event.User{created, updated})
+		assert.NoError(t, err)
+		assert.Equal(t, userID, u.ID)
+		assert.Equal(t, "Nacho", u.FirstName)
+		assert.Equal(t, "VE", u.Country)
+		assert.Equal(t, "csgolover", u.Nickname)
+		assert.Equal(t, base, u.CreatedAt)
+		assert.Equal(t, updated.CreatedAt, u.UpdatedAt)
+		assert.False(t, u.DeletedAt.Valid)
+	})
+
+	t.Run("should apply the changed fields", func(t *testing.T) {
+		profile := event.User{
+			ID:        uuid.New(),
+			UserID:    userID,
+			EventType: event.UserUpdated,
+			Payload: []byte(`{"user_id":"` + userID.String() + `","nickname":"csgolover","country":"UY","changes":[` +
+				`{"field":"first_name","old":"Nacho","new":"Ignacio"},{"field":"email","old":"nacho@gmail.com","new":"ignacio@gmail.com"},` +
+				`{"field":"country","old":"VE","new":"UY"}]}`),
+			CreatedAt: base.Add(90 * time.Minute),
+		}
+
+		u, err := projector.Project([]event.User{created, updated, profile})
+		assert.NoError(t, err)
+		assert.Equal(t, "Ignacio", u.FirstName)
+		assert.Equal(t, "Calcagno", u.LastName)
+		assert.Equal(t, "csgolover", u.Nickname)
+		assert.Equal(t, "ignacio@gmail.com", u.Email)
+		assert.Equal(t, "UY", u.Country)
+	})
+
+	t.Run("should mark the user as deleted", func(t *testing.T) {
+		u, err := projector.Project([]event.User{created, updated, deleted})
+		assert.NoError(t, err)
+		assert.True(t, u.DeletedAt.Valid)
+		assert.Equal(t, deleted.CreatedAt, u.DeletedAt.Time)
+	})
+
+	t.Run("should read legacy created payloads", func(t *testing.T) {
+		legacy := created
+		legacy.Payload = []byte(`{"ID":"` + userID.String() + `","FirstName":"Old","LastName":"School","Nickname":"oldie","Email":"old@gmail.com","Country":"AR"}`)
+
+		u, err := projector.Project([]event.User{legacy})
+		assert.NoError(t, err)
+		assert.Equal(t, "Old", u.FirstName)
+		assert.Equal(t, "oldie", u.Nickname)
+		assert.Equal(t, "AR", u.Country)
+	})
+
+	t.Run("should fail without events", func(t *testing.T) {
+		_, err := projector.Project(nil)
+		assert.ErrorIs(t, err, projector.ErrUserNotFound)
+	})
+
+	t.Run("should fail when the log does not start with USER_CREATED", func(t *testing.T) {
+		_, err := projector.Project([]event.User{updated})
+		assert.ErrorIs(t, err, projector.ErrMissingCreatedEvent)
+	})
+
+	t.Run("should fail with duplicated USER_CREATED", func(t *testing.T) {
+		_, err := projector.Project([]event.User{created, created})
+		assert.ErrorIs(t, err, projector.ErrDuplicatedCreatedEvent)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	projected := &user.Entity{FirstName: "Nacho", LastName: "Calcagno", Nickname: "bandido", Email: "nacho@gmail.com", Country: "VE"}
+
+	t.Run("should not report drift for equal users", func(t *testing.T) {
+		stored := *projected
+		assert.Empty(t, projector.Diff(projected, &stored))
+	})
+
+	t.Run("should report drifted fields", func(t *testing.T) {
+		stored := *projected
+		stored.Nickname = "changedbyhand"
+		stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+
+		drifts := projector.Diff(projected, &stored)
+		assert.Equal(t, []projector.Drift{
+			{Field: "nickname", Projected: "bandido", Stored: "changedbyhand"},
+			{Field: "deleted", Projected: "false", Stored: "true"},
+		}, drifts)
+	})
+}
@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+)
+
+var (
+	// ErrInvalidCursor used when a page cursor was not returned by Find for the same sort or was tampered with
+	ErrInvalidCursor = domainerr.Validation("cursor", errors.New("cursor is not valid"))
+	// ErrInvalidSort used when users are sorted by a field that is not allowed
+	ErrInvalidSort = domainerr.Validation("sort", errors.New("users can only be sorted by created_at or nickname"))
+)
+
+// userSortColumns whitelists the columns users can be sorted by. Only these are ever put in the query
+var userSortColumns = map[string]string{
+	model.UserSortCreatedAt: "created_at",
+	model.UserSortNickname:  "nickname",
+}
+
+// userSort is a validated sort of the users, always followed by the ID to break ties
+type userSort struct {
+	column     string
+	descending bool
+}
+
+func userSortFor(filter model.UserFilter) (userSort, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = model.UserSortCreatedAt
+	}
+	column, ok := userSortColumns[sortBy]
+	if !ok {
+		return userSort{}, ErrInvalidSort
+	}
+	return userSort{column: column, descending: filter.Descending}, nil
+}
+
+func (s userSort) String() string {
+	if s.descending {
+		return "-" + s.column
+	}
+	return s.column
+}
+
+func (s userSort) orderBy() string {
+	if s.descending {
+		return fmt.Sprintf("%s DESC, id DESC", s.column)
+	}
+	return fmt.Sprintf("%s ASC, id ASC", s.column)
+}
+
+// after returns the condition of the users coming after a cursor
+func (s userSort) after() string {
+	if s.descending {
+		return fmt.Sprintf("(%s, id) < (?, ?)", s.column)
+	}
+	return fmt.Sprintf("(%s, id) > (?, ?)", s.column)
+}
+
+// userCursor is the position of the last user of a page. Clients get it as an opaque token
+type userCursor struct {
+	Sort      string    `json:"s"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Nickname  string    `json:"n,omitempty"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// value returns the sort column value of the cursor
+func (c *userCursor) value(s userSort) any {
+	if s.column == "nickname" {
+		return c.Nickname
+	}
+	return c.CreatedAt
+}
+
+func encodeUserCursor(u user.Entity, s userSort) (string, error) {
+	c := userCursor{Sort: s.String(), ID: u.ID}
+	if s.column == "nickname" {
+		c.Nickname = u.Nickname
+	} else {
+		c.CreatedAt = u.CreatedAt
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeUserCursor(token string, s userSort) (*userCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c userCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil || c.Sort != s.String() {
+		return nil, ErrInvalidCursor
+	}
+	if s.column == "created_at" && c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+)
+
+// Postgres error codes translated to domain errors
+const (
+	pgUniqueViolation     = "23505"
+	pgCheckViolation      = "23514"
+	pgNotNullViolation    = "23502"
+	pgInvalidTextFormat   = "22P02"
+	pgStringDataTruncated = "22001"
+)
+
+// translateError maps gorm and Postgres errors to domain errors, so the transports
+// can answer with the right status without knowing about the DB
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRecordNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		// The DB error is not kept, it would leak the constraint to the clients
+		return domainerr.Conflict(constraintField(pgErr.TableName, pgErr.ConstraintName), "already taken", nil)
+	case pgCheckViolation:
+		return domainerr.Validation(constraintField(pgErr.TableName, pgErr.ConstraintName), err)
+	case pgNotNullViolation:
+		return domainerr.Validation(pgErr.ColumnName, err)
+	case pgInvalidTextFormat, pgStringDataTruncated:
+		return domainerr.Validation(pgErr.ColumnName, err)
+	default:
+		return err
+	}
+}
+
+// constraintField returns the column a constraint is about, following the Postgres
+// naming convention <table>_<column>_<suffix>, e.g. user_email_key is email
+func constraintField(table, constraint string) string {
+	field := strings.TrimPrefix(constraint, table+"_")
+	for _, suffix := range []string{"_key", "_idx", "_check"} {
+		field = strings.TrimSuffix(field, suffix)
+	}
+	return field
+}
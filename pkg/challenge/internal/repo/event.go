@@ -0,0 +1,227 @@
+package repo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+)
+
+// FindUnpublishedEvents returns pending events created before the given time, oldest first.
+// Rows are locked with FOR UPDATE SKIP LOCKED so concurrent relays never pick the same event
+func FindUnpublishedEvents(tx *gorm.DB, createdBefore time.Time, limit int) ([]event.User, error) {
+	var events []event.User
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+
+	err := tx.Model(&event.User{}).
+		Where("published = ?", false).
+		Where("created_at <= ?", createdBefore).
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", time.Now()).
+		Order("created_at ASC").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ClaimEvents pushes the next attempt of unpublished events to the given time while
+// they are being published, so other relays skip them until then
+func ClaimEvents(ids []uuid.UUID, until time.Time, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.Model(&event.User{}).
+		Where("id IN ? AND published = ?", ids, false).
+		Update("next_attempt_at", until).Error
+}
+
+// CountUnpublishedEvents returns how many events are waiting for the outbox relay,
+// including the ones waiting for a retry
+func CountUnpublishedEvents(tx *gorm.DB) (int64, error) {
+	var count int64
+	if tx == nil {
+		return 0, ErrMissingDB
+	}
+
+	if err := tx.Model(&event.User{}).Where("published = ?", false).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetEvent returns an user event by ID
+func GetEvent(id uuid.UUID, tx *gorm.DB) (*event.User, error) {
+	var e event.User
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	if err := tx.Where("id = ?", id).First(&e).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+// MarkEventPublished flags an event as delivered
+func MarkEventPublished(id uuid.UUID, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return ErrIDShouldNotBeEmpty
+	}
+
+	return tx.Model(&event.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"published":       true,
+			"next_attempt_at": nil,
+			"last_error":      "",
+		}).Error
+}
+
+// MarkEventsPublished flags several events as delivered
+func MarkEventsPublished(ids []uuid.UUID, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.Model(&event.User{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"published":       true,
+			"next_attempt_at": nil,
+			"last_error":      "",
+		}).Error
+}
+
+// MarkEventFailed records a failed delivery and when it should be attempted again
+func MarkEventFailed(id uuid.UUID, attempts int, nextAttemptAt time.Time, reason string, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return ErrIDShouldNotBeEmpty
+	}
+
+	return tx.Model(&event.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      reason,
+		}).Error
+}
+
+// FindUserEvents returns the events of an user in chronological order.
+// It can be paginated and filtered by event type and creation time range
+func FindUserEvents(tx *gorm.DB, filter model.UserEventFilter) ([]event.User, error) {
+	var events []event.User
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if filter.UserID == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	query := tx.Model(&event.User{}).Where("user_id = ?", filter.UserID)
+	if len(filter.EventTypes) > 0 {
+		query = query.Where("event_type IN ?", filter.EventTypes)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at <= ?", filter.To)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	page := filter.Page
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	if err := query.Order("created_at ASC, id ASC").Limit(limit).Offset(offset).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// FindUserEventsUntil returns every event of an user created up to the given time,
+// in chronological order. A zero time returns the whole log
+func FindUserEventsUntil(tx *gorm.DB, userID uuid.UUID, until time.Time) ([]event.User, error) {
+	var events []event.User
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if userID == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	query := tx.Model(&event.User{}).Where("user_id = ?", userID)
+	if !until.IsZero() {
+		query = query.Where("created_at <= ?", until)
+	}
+
+	if err := query.Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// FindEventsAfter returns up to limit events stored after the given event, in chronological
+// order. It can be filtered by user IDs. It is used to resume event feeds
+func FindEventsAfter(tx *gorm.DB, after uuid.UUID, userIDs []uuid.UUID, limit int) ([]event.User, error) {
+	var events []event.User
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if after == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	var cursor event.User
+	if err := tx.Select("id", "created_at").Where("id = ?", after).First(&cursor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	query := tx.Model(&event.User{}).Where("(created_at, id) > (?, ?)", cursor.CreatedAt, cursor.ID)
+	if len(userIDs) > 0 {
+		query = query.Where("user_id IN ?", userIDs)
+	}
+
+	if err := query.Order("created_at ASC, id ASC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
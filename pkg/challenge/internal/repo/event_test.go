@@ -0,0 +1,139 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+func TestRepository_FindUserEvents(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	userID := uuid.New()
+	base := time.Date(2025, 4, 20, 10, 0, 0, 0, time.UTC)
+	events := []event.User{
+		{ID: uuid.New(), UserID: userID, EventType: event.UserCreated, Payload: []byte(`{}`), CreatedAt: base},
+		{ID: uuid.New(), UserID: userID, EventType: event.UserUpdated, Payload: []byte(`{}`), CreatedAt: base.Add(time.Hour)},
+		{ID: uuid.New(), UserID: userID, EventType: event.UserUpdated, Payload: []byte(`{}`), CreatedAt: base.Add(2 * time.Hour)},
+		{ID: uuid.New(), UserID: uuid.New(), EventType: event.UserCreated, Payload: []byte(`{}`), CreatedAt: base},
+	}
+	for _, e := range events {
+		assert.NoError(t, db.Create(&e).Error)
+	}
+
+	t.Run("should return only the user events in chronological order", func(t *testing.T) {
+		res, err := repo.FindUserEvents(db, model.UserEventFilter{UserID: userID})
+		assert.NoError(t, err)
+		assert.Len(t, res, 3)
+		assert.Equal(t, event.UserCreated, res[0].EventType)
+	})
+
+	t.Run("should filter by event type", func(t *testing.T) {
+		res, err := repo.FindUserEvents(db, model.UserEventFilter{UserID: userID, EventTypes: []string{event.UserUpdated}})
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+	})
+
+	t.Run("should filter by time range", func(t *testing.T) {
+		res, err := repo.FindUserEvents(db, model.UserEventFilter{
+			UserID: userID,
+			From:   base.Add(30 * time.Minute),
+			To:     base.Add(90 * time.Minute),
+		})
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+	})
+
+	t.Run("should paginate", func(t *testing.T) {
+		res, err := repo.FindUserEvents(db, model.UserEventFilter{UserID: userID, Page: 2, Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+	})
+
+	t.Run("should return error if user ID is nil", func(t *testing.T) {
+		_, err := repo.FindUserEvents(db, model.UserEventFilter{})
+		assert.Equal(t, repo.ErrIDShouldNotBeEmpty, err)
+	})
+}
+
+func TestRepository_FindEventsAfter(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	userID := uuid.New()
+	otherUserID := uuid.New()
+	base := time.Date(2025, 4, 20, 10, 0, 0, 0, time.UTC)
+	events := []event.User{
+		{ID: uuid.New(), UserID: userID, EventType: event.UserCreated, Payload: []byte(`{}`), CreatedAt: base},
+		{ID: uuid.New(), UserID: otherUserID, EventType: event.UserCreated, Payload: []byte(`{}`), CreatedAt: base.Add(time.Minute)},
+		{ID: uuid.New(), UserID: userID, EventType: event.UserUpdated, Payload: []byte(`{}`), CreatedAt: base.Add(2 * time.Minute)},
+	}
+	for _, e := range events {
+		assert.NoError(t, db.Create(&e).Error)
+	}
+
+	t.Run("should return events stored after the given one", func(t *testing.T) {
+		res, err := repo.FindEventsAfter(db, events[0].ID, nil, 10)
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, events[1].ID, res[0].ID)
+	})
+
+	t.Run("should filter by user", func(t *testing.T) {
+		res, err := repo.FindEventsAfter(db, events[0].ID, []uuid.UUID{userID}, 10)
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, events[2].ID, res[0].ID)
+	})
+
+	t.Run("should fail when the event does not exist", func(t *testing.T) {
+		_, err := repo.FindEventsAfter(db, uuid.New(), nil, 10)
+		assert.Equal(t, repo.ErrRecordNotFound, err)
+	})
+}
+
+func TestRepository_CountUnpublishedEvents(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	before, err := repo.CountUnpublishedEvents(db)
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	events := []event.User{
+		{ID: uuid.New(), UserID: userID, EventType: event.UserCreated, Payload: []byte(`{}`)},
+		{ID: uuid.New(), UserID: userID, EventType: event.UserUpdated, Payload: []byte(`{}`), Attempts: 2},
+		{ID: uuid.New(), UserID: userID, EventType: event.UserUpdated, Payload: []byte(`{}`), Published: true},
+	}
+	for _, e := range events {
+		assert.NoError(t, db.Create(&e).Error)
+	}
+
+	t.Run("should count the pending and retried events", func(t *testing.T) {
+		count, err := repo.CountUnpublishedEvents(db)
+		assert.NoError(t, err)
+		assert.Equal(t, before+2, count)
+	})
+
+	t.Run("should return error if DB is nil", func(t *testing.T) {
+		_, err := repo.CountUnpublishedEvents(nil)
+		assert.Equal(t, repo.ErrMissingDB, err)
+	})
+}
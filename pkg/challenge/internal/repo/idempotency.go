@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
+)
+
+// ErrEmptyIdempotencyKey used when the scope or the key of an idempotency key are empty
+var ErrEmptyIdempotencyKey = errors.New("idempotency key scope and key cannot be empty")
+
+// ClaimIdempotencyKey stores a new idempotency key and reports whether it was stored.
+// When the key already exists nothing is stored and the existing one is returned, unless
+// it is older than ttl: expired keys are claimed again as new ones, even before they are
+// removed. Concurrent claims of the same key wait for the first transaction to finish
+func ClaimIdempotencyKey(k *idempotency.Entity, ttl time.Duration, tx *gorm.DB) (*idempotency.Entity, bool, error) {
+	if tx == nil {
+		return nil, false, ErrMissingDB
+	}
+	if k.Scope == "" || k.Key == "" {
+		return nil, false, ErrEmptyIdempotencyKey
+	}
+
+	k.CreatedAt = time.Now()
+	k.Response = nil
+	res := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "scope"}, {Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"request_hash": k.RequestHash,
+			"response":     nil,
+			"created_at":   k.CreatedAt,
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("idempotency_key.created_at < ?", k.CreatedAt.Add(-ttl)),
+		}},
+	}).Create(k)
+	if res.Error != nil {
+		return nil, false, translateError(res.Error)
+	}
+	if res.RowsAffected == 1 {
+		return k, true, nil
+	}
+
+	var existing idempotency.Entity
+	if err := tx.Where("scope = ? AND key = ?", k.Scope, k.Key).First(&existing).Error; err != nil {
+		return nil, false, translateError(err)
+	}
+	return &existing, false, nil
+}
+
+// SaveIdempotencyResponse stores the response sent back for an idempotency key
+func SaveIdempotencyResponse(k *idempotency.Entity, response []byte, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+
+	res := tx.Model(&idempotency.Entity{}).
+		Where("scope = ? AND key = ?", k.Scope, k.Key).
+		Update("response", response)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	k.Response = response
+	return nil
+}
+
+// DeleteIdempotencyKeysBefore removes the idempotency keys stored before the given time
+// and returns how many were removed
+func DeleteIdempotencyKeysBefore(before time.Time, tx *gorm.DB) (int64, error) {
+	if tx == nil {
+		return 0, ErrMissingDB
+	}
+
+	res := tx.Where("created_at < ?", before).Delete(&idempotency.Entity{})
+	return res.RowsAffected, res.Error
+}
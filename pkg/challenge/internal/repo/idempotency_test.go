@@ -0,0 +1,94 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+func TestRepository_ClaimIdempotencyKey(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	t.Run("should claim a new key", func(t *testing.T) {
+		k := &idempotency.Entity{Scope: idempotency.ScopeCreateUser, Key: "first", RequestHash: "hash"}
+		res, claimed, err := repo.ClaimIdempotencyKey(k, time.Hour, db)
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+		assert.Equal(t, "hash", res.RequestHash)
+
+		assert.NoError(t, repo.SaveIdempotencyResponse(res, []byte(`{"id":"1"}`), db))
+	})
+
+	t.Run("should return the existing key with its response", func(t *testing.T) {
+		k := &idempotency.Entity{Scope: idempotency.ScopeCreateUser, Key: "first", RequestHash: "other"}
+		res, claimed, err := repo.ClaimIdempotencyKey(k, time.Hour, db)
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+		assert.Equal(t, "hash", res.RequestHash)
+		assert.JSONEq(t, `{"id":"1"}`, string(res.Response))
+	})
+
+	t.Run("should claim a key again once it is expired", func(t *testing.T) {
+		expired := idempotency.Entity{Scope: idempotency.ScopeCreateUser, Key: "expired", RequestHash: "hash", CreatedAt: time.Now().Add(-2 * time.Hour)}
+		assert.NoError(t, db.Create(&expired).Error)
+		assert.NoError(t, repo.SaveIdempotencyResponse(&expired, []byte(`{"id":"1"}`), db))
+
+		k := &idempotency.Entity{Scope: idempotency.ScopeCreateUser, Key: "expired", RequestHash: "other"}
+		res, claimed, err := repo.ClaimIdempotencyKey(k, time.Hour, db)
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+		assert.Equal(t, "other", res.RequestHash)
+
+		var stored idempotency.Entity
+		assert.NoError(t, db.Where("scope = ? AND key = ?", k.Scope, k.Key).First(&stored).Error)
+		assert.Equal(t, "other", stored.RequestHash)
+		assert.Nil(t, stored.Response)
+		assert.WithinDuration(t, time.Now(), stored.CreatedAt, time.Minute)
+	})
+
+	t.Run("should fail if the key is empty", func(t *testing.T) {
+		_, _, err := repo.ClaimIdempotencyKey(&idempotency.Entity{Scope: idempotency.ScopeCreateUser}, time.Hour, db)
+		assert.Equal(t, repo.ErrEmptyIdempotencyKey, err)
+	})
+
+	t.Run("should fail if DB is nil", func(t *testing.T) {
+		_, _, err := repo.ClaimIdempotencyKey(&idempotency.Entity{}, time.Hour, nil)
+		assert.Equal(t, repo.ErrMissingDB, err)
+	})
+}
+
+func TestRepository_DeleteIdempotencyKeysBefore(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	now := time.Now()
+	keys := []idempotency.Entity{
+		{Scope: idempotency.ScopeCreateUser, Key: "old", RequestHash: "hash", CreatedAt: now.Add(-48 * time.Hour)},
+		{Scope: idempotency.ScopeCreateUser, Key: "new", RequestHash: "hash", CreatedAt: now},
+	}
+	for _, k := range keys {
+		assert.NoError(t, db.Create(&k).Error)
+	}
+
+	t.Run("should only delete the keys stored before the given time", func(t *testing.T) {
+		deleted, err := repo.DeleteIdempotencyKeysBefore(now.Add(-24*time.Hour), db)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		_, claimed, err := repo.ClaimIdempotencyKey(&idempotency.Entity{Scope: idempotency.ScopeCreateUser, Key: "old", RequestHash: "hash"}, 24*time.Hour, db)
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+	})
+}
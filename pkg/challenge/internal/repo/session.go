@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+)
+
+// CreateSession stores a new refresh token
+func CreateSession(s *session.Entity, tx *gorm.DB) (*session.Entity, error) {
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if s.UserID == uuid.Nil || s.SessionID == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	if err := tx.Create(s).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetSessionByTokenHashForUpdate returns the refresh token with the given hash
+// and locks the row so it can only be rotated once
+func GetSessionByTokenHashForUpdate(hash string, tx *gorm.DB) (*session.Entity, error) {
+	var s session.Entity
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+
+	if err := tx.Where("token_hash = ?", hash).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&s).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// MarkSessionUsed flags a refresh token as rotated
+func MarkSessionUsed(id uuid.UUID, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return ErrIDShouldNotBeEmpty
+	}
+
+	return tx.Model(&session.Entity{}).
+		Where("id = ?", id).
+		Update("used_at", time.Now()).Error
+}
+
+// FindActiveSessions returns the current refresh token of every active session of an user, newest first
+func FindActiveSessions(userID uuid.UUID, tx *gorm.DB) ([]session.Entity, error) {
+	var sessions []session.Entity
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if userID == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	if err := tx.Where("user_id = ?", userID).
+		Where("used_at IS NULL AND revoked_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Order("started_at DESC, id DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSessions revokes every not revoked refresh token of an user, or only the ones
+// of the given session when sessionID is not uuid.Nil. It returns the revoked session IDs
+func RevokeSessions(userID, sessionID uuid.UUID, reason string, tx *gorm.DB) ([]uuid.UUID, error) {
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if userID == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	query := tx.Model(&session.Entity{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+	if sessionID != uuid.Nil {
+		query = query.Where("session_id = ?", sessionID)
+	}
+
+	var revoked []session.Entity
+	if err := query.
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "session_id"}}}).
+		Updates(map[string]interface{}{
+			"revoked_at":    time.Now(),
+			"revoke_reason": reason,
+		}).
+		Scan(&revoked).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(revoked))
+	ids := make([]uuid.UUID, 0, len(revoked))
+	for _, s := range revoked {
+		if _, ok := seen[s.SessionID]; ok {
+			continue
+		}
+		seen[s.SessionID] = struct{}{}
+		ids = append(ids, s.SessionID)
+	}
+	return ids, nil
+}
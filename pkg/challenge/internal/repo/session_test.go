@@ -0,0 +1,160 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+func TestRepository_RotateSession(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	u := createSessionUser(t, db, "rotate")
+	first := createTestSession(t, db, u.ID, uuid.Nil, time.Hour)
+
+	t.Run("should find a refresh token by its hash", func(t *testing.T) {
+		s, err := repo.GetSessionByTokenHashForUpdate(session.HashToken(first.Token), db)
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, s.ID)
+		assert.Nil(t, s.UsedAt)
+	})
+
+	t.Run("should fail with not found on unknown hashes", func(t *testing.T) {
+		_, err := repo.GetSessionByTokenHashForUpdate(session.HashToken("unknown"), db)
+		assert.Equal(t, repo.ErrRecordNotFound, err)
+	})
+
+	t.Run("should only list the current token of a rotated session", func(t *testing.T) {
+		assert.NoError(t, repo.MarkSessionUsed(first.ID, db))
+		second := createTestSession(t, db, u.ID, first.SessionID, time.Hour)
+
+		used, err := repo.GetSessionByTokenHashForUpdate(session.HashToken(first.Token), db)
+		assert.NoError(t, err)
+		assert.NotNil(t, used.UsedAt)
+
+		sessions, err := repo.FindActiveSessions(u.ID, db)
+		assert.NoError(t, err)
+		if assert.Len(t, sessions, 1) {
+			assert.Equal(t, second.ID, sessions[0].ID)
+			assert.Equal(t, first.SessionID, sessions[0].SessionID)
+		}
+	})
+
+	t.Run("should not list expired sessions", func(t *testing.T) {
+		createTestSession(t, db, u.ID, uuid.Nil, -time.Minute)
+
+		sessions, err := repo.FindActiveSessions(u.ID, db)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+
+	t.Run("should fail if the ID is empty", func(t *testing.T) {
+		assert.Equal(t, repo.ErrIDShouldNotBeEmpty, repo.MarkSessionUsed(uuid.Nil, db))
+		_, err := repo.FindActiveSessions(uuid.Nil, db)
+		assert.Equal(t, repo.ErrIDShouldNotBeEmpty, err)
+	})
+}
+
+func TestRepository_RevokeSessions(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	u := createSessionUser(t, db, "revoke")
+	other := createSessionUser(t, db, "other")
+
+	// A rotated session has two rows, both are revoked but its ID is returned once
+	rotated := createTestSession(t, db, u.ID, uuid.Nil, time.Hour)
+	assert.NoError(t, repo.MarkSessionUsed(rotated.ID, db))
+	createTestSession(t, db, u.ID, rotated.SessionID, time.Hour)
+	single := createTestSession(t, db, u.ID, uuid.Nil, time.Hour)
+	otherUsers := createTestSession(t, db, other.ID, uuid.Nil, time.Hour)
+
+	t.Run("should revoke one session and return its ID once", func(t *testing.T) {
+		ids, err := repo.RevokeSessions(u.ID, rotated.SessionID, session.RevokedByUser, db)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{rotated.SessionID}, ids)
+
+		var rows []session.Entity
+		assert.NoError(t, db.Where("session_id = ?", rotated.SessionID).Find(&rows).Error)
+		assert.Len(t, rows, 2)
+		for _, s := range rows {
+			assert.NotNil(t, s.RevokedAt)
+			assert.Equal(t, session.RevokedByUser, s.RevokeReason)
+		}
+	})
+
+	t.Run("should not return sessions already revoked", func(t *testing.T) {
+		ids, err := repo.RevokeSessions(u.ID, rotated.SessionID, session.RevokedOnReuse, db)
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+
+		var s session.Entity
+		assert.NoError(t, db.Where("id = ?", rotated.ID).First(&s).Error)
+		assert.Equal(t, session.RevokedByUser, s.RevokeReason)
+	})
+
+	t.Run("should not revoke the session of another user", func(t *testing.T) {
+		ids, err := repo.RevokeSessions(u.ID, otherUsers.SessionID, session.RevokedByUser, db)
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+
+	t.Run("should revoke the remaining sessions of an user", func(t *testing.T) {
+		ids, err := repo.RevokeSessions(u.ID, uuid.Nil, session.RevokedByUser, db)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{single.SessionID}, ids)
+
+		sessions, err := repo.FindActiveSessions(u.ID, db)
+		assert.NoError(t, err)
+		assert.Empty(t, sessions)
+
+		sessions, err = repo.FindActiveSessions(other.ID, db)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+
+	t.Run("should return no IDs when nothing is left to revoke", func(t *testing.T) {
+		ids, err := repo.RevokeSessions(u.ID, uuid.Nil, session.RevokedByUser, db)
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+
+	t.Run("should fail if the user ID is empty", func(t *testing.T) {
+		_, err := repo.RevokeSessions(uuid.Nil, uuid.Nil, session.RevokedByUser, db)
+		assert.Equal(t, repo.ErrIDShouldNotBeEmpty, err)
+	})
+
+	t.Run("should fail if DB is nil", func(t *testing.T) {
+		_, err := repo.RevokeSessions(u.ID, uuid.Nil, session.RevokedByUser, nil)
+		assert.Equal(t, repo.ErrMissingDB, err)
+	})
+}
+
+func createSessionUser(t *testing.T, db *gorm.DB, nickname string) *user.Entity {
+	u := validUser
+	u.Nickname = nickname
+	u.Email = nickname + "@sessions.com"
+	created, err := repo.Create(&u, db)
+	assert.NoError(t, err)
+	return created
+}
+
+// createTestSession stores a refresh token of the given session, a new one when sessionID is uuid.Nil
+func createTestSession(t *testing.T, db *gorm.DB, userID, sessionID uuid.UUID, ttl time.Duration) *session.Entity {
+	s, err := session.New(userID, sessionID, session.Metadata{UserAgent: "curl/8.0"}, ttl)
+	assert.NoError(t, err)
+	s, err = repo.CreateSession(s, db)
+	assert.NoError(t, err)
+	return s
+}
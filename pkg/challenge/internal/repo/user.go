@@ -1,13 +1,17 @@
 package repo
 
 import (
+	"database/sql"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
 
 	"gorm.io/gorm/clause"
 )
@@ -16,21 +20,29 @@ var (
 	// ErrMissingDB used when DB is nil
 	ErrMissingDB = errors.New("DB connection is missing")
 	// ErrIDShouldNotBeEmpty used when entity ID is empty
-	ErrIDShouldNotBeEmpty = errors.New("entity ID should not be empty")
+	ErrIDShouldNotBeEmpty = domainerr.Validation("id", errors.New("entity ID should not be empty"))
 	// ErrIDnotValid used when entity ID is not valid
 	ErrIDnotValid = errors.New("entity ID not valid")
 	// ErrRecordNotFound used when record is not found
-	ErrRecordNotFound = errors.New("record not found")
+	ErrRecordNotFound = domainerr.NotFound("record not found")
+	// ErrEmptyNickname used when updating an user with an empty nickname
+	ErrEmptyNickname = domainerr.Validation("", errors.New("nickname cannot be empty"))
 	// ErrHashingPassword used when there was an error hashing the password
 	ErrHashingPassword = errors.New("error hashing password")
+	// ErrEmptySearchQuery used when searching users without a query
+	ErrEmptySearchQuery = domainerr.Validation("q", errors.New("search query cannot be empty"))
 )
 
+// MaxFindLimit is the max number of users returned per page
+const MaxFindLimit = 100
+
 // Create creates a new user in the DB
 func Create(u *user.Entity, tx *gorm.DB) (*user.Entity, error) {
 	if tx == nil {
 		return nil, ErrMissingDB
 	}
 	u.ID = uuid.MustParse(uuid.NewString())
+	u.Version = 1
 
 	err := u.HashPassword(u.Password)
 	if err != nil {
@@ -38,34 +50,149 @@ func Create(u *user.Entity, tx *gorm.DB) (*user.Entity, error) {
 	}
 
 	if res := tx.Create(&u); res.Error != nil {
-		return nil, res.Error
+		return nil, translateError(res.Error)
 	}
 	return u, nil
 }
 
-// Find returns a list of users. It can be paginated and filtered by user country
-func Find(tx *gorm.DB, country string, page, limit int) ([]user.Entity, error) {
+// Find returns a page of users matching the filter, in the order it asks for, and the cursor
+// of the next page, empty on the last one. Pages are read after the filter cursor when it is
+// set, otherwise with page and limit
+func Find(tx *gorm.DB, filter model.UserFilter) ([]user.Entity, string, error) {
 	var users []user.Entity
-	query := tx.Model(&user.Entity{})
+	if tx == nil {
+		return nil, "", ErrMissingDB
+	}
+
+	sort, err := userSortFor(filter)
+	if err != nil {
+		return nil, "", err
+	}
 
+	limit := filter.Limit
+	switch {
+	case limit <= 0:
+		limit = 10
+	case limit > MaxFindLimit:
+		limit = MaxFindLimit
+	}
+
+	query := filterUsers(tx.Model(&user.Entity{}), filter)
+	if filter.Cursor != "" {
+		after, err := decodeUserCursor(filter.Cursor, sort)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.Where(sort.after(), after.value(sort), after.ID)
+	} else if filter.Page > 1 {
+		query = query.Offset((filter.Page - 1) * limit)
+	}
+
+	// One more user is read to know whether there is a next page
+	if err := query.Order(sort.orderBy()).Limit(limit + 1).Find(&users).Error; err != nil {
+		return nil, "", err
+	}
+	if len(users) <= limit {
+		return users, "", nil
+	}
+
+	users = users[:limit]
+	next, err := encodeUserCursor(users[limit-1], sort)
+	if err != nil {
+		return nil, "", err
+	}
+	return users, next, nil
+}
+
+// Count returns how many users match the filter, ignoring its sort and pagination
+func Count(tx *gorm.DB, filter model.UserFilter) (int64, error) {
+	var total int64
 	if tx == nil {
-		return nil, ErrMissingDB
+		return 0, ErrMissingDB
 	}
 
-	if country != "" {
-		query = query.Where("country = ?", country)
+	if err := filterUsers(tx.Model(&user.Entity{}), filter).Count(&total).Error; err != nil {
+		return 0, err
 	}
+	return total, nil
+}
 
-	// Default limit if none provided
+// Search returns the not deleted users whose nickname or full name are similar to the query,
+// using pg_trgm trigram similarity, best matches first
+func Search(tx *gorm.DB, query string, limit int) ([]user.Match, error) {
+	var matches []user.Match
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, ErrEmptySearchQuery
+	}
 	if limit <= 0 {
 		limit = 10
 	}
-	offset := (page - 1) * limit
+	if limit > MaxFindLimit {
+		limit = MaxFindLimit
+	}
 
-	if err := query.Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+	if err := tx.Model(&user.Entity{}).
+		Select("*, GREATEST(similarity(nickname, @q), similarity(first_name || ' ' || last_name, @q)) AS score", sql.Named("q", query)).
+		Where("nickname % @q OR (first_name || ' ' || last_name) % @q", sql.Named("q", query)).
+		Order("score DESC, id ASC").
+		Limit(limit).
+		Find(&matches).Error; err != nil {
 		return nil, err
 	}
-	return users, nil
+	return matches, nil
+}
+
+func filterUsers(query *gorm.DB, filter model.UserFilter) *gorm.DB {
+	if len(filter.Countries) > 0 {
+		query = query.Where("country IN ?", filter.Countries)
+	}
+	if filter.NicknamePrefix != "" {
+		query = query.Where(`lower(nickname) LIKE ? ESCAPE '\'`, likePrefix(filter.NicknamePrefix))
+	}
+	if filter.EmailPrefix != "" {
+		query = query.Where(`lower(email) LIKE ? ESCAPE '\'`, likePrefix(filter.EmailPrefix))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at <= ?", filter.CreatedTo)
+	}
+	return query
+}
+
+// likePrefix returns a LIKE pattern matching the lowercased prefix, with its wildcards escaped
+func likePrefix(prefix string) string {
+	return likeEscaper.Replace(strings.ToLower(prefix)) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// TakenNicknames returns which of the given nicknames are used by not deleted users,
+// normalized with user.NormalizeNickname. Nicknames are compared ignoring case
+func TakenNicknames(nicknames []string, tx *gorm.DB) ([]string, error) {
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if len(nicknames) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(nicknames))
+	for _, n := range nicknames {
+		normalized = append(normalized, user.NormalizeNickname(n))
+	}
+
+	var taken []string
+	if err := tx.Model(&user.Entity{}).
+		Where("lower(nickname) IN ?", normalized).
+		Pluck("lower(nickname)", &taken).Error; err != nil {
+		return nil, err
+	}
+	return taken, nil
 }
 
 // GetUserForUpdate returns an user and will lock the row in order to update it
@@ -84,12 +211,92 @@ func GetUserForUpdate(id uuid.UUID, tx *gorm.DB) (*user.Entity, error) {
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
 			return nil, ErrRecordNotFound
 		}
+		return nil, res.Error
 	}
 
 	return &u, nil
 }
 
-// Update updates only the nickname of an existing user
+// Get returns a not deleted user by ID
+func Get(id uuid.UUID, tx *gorm.DB) (*user.Entity, error) {
+	var u user.Entity
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	if err := tx.Where("id = ?", id).First(&u).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetByLogin returns the user whose email matches login or, when none does, the one
+// whose nickname does, ignoring case. Emails go first so nobody can pick the email of
+// another user as nickname to be found instead of them
+func GetByLogin(login string, tx *gorm.DB) (*user.Entity, error) {
+	var u user.Entity
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+
+	err := tx.Where("lower(email) = lower(?)", login).First(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = tx.Where("lower(nickname) = lower(?)", login).First(&u).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetUnscoped returns an user by ID, including soft deleted ones
+func GetUnscoped(id uuid.UUID, tx *gorm.DB) (*user.Entity, error) {
+	var u user.Entity
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	if err := tx.Unscoped().Where("id = ?", id).First(&u).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// FindIDs returns up to limit user IDs greater than after, including soft deleted users.
+// It is meant to walk the whole table in batches
+func FindIDs(tx *gorm.DB, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+
+	if err := tx.Unscoped().Model(&user.Entity{}).
+		Where("id > ?", after).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Update updates the profile fields of an existing user: names, nickname, email and country,
+// and stores its version. Callers lock the row and increment the version
 func Update(u *user.Entity, tx *gorm.DB) (*user.Entity, error) {
 	if tx == nil {
 		return nil, ErrMissingDB
@@ -98,22 +305,32 @@ func Update(u *user.Entity, tx *gorm.DB) (*user.Entity, error) {
 		return nil, ErrIDShouldNotBeEmpty
 	}
 	if strings.TrimSpace(u.Nickname) == "" {
-		return nil, errors.New("nickname cannot be empty")
+		return nil, ErrEmptyNickname
 	}
 
-	if err := tx.Model(&user.Entity{}).
+	res := tx.Model(&user.Entity{}).
 		Where("id = ?", u.ID).
-		Select("nickname").
+		Select("first_name", "last_name", "nickname", "email", "country", "version").
 		Updates(&user.Entity{
-			Nickname: u.Nickname,
-		}).Error; err != nil {
-		return nil, err
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Nickname:  u.Nickname,
+			Email:     u.Email,
+			Country:   u.Country,
+			Version:   u.Version,
+		})
+	if res.Error != nil {
+		return nil, translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrRecordNotFound
 	}
 
 	return u, nil
 }
 
-// Delete soft deletes a user by ID
+// Delete soft deletes a user by ID and increments its version. Missing and already
+// deleted users are not found
 func Delete(id uuid.UUID, tx *gorm.DB) error {
 	if tx == nil {
 		return ErrMissingDB
@@ -122,8 +339,17 @@ func Delete(id uuid.UUID, tx *gorm.DB) error {
 		return ErrIDShouldNotBeEmpty
 	}
 
-	if err := tx.Delete(&user.Entity{}, "id = ?", id).Error; err != nil {
-		return err
+	res := tx.Model(&user.Entity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deleted_at": time.Now(),
+			"version":    gorm.Expr("version + 1"),
+		})
+	if res.Error != nil {
+		return translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
 	}
 	return nil
 }
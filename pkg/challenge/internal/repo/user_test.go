@@ -1,6 +1,7 @@
 package repo_test
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -10,7 +11,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
 )
 
@@ -51,9 +54,74 @@ func TestRepository_Create(t *testing.T) {
 		})
 	})
 
-	t.Run("it should fail if the user already exists", func(t *testing.T) {
+	t.Run("it should fail with a conflict on the email if the user already exists", func(t *testing.T) {
 		_, err = repo.Create(&validUser, db)
-		assert.NotNil(t, err)
+		assert.ErrorIs(t, err, domainerr.ErrConflict)
+
+		e, ok := domainerr.As(err)
+		assert.True(t, ok)
+		assert.Equal(t, "email", e.Field)
+	})
+
+	t.Run("it should fail with a conflict on an email differing only in case", func(t *testing.T) {
+		other := validUser
+		other.Nickname = "other" + validUser.Nickname
+		other.Email = strings.ToUpper(validUser.Email)
+		_, err = repo.Create(&other, db)
+		assert.ErrorIs(t, err, domainerr.ErrConflict)
+
+		e, ok := domainerr.As(err)
+		assert.True(t, ok)
+		assert.Equal(t, "email", e.Field)
+	})
+}
+
+func TestRepository_Create_TakenNickname(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+	insertTestUsers(t, db)
+
+	_, err = repo.Create(&user.Entity{
+		FirstName: "Other",
+		LastName:  "Juan",
+		Nickname:  "JUAN",
+		Password:  password,
+		Email:     "otherjuan@gmail.com",
+		Country:   "UK",
+	}, db)
+	assert.ErrorIs(t, err, domainerr.ErrConflict)
+
+	e, ok := domainerr.As(err)
+	assert.True(t, ok)
+	assert.Equal(t, "nickname", e.Field)
+}
+
+func TestRepository_TakenNicknames(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+	insertTestUsers(t, db)
+
+	t.Run("should return the taken nicknames ignoring case", func(t *testing.T) {
+		taken, err := repo.TakenNicknames([]string{"JUAN", "nachocalcagno", "ropz"}, db)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"juan", "nachocalcagno"}, taken)
+	})
+
+	t.Run("should release the nicknames of deleted users", func(t *testing.T) {
+		u, err := repo.GetByLogin("Juan", db)
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Delete(u.ID, db))
+
+		taken, err := repo.TakenNicknames([]string{"juan"}, db)
+		assert.NoError(t, err)
+		assert.Empty(t, taken)
+	})
+
+	t.Run("should return error if tx is nil", func(t *testing.T) {
+		_, err := repo.TakenNicknames([]string{"juan"}, nil)
+		assert.Equal(t, repo.ErrMissingDB, err)
 	})
 }
 
@@ -66,28 +134,162 @@ func TestRepository_Find(t *testing.T) {
 	insertTestUsers(t, db)
 
 	t.Run("should return paginated users filtered by country", func(t *testing.T) {
-		users, err := repo.Find(db, "ES", 1, 0)
+		users, next, err := repo.Find(db, model.UserFilter{Countries: []string{"ES"}, Page: 1})
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
 		assert.Equal(t, "ES", users[0].Country)
 		assert.Equal(t, "ES", users[1].Country)
+		assert.Empty(t, next)
 	})
 
 	t.Run("should return all users when no country is specified", func(t *testing.T) {
-		users, err := repo.Find(db, "", 1, 0)
+		users, _, err := repo.Find(db, model.UserFilter{Page: 1})
 		assert.NoError(t, err)
 		assert.Len(t, users, 3)
 	})
 
 	t.Run("should return paginated results correctly", func(t *testing.T) {
-		usersPage1, err := repo.Find(db, "", 1, 2)
+		usersPage1, next, err := repo.Find(db, model.UserFilter{Page: 1, Limit: 2})
 		assert.NoError(t, err)
 		assert.Len(t, usersPage1, 2)
+		assert.NotEmpty(t, next)
 
-		usersPage2, err := repo.Find(db, "", 2, 2)
+		usersPage2, _, err := repo.Find(db, model.UserFilter{Page: 2, Limit: 2})
 		assert.NoError(t, err)
 		assert.Len(t, usersPage2, 1)
 	})
+
+	t.Run("should return the next page after the cursor", func(t *testing.T) {
+		all, _, err := repo.Find(db, model.UserFilter{})
+		assert.NoError(t, err)
+
+		first, next, err := repo.Find(db, model.UserFilter{Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, all[:2], first)
+
+		second, last, err := repo.Find(db, model.UserFilter{Limit: 2, Cursor: next})
+		assert.NoError(t, err)
+		assert.Equal(t, all[2:], second)
+		assert.Empty(t, last)
+	})
+
+	t.Run("should fail with an invalid cursor", func(t *testing.T) {
+		_, _, err := repo.Find(db, model.UserFilter{Cursor: "not-a-cursor"})
+		assert.ErrorIs(t, err, repo.ErrInvalidCursor)
+	})
+
+	t.Run("should filter by several countries", func(t *testing.T) {
+		users, _, err := repo.Find(db, model.UserFilter{Countries: []string{"ES", "IT"}})
+		assert.NoError(t, err)
+		assert.Len(t, users, 3)
+	})
+
+	t.Run("should filter by case insensitive nickname and email prefixes", func(t *testing.T) {
+		users, _, err := repo.Find(db, model.UserFilter{NicknamePrefix: "NACHO"})
+		assert.NoError(t, err)
+		assert.Len(t, users, 2)
+
+		users, _, err = repo.Find(db, model.UserFilter{NicknamePrefix: "nacho", EmailPrefix: "nachoc@"})
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Equal(t, "NachoCalcagno", users[0].Nickname)
+	})
+
+	t.Run("should not treat wildcards in prefixes as patterns", func(t *testing.T) {
+		users, _, err := repo.Find(db, model.UserFilter{NicknamePrefix: "%"})
+		assert.NoError(t, err)
+		assert.Empty(t, users)
+
+		users, _, err = repo.Find(db, model.UserFilter{EmailPrefix: "nacho_"})
+		assert.NoError(t, err)
+		assert.Empty(t, users)
+	})
+
+	t.Run("should filter by creation time range", func(t *testing.T) {
+		all, _, err := repo.Find(db, model.UserFilter{})
+		assert.NoError(t, err)
+
+		users, _, err := repo.Find(db, model.UserFilter{CreatedFrom: all[1].CreatedAt, CreatedTo: all[1].CreatedAt})
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Equal(t, all[1].ID, users[0].ID)
+	})
+
+	t.Run("should sort by nickname descending across pages", func(t *testing.T) {
+		filter := model.UserFilter{SortBy: model.UserSortNickname, Descending: true, Limit: 2}
+		first, next, err := repo.Find(db, filter)
+		assert.NoError(t, err)
+		assert.Len(t, first, 2)
+		assert.NotContains(t, []string{first[0].Nickname, first[1].Nickname}, "Juan")
+
+		filter.Cursor = next
+		second, _, err := repo.Find(db, filter)
+		assert.NoError(t, err)
+		assert.Len(t, second, 1)
+		assert.Equal(t, "Juan", second[0].Nickname)
+
+		t.Run("and reject the cursor with another sort", func(t *testing.T) {
+			_, _, err := repo.Find(db, model.UserFilter{Cursor: next})
+			assert.ErrorIs(t, err, repo.ErrInvalidCursor)
+		})
+	})
+
+	t.Run("should reject sorts that are not allowed", func(t *testing.T) {
+		_, _, err := repo.Find(db, model.UserFilter{SortBy: "password; DROP TABLE challenge.user"})
+		assert.ErrorIs(t, err, repo.ErrInvalidSort)
+	})
+
+	t.Run("should count the users matching the filter", func(t *testing.T) {
+		total, err := repo.Count(db, model.UserFilter{Countries: []string{"ES"}, Page: 2, Limit: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+	})
+}
+
+func TestRepository_Search(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+	insertTestUsers(t, db)
+
+	t.Run("should find misspelled nicknames, best match first", func(t *testing.T) {
+		matches, err := repo.Search(db, "NachoCalcano", 10)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, matches) {
+			assert.Equal(t, "NachoCalcagno", matches[0].Nickname)
+			assert.Greater(t, matches[0].Score, 0.3)
+		}
+		for i := 1; i < len(matches); i++ {
+			assert.GreaterOrEqual(t, matches[i-1].Score, matches[i].Score)
+		}
+	})
+
+	t.Run("should find users by full name", func(t *testing.T) {
+		matches, err := repo.Search(db, "juan calcagn", 10)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, matches) {
+			assert.Equal(t, "Juan", matches[0].Nickname)
+		}
+	})
+
+	t.Run("should not find soft deleted users", func(t *testing.T) {
+		matches, err := repo.Search(db, "Juan Calcagno", 10)
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Delete(matches[0].ID, db))
+
+		matches, err = repo.Search(db, "Juan Calcagno", 10)
+		assert.NoError(t, err)
+		for _, m := range matches {
+			assert.NotEqual(t, "Juan", m.Nickname)
+		}
+	})
+
+	t.Run("should fail without a query", func(t *testing.T) {
+		_, err := repo.Search(db, " ", 10)
+		assert.ErrorIs(t, err, repo.ErrEmptySearchQuery)
+	})
 }
 
 func TestRepository_GetUserForUpdate(t *testing.T) {
@@ -159,7 +361,7 @@ func TestRepository_Update(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, "letsplaycsgo", updated.Nickname)
 
-		fromDB, err := repo.Find(db, "", 1, 10)
+		fromDB, _, err := repo.Find(db, model.UserFilter{Page: 1, Limit: 10})
 		assert.NoError(t, err)
 		assert.Equal(t, "letsplaycsgo", fromDB[0].Nickname)
 	})
@@ -190,6 +392,16 @@ func TestRepository_Update(t *testing.T) {
 		assert.Equal(t, repo.ErrIDShouldNotBeEmpty, err)
 	})
 
+	t.Run("should return not found if user does not exist", func(t *testing.T) {
+		user := &user.Entity{
+			ID:       uuid.New(),
+			Nickname: "ghost",
+		}
+		updated, err := repo.Update(user, db)
+		assert.Nil(t, updated)
+		assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+	})
+
 	t.Run("should return error if nickname is empty", func(t *testing.T) {
 		user := &user.Entity{
 			ID:       uuid.New(),
@@ -224,7 +436,7 @@ func TestRepository_Delete(t *testing.T) {
 		err = repo.Delete(created.ID, db)
 		assert.NoError(t, err)
 
-		users, err := repo.Find(db, "", 1, 10)
+		users, _, err := repo.Find(db, model.UserFilter{Page: 1, Limit: 10})
 		assert.NoError(t, err)
 
 		found := false
@@ -245,6 +457,106 @@ func TestRepository_Delete(t *testing.T) {
 		err := repo.Delete(uuid.Nil, db)
 		assert.Equal(t, repo.ErrIDShouldNotBeEmpty, err)
 	})
+
+	t.Run("should return not found for missing or already deleted users", func(t *testing.T) {
+		err := repo.Delete(uuid.New(), db)
+		assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+
+		created, err := repo.Create(&user.Entity{
+			FirstName: "Twice",
+			LastName:  "Delete",
+			Nickname:  "twice",
+			Password:  "securepass",
+			Email:     "twice@delete.com",
+			Country:   "CL",
+		}, db)
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Delete(created.ID, db))
+		assert.ErrorIs(t, repo.Delete(created.ID, db), repo.ErrRecordNotFound)
+	})
+}
+
+func TestRepository_GetByLogin(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	insertTestUsers(t, db)
+
+	t.Run("should find by email ignoring case", func(t *testing.T) {
+		u, err := repo.GetByLogin("NACHO1@gmail.com", db)
+		assert.NoError(t, err)
+		assert.Equal(t, "nacho1", u.Nickname)
+	})
+
+	t.Run("should find by nickname", func(t *testing.T) {
+		u, err := repo.GetByLogin("Juan", db)
+		assert.NoError(t, err)
+		assert.Equal(t, "jcalcagno@nacho.com", u.Email)
+	})
+
+	t.Run("should find by nickname ignoring case", func(t *testing.T) {
+		u, err := repo.GetByLogin("nachocalcagno", db)
+		assert.NoError(t, err)
+		assert.Equal(t, "nachoc@gmail.com", u.Email)
+	})
+
+	t.Run("should prefer the email match over a nickname match", func(t *testing.T) {
+		_, err := repo.Create(&user.Entity{
+			FirstName: "Impostor",
+			LastName:  "Calcagno",
+			Nickname:  "NACHOC@gmail.com",
+			Password:  password,
+			Email:     "impostor@gmail.com",
+			Country:   "UK",
+		}, db)
+		assert.NoError(t, err)
+
+		u, err := repo.GetByLogin("nachoc@gmail.com", db)
+		assert.NoError(t, err)
+		assert.Equal(t, "NachoCalcagno", u.Nickname)
+	})
+
+	t.Run("should not find deleted users", func(t *testing.T) {
+		u, err := repo.GetByLogin("Juan", db)
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Delete(u.ID, db))
+
+		_, err = repo.GetByLogin("Juan", db)
+		assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+	})
+}
+
+func TestRepository_Get(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	insertTestUsers(t, db)
+	existing, err := repo.GetByLogin("nacho1", db)
+	assert.NoError(t, err)
+
+	t.Run("should get an user by ID", func(t *testing.T) {
+		u, err := repo.Get(existing.ID, db)
+		assert.NoError(t, err)
+		assert.Equal(t, "nacho1", u.Nickname)
+	})
+
+	t.Run("should not find unknown users", func(t *testing.T) {
+		_, err := repo.Get(uuid.New(), db)
+		assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+	})
+
+	t.Run("should only find deleted users unscoped", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(existing.ID, db))
+
+		_, err := repo.Get(existing.ID, db)
+		assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+
+		u, err := repo.GetUnscoped(existing.ID, db)
+		assert.NoError(t, err)
+		assert.True(t, u.DeletedAt.Valid)
+	})
 }
 
 func insertTestUsers(t *testing.T, db *gorm.DB) {
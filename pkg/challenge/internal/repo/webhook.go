@@ -0,0 +1,278 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+)
+
+// CreateWebhook creates a new webhook subscription in the DB
+func CreateWebhook(w *webhook.Entity, tx *gorm.DB) (*webhook.Entity, error) {
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	w.ID = uuid.New()
+	w.Active = true
+
+	if err := tx.Create(w).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return w, nil
+}
+
+// GetWebhook returns a webhook by ID
+func GetWebhook(id uuid.UUID, tx *gorm.DB) (*webhook.Entity, error) {
+	var w webhook.Entity
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	if err := tx.Where("id = ?", id).First(&w).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+// GetWebhookForUpdate returns a webhook and will lock the row in order to update it
+func GetWebhookForUpdate(id uuid.UUID, tx *gorm.DB) (*webhook.Entity, error) {
+	var w webhook.Entity
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	if err := tx.Where("id = ?", id).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&w).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+// FindWebhooks returns a paginated list of webhooks
+func FindWebhooks(tx *gorm.DB, page, limit int) ([]webhook.Entity, error) {
+	var webhooks []webhook.Entity
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	if err := tx.Order("created_at ASC, id ASC").Limit(limit).Offset(offset).Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// FindActiveWebhooksForEvent returns the enabled webhooks subscribed to the given event type
+func FindActiveWebhooksForEvent(tx *gorm.DB, eventType string) ([]webhook.Entity, error) {
+	var webhooks []webhook.Entity
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+
+	contains, err := json.Marshal([]string{eventType})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("active = ?", true).
+		Where("event_types @> ?", string(contains)).
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook updates the url, event types and active flag of an existing webhook.
+// Enabling a webhook again resets its failure counter
+func UpdateWebhook(w *webhook.Entity, tx *gorm.DB) (*webhook.Entity, error) {
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if w == nil || w.ID == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	if w.Active {
+		w.ConsecutiveFailures = 0
+		w.DisabledAt = nil
+	}
+
+	if err := tx.Model(&webhook.Entity{}).
+		Where("id = ?", w.ID).
+		Select("url", "event_types", "active", "consecutive_failures", "disabled_at").
+		Updates(w).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return w, nil
+}
+
+// DeleteWebhook soft deletes a webhook by ID
+func DeleteWebhook(id uuid.UUID, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return ErrIDShouldNotBeEmpty
+	}
+
+	res := tx.Delete(&webhook.Entity{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateWebhookDelivery records a delivery attempt
+func CreateWebhookDelivery(d *webhook.Delivery, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+	d.ID = uuid.New()
+	return tx.Create(d).Error
+}
+
+// FindDueWebhookDeliveries returns the attempts not sent yet that are due, locking them
+// so concurrent workers don't send the same attempt
+func FindDueWebhookDeliveries(tx *gorm.DB, now time.Time, limit int) ([]webhook.Delivery, error) {
+	var deliveries []webhook.Delivery
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+
+	if err := tx.Where("next_attempt_at <= ?", now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimWebhookDelivery pushes an attempt that is due to the given time while it is
+// sent, so other workers skip it and it is only sent again if the sender dies.
+// It reports whether the attempt was still due
+func ClaimWebhookDelivery(id uuid.UUID, now, until time.Time, tx *gorm.DB) (bool, error) {
+	if tx == nil {
+		return false, ErrMissingDB
+	}
+	if id == uuid.Nil {
+		return false, ErrIDShouldNotBeEmpty
+	}
+
+	res := tx.Model(&webhook.Delivery{}).
+		Where("id = ? AND next_attempt_at <= ?", id, now).
+		Update("next_attempt_at", until)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// CompleteWebhookDelivery records the outcome of an attempt, which will not be sent again
+func CompleteWebhookDelivery(d *webhook.Delivery, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+	if d == nil || d.ID == uuid.Nil {
+		return ErrIDShouldNotBeEmpty
+	}
+
+	d.NextAttemptAt = nil
+	return tx.Model(&webhook.Delivery{}).
+		Where("id = ?", d.ID).
+		Select("success", "status_code", "error", "duration_ms", "next_attempt_at").
+		Updates(d).Error
+}
+
+// FindWebhookDeliveries returns the latest delivery attempts sent to a webhook
+func FindWebhookDeliveries(tx *gorm.DB, webhookID uuid.UUID, page, limit int) ([]webhook.Delivery, error) {
+	var deliveries []webhook.Delivery
+	if tx == nil {
+		return nil, ErrMissingDB
+	}
+	if webhookID == uuid.Nil {
+		return nil, ErrIDShouldNotBeEmpty
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	if err := tx.Where("webhook_id = ? AND next_attempt_at IS NULL", webhookID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// MarkWebhookSucceeded resets the failure counter of a webhook
+func MarkWebhookSucceeded(id uuid.UUID, tx *gorm.DB) error {
+	if tx == nil {
+		return ErrMissingDB
+	}
+
+	return tx.Model(&webhook.Entity{}).
+		Where("id = ? AND consecutive_failures > 0", id).
+		Update("consecutive_failures", 0).Error
+}
+
+// MarkWebhookFailed increases the failure counter of a webhook and disables it once
+// it reaches disableAfter. It reports whether the webhook is disabled
+func MarkWebhookFailed(id uuid.UUID, disableAfter int, tx *gorm.DB) (bool, error) {
+	if tx == nil {
+		return false, ErrMissingDB
+	}
+
+	var w webhook.Entity
+	res := tx.Model(&w).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "active"}}}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+			"active":               gorm.Expr("active AND consecutive_failures + 1 < ?", disableAfter),
+			"disabled_at": gorm.Expr(
+				"CASE WHEN active AND consecutive_failures + 1 >= ? THEN ? ELSE disabled_at END",
+				disableAfter, time.Now(),
+			),
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0 && !w.Active, nil
+}
@@ -0,0 +1,271 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+)
+
+func TestRepository_FindDueWebhookDeliveries(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	hook := createTestWebhook(t, db)
+	now := time.Now()
+	older := createTestDelivery(t, db, hook.ID, now.Add(-2*time.Minute))
+	due := createTestDelivery(t, db, hook.ID, now.Add(-time.Minute))
+	createTestDelivery(t, db, hook.ID, now.Add(time.Minute))
+	sent := createTestDelivery(t, db, hook.ID, now.Add(-time.Minute))
+	assert.NoError(t, repo.CompleteWebhookDelivery(sent, db))
+
+	t.Run("should return the due attempts not sent yet, oldest first", func(t *testing.T) {
+		deliveries, err := repo.FindDueWebhookDeliveries(db, now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{older.ID, due.ID}, deliveryIDs(deliveries))
+	})
+
+	t.Run("should return at most limit attempts", func(t *testing.T) {
+		deliveries, err := repo.FindDueWebhookDeliveries(db, now, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{older.ID}, deliveryIDs(deliveries))
+	})
+
+	t.Run("should fail if DB is nil", func(t *testing.T) {
+		_, err := repo.FindDueWebhookDeliveries(nil, now, 10)
+		assert.Equal(t, repo.ErrMissingDB, err)
+	})
+}
+
+func TestRepository_FindDueWebhookDeliveries_SkipLocked(t *testing.T) {
+	// Two transactions act as two workers, so the rows have to be committed
+	db, teardown, err := helpers.NewTestConn()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	hook := createTestWebhook(t, db)
+	// Far in the past so they are the first due attempts of the table
+	first := createTestDelivery(t, db, hook.ID, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	second := createTestDelivery(t, db, hook.ID, time.Date(2000, 1, 1, 0, 0, 1, 0, time.UTC))
+	// Deliveries are deleted in cascade
+	defer db.Unscoped().Delete(&webhook.Entity{}, "id = ?", hook.ID)
+
+	tx1 := db.Begin()
+	defer tx1.Rollback()
+	tx2 := db.Begin()
+	defer tx2.Rollback()
+
+	locked, err := repo.FindDueWebhookDeliveries(tx1, time.Now(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{first.ID}, deliveryIDs(locked))
+
+	t.Run("should skip the attempts locked by another transaction", func(t *testing.T) {
+		deliveries, err := repo.FindDueWebhookDeliveries(tx2, time.Now(), 2)
+		assert.NoError(t, err)
+		assert.NotContains(t, deliveryIDs(deliveries), first.ID)
+		assert.Contains(t, deliveryIDs(deliveries), second.ID)
+	})
+}
+
+func TestRepository_ClaimWebhookDelivery(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	hook := createTestWebhook(t, db)
+	now := time.Now()
+	d := createTestDelivery(t, db, hook.ID, now.Add(-time.Second))
+
+	t.Run("should claim a due attempt until the given time", func(t *testing.T) {
+		claimed, err := repo.ClaimWebhookDelivery(d.ID, now, now.Add(time.Minute), db)
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+
+		deliveries, err := repo.FindDueWebhookDeliveries(db, now, 10)
+		assert.NoError(t, err)
+		assert.NotContains(t, deliveryIDs(deliveries), d.ID)
+	})
+
+	t.Run("should not claim an attempt claimed already", func(t *testing.T) {
+		claimed, err := repo.ClaimWebhookDelivery(d.ID, now, now.Add(time.Minute), db)
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+	})
+
+	t.Run("should not claim an attempt already sent", func(t *testing.T) {
+		sent := createTestDelivery(t, db, hook.ID, now.Add(-time.Second))
+		assert.NoError(t, repo.CompleteWebhookDelivery(sent, db))
+
+		claimed, err := repo.ClaimWebhookDelivery(sent.ID, now, now.Add(time.Minute), db)
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+	})
+
+	t.Run("should fail if the ID is empty", func(t *testing.T) {
+		_, err := repo.ClaimWebhookDelivery(uuid.Nil, now, now, db)
+		assert.Equal(t, repo.ErrIDShouldNotBeEmpty, err)
+	})
+}
+
+func TestRepository_CompleteWebhookDelivery(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	hook := createTestWebhook(t, db)
+	d := createTestDelivery(t, db, hook.ID, time.Now())
+
+	t.Run("should not list attempts not sent yet", func(t *testing.T) {
+		deliveries, err := repo.FindWebhookDeliveries(db, hook.ID, 1, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, deliveries)
+	})
+
+	t.Run("should record the outcome and unschedule the attempt", func(t *testing.T) {
+		d.StatusCode = 502
+		d.Error = "unexpected status code 502"
+		d.DurationMS = 120
+		assert.NoError(t, repo.CompleteWebhookDelivery(d, db))
+
+		deliveries, err := repo.FindWebhookDeliveries(db, hook.ID, 1, 10)
+		assert.NoError(t, err)
+		if assert.Len(t, deliveries, 1) {
+			assert.Equal(t, d.ID, deliveries[0].ID)
+			assert.False(t, deliveries[0].Success)
+			assert.Equal(t, 502, deliveries[0].StatusCode)
+			assert.Equal(t, "unexpected status code 502", deliveries[0].Error)
+			assert.Equal(t, int64(120), deliveries[0].DurationMS)
+			assert.Nil(t, deliveries[0].NextAttemptAt)
+		}
+	})
+
+	t.Run("should fail if the ID is empty", func(t *testing.T) {
+		assert.Equal(t, repo.ErrIDShouldNotBeEmpty, repo.CompleteWebhookDelivery(&webhook.Delivery{}, db))
+	})
+}
+
+func TestRepository_MarkWebhookFailed(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	if err != nil {
+		assert.Nil(t, err)
+	}
+	defer teardown()
+
+	hook := createTestWebhook(t, db)
+
+	t.Run("should count the failures below the threshold", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			disabled, err := repo.MarkWebhookFailed(hook.ID, 3, db)
+			assert.NoError(t, err)
+			assert.False(t, disabled)
+		}
+
+		w, err := repo.GetWebhook(hook.ID, db)
+		assert.NoError(t, err)
+		assert.True(t, w.Active)
+		assert.Equal(t, 2, w.ConsecutiveFailures)
+		assert.Nil(t, w.DisabledAt)
+	})
+
+	t.Run("should reset the counter on success", func(t *testing.T) {
+		assert.NoError(t, repo.MarkWebhookSucceeded(hook.ID, db))
+
+		w, err := repo.GetWebhook(hook.ID, db)
+		assert.NoError(t, err)
+		assert.True(t, w.Active)
+		assert.Equal(t, 0, w.ConsecutiveFailures)
+	})
+
+	var disabledAt time.Time
+	t.Run("should disable the webhook once it reaches the threshold", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			disabled, err := repo.MarkWebhookFailed(hook.ID, 3, db)
+			assert.NoError(t, err)
+			assert.False(t, disabled)
+		}
+		disabled, err := repo.MarkWebhookFailed(hook.ID, 3, db)
+		assert.NoError(t, err)
+		assert.True(t, disabled)
+
+		w, err := repo.GetWebhook(hook.ID, db)
+		assert.NoError(t, err)
+		assert.False(t, w.Active)
+		assert.Equal(t, 3, w.ConsecutiveFailures)
+		if assert.NotNil(t, w.DisabledAt) {
+			disabledAt = *w.DisabledAt
+		}
+	})
+
+	t.Run("should keep when it was disabled on later failures", func(t *testing.T) {
+		disabled, err := repo.MarkWebhookFailed(hook.ID, 3, db)
+		assert.NoError(t, err)
+		assert.True(t, disabled)
+
+		w, err := repo.GetWebhook(hook.ID, db)
+		assert.NoError(t, err)
+		assert.False(t, w.Active)
+		assert.Equal(t, 4, w.ConsecutiveFailures)
+		if assert.NotNil(t, w.DisabledAt) {
+			assert.True(t, disabledAt.Equal(*w.DisabledAt))
+		}
+	})
+
+	t.Run("should not report unknown webhooks as disabled", func(t *testing.T) {
+		disabled, err := repo.MarkWebhookFailed(uuid.New(), 3, db)
+		assert.NoError(t, err)
+		assert.False(t, disabled)
+	})
+
+	t.Run("should fail if DB is nil", func(t *testing.T) {
+		_, err := repo.MarkWebhookFailed(hook.ID, 3, nil)
+		assert.Equal(t, repo.ErrMissingDB, err)
+	})
+}
+
+func createTestWebhook(t *testing.T, db *gorm.DB) *webhook.Entity {
+	w, err := repo.CreateWebhook(&webhook.Entity{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{event.UserCreated},
+		Secret:     "0123456789abcdef",
+	}, db)
+	assert.NoError(t, err)
+	return w
+}
+
+// createTestDelivery stores an attempt not sent yet, due at the given time
+func createTestDelivery(t *testing.T, db *gorm.DB, webhookID uuid.UUID, due time.Time) *webhook.Delivery {
+	d := &webhook.Delivery{
+		WebhookID:     webhookID,
+		EventID:       uuid.New(),
+		EventType:     event.UserCreated,
+		Attempt:       1,
+		Payload:       []byte(`{}`),
+		NextAttemptAt: &due,
+	}
+	assert.NoError(t, repo.CreateWebhookDelivery(d, db))
+	return d
+}
+
+func deliveryIDs(deliveries []webhook.Delivery) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(deliveries))
+	for _, d := range deliveries {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	userAgg "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+// tokenType is the type of the issued access tokens
+const tokenType = "Bearer"
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+type service struct {
+	userAggregate userAgg.Aggregate
+	tokens        *auth.TokenIssuer
+}
+
+type Service interface {
+	Login(ctx context.Context, input *model.LoginInput) (*model.TokenOutput, error)
+	Token(ctx context.Context, input *model.ClientCredentialsInput) (*model.TokenOutput, error)
+	Refresh(ctx context.Context, input *model.RefreshInput) (*model.TokenOutput, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]model.SessionOutput, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeSessions(ctx context.Context, userID uuid.UUID) (int, error)
+	JWKS() auth.JWKS
+}
+
+// New returns a new Auth service
+func New(agg userAgg.Aggregate, tokens *auth.TokenIssuer) Service {
+	return service{
+		userAggregate: agg,
+		tokens:        tokens,
+	}
+}
+
+// Login checks the credentials of an user and starts a new session.
+// Unknown users and wrong passwords fail with the same user.ErrInvalidCredentials
+func (s service) Login(ctx context.Context, input *model.LoginInput) (*model.TokenOutput, error) {
+	ctx, span := tracing.Start(ctx, "authService.Login")
+	defer span.End()
+
+	u, err := s.userAggregate.GetByLogin(ctx, input.Login)
+	if err != nil {
+		if errors.Is(err, repo.ErrRecordNotFound) {
+			// Spend the same time as a wrong password so users can not be enumerated
+			compareDummyHash(input.Password)
+			return nil, user.ErrInvalidCredentials
+		}
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("authService", "Login").Msg("could not get user")
+		return nil, err
+	}
+
+	if err := u.CheckPassword(input.Password); err != nil {
+		return nil, err
+	}
+
+	sess, err := s.userAggregate.CreateSession(ctx, u.ID, session.Metadata{
+		UserAgent: input.UserAgent,
+		IPAddress: input.IPAddress,
+	}, s.tokens.RefreshTTL())
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("authService", "Login").Msg("could not create session")
+		return nil, err
+	}
+
+	out, err := s.issue(u, sess)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("authService", "Login").Msg("could not issue token")
+		return nil, err
+	}
+	return out, nil
+}
+
+// Token returns an access token for a service account. Service accounts have no session
+// and get a new token with their client credentials when it expires
+func (s service) Token(ctx context.Context, input *model.ClientCredentialsInput) (*model.TokenOutput, error) {
+	ctx, span := tracing.Start(ctx, "authService.Token")
+	defer span.End()
+
+	principal, err := s.tokens.AuthenticateService(input.ClientID, input.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := s.tokens.IssueFor(principal)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("authService", "Token").Msg("could not issue token")
+		return nil, err
+	}
+
+	return &model.TokenOutput{
+		AccessToken: token,
+		TokenType:   tokenType,
+		ExpiresIn:   int64(s.tokens.AccessTTL().Seconds()),
+		ExpiresAt:   expiresAt,
+		Scope:       strings.Join(principal.Scopes, " "),
+	}, nil
+}
+
+// Refresh rotates a refresh token and returns a new access token for its session.
+// The presented refresh token can not be used again
+func (s service) Refresh(ctx context.Context, input *model.RefreshInput) (*model.TokenOutput, error) {
+	ctx, span := tracing.Start(ctx, "authService.Refresh")
+	defer span.End()
+
+	u, sess, err := s.userAggregate.RotateSession(ctx, input.RefreshToken, session.Metadata{
+		UserAgent: input.UserAgent,
+		IPAddress: input.IPAddress,
+	}, s.tokens.RefreshTTL())
+	if err != nil {
+		if errors.Is(err, session.ErrRefreshTokenReused) {
+			log.Warn().Ctx(ctx).Str("authService", "Refresh").Msg("refresh token reused, session revoked")
+			return nil, err
+		}
+		if !errors.Is(err, session.ErrInvalidRefreshToken) {
+			span.RecordError(err)
+			log.Error().Ctx(ctx).Err(err).Str("authService", "Refresh").Msg("could not rotate session")
+		}
+		return nil, err
+	}
+
+	out, err := s.issue(u, sess)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("authService", "Refresh").Msg("could not issue token")
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListSessions returns the active sessions of an user
+func (s service) ListSessions(ctx context.Context, userID uuid.UUID) ([]model.SessionOutput, error) {
+	ctx, span := tracing.Start(ctx, "authService.ListSessions")
+	defer span.End()
+
+	sessions, err := s.userAggregate.ListSessions(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("authService", "ListSessions").Msg("could not list sessions")
+		return nil, err
+	}
+
+	mapped := make([]model.SessionOutput, 0, len(sessions))
+	for _, sess := range sessions {
+		mapped = append(mapped, model.SessionOutput{
+			ID:              sess.SessionID.String(),
+			UserAgent:       sess.UserAgent,
+			IPAddress:       sess.IPAddress,
+			StartedAt:       sess.StartedAt,
+			LastRefreshedAt: sess.CreatedAt,
+			ExpiresAt:       sess.ExpiresAt,
+		})
+	}
+	return mapped, nil
+}
+
+// RevokeSession revokes one session of an user, its refresh token stops working
+func (s service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "authService.RevokeSession")
+	defer span.End()
+
+	if err := s.userAggregate.RevokeSession(ctx, userID, sessionID); err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("authService", "RevokeSession").Msg("could not revoke session")
+		return err
+	}
+	return nil
+}
+
+// RevokeSessions revokes every session of an user and returns how many were revoked
+func (s service) RevokeSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	ctx, span := tracing.Start(ctx, "authService.RevokeSessions")
+	defer span.End()
+
+	revoked, err := s.userAggregate.RevokeSessions(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("authService", "RevokeSessions").Msg("could not revoke sessions")
+		return 0, err
+	}
+	return revoked, nil
+}
+
+// JWKS returns the public keys used to verify the access tokens
+func (s service) JWKS() auth.JWKS {
+	return s.tokens.JWKS()
+}
+
+// issue returns a new access token together with the refresh token of the session
+func (s service) issue(u *user.Entity, sess *session.Entity) (*model.TokenOutput, error) {
+	token, expiresAt, err := s.tokens.IssueFor(&auth.Principal{
+		Subject:  u.ID.String(),
+		Nickname: u.Nickname,
+		Role:     u.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenOutput{
+		AccessToken:  token,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(s.tokens.AccessTTL().Seconds()),
+		ExpiresAt:    expiresAt,
+		RefreshToken: sess.Token,
+		SessionID:    sess.SessionID.String(),
+	}, nil
+}
+
+func compareDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
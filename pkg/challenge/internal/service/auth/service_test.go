@@ -0,0 +1,184 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/session"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	service "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/auth"
+)
+
+func TestService_Login(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens, err := auth.New(auth.WithHMACSecret("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg, tokens)
+
+	stored := &user.Entity{ID: uuid.New(), Nickname: "bandido"}
+	assert.NoError(t, stored.HashPassword("supersecure"))
+
+	t.Run("should return a token for valid credentials", func(t *testing.T) {
+		sess := &session.Entity{SessionID: uuid.New(), Token: "refresh-token"}
+		mockAgg.EXPECT().GetByLogin(gomock.Any(), "bandido").Return(stored, nil)
+		mockAgg.EXPECT().
+			CreateSession(gomock.Any(), stored.ID, session.Metadata{UserAgent: "curl/8.0", IPAddress: "127.0.0.1"}, tokens.RefreshTTL()).
+			Return(sess, nil)
+
+		res, err := svc.Login(context.Background(), &model.LoginInput{
+			Login:     "bandido",
+			Password:  "supersecure",
+			UserAgent: "curl/8.0",
+			IPAddress: "127.0.0.1",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer", res.TokenType)
+		assert.Equal(t, int64(900), res.ExpiresIn)
+		assert.Equal(t, "refresh-token", res.RefreshToken)
+		assert.Equal(t, sess.SessionID.String(), res.SessionID)
+
+		claims, err := tokens.Verify(res.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, stored.ID.String(), claims.Subject)
+	})
+
+	t.Run("should fail the same way for wrong passwords and unknown users", func(t *testing.T) {
+		mockAgg.EXPECT().GetByLogin(gomock.Any(), "bandido").Return(stored, nil)
+		_, err := svc.Login(context.Background(), &model.LoginInput{Login: "bandido", Password: "wrong-password"})
+		assert.ErrorIs(t, err, user.ErrInvalidCredentials)
+
+		mockAgg.EXPECT().GetByLogin(gomock.Any(), "nobody@test.com").Return(nil, repo.ErrRecordNotFound)
+		_, err = svc.Login(context.Background(), &model.LoginInput{Login: "nobody@test.com", Password: "supersecure"})
+		assert.ErrorIs(t, err, user.ErrInvalidCredentials)
+	})
+
+	t.Run("should fail when the aggregate fails", func(t *testing.T) {
+		mockAgg.EXPECT().GetByLogin(gomock.Any(), "bandido").Return(nil, errors.New("db error"))
+		_, err := svc.Login(context.Background(), &model.LoginInput{Login: "bandido", Password: "supersecure"})
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, user.ErrInvalidCredentials)
+	})
+}
+
+func TestService_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens, err := auth.New(auth.WithHMACSecret("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg, tokens)
+
+	stored := &user.Entity{ID: uuid.New(), Nickname: "bandido"}
+
+	t.Run("should return new tokens for a valid refresh token", func(t *testing.T) {
+		sess := &session.Entity{SessionID: uuid.New(), Token: "new-refresh-token"}
+		mockAgg.EXPECT().
+			RotateSession(gomock.Any(), "refresh-token", session.Metadata{}, tokens.RefreshTTL()).
+			Return(stored, sess, nil)
+
+		res, err := svc.Refresh(context.Background(), &model.RefreshInput{RefreshToken: "refresh-token"})
+		assert.NoError(t, err)
+		assert.Equal(t, "new-refresh-token", res.RefreshToken)
+
+		claims, err := tokens.Verify(res.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, stored.ID.String(), claims.Subject)
+	})
+
+	t.Run("should fail when the refresh token was reused", func(t *testing.T) {
+		mockAgg.EXPECT().
+			RotateSession(gomock.Any(), "refresh-token", gomock.Any(), gomock.Any()).
+			Return(nil, nil, session.ErrRefreshTokenReused)
+
+		_, err := svc.Refresh(context.Background(), &model.RefreshInput{RefreshToken: "refresh-token"})
+		assert.ErrorIs(t, err, session.ErrRefreshTokenReused)
+	})
+}
+
+func TestService_Sessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens, err := auth.New(auth.WithHMACSecret("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg, tokens)
+	userID := uuid.New()
+
+	t.Run("should list the active sessions", func(t *testing.T) {
+		sessionID := uuid.New()
+		mockAgg.EXPECT().ListSessions(gomock.Any(), userID).Return([]session.Entity{
+			{ID: uuid.New(), SessionID: sessionID, UserAgent: "curl/8.0"},
+		}, nil)
+
+		res, err := svc.ListSessions(context.Background(), userID)
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, sessionID.String(), res[0].ID)
+		assert.Equal(t, "curl/8.0", res[0].UserAgent)
+	})
+
+	t.Run("should revoke one session", func(t *testing.T) {
+		sessionID := uuid.New()
+		mockAgg.EXPECT().RevokeSession(gomock.Any(), userID, sessionID).Return(repo.ErrRecordNotFound)
+
+		err := svc.RevokeSession(context.Background(), userID, sessionID)
+		assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+	})
+
+	t.Run("should revoke every session", func(t *testing.T) {
+		mockAgg.EXPECT().RevokeSessions(gomock.Any(), userID).Return(3, nil)
+
+		revoked, err := svc.RevokeSessions(context.Background(), userID)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, revoked)
+	})
+}
+
+func TestService_Token(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens, err := auth.New(
+		auth.WithHMACSecret("0123456789abcdef0123456789abcdef"),
+		auth.WithServiceAccount("reporting", "reporting-secret-0123456789abcdef", auth.ScopeUsersRead),
+	)
+	assert.NoError(t, err)
+
+	svc := service.New(mocks.NewMockUserAggregate(ctrl), tokens)
+
+	t.Run("should return a scoped token for valid client credentials", func(t *testing.T) {
+		res, err := svc.Token(context.Background(), &model.ClientCredentialsInput{
+			ClientID:     "reporting",
+			ClientSecret: "reporting-secret-0123456789abcdef",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, auth.ScopeUsersRead, res.Scope)
+		assert.Empty(t, res.RefreshToken)
+
+		claims, err := tokens.Verify(res.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, auth.RoleService, claims.Role)
+	})
+
+	t.Run("should fail for invalid client credentials", func(t *testing.T) {
+		_, err := svc.Token(context.Background(), &model.ClientCredentialsInput{ClientID: "reporting", ClientSecret: "wrong"})
+		assert.ErrorIs(t, err, auth.ErrInvalidClient)
+	})
+}
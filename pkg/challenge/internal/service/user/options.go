@@ -0,0 +1,33 @@
+package service
+
+import "time"
+
+// Retrieve the default options
+func defaultOptions() Options {
+	return Options{
+		IdempotencyKeyTTL: 24 * time.Hour,
+	}
+}
+
+type Options struct {
+	// IdempotencySecret keys the hash of the requests stored with their idempotency key
+	IdempotencySecret []byte
+	// IdempotencyKeyTTL is how long an idempotency key is honored. Retries after it are handled as new requests
+	IdempotencyKeyTTL time.Duration
+}
+
+// WithIdempotencySecret sets the secret keying the hash of the requests stored with their idempotency key
+func WithIdempotencySecret(secret string) Option {
+	return func(o *Options) {
+		o.IdempotencySecret = []byte(secret)
+	}
+}
+
+// WithIdempotencyKeyTTL sets how long an idempotency key is honored
+func WithIdempotencyKeyTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.IdempotencyKeyTTL = d
+	}
+}
+
+type Option func(*Options)
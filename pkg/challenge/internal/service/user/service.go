@@ -2,84 +2,254 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 
 	userAgg "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
 )
 
 type service struct {
 	userAggregate userAgg.Aggregate
+	opts          Options
 }
 
 type Service interface {
 	Create(ctx context.Context, input *model.CreateUserInput) (*model.UserOutput, error)
-	Find(ctx context.Context, country string, page, limit int) ([]model.UserOutput, error)
-	Update(ctx context.Context, id uuid.UUID, nickname string) (*model.UserOutput, error)
-	Delete(ctx context.Context, id uuid.UUID) error
+	Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.UserOutput, error)
+	Find(ctx context.Context, filter model.UserFilter) (*model.UsersPage, error)
+	Search(ctx context.Context, query string, limit int) ([]model.UserMatch, error)
+	NicknameAvailability(ctx context.Context, nickname string) (*model.NicknameAvailability, error)
+	Update(ctx context.Context, id uuid.UUID, input model.UpdateUserInput, expectedVersion int64) (*model.UserOutput, error)
+	Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error
+	ListEvents(ctx context.Context, filter model.UserEventFilter) ([]model.UserEventOutput, error)
+	GetAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*model.UserOutput, error)
+	ListEventsAfter(ctx context.Context, eventID uuid.UUID, userIDs []uuid.UUID, limit int) ([]model.UserEventOutput, error)
 }
 
 // New returns a new User service
-func New(userAgg userAgg.Aggregate) Service {
-	return service{userAggregate: userAgg}
+func New(userAgg userAgg.Aggregate, opts ...Option) Service {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+	return service{userAggregate: userAgg, opts: options}
 }
 
-// Create creates a new user and emits event after commit
+// Create creates a new user and emits event after commit. With an idempotency key,
+// retries of the same input return the user first created
 func (s service) Create(ctx context.Context, input *model.CreateUserInput) (*model.UserOutput, error) {
+	ctx, span := tracing.Start(ctx, "userService.Create")
+	defer span.End()
+
 	userEntity := mapCreateInputToEntity(input)
 
-	created, err := s.userAggregate.Create(ctx, userEntity)
+	var created *user.Entity
+	var err error
+	if input.IdempotencyKey == "" {
+		created, err = s.userAggregate.Create(ctx, userEntity)
+	} else {
+		var requestHash string
+		requestHash, err = idempotency.HashRequest(s.opts.IdempotencySecret, input)
+		if err == nil {
+			created, err = s.userAggregate.CreateIdempotent(ctx, userEntity, input.IdempotencyKey, requestHash, s.opts.IdempotencyKeyTTL)
+		}
+	}
 	if err != nil {
-		log.Error().Err(err).Str("userService", "Create").Msg("could not create user")
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "Create").Msg("could not create user")
 		return nil, err
 	}
 
 	return mapEntityToOutput(created), nil
 }
 
-// Find returns a list of users with pagination and country filter
-func (s service) Find(ctx context.Context, country string, page, limit int) ([]model.UserOutput, error) {
-	users, err := s.userAggregate.Find(ctx, country, page, limit)
+// Get returns an user by ID. Soft deleted users are only found with includeDeleted
+func (s service) Get(ctx context.Context, id uuid.UUID, includeDeleted bool) (*model.UserOutput, error) {
+	ctx, span := tracing.Start(ctx, "userService.Get")
+	defer span.End()
+
+	u, err := s.userAggregate.Get(ctx, id, includeDeleted)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "Get").Msg("could not get user")
+		return nil, err
+	}
+
+	return mapEntityToOutput(u), nil
+}
+
+// Find returns a page of users matching the filter, counting them all when requested
+func (s service) Find(ctx context.Context, filter model.UserFilter) (*model.UsersPage, error) {
+	ctx, span := tracing.Start(ctx, "userService.Find")
+	defer span.End()
+
+	users, next, err := s.userAggregate.Find(ctx, filter)
 	if err != nil {
-		log.Error().Err(err).Str("userService", "Find").Msg("could not find users")
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "Find").Msg("could not find users")
 		return nil, err
 	}
 
-	mappedUsers := make([]model.UserOutput, 0, len(users))
+	page := &model.UsersPage{
+		Users:         make([]model.UserOutput, 0, len(users)),
+		NextPageToken: next,
+	}
 	for _, u := range users {
-		mappedUsers = append(mappedUsers, *mapEntityToOutput(&u))
+		page.Users = append(page.Users, *mapEntityToOutput(&u))
+	}
+
+	if filter.IncludeTotal {
+		total, err := s.userAggregate.Count(ctx, filter)
+		if err != nil {
+			span.RecordError(err)
+			log.Error().Ctx(ctx).Err(err).Str("userService", "Find").Msg("could not count users")
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// Search returns the users whose nickname or name are similar to the query, best matches first
+func (s service) Search(ctx context.Context, query string, limit int) ([]model.UserMatch, error) {
+	ctx, span := tracing.Start(ctx, "userService.Search")
+	defer span.End()
+
+	matches, err := s.userAggregate.Search(ctx, query, limit)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "Search").Msg("could not search users")
+		return nil, err
+	}
+
+	mappedMatches := make([]model.UserMatch, 0, len(matches))
+	for _, m := range matches {
+		mappedMatches = append(mappedMatches, model.UserMatch{
+			UserOutput: *mapEntityToOutput(&m.Entity),
+			Score:      m.Score,
+		})
+	}
+
+	return mappedMatches, nil
+}
+
+// NicknameAvailability tells whether a nickname is free, ignoring case, suggesting
+// alternatives when it is taken
+func (s service) NicknameAvailability(ctx context.Context, nickname string) (*model.NicknameAvailability, error) {
+	ctx, span := tracing.Start(ctx, "userService.NicknameAvailability")
+	defer span.End()
+
+	available, suggestions, err := s.userAggregate.NicknameAvailability(ctx, nickname)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "NicknameAvailability").Msg("could not check nickname availability")
+		return nil, err
 	}
 
-	return mappedUsers, nil
+	if suggestions == nil {
+		suggestions = []string{}
+	}
+	return &model.NicknameAvailability{
+		Nickname:    nickname,
+		Available:   available,
+		Suggestions: suggestions,
+	}, nil
 }
 
-// Update only updates nickname and emits event
-func (s service) Update(ctx context.Context, id uuid.UUID, nickname string) (*model.UserOutput, error) {
-	updated, err := s.userAggregate.Update(ctx, &user.Entity{
-		ID:       id,
-		Nickname: nickname,
-	})
+// Update changes the profile fields set in the input and emits event.
+// An expected version of 0 skips the version check
+func (s service) Update(ctx context.Context, id uuid.UUID, input model.UpdateUserInput, expectedVersion int64) (*model.UserOutput, error) {
+	ctx, span := tracing.Start(ctx, "userService.Update")
+	defer span.End()
+
+	updated, err := s.userAggregate.Update(ctx, id, input, expectedVersion)
 	if err != nil {
-		log.Error().Err(err).Str("userService", "Update").Msg("could not update user")
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "Update").Msg("could not update user")
 		return nil, err
 	}
 
 	return mapEntityToOutput(updated), nil
 }
 
-// Delete performs a soft delete and emits event
-func (s service) Delete(ctx context.Context, id uuid.UUID) error {
-	err := s.userAggregate.Delete(ctx, id)
+// Delete performs a soft delete and emits event. An expected version of 0 skips the version check
+func (s service) Delete(ctx context.Context, id uuid.UUID, expectedVersion int64) error {
+	ctx, span := tracing.Start(ctx, "userService.Delete")
+	defer span.End()
+
+	err := s.userAggregate.Delete(ctx, id, expectedVersion)
 	if err != nil {
-		log.Error().Err(err).Str("userService", "Delete").Msg("could not soft delete user")
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "Delete").Msg("could not soft delete user")
 		return err
 	}
 	return nil
 }
 
+// ListEvents returns the change history of an user
+func (s service) ListEvents(ctx context.Context, filter model.UserEventFilter) ([]model.UserEventOutput, error) {
+	ctx, span := tracing.Start(ctx, "userService.ListEvents")
+	defer span.End()
+
+	events, err := s.userAggregate.ListEvents(ctx, filter)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "ListEvents").Msg("could not list user events")
+		return nil, err
+	}
+
+	mappedEvents := make([]model.UserEventOutput, 0, len(events))
+	for _, e := range events {
+		mappedEvents = append(mappedEvents, mapEventToOutput(e))
+	}
+
+	return mappedEvents, nil
+}
+
+// ListEventsAfter returns the events stored after the given one, used to resume event feeds
+func (s service) ListEventsAfter(ctx context.Context, eventID uuid.UUID, userIDs []uuid.UUID, limit int) ([]model.UserEventOutput, error) {
+	ctx, span := tracing.Start(ctx, "userService.ListEventsAfter")
+	defer span.End()
+
+	events, err := s.userAggregate.ListEventsAfter(ctx, eventID, userIDs, limit)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "ListEventsAfter").Msg("could not list events")
+		return nil, err
+	}
+
+	mappedEvents := make([]model.UserEventOutput, 0, len(events))
+	for _, e := range events {
+		mappedEvents = append(mappedEvents, mapEventToOutput(e))
+	}
+
+	return mappedEvents, nil
+}
+
+// GetAsOf returns the user as it was at the given time, rebuilt from its events
+func (s service) GetAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*model.UserOutput, error) {
+	ctx, span := tracing.Start(ctx, "userService.GetAsOf")
+	defer span.End()
+
+	u, err := s.userAggregate.Project(ctx, id, asOf)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("userService", "GetAsOf").Msg("could not project user")
+		return nil, err
+	}
+
+	return mapEntityToOutput(u), nil
+}
+
 func mapCreateInputToEntity(in *model.CreateUserInput) *user.Entity {
 	return &user.Entity{
 		FirstName: in.FirstName,
@@ -99,5 +269,24 @@ func mapEntityToOutput(u *user.Entity) *model.UserOutput {
 		Nickname:  u.Nickname,
 		Email:     u.Email,
 		Country:   u.Country,
+		Version:   u.Version,
+		DeletedAt: deletedAt(u),
+	}
+}
+
+func deletedAt(u *user.Entity) *time.Time {
+	if !u.DeletedAt.Valid {
+		return nil
+	}
+	return &u.DeletedAt.Time
+}
+
+func mapEventToOutput(e event.User) model.UserEventOutput {
+	return model.UserEventOutput{
+		ID:        e.ID.String(),
+		UserID:    e.UserID.String(),
+		EventType: e.EventType,
+		Payload:   json.RawMessage(e.Payload),
+		CreatedAt: e.CreatedAt,
 	}
 }
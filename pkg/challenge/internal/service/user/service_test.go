@@ -4,14 +4,19 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
 
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/idempotency"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
 	service "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/user"
 )
 
@@ -74,6 +79,147 @@ func TestService_Create_Fail(t *testing.T) {
 	assert.Nil(t, res)
 }
 
+func TestService_Create_Idempotent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg, service.WithIdempotencySecret("idempotency-secret"), service.WithIdempotencyKeyTTL(time.Hour))
+
+	newInput := func(nickname string) *model.CreateUserInput {
+		return &model.CreateUserInput{
+			FirstName:      "Nacho",
+			LastName:       "Calcagno",
+			Nickname:       nickname,
+			Password:       "123123123",
+			Email:          "nacho@gmail.com",
+			Country:        "VE",
+			IdempotencyKey: "key-1",
+		}
+	}
+	expected := &user.Entity{ID: uuid.New(), Nickname: "bandido"}
+
+	var hashes []string
+	mockAgg.EXPECT().
+		CreateIdempotent(gomock.Any(), gomock.Any(), "key-1", gomock.Any(), time.Hour).
+		DoAndReturn(func(_ context.Context, _ *user.Entity, _, requestHash string, _ time.Duration) (*user.Entity, error) {
+			hashes = append(hashes, requestHash)
+			return expected, nil
+		}).
+		Times(3)
+
+	for _, nickname := range []string{"bandido", "bandido", "other"} {
+		result, err := svc.Create(context.Background(), newInput(nickname))
+		assert.NoError(t, err)
+		assert.Equal(t, expected.ID.String(), result.ID)
+	}
+
+	t.Run("should hash the same input the same", func(t *testing.T) {
+		assert.Equal(t, hashes[0], hashes[1])
+		assert.NotEqual(t, hashes[0], hashes[2])
+	})
+
+	t.Run("should hash with the idempotency secret", func(t *testing.T) {
+		expected, err := idempotency.HashRequest([]byte("idempotency-secret"), newInput("bandido"))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, hashes[0])
+	})
+}
+
+func TestService_Search(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg)
+
+	mockAgg.EXPECT().
+		Search(gomock.Any(), "bandid", 5).
+		Return([]user.Match{{Entity: user.Entity{ID: uuid.New(), Nickname: "bandido"}, Score: 0.7}}, nil)
+
+	res, err := svc.Search(context.Background(), "bandid", 5)
+	assert.NoError(t, err)
+	if assert.Len(t, res, 1) {
+		assert.Equal(t, "bandido", res[0].Nickname)
+		assert.Equal(t, 0.7, res[0].Score)
+	}
+}
+
+func TestService_NicknameAvailability(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg)
+
+	t.Run("should return the suggestions of a taken nickname", func(t *testing.T) {
+		mockAgg.EXPECT().
+			NicknameAvailability(gomock.Any(), "bandido").
+			Return(false, []string{"bandido7", "bandido_42"}, nil)
+
+		res, err := svc.NicknameAvailability(context.Background(), "bandido")
+		assert.NoError(t, err)
+		assert.Equal(t, &model.NicknameAvailability{
+			Nickname:    "bandido",
+			Available:   false,
+			Suggestions: []string{"bandido7", "bandido_42"},
+		}, res)
+	})
+
+	t.Run("should return no suggestions for a free nickname", func(t *testing.T) {
+		mockAgg.EXPECT().NicknameAvailability(gomock.Any(), "ropz").Return(true, nil, nil)
+
+		res, err := svc.NicknameAvailability(context.Background(), "ropz")
+		assert.NoError(t, err)
+		assert.True(t, res.Available)
+		assert.NotNil(t, res.Suggestions)
+		assert.Empty(t, res.Suggestions)
+	})
+
+	t.Run("should return the aggregate error", func(t *testing.T) {
+		mockAgg.EXPECT().NicknameAvailability(gomock.Any(), "").Return(false, nil, user.ErrInvalidNickname)
+
+		res, err := svc.NicknameAvailability(context.Background(), "")
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, user.ErrInvalidNickname)
+	})
+}
+
+func TestService_Get_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg)
+
+	id := uuid.New()
+	deletedAt := time.Date(2025, 4, 21, 10, 0, 0, 0, time.UTC)
+	mockAgg.EXPECT().
+		Get(gomock.Any(), id, true).
+		Return(&user.Entity{ID: id, Nickname: "bandido", DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}}, nil)
+
+	res, err := svc.Get(context.Background(), id, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "bandido", res.Nickname)
+	assert.Equal(t, &deletedAt, res.DeletedAt)
+}
+
+func TestService_Get_Fail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg)
+
+	mockAgg.EXPECT().
+		Get(gomock.Any(), gomock.Any(), false).
+		Return(nil, repo.ErrRecordNotFound)
+
+	res, err := svc.Get(context.Background(), uuid.New(), false)
+	assert.ErrorIs(t, err, repo.ErrRecordNotFound)
+	assert.Nil(t, res)
+}
+
 func TestService_Find_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -92,14 +238,32 @@ func TestService_Find_Success(t *testing.T) {
 		},
 	}
 
+	filter := model.UserFilter{Countries: []string{"VE"}, Page: 1, Limit: 10}
 	mockAgg.EXPECT().
-		Find(gomock.Any(), "VE", 1, 10).
-		Return(mockUsers, nil)
+		Find(gomock.Any(), filter).
+		Return(mockUsers, "next", nil)
 
-	res, err := svc.Find(context.Background(), "VE", 1, 10)
+	res, err := svc.Find(context.Background(), filter)
 	assert.NoError(t, err)
-	assert.Len(t, res, 1)
-	assert.Equal(t, "nacho", res[0].Nickname)
+	assert.Len(t, res.Users, 1)
+	assert.Equal(t, "nacho", res.Users[0].Nickname)
+	assert.Equal(t, "next", res.NextPageToken)
+	assert.Nil(t, res.Total)
+
+	t.Run("should count the users when the total is requested", func(t *testing.T) {
+		filter.IncludeTotal = true
+		mockAgg.EXPECT().
+			Find(gomock.Any(), filter).
+			Return(mockUsers, "", nil)
+		mockAgg.EXPECT().
+			Count(gomock.Any(), filter).
+			Return(int64(1), nil)
+
+		res, err := svc.Find(context.Background(), filter)
+		assert.NoError(t, err)
+		assert.Empty(t, res.NextPageToken)
+		assert.Equal(t, int64(1), *res.Total)
+	})
 }
 
 func TestService_Find_Fail(t *testing.T) {
@@ -110,10 +274,10 @@ func TestService_Find_Fail(t *testing.T) {
 	svc := service.New(mockAgg)
 
 	mockAgg.EXPECT().
-		Find(gomock.Any(), "VE", 1, 10).
-		Return(nil, errors.New("db failure"))
+		Find(gomock.Any(), gomock.Any()).
+		Return(nil, "", errors.New("db failure"))
 
-	res, err := svc.Find(context.Background(), "VE", 1, 10)
+	res, err := svc.Find(context.Background(), model.UserFilter{Countries: []string{"VE"}, Page: 1, Limit: 10})
 	assert.Error(t, err)
 	assert.Nil(t, res)
 }
@@ -126,17 +290,19 @@ func TestService_Update_Success(t *testing.T) {
 	svc := service.New(mockAgg)
 
 	id := uuid.New()
-	nick := "cslover"
+	nick, country := "cslover", "UY"
+	input := model.UpdateUserInput{Nickname: &nick, Country: &country}
 
-	entityUser := &user.Entity{ID: id, Nickname: nick}
+	entityUser := &user.Entity{ID: id, Nickname: nick, Country: country}
 
 	mockAgg.EXPECT().
-		Update(gomock.Any(), entityUser).
+		Update(gomock.Any(), id, input, int64(0)).
 		Return(entityUser, nil)
 
-	res, err := svc.Update(context.Background(), id, nick)
+	res, err := svc.Update(context.Background(), id, input, 0)
 	assert.NoError(t, err)
 	assert.Equal(t, nick, res.Nickname)
+	assert.Equal(t, country, res.Country)
 }
 
 func TestService_Update_Fail(t *testing.T) {
@@ -148,13 +314,13 @@ func TestService_Update_Fail(t *testing.T) {
 
 	id := uuid.New()
 	testNickName := "nachin"
-	user := &user.Entity{ID: id, Nickname: testNickName}
+	input := model.UpdateUserInput{Nickname: &testNickName}
 
 	mockAgg.EXPECT().
-		Update(gomock.Any(), user).
+		Update(gomock.Any(), id, input, int64(0)).
 		Return(nil, errors.New("update failed"))
 
-	res, err := svc.Update(context.Background(), id, testNickName)
+	res, err := svc.Update(context.Background(), id, input, 0)
 	assert.Error(t, err)
 	assert.Nil(t, res)
 }
@@ -168,10 +334,10 @@ func TestService_Delete_Success(t *testing.T) {
 
 	id := uuid.New()
 	mockAgg.EXPECT().
-		Delete(gomock.Any(), id).
+		Delete(gomock.Any(), id, int64(0)).
 		Return(nil)
 
-	err := svc.Delete(context.Background(), id)
+	err := svc.Delete(context.Background(), id, 0)
 	assert.NoError(t, err)
 }
 
@@ -184,9 +350,88 @@ func TestService_Delete_Fail(t *testing.T) {
 
 	id := uuid.New()
 	mockAgg.EXPECT().
-		Delete(gomock.Any(), id).
+		Delete(gomock.Any(), id, int64(0)).
 		Return(errors.New("delete error"))
 
-	err := svc.Delete(context.Background(), id)
+	err := svc.Delete(context.Background(), id, 0)
 	assert.Error(t, err)
 }
+
+func TestService_ListEvents_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg)
+
+	userID := uuid.New()
+	filter := model.UserEventFilter{UserID: userID, Page: 1, Limit: 10}
+	mockEvents := []event.User{
+		{
+			ID:        uuid.New(),
+			UserID:    userID,
+			EventType: event.UserUpdated,
+			Payload:   []byte(`{"nickname":"nacho"}`),
+		},
+	}
+
+	mockAgg.EXPECT().
+		ListEvents(gomock.Any(), filter).
+		Return(mockEvents, nil)
+
+	res, err := svc.ListEvents(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Equal(t, event.UserUpdated, res[0].EventType)
+	assert.JSONEq(t, `{"nickname":"nacho"}`, string(res[0].Payload))
+}
+
+func TestService_ListEvents_Fail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg)
+
+	mockAgg.EXPECT().
+		ListEvents(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("db failure"))
+
+	res, err := svc.ListEvents(context.Background(), model.UserEventFilter{UserID: uuid.New()})
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
+
+func TestService_GetAsOf_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg)
+
+	id := uuid.New()
+	asOf := time.Date(2025, 4, 20, 10, 0, 0, 0, time.UTC)
+	mockAgg.EXPECT().
+		Project(gomock.Any(), id, asOf).
+		Return(&user.Entity{ID: id, Nickname: "oldnick"}, nil)
+
+	res, err := svc.GetAsOf(context.Background(), id, asOf)
+	assert.NoError(t, err)
+	assert.Equal(t, "oldnick", res.Nickname)
+}
+
+func TestService_GetAsOf_Fail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockUserAggregate(ctrl)
+	svc := service.New(mockAgg)
+
+	mockAgg.EXPECT().
+		Project(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("projection failed"))
+
+	res, err := svc.GetAsOf(context.Background(), uuid.New(), time.Now())
+	assert.Error(t, err)
+	assert.Nil(t, res)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	webhookAgg "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/aggregate/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+type service struct {
+	webhookAggregate webhookAgg.Aggregate
+}
+
+type Service interface {
+	Create(ctx context.Context, input *model.CreateWebhookInput) (*model.WebhookOutput, error)
+	Get(ctx context.Context, id uuid.UUID) (*model.WebhookOutput, error)
+	Find(ctx context.Context, page, limit int) ([]model.WebhookOutput, error)
+	Update(ctx context.Context, id uuid.UUID, input model.UpdateWebhookInput) (*model.WebhookOutput, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, id uuid.UUID, page, limit int) ([]model.WebhookDeliveryOutput, error)
+}
+
+// New returns a new Webhook service
+func New(agg webhookAgg.Aggregate) Service {
+	return service{webhookAggregate: agg}
+}
+
+// Create registers a new webhook. The secret is only returned here
+func (s service) Create(ctx context.Context, input *model.CreateWebhookInput) (*model.WebhookOutput, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.Create")
+	defer span.End()
+
+	created, err := s.webhookAggregate.Create(ctx, &webhook.Entity{
+		URL:        input.URL,
+		EventTypes: input.EventTypes,
+		Secret:     input.Secret,
+	})
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("webhookService", "Create").Msg("could not create webhook")
+		return nil, err
+	}
+
+	out := mapEntityToOutput(created)
+	out.Secret = created.Secret
+	return out, nil
+}
+
+// Get returns a webhook by ID
+func (s service) Get(ctx context.Context, id uuid.UUID) (*model.WebhookOutput, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.Get")
+	defer span.End()
+
+	w, err := s.webhookAggregate.Get(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("webhookService", "Get").Msg("could not get webhook")
+		return nil, err
+	}
+	return mapEntityToOutput(w), nil
+}
+
+// Find returns a paginated list of webhooks
+func (s service) Find(ctx context.Context, page, limit int) ([]model.WebhookOutput, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.Find")
+	defer span.End()
+
+	webhooks, err := s.webhookAggregate.Find(ctx, page, limit)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("webhookService", "Find").Msg("could not find webhooks")
+		return nil, err
+	}
+
+	mapped := make([]model.WebhookOutput, 0, len(webhooks))
+	for _, w := range webhooks {
+		mapped = append(mapped, *mapEntityToOutput(&w))
+	}
+	return mapped, nil
+}
+
+// Update changes the given fields of a webhook
+func (s service) Update(ctx context.Context, id uuid.UUID, input model.UpdateWebhookInput) (*model.WebhookOutput, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.Update")
+	defer span.End()
+
+	updated, err := s.webhookAggregate.Update(ctx, id, input)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("webhookService", "Update").Msg("could not update webhook")
+		return nil, err
+	}
+	return mapEntityToOutput(updated), nil
+}
+
+// Delete removes a webhook
+func (s service) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "webhookService.Delete")
+	defer span.End()
+
+	if err := s.webhookAggregate.Delete(ctx, id); err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("webhookService", "Delete").Msg("could not delete webhook")
+		return err
+	}
+	return nil
+}
+
+// ListDeliveries returns the latest delivery attempts of a webhook
+func (s service) ListDeliveries(ctx context.Context, id uuid.UUID, page, limit int) ([]model.WebhookDeliveryOutput, error) {
+	ctx, span := tracing.Start(ctx, "webhookService.ListDeliveries")
+	defer span.End()
+
+	deliveries, err := s.webhookAggregate.ListDeliveries(ctx, id, page, limit)
+	if err != nil {
+		span.RecordError(err)
+		log.Error().Ctx(ctx).Err(err).Str("webhookService", "ListDeliveries").Msg("could not list webhook deliveries")
+		return nil, err
+	}
+
+	mapped := make([]model.WebhookDeliveryOutput, 0, len(deliveries))
+	for _, d := range deliveries {
+		mapped = append(mapped, model.WebhookDeliveryOutput{
+			ID:         d.ID.String(),
+			EventID:    d.EventID.String(),
+			EventType:  d.EventType,
+			Attempt:    d.Attempt,
+			Success:    d.Success,
+			StatusCode: d.StatusCode,
+			Error:      d.Error,
+			DurationMS: d.DurationMS,
+			CreatedAt:  d.CreatedAt,
+		})
+	}
+	return mapped, nil
+}
+
+func mapEntityToOutput(w *webhook.Entity) *model.WebhookOutput {
+	return &model.WebhookOutput{
+		ID:                  w.ID.String(),
+		URL:                 w.URL,
+		EventTypes:          w.EventTypes,
+		Active:              w.Active,
+		ConsecutiveFailures: w.ConsecutiveFailures,
+		DisabledAt:          w.DisabledAt,
+		CreatedAt:           w.CreatedAt,
+	}
+}
@@ -0,0 +1,97 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	service "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/service/webhook"
+)
+
+func TestService_Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockWebhookAggregate(ctrl)
+	svc := service.New(mockAgg)
+
+	input := &model.CreateWebhookInput{
+		URL:        "https://partner.example.com/hooks",
+		EventTypes: []string{event.UserCreated},
+	}
+
+	t.Run("should return the secret only on create", func(t *testing.T) {
+		mockAgg.EXPECT().
+			Create(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, w *webhook.Entity) (*webhook.Entity, error) {
+				w.ID = uuid.New()
+				w.Secret = "generated-secret-value"
+				w.Active = true
+				return w, nil
+			})
+
+		result, err := svc.Create(context.Background(), input)
+		assert.NoError(t, err)
+		assert.Equal(t, "generated-secret-value", result.Secret)
+		assert.True(t, result.Active)
+
+		mockAgg.EXPECT().
+			Get(gomock.Any(), gomock.Any()).
+			Return(&webhook.Entity{ID: uuid.New(), Secret: "generated-secret-value"}, nil)
+
+		got, err := svc.Get(context.Background(), uuid.New())
+		assert.NoError(t, err)
+		assert.Empty(t, got.Secret)
+	})
+
+	t.Run("should fail when the aggregate fails", func(t *testing.T) {
+		mockAgg.EXPECT().
+			Create(gomock.Any(), gomock.Any()).
+			Return(nil, webhook.ErrInvalidURL)
+
+		result, err := svc.Create(context.Background(), input)
+		assert.ErrorIs(t, err, webhook.ErrInvalidURL)
+		assert.Nil(t, result)
+	})
+}
+
+func TestService_ListDeliveries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAgg := mocks.NewMockWebhookAggregate(ctrl)
+	svc := service.New(mockAgg)
+	id := uuid.New()
+
+	t.Run("should map the deliveries", func(t *testing.T) {
+		mockAgg.EXPECT().
+			ListDeliveries(gomock.Any(), id, 1, 10).
+			Return([]webhook.Delivery{
+				{ID: uuid.New(), WebhookID: id, EventType: event.UserCreated, Attempt: 2, Success: true, StatusCode: 200},
+			}, nil)
+
+		result, err := svc.ListDeliveries(context.Background(), id, 1, 10)
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, 2, result[0].Attempt)
+		assert.True(t, result[0].Success)
+	})
+
+	t.Run("should fail when the aggregate fails", func(t *testing.T) {
+		mockAgg.EXPECT().
+			ListDeliveries(gomock.Any(), id, 1, 10).
+			Return(nil, errors.New("db error"))
+
+		result, err := svc.ListDeliveries(context.Background(), id, 1, 10)
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+}
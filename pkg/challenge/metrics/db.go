@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// startKey is the statement setting holding when the statement started
+	startKey = "metrics:start"
+	// unknownTable labels the statements without table, such as the raw ones
+	unknownTable = "unknown"
+	// countTimeout bounds the query counting the unpublished events on each scrape
+	countTimeout = 5 * time.Second
+)
+
+// InstrumentDB times every statement run through db with gorm callbacks and exposes
+// the pool stats of its sql.DB. It can only be called once per Metrics
+func (m *Metrics) InstrumentDB(db *gorm.DB) error {
+	if m == nil {
+		return nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := m.registry.Register(collectors.NewDBStatsCollector(sqlDB, Namespace)); err != nil {
+		return err
+	}
+
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", m.startStatement),
+		cb.Create().After("gorm:create").Register("metrics:after_create", m.endStatement("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", m.startStatement),
+		cb.Query().After("gorm:query").Register("metrics:after_query", m.endStatement("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", m.startStatement),
+		cb.Update().After("gorm:update").Register("metrics:after_update", m.endStatement("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", m.startStatement),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", m.endStatement("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", m.startStatement),
+		cb.Row().After("gorm:row").Register("metrics:after_row", m.endStatement("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", m.startStatement),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", m.endStatement("raw")),
+	)
+}
+
+func (m *Metrics) startStatement(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (m *Metrics) endStatement(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = unknownTable
+		}
+		m.dbDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			m.dbErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
+
+// WatchUnpublishedEvents exposes the number of unpublished events of the outbox,
+// counted on each scrape. A failing count is logged and left out of the scrape
+func (m *Metrics) WatchUnpublishedEvents(count func(ctx context.Context) (int64, error)) error {
+	if m == nil {
+		return nil
+	}
+	return m.registry.Register(&unpublishedEventsCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "outbox", "unpublished_events"),
+			"Events of challenge.user_event not published yet.",
+			nil, nil,
+		),
+		count: count,
+	})
+}
+
+type unpublishedEventsCollector struct {
+	desc  *prometheus.Desc
+	count func(ctx context.Context) (int64, error)
+}
+
+// Describe implements prometheus.Collector
+func (c *unpublishedEventsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *unpublishedEventsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), countTimeout)
+	defer cancel()
+
+	n, err := c.count(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("metrics", "Collect").Msg("failed to count unpublished events")
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
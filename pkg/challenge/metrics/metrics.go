@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric of the service
+const Namespace = "challenge"
+
+// Metrics holds the collectors of the service and its own registry, so several
+// instances (tests) never collide on the default Prometheus registry.
+// Every method can be called on a nil *Metrics and does nothing, components built
+// without metrics do not need to check for them
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	grpcRequests *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+
+	dbDuration *prometheus.HistogramVec
+	dbErrors   *prometheus.CounterVec
+
+	busPublished  *prometheus.CounterVec
+	busHandled    *prometheus.CounterVec
+	busFailures   *prometheus.CounterVec
+	busQueueDepth *prometheus.GaugeVec
+}
+
+// New returns the metrics of the service with the Go runtime and process collectors registered
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests handled, by method, route and status code.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the HTTP requests, by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "gRPC calls handled, by full method and status code.",
+		}, []string{"method", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the gRPC calls, by full method. Streams are observed when they end.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Latency of the gorm statements, by operation and table.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		dbErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "db",
+			Name:      "query_errors_total",
+			Help:      "Failed gorm statements, by operation and table. Not found records are not failures.",
+		}, []string{"operation", "table"}),
+		busPublished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "bus",
+			Name:      "published_total",
+			Help:      "Events published on the local bus, by event type.",
+		}, []string{"event_type"}),
+		busHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "bus",
+			Name:      "handled_total",
+			Help:      "Handler runs finished by the local bus, by event type.",
+		}, []string{"event_type"}),
+		busFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "bus",
+			Name:      "handler_failures_total",
+			Help:      "Handler runs of the local bus that panicked, by event type.",
+		}, []string{"event_type"}),
+		busQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "bus",
+			Name:      "queue_depth",
+			Help:      "Handler runs dispatched by the local bus and not finished yet, by event type.",
+		}, []string{"event_type"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.grpcRequests,
+		m.grpcDuration,
+		m.dbDuration,
+		m.dbErrors,
+		m.busPublished,
+		m.busHandled,
+		m.busFailures,
+		m.busQueueDepth,
+	)
+	return m
+}
+
+// Registry returns the registry the metrics are collected from
+func (m *Metrics) Registry() *prometheus.Registry {
+	if m == nil {
+		return nil
+	}
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus text format. A failing collector
+// (e.g. the database is down) is left out of the scrape instead of failing it
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// ObserveHTTPRequest records a handled HTTP request. route is the route template
+// (/users/:id) and not the path, so each route is a single series
+func (m *Metrics) ObserveHTTPRequest(method, route string, code int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(code)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// ObserveGRPCCall records a handled gRPC call
+func (m *Metrics) ObserveGRPCCall(method, code string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.grpcRequests.WithLabelValues(method, code).Inc()
+	m.grpcDuration.WithLabelValues(method).Observe(d.Seconds())
+}
+
+// EventPublished records an event published on the local bus
+func (m *Metrics) EventPublished(eventType string) {
+	if m == nil {
+		return
+	}
+	m.busPublished.WithLabelValues(eventType).Inc()
+}
+
+// HandlerStarted records a handler run dispatched by the local bus
+func (m *Metrics) HandlerStarted(eventType string) {
+	if m == nil {
+		return
+	}
+	m.busQueueDepth.WithLabelValues(eventType).Inc()
+}
+
+// HandlerFinished records the end of a handler run started with HandlerStarted
+func (m *Metrics) HandlerFinished(eventType string, failed bool) {
+	if m == nil {
+		return
+	}
+	m.busQueueDepth.WithLabelValues(eventType).Dec()
+	m.busHandled.WithLabelValues(eventType).Inc()
+	if failed {
+		m.busFailures.WithLabelValues(eventType).Inc()
+	}
+}
@@ -0,0 +1,145 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+)
+
+type row struct {
+	ID int
+}
+
+func (row) TableName() string {
+	return "challenge.row"
+}
+
+// newUnreachableDB returns a gorm DB whose statements fail to connect, unless run in dry run mode
+func newUnreachableDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.Open("host=127.0.0.1 port=1 connect_timeout=1"), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	assert.NoError(t, err)
+	return db
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := metrics.New()
+	m.ObserveHTTPRequest(http.MethodGet, "/users/:id", http.StatusOK, 20*time.Millisecond)
+	m.ObserveGRPCCall("/user.UserService/GetUser", "NotFound", time.Millisecond)
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/plain")
+
+	body, err := helpers.ScrapeMetrics(m)
+	assert.NoError(t, err)
+	assert.Contains(t, body, `challenge_http_requests_total{code="200",method="GET",route="/users/:id"} 1`)
+	assert.Contains(t, body, `challenge_http_request_duration_seconds_bucket{method="GET",route="/users/:id",le="0.025"} 1`)
+	assert.Contains(t, body, `challenge_grpc_requests_total{code="NotFound",method="/user.UserService/GetUser"} 1`)
+	assert.Contains(t, body, `challenge_grpc_request_duration_seconds_count{method="/user.UserService/GetUser"} 1`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestMetrics_Bus(t *testing.T) {
+	m := metrics.New()
+	m.EventPublished("USER_CREATED")
+	m.HandlerStarted("USER_CREATED")
+	m.HandlerStarted("USER_CREATED")
+	m.HandlerFinished("USER_CREATED", true)
+
+	body, err := helpers.ScrapeMetrics(m)
+	assert.NoError(t, err)
+	assert.Contains(t, body, `challenge_bus_published_total{event_type="USER_CREATED"} 1`)
+	assert.Contains(t, body, `challenge_bus_handled_total{event_type="USER_CREATED"} 1`)
+	assert.Contains(t, body, `challenge_bus_handler_failures_total{event_type="USER_CREATED"} 1`)
+	assert.Contains(t, body, `challenge_bus_queue_depth{event_type="USER_CREATED"} 1`)
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *metrics.Metrics
+	assert.NotPanics(t, func() {
+		m.ObserveHTTPRequest(http.MethodGet, "/health", http.StatusOK, time.Millisecond)
+		m.ObserveGRPCCall("/user.UserService/GetUser", "OK", time.Millisecond)
+		m.EventPublished("USER_CREATED")
+		m.HandlerStarted("USER_CREATED")
+		m.HandlerFinished("USER_CREATED", false)
+		assert.NoError(t, m.InstrumentDB(nil))
+	})
+}
+
+func TestMetrics_InstrumentDB(t *testing.T) {
+	m := metrics.New()
+	db := newUnreachableDB(t)
+	assert.NoError(t, m.InstrumentDB(db))
+
+	dryRun := db.Session(&gorm.Session{DryRun: true})
+	assert.NoError(t, dryRun.Create(&row{ID: 1}).Error)
+	assert.NoError(t, dryRun.Find(&[]row{}).Error)
+	assert.NoError(t, dryRun.Model(&row{}).Where("id = ?", 1).Update("id", 2).Error)
+	assert.NoError(t, dryRun.Delete(&row{ID: 1}).Error)
+	assert.Error(t, db.First(&row{}).Error)
+
+	body, err := helpers.ScrapeMetrics(m)
+	assert.NoError(t, err)
+	assert.Contains(t, body, `challenge_db_query_duration_seconds_count{operation="create",table="row"} 1`)
+	assert.Contains(t, body, `challenge_db_query_duration_seconds_count{operation="query",table="row"} 2`)
+	assert.Contains(t, body, `challenge_db_query_duration_seconds_count{operation="update",table="row"} 1`)
+	assert.Contains(t, body, `challenge_db_query_duration_seconds_count{operation="delete",table="row"} 1`)
+	assert.Contains(t, body, `challenge_db_query_errors_total{operation="query",table="row"} 1`)
+	assert.Contains(t, body, `go_sql_max_open_connections{db_name="challenge"} 0`)
+
+	t.Run("should fail to instrument the same metrics twice", func(t *testing.T) {
+		assert.Error(t, m.InstrumentDB(newUnreachableDB(t)))
+	})
+}
+
+func TestMetrics_WatchUnpublishedEvents(t *testing.T) {
+	t.Run("should count the unpublished events on each scrape", func(t *testing.T) {
+		m := metrics.New()
+		var count int64
+		assert.NoError(t, m.WatchUnpublishedEvents(func(context.Context) (int64, error) {
+			count++
+			return count, nil
+		}))
+
+		body, err := helpers.ScrapeMetrics(m)
+		assert.NoError(t, err)
+		assert.Contains(t, body, "challenge_outbox_unpublished_events 1")
+
+		body, err = helpers.ScrapeMetrics(m)
+		assert.NoError(t, err)
+		assert.Contains(t, body, "challenge_outbox_unpublished_events 2")
+	})
+
+	t.Run("should leave a failing count out of the scrape", func(t *testing.T) {
+		m := metrics.New()
+		m.EventPublished("USER_CREATED")
+		assert.NoError(t, m.WatchUnpublishedEvents(func(context.Context) (int64, error) {
+			return 0, errors.New("db is down")
+		}))
+
+		body, err := helpers.ScrapeMetrics(m)
+		assert.NoError(t, err)
+		assert.NotContains(t, body, "challenge_outbox_unpublished_events")
+		assert.Contains(t, body, "challenge_bus_published_total")
+	})
+}
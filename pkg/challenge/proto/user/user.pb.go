@@ -9,6 +9,8 @@ package user_proto
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -22,15 +24,17 @@ const (
 )
 
 type CreateUserRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	FirstName     string                 `protobuf:"bytes,1,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
-	LastName      string                 `protobuf:"bytes,2,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
-	Nickname      string                 `protobuf:"bytes,3,opt,name=nickname,proto3" json:"nickname,omitempty"`
-	Password      string                 `protobuf:"bytes,4,opt,name=password,proto3" json:"password,omitempty"`
-	Email         string                 `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
-	Country       string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	FirstName string                 `protobuf:"bytes,1,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName  string                 `protobuf:"bytes,2,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	Nickname  string                 `protobuf:"bytes,3,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Password  string                 `protobuf:"bytes,4,opt,name=password,proto3" json:"password,omitempty"`
+	Email     string                 `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
+	Country   string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	// Retries with the same key and request return the user first created
+	IdempotencyKey string `protobuf:"bytes,7,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *CreateUserRequest) Reset() {
@@ -105,17 +109,86 @@ func (x *CreateUserRequest) GetCountry() string {
 	return ""
 }
 
+func (x *CreateUserRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type GetUserRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Also return soft deleted users. Only allowed to admins
+	IncludeDeleted bool `protobuf:"varint,2,opt,name=include_deleted,json=includeDeleted,proto3" json:"include_deleted,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetUserRequest) Reset() {
+	*x = GetUserRequest{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserRequest) ProtoMessage() {}
+
+func (x *GetUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserRequest.ProtoReflect.Descriptor instead.
+func (*GetUserRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetUserRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetUserRequest) GetIncludeDeleted() bool {
+	if x != nil {
+		return x.IncludeDeleted
+	}
+	return false
+}
+
 type UpdateUserRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Nickname      string                 `protobuf:"bytes,2,opt,name=nickname,proto3" json:"nickname,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Nickname  string                 `protobuf:"bytes,2,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	FirstName string                 `protobuf:"bytes,3,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName  string                 `protobuf:"bytes,4,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	Email     string                 `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
+	Country   string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	// Fields to update: first_name, last_name, nickname, email and country.
+	// Without a mask every non empty field is updated
+	UpdateMask *fieldmaskpb.FieldMask `protobuf:"bytes,7,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	// Fails with FAILED_PRECONDITION if the user is not at this version. 0 skips the check
+	ExpectedVersion int64 `protobuf:"varint,8,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *UpdateUserRequest) Reset() {
 	*x = UpdateUserRequest{}
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[1]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -127,7 +200,7 @@ func (x *UpdateUserRequest) String() string {
 func (*UpdateUserRequest) ProtoMessage() {}
 
 func (x *UpdateUserRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[1]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -140,7 +213,7 @@ func (x *UpdateUserRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateUserRequest.ProtoReflect.Descriptor instead.
 func (*UpdateUserRequest) Descriptor() ([]byte, []int) {
-	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{1}
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *UpdateUserRequest) GetId() string {
@@ -157,16 +230,60 @@ func (x *UpdateUserRequest) GetNickname() string {
 	return ""
 }
 
+func (x *UpdateUserRequest) GetFirstName() string {
+	if x != nil {
+		return x.FirstName
+	}
+	return ""
+}
+
+func (x *UpdateUserRequest) GetLastName() string {
+	if x != nil {
+		return x.LastName
+	}
+	return ""
+}
+
+func (x *UpdateUserRequest) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *UpdateUserRequest) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *UpdateUserRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+func (x *UpdateUserRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
 type DeleteUserRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Fails with FAILED_PRECONDITION if the user is not at this version. 0 skips the check
+	ExpectedVersion int64 `protobuf:"varint,2,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *DeleteUserRequest) Reset() {
 	*x = DeleteUserRequest{}
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[2]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -178,7 +295,7 @@ func (x *DeleteUserRequest) String() string {
 func (*DeleteUserRequest) ProtoMessage() {}
 
 func (x *DeleteUserRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[2]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -191,7 +308,7 @@ func (x *DeleteUserRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteUserRequest.ProtoReflect.Descriptor instead.
 func (*DeleteUserRequest) Descriptor() ([]byte, []int) {
-	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{2}
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *DeleteUserRequest) GetId() string {
@@ -201,18 +318,39 @@ func (x *DeleteUserRequest) GetId() string {
 	return ""
 }
 
+func (x *DeleteUserRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
 type FindUsersRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Country       string                 `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
-	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
-	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Country string                 `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
+	Page    int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	Limit   int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	// next_page_token of the previous page, it takes precedence over page
+	PageToken string `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Also counts every user matching the filter
+	IncludeTotal bool `protobuf:"varint,5,opt,name=include_total,json=includeTotal,proto3" json:"include_total,omitempty"`
+	// Matches any of the countries, along with country
+	Countries []string `protobuf:"bytes,6,rep,name=countries,proto3" json:"countries,omitempty"`
+	// Case insensitive prefixes
+	NicknamePrefix string                 `protobuf:"bytes,7,opt,name=nickname_prefix,json=nicknamePrefix,proto3" json:"nickname_prefix,omitempty"`
+	EmailPrefix    string                 `protobuf:"bytes,8,opt,name=email_prefix,json=emailPrefix,proto3" json:"email_prefix,omitempty"`
+	CreatedFrom    *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"`
+	CreatedTo      *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`
+	// created_at (default) or nickname
+	SortBy        string `protobuf:"bytes,11,opt,name=sort_by,json=sortBy,proto3" json:"sort_by,omitempty"`
+	Descending    bool   `protobuf:"varint,12,opt,name=descending,proto3" json:"descending,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *FindUsersRequest) Reset() {
 	*x = FindUsersRequest{}
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[3]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -224,7 +362,7 @@ func (x *FindUsersRequest) String() string {
 func (*FindUsersRequest) ProtoMessage() {}
 
 func (x *FindUsersRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[3]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -237,7 +375,7 @@ func (x *FindUsersRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FindUsersRequest.ProtoReflect.Descriptor instead.
 func (*FindUsersRequest) Descriptor() ([]byte, []int) {
-	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{3}
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *FindUsersRequest) GetCountry() string {
@@ -261,21 +399,88 @@ func (x *FindUsersRequest) GetLimit() int32 {
 	return 0
 }
 
+func (x *FindUsersRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *FindUsersRequest) GetIncludeTotal() bool {
+	if x != nil {
+		return x.IncludeTotal
+	}
+	return false
+}
+
+func (x *FindUsersRequest) GetCountries() []string {
+	if x != nil {
+		return x.Countries
+	}
+	return nil
+}
+
+func (x *FindUsersRequest) GetNicknamePrefix() string {
+	if x != nil {
+		return x.NicknamePrefix
+	}
+	return ""
+}
+
+func (x *FindUsersRequest) GetEmailPrefix() string {
+	if x != nil {
+		return x.EmailPrefix
+	}
+	return ""
+}
+
+func (x *FindUsersRequest) GetCreatedFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedFrom
+	}
+	return nil
+}
+
+func (x *FindUsersRequest) GetCreatedTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return nil
+}
+
+func (x *FindUsersRequest) GetSortBy() string {
+	if x != nil {
+		return x.SortBy
+	}
+	return ""
+}
+
+func (x *FindUsersRequest) GetDescending() bool {
+	if x != nil {
+		return x.Descending
+	}
+	return false
+}
+
 type UserResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	FirstName     string                 `protobuf:"bytes,2,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
-	LastName      string                 `protobuf:"bytes,3,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
-	Nickname      string                 `protobuf:"bytes,4,opt,name=nickname,proto3" json:"nickname,omitempty"`
-	Email         string                 `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
-	Country       string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	FirstName string                 `protobuf:"bytes,2,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName  string                 `protobuf:"bytes,3,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	Nickname  string                 `protobuf:"bytes,4,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Email     string                 `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
+	Country   string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	// Only set for soft deleted users
+	DeletedAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`
+	// Incremented by every write
+	Version       int64 `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UserResponse) Reset() {
 	*x = UserResponse{}
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[4]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -287,7 +492,7 @@ func (x *UserResponse) String() string {
 func (*UserResponse) ProtoMessage() {}
 
 func (x *UserResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[4]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -300,7 +505,7 @@ func (x *UserResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UserResponse.ProtoReflect.Descriptor instead.
 func (*UserResponse) Descriptor() ([]byte, []int) {
-	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{4}
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *UserResponse) GetId() string {
@@ -345,16 +550,34 @@ func (x *UserResponse) GetCountry() string {
 	return ""
 }
 
+func (x *UserResponse) GetDeletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return nil
+}
+
+func (x *UserResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
 type UsersResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Users         []*UserResponse        `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Users []*UserResponse        `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	// Empty on the last page
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	// Only set with include_total
+	TotalCount    *int64 `protobuf:"varint,3,opt,name=total_count,json=totalCount,proto3,oneof" json:"total_count,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UsersResponse) Reset() {
 	*x = UsersResponse{}
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[5]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -366,7 +589,7 @@ func (x *UsersResponse) String() string {
 func (*UsersResponse) ProtoMessage() {}
 
 func (x *UsersResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[5]
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -379,7 +602,7 @@ func (x *UsersResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UsersResponse.ProtoReflect.Descriptor instead.
 func (*UsersResponse) Descriptor() ([]byte, []int) {
-	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{5}
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *UsersResponse) GetUsers() []*UserResponse {
@@ -389,27 +612,44 @@ func (x *UsersResponse) GetUsers() []*UserResponse {
 	return nil
 }
 
-type Empty struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+func (x *UsersResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *UsersResponse) GetTotalCount() int64 {
+	if x != nil && x.TotalCount != nil {
+		return *x.TotalCount
+	}
+	return 0
+}
+
+type SearchUsersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Misspelled nicknames and names are found too
+	Query         string `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Limit         int32  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Empty) Reset() {
-	*x = Empty{}
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[6]
+func (x *SearchUsersRequest) Reset() {
+	*x = SearchUsersRequest{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Empty) String() string {
+func (x *SearchUsersRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Empty) ProtoMessage() {}
+func (*SearchUsersRequest) ProtoMessage() {}
 
-func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[6]
+func (x *SearchUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -420,90 +660,1011 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
-func (*Empty) Descriptor() ([]byte, []int) {
-	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use SearchUsersRequest.ProtoReflect.Descriptor instead.
+func (*SearchUsersRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{7}
 }
 
-var File_pkg_challenge_proto_user_user_proto protoreflect.FileDescriptor
-
-const file_pkg_challenge_proto_user_user_proto_rawDesc = "" +
-	"\n" +
-	"#pkg/challenge/proto/user/user.proto\x12\x04user\"\xb7\x01\n" +
-	"\x11CreateUserRequest\x12\x1d\n" +
-	"\n" +
-	"first_name\x18\x01 \x01(\tR\tfirstName\x12\x1b\n" +
-	"\tlast_name\x18\x02 \x01(\tR\blastName\x12\x1a\n" +
-	"\bnickname\x18\x03 \x01(\tR\bnickname\x12\x1a\n" +
-	"\bpassword\x18\x04 \x01(\tR\bpassword\x12\x14\n" +
-	"\x05email\x18\x05 \x01(\tR\x05email\x12\x18\n" +
-	"\acountry\x18\x06 \x01(\tR\acountry\"?\n" +
-	"\x11UpdateUserRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
-	"\bnickname\x18\x02 \x01(\tR\bnickname\"#\n" +
-	"\x11DeleteUserRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"V\n" +
-	"\x10FindUsersRequest\x12\x18\n" +
-	"\acountry\x18\x01 \x01(\tR\acountry\x12\x12\n" +
-	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x14\n" +
-	"\x05limit\x18\x03 \x01(\x05R\x05limit\"\xa6\x01\n" +
-	"\fUserResponse\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
-	"\n" +
-	"first_name\x18\x02 \x01(\tR\tfirstName\x12\x1b\n" +
-	"\tlast_name\x18\x03 \x01(\tR\blastName\x12\x1a\n" +
-	"\bnickname\x18\x04 \x01(\tR\bnickname\x12\x14\n" +
-	"\x05email\x18\x05 \x01(\tR\x05email\x12\x18\n" +
-	"\acountry\x18\x06 \x01(\tR\acountry\"9\n" +
-	"\rUsersResponse\x12(\n" +
-	"\x05users\x18\x01 \x03(\v2\x12.user.UserResponseR\x05users\"\a\n" +
-	"\x05Empty2\xf1\x01\n" +
-	"\vUserService\x129\n" +
-	"\n" +
-	"CreateUser\x12\x17.user.CreateUserRequest\x1a\x12.user.UserResponse\x129\n" +
-	"\n" +
-	"UpdateUser\x12\x17.user.UpdateUserRequest\x1a\x12.user.UserResponse\x122\n" +
-	"\n" +
-	"DeleteUser\x12\x17.user.DeleteUserRequest\x1a\v.user.Empty\x128\n" +
-	"\tFindUsers\x12\x16.user.FindUsersRequest\x1a\x13.user.UsersResponseBBZ@github.com/nachoconques0/user_challenge_svc/pkg/proto/user.protob\x06proto3"
+func (x *SearchUsersRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
 
-var (
-	file_pkg_challenge_proto_user_user_proto_rawDescOnce sync.Once
-	file_pkg_challenge_proto_user_user_proto_rawDescData []byte
-)
+func (x *SearchUsersRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
 
-func file_pkg_challenge_proto_user_user_proto_rawDescGZIP() []byte {
-	file_pkg_challenge_proto_user_user_proto_rawDescOnce.Do(func() {
-		file_pkg_challenge_proto_user_user_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_challenge_proto_user_user_proto_rawDesc), len(file_pkg_challenge_proto_user_user_proto_rawDesc)))
-	})
-	return file_pkg_challenge_proto_user_user_proto_rawDescData
+type UserMatch struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	User  *UserResponse          `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	// Similarity to the query, from 0 to 1
+	Score         float64 `protobuf:"fixed64,2,opt,name=score,proto3" json:"score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-var file_pkg_challenge_proto_user_user_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
-var file_pkg_challenge_proto_user_user_proto_goTypes = []any{
-	(*CreateUserRequest)(nil), // 0: user.CreateUserRequest
-	(*UpdateUserRequest)(nil), // 1: user.UpdateUserRequest
-	(*DeleteUserRequest)(nil), // 2: user.DeleteUserRequest
-	(*FindUsersRequest)(nil),  // 3: user.FindUsersRequest
-	(*UserResponse)(nil),      // 4: user.UserResponse
-	(*UsersResponse)(nil),     // 5: user.UsersResponse
-	(*Empty)(nil),             // 6: user.Empty
+func (x *UserMatch) Reset() {
+	*x = UserMatch{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
-var file_pkg_challenge_proto_user_user_proto_depIdxs = []int32{
-	4, // 0: user.UsersResponse.users:type_name -> user.UserResponse
-	0, // 1: user.UserService.CreateUser:input_type -> user.CreateUserRequest
-	1, // 2: user.UserService.UpdateUser:input_type -> user.UpdateUserRequest
-	2, // 3: user.UserService.DeleteUser:input_type -> user.DeleteUserRequest
-	3, // 4: user.UserService.FindUsers:input_type -> user.FindUsersRequest
-	4, // 5: user.UserService.CreateUser:output_type -> user.UserResponse
-	4, // 6: user.UserService.UpdateUser:output_type -> user.UserResponse
-	6, // 7: user.UserService.DeleteUser:output_type -> user.Empty
-	5, // 8: user.UserService.FindUsers:output_type -> user.UsersResponse
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+
+func (x *UserMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserMatch) ProtoMessage() {}
+
+func (x *UserMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserMatch.ProtoReflect.Descriptor instead.
+func (*UserMatch) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UserMatch) GetUser() *UserResponse {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *UserMatch) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+type SearchUsersResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Best matches first
+	Matches       []*UserMatch `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchUsersResponse) Reset() {
+	*x = SearchUsersResponse{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchUsersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchUsersResponse) ProtoMessage() {}
+
+func (x *SearchUsersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchUsersResponse.ProtoReflect.Descriptor instead.
+func (*SearchUsersResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SearchUsersResponse) GetMatches() []*UserMatch {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+type ListUserEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	EventTypes    []string               `protobuf:"bytes,2,rep,name=event_types,json=eventTypes,proto3" json:"event_types,omitempty"`
+	From          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	To            *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	Page          int32                  `protobuf:"varint,5,opt,name=page,proto3" json:"page,omitempty"`
+	Limit         int32                  `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserEventsRequest) Reset() {
+	*x = ListUserEventsRequest{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserEventsRequest) ProtoMessage() {}
+
+func (x *ListUserEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserEventsRequest.ProtoReflect.Descriptor instead.
+func (*ListUserEventsRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListUserEventsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListUserEventsRequest) GetEventTypes() []string {
+	if x != nil {
+		return x.EventTypes
+	}
+	return nil
+}
+
+func (x *ListUserEventsRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *ListUserEventsRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *ListUserEventsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListUserEventsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type UserEventResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId    string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	EventType string                 `protobuf:"bytes,3,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	// JSON encoded event payload
+	Payload       string                 `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserEventResponse) Reset() {
+	*x = UserEventResponse{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserEventResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserEventResponse) ProtoMessage() {}
+
+func (x *UserEventResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserEventResponse.ProtoReflect.Descriptor instead.
+func (*UserEventResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *UserEventResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UserEventResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UserEventResponse) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *UserEventResponse) GetPayload() string {
+	if x != nil {
+		return x.Payload
+	}
+	return ""
+}
+
+func (x *UserEventResponse) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type UserEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*UserEventResponse   `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserEventsResponse) Reset() {
+	*x = UserEventsResponse{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserEventsResponse) ProtoMessage() {}
+
+func (x *UserEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserEventsResponse.ProtoReflect.Descriptor instead.
+func (*UserEventsResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *UserEventsResponse) GetEvents() []*UserEventResponse {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type WatchUsersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only stream changes of these users. Empty means every user
+	UserIds []string `protobuf:"bytes,1,rep,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"`
+	// Only stream changes of users from these countries. Empty means every country
+	Countries []string `protobuf:"bytes,2,rep,name=countries,proto3" json:"countries,omitempty"`
+	// Backfill every event stored after this one before streaming live changes
+	ResumeAfterEventId string `protobuf:"bytes,3,opt,name=resume_after_event_id,json=resumeAfterEventId,proto3" json:"resume_after_event_id,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *WatchUsersRequest) Reset() {
+	*x = WatchUsersRequest{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchUsersRequest) ProtoMessage() {}
+
+func (x *WatchUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchUsersRequest.ProtoReflect.Descriptor instead.
+func (*WatchUsersRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchUsersRequest) GetUserIds() []string {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+func (x *WatchUsersRequest) GetCountries() []string {
+	if x != nil {
+		return x.Countries
+	}
+	return nil
+}
+
+func (x *WatchUsersRequest) GetResumeAfterEventId() string {
+	if x != nil {
+		return x.ResumeAfterEventId
+	}
+	return ""
+}
+
+type UserChange struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	EventId string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	UserId  string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	TraceId string                 `protobuf:"bytes,3,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	// Types that are valid to be assigned to Change:
+	//
+	//	*UserChange_Created
+	//	*UserChange_Updated
+	//	*UserChange_Deleted
+	Change isUserChange_Change `protobuf_oneof:"change"`
+	// Version of the user after the change. 0 for older events
+	Version       int64 `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserChange) Reset() {
+	*x = UserChange{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserChange) ProtoMessage() {}
+
+func (x *UserChange) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserChange.ProtoReflect.Descriptor instead.
+func (*UserChange) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UserChange) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *UserChange) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UserChange) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+func (x *UserChange) GetChange() isUserChange_Change {
+	if x != nil {
+		return x.Change
+	}
+	return nil
+}
+
+func (x *UserChange) GetCreated() *UserCreated {
+	if x != nil {
+		if x, ok := x.Change.(*UserChange_Created); ok {
+			return x.Created
+		}
+	}
+	return nil
+}
+
+func (x *UserChange) GetUpdated() *UserUpdated {
+	if x != nil {
+		if x, ok := x.Change.(*UserChange_Updated); ok {
+			return x.Updated
+		}
+	}
+	return nil
+}
+
+func (x *UserChange) GetDeleted() *UserDeleted {
+	if x != nil {
+		if x, ok := x.Change.(*UserChange_Deleted); ok {
+			return x.Deleted
+		}
+	}
+	return nil
+}
+
+func (x *UserChange) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type isUserChange_Change interface {
+	isUserChange_Change()
+}
+
+type UserChange_Created struct {
+	Created *UserCreated `protobuf:"bytes,4,opt,name=created,proto3,oneof"`
+}
+
+type UserChange_Updated struct {
+	Updated *UserUpdated `protobuf:"bytes,5,opt,name=updated,proto3,oneof"`
+}
+
+type UserChange_Deleted struct {
+	Deleted *UserDeleted `protobuf:"bytes,6,opt,name=deleted,proto3,oneof"`
+}
+
+func (*UserChange_Created) isUserChange_Change() {}
+
+func (*UserChange_Updated) isUserChange_Change() {}
+
+func (*UserChange_Deleted) isUserChange_Change() {}
+
+type UserCreated struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FirstName     string                 `protobuf:"bytes,1,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName      string                 `protobuf:"bytes,2,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	Nickname      string                 `protobuf:"bytes,3,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Email         string                 `protobuf:"bytes,4,opt,name=email,proto3" json:"email,omitempty"`
+	Country       string                 `protobuf:"bytes,5,opt,name=country,proto3" json:"country,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserCreated) Reset() {
+	*x = UserCreated{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserCreated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserCreated) ProtoMessage() {}
+
+func (x *UserCreated) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserCreated.ProtoReflect.Descriptor instead.
+func (*UserCreated) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UserCreated) GetFirstName() string {
+	if x != nil {
+		return x.FirstName
+	}
+	return ""
+}
+
+func (x *UserCreated) GetLastName() string {
+	if x != nil {
+		return x.LastName
+	}
+	return ""
+}
+
+func (x *UserCreated) GetNickname() string {
+	if x != nil {
+		return x.Nickname
+	}
+	return ""
+}
+
+func (x *UserCreated) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *UserCreated) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+type UserUpdated struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Nickname string                 `protobuf:"bytes,1,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Country  string                 `protobuf:"bytes,2,opt,name=country,proto3" json:"country,omitempty"`
+	// Empty for older events, which only changed the nickname
+	Changes       []*FieldChange `protobuf:"bytes,3,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserUpdated) Reset() {
+	*x = UserUpdated{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserUpdated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserUpdated) ProtoMessage() {}
+
+func (x *UserUpdated) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserUpdated.ProtoReflect.Descriptor instead.
+func (*UserUpdated) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *UserUpdated) GetNickname() string {
+	if x != nil {
+		return x.Nickname
+	}
+	return ""
+}
+
+func (x *UserUpdated) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *UserUpdated) GetChanges() []*FieldChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+type FieldChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Old           string                 `protobuf:"bytes,2,opt,name=old,proto3" json:"old,omitempty"`
+	New           string                 `protobuf:"bytes,3,opt,name=new,proto3" json:"new,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldChange) Reset() {
+	*x = FieldChange{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldChange) ProtoMessage() {}
+
+func (x *FieldChange) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldChange.ProtoReflect.Descriptor instead.
+func (*FieldChange) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FieldChange) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *FieldChange) GetOld() string {
+	if x != nil {
+		return x.Old
+	}
+	return ""
+}
+
+func (x *FieldChange) GetNew() string {
+	if x != nil {
+		return x.New
+	}
+	return ""
+}
+
+type UserDeleted struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Country       string                 `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserDeleted) Reset() {
+	*x = UserDeleted{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserDeleted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserDeleted) ProtoMessage() {}
+
+func (x *UserDeleted) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserDeleted.ProtoReflect.Descriptor instead.
+func (*UserDeleted) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *UserDeleted) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_challenge_proto_user_user_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_pkg_challenge_proto_user_user_proto_rawDescGZIP(), []int{19}
+}
+
+var File_pkg_challenge_proto_user_user_proto protoreflect.FileDescriptor
+
+const file_pkg_challenge_proto_user_user_proto_rawDesc = "" +
+	"\n" +
+	"#pkg/challenge/proto/user/user.proto\x12\x04user\x1a google/protobuf/field_mask.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe0\x01\n" +
+	"\x11CreateUserRequest\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x01 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x02 \x01(\tR\blastName\x12\x1a\n" +
+	"\bnickname\x18\x03 \x01(\tR\bnickname\x12\x1a\n" +
+	"\bpassword\x18\x04 \x01(\tR\bpassword\x12\x14\n" +
+	"\x05email\x18\x05 \x01(\tR\x05email\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x12'\n" +
+	"\x0fidempotency_key\x18\a \x01(\tR\x0eidempotencyKey\"I\n" +
+	"\x0eGetUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12'\n" +
+	"\x0finclude_deleted\x18\x02 \x01(\bR\x0eincludeDeleted\"\x93\x02\n" +
+	"\x11UpdateUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bnickname\x18\x02 \x01(\tR\bnickname\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x03 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x04 \x01(\tR\blastName\x12\x14\n" +
+	"\x05email\x18\x05 \x01(\tR\x05email\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x12;\n" +
+	"\vupdate_mask\x18\a \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\x12)\n" +
+	"\x10expected_version\x18\b \x01(\x03R\x0fexpectedVersion\"N\n" +
+	"\x11DeleteUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12)\n" +
+	"\x10expected_version\x18\x02 \x01(\x03R\x0fexpectedVersion\"\xb7\x03\n" +
+	"\x10FindUsersRequest\x12\x18\n" +
+	"\acountry\x18\x01 \x01(\tR\acountry\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x04 \x01(\tR\tpageToken\x12#\n" +
+	"\rinclude_total\x18\x05 \x01(\bR\fincludeTotal\x12\x1c\n" +
+	"\tcountries\x18\x06 \x03(\tR\tcountries\x12'\n" +
+	"\x0fnickname_prefix\x18\a \x01(\tR\x0enicknamePrefix\x12!\n" +
+	"\femail_prefix\x18\b \x01(\tR\vemailPrefix\x12=\n" +
+	"\fcreated_from\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\vcreatedFrom\x129\n" +
+	"\n" +
+	"created_to\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedTo\x12\x17\n" +
+	"\asort_by\x18\v \x01(\tR\x06sortBy\x12\x1e\n" +
+	"\n" +
+	"descending\x18\f \x01(\bR\n" +
+	"descending\"\xfb\x01\n" +
+	"\fUserResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x02 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x03 \x01(\tR\blastName\x12\x1a\n" +
+	"\bnickname\x18\x04 \x01(\tR\bnickname\x12\x14\n" +
+	"\x05email\x18\x05 \x01(\tR\x05email\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x129\n" +
+	"\n" +
+	"deleted_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\x12\x18\n" +
+	"\aversion\x18\b \x01(\x03R\aversion\"\x97\x01\n" +
+	"\rUsersResponse\x12(\n" +
+	"\x05users\x18\x01 \x03(\v2\x12.user.UserResponseR\x05users\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12$\n" +
+	"\vtotal_count\x18\x03 \x01(\x03H\x00R\n" +
+	"totalCount\x88\x01\x01B\x0e\n" +
+	"\f_total_count\"@\n" +
+	"\x12SearchUsersRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"I\n" +
+	"\tUserMatch\x12&\n" +
+	"\x04user\x18\x01 \x01(\v2\x12.user.UserResponseR\x04user\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\"@\n" +
+	"\x13SearchUsersResponse\x12)\n" +
+	"\amatches\x18\x01 \x03(\v2\x0f.user.UserMatchR\amatches\"\xd7\x01\n" +
+	"\x15ListUserEventsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1f\n" +
+	"\vevent_types\x18\x02 \x03(\tR\n" +
+	"eventTypes\x12.\n" +
+	"\x04from\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12\x12\n" +
+	"\x04page\x18\x05 \x01(\x05R\x04page\x12\x14\n" +
+	"\x05limit\x18\x06 \x01(\x05R\x05limit\"\xb0\x01\n" +
+	"\x11UserEventResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x03 \x01(\tR\teventType\x12\x18\n" +
+	"\apayload\x18\x04 \x01(\tR\apayload\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"E\n" +
+	"\x12UserEventsResponse\x12/\n" +
+	"\x06events\x18\x01 \x03(\v2\x17.user.UserEventResponseR\x06events\"\x7f\n" +
+	"\x11WatchUsersRequest\x12\x19\n" +
+	"\buser_ids\x18\x01 \x03(\tR\auserIds\x12\x1c\n" +
+	"\tcountries\x18\x02 \x03(\tR\tcountries\x121\n" +
+	"\x15resume_after_event_id\x18\x03 \x01(\tR\x12resumeAfterEventId\"\x8c\x02\n" +
+	"\n" +
+	"UserChange\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x19\n" +
+	"\btrace_id\x18\x03 \x01(\tR\atraceId\x12-\n" +
+	"\acreated\x18\x04 \x01(\v2\x11.user.UserCreatedH\x00R\acreated\x12-\n" +
+	"\aupdated\x18\x05 \x01(\v2\x11.user.UserUpdatedH\x00R\aupdated\x12-\n" +
+	"\adeleted\x18\x06 \x01(\v2\x11.user.UserDeletedH\x00R\adeleted\x12\x18\n" +
+	"\aversion\x18\a \x01(\x03R\aversionB\b\n" +
+	"\x06change\"\x95\x01\n" +
+	"\vUserCreated\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x01 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x02 \x01(\tR\blastName\x12\x1a\n" +
+	"\bnickname\x18\x03 \x01(\tR\bnickname\x12\x14\n" +
+	"\x05email\x18\x04 \x01(\tR\x05email\x12\x18\n" +
+	"\acountry\x18\x05 \x01(\tR\acountry\"p\n" +
+	"\vUserUpdated\x12\x1a\n" +
+	"\bnickname\x18\x01 \x01(\tR\bnickname\x12\x18\n" +
+	"\acountry\x18\x02 \x01(\tR\acountry\x12+\n" +
+	"\achanges\x18\x03 \x03(\v2\x11.user.FieldChangeR\achanges\"G\n" +
+	"\vFieldChange\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x10\n" +
+	"\x03old\x18\x02 \x01(\tR\x03old\x12\x10\n" +
+	"\x03new\x18\x03 \x01(\tR\x03new\"'\n" +
+	"\vUserDeleted\x12\x18\n" +
+	"\acountry\x18\x01 \x01(\tR\acountry\"\a\n" +
+	"\x05Empty2\xee\x03\n" +
+	"\vUserService\x129\n" +
+	"\n" +
+	"CreateUser\x12\x17.user.CreateUserRequest\x1a\x12.user.UserResponse\x123\n" +
+	"\aGetUser\x12\x14.user.GetUserRequest\x1a\x12.user.UserResponse\x129\n" +
+	"\n" +
+	"UpdateUser\x12\x17.user.UpdateUserRequest\x1a\x12.user.UserResponse\x122\n" +
+	"\n" +
+	"DeleteUser\x12\x17.user.DeleteUserRequest\x1a\v.user.Empty\x128\n" +
+	"\tFindUsers\x12\x16.user.FindUsersRequest\x1a\x13.user.UsersResponse\x12B\n" +
+	"\vSearchUsers\x12\x18.user.SearchUsersRequest\x1a\x19.user.SearchUsersResponse\x12G\n" +
+	"\x0eListUserEvents\x12\x1b.user.ListUserEventsRequest\x1a\x18.user.UserEventsResponse\x129\n" +
+	"\n" +
+	"WatchUsers\x12\x17.user.WatchUsersRequest\x1a\x10.user.UserChange0\x01BBZ@github.com/nachoconques0/user_challenge_svc/pkg/proto/user.protob\x06proto3"
+
+var (
+	file_pkg_challenge_proto_user_user_proto_rawDescOnce sync.Once
+	file_pkg_challenge_proto_user_user_proto_rawDescData []byte
+)
+
+func file_pkg_challenge_proto_user_user_proto_rawDescGZIP() []byte {
+	file_pkg_challenge_proto_user_user_proto_rawDescOnce.Do(func() {
+		file_pkg_challenge_proto_user_user_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_challenge_proto_user_user_proto_rawDesc), len(file_pkg_challenge_proto_user_user_proto_rawDesc)))
+	})
+	return file_pkg_challenge_proto_user_user_proto_rawDescData
+}
+
+var file_pkg_challenge_proto_user_user_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_pkg_challenge_proto_user_user_proto_goTypes = []any{
+	(*CreateUserRequest)(nil),     // 0: user.CreateUserRequest
+	(*GetUserRequest)(nil),        // 1: user.GetUserRequest
+	(*UpdateUserRequest)(nil),     // 2: user.UpdateUserRequest
+	(*DeleteUserRequest)(nil),     // 3: user.DeleteUserRequest
+	(*FindUsersRequest)(nil),      // 4: user.FindUsersRequest
+	(*UserResponse)(nil),          // 5: user.UserResponse
+	(*UsersResponse)(nil),         // 6: user.UsersResponse
+	(*SearchUsersRequest)(nil),    // 7: user.SearchUsersRequest
+	(*UserMatch)(nil),             // 8: user.UserMatch
+	(*SearchUsersResponse)(nil),   // 9: user.SearchUsersResponse
+	(*ListUserEventsRequest)(nil), // 10: user.ListUserEventsRequest
+	(*UserEventResponse)(nil),     // 11: user.UserEventResponse
+	(*UserEventsResponse)(nil),    // 12: user.UserEventsResponse
+	(*WatchUsersRequest)(nil),     // 13: user.WatchUsersRequest
+	(*UserChange)(nil),            // 14: user.UserChange
+	(*UserCreated)(nil),           // 15: user.UserCreated
+	(*UserUpdated)(nil),           // 16: user.UserUpdated
+	(*FieldChange)(nil),           // 17: user.FieldChange
+	(*UserDeleted)(nil),           // 18: user.UserDeleted
+	(*Empty)(nil),                 // 19: user.Empty
+	(*fieldmaskpb.FieldMask)(nil), // 20: google.protobuf.FieldMask
+	(*timestamppb.Timestamp)(nil), // 21: google.protobuf.Timestamp
+}
+var file_pkg_challenge_proto_user_user_proto_depIdxs = []int32{
+	20, // 0: user.UpdateUserRequest.update_mask:type_name -> google.protobuf.FieldMask
+	21, // 1: user.FindUsersRequest.created_from:type_name -> google.protobuf.Timestamp
+	21, // 2: user.FindUsersRequest.created_to:type_name -> google.protobuf.Timestamp
+	21, // 3: user.UserResponse.deleted_at:type_name -> google.protobuf.Timestamp
+	5,  // 4: user.UsersResponse.users:type_name -> user.UserResponse
+	5,  // 5: user.UserMatch.user:type_name -> user.UserResponse
+	8,  // 6: user.SearchUsersResponse.matches:type_name -> user.UserMatch
+	21, // 7: user.ListUserEventsRequest.from:type_name -> google.protobuf.Timestamp
+	21, // 8: user.ListUserEventsRequest.to:type_name -> google.protobuf.Timestamp
+	21, // 9: user.UserEventResponse.created_at:type_name -> google.protobuf.Timestamp
+	11, // 10: user.UserEventsResponse.events:type_name -> user.UserEventResponse
+	15, // 11: user.UserChange.created:type_name -> user.UserCreated
+	16, // 12: user.UserChange.updated:type_name -> user.UserUpdated
+	18, // 13: user.UserChange.deleted:type_name -> user.UserDeleted
+	17, // 14: user.UserUpdated.changes:type_name -> user.FieldChange
+	0,  // 15: user.UserService.CreateUser:input_type -> user.CreateUserRequest
+	1,  // 16: user.UserService.GetUser:input_type -> user.GetUserRequest
+	2,  // 17: user.UserService.UpdateUser:input_type -> user.UpdateUserRequest
+	3,  // 18: user.UserService.DeleteUser:input_type -> user.DeleteUserRequest
+	4,  // 19: user.UserService.FindUsers:input_type -> user.FindUsersRequest
+	7,  // 20: user.UserService.SearchUsers:input_type -> user.SearchUsersRequest
+	10, // 21: user.UserService.ListUserEvents:input_type -> user.ListUserEventsRequest
+	13, // 22: user.UserService.WatchUsers:input_type -> user.WatchUsersRequest
+	5,  // 23: user.UserService.CreateUser:output_type -> user.UserResponse
+	5,  // 24: user.UserService.GetUser:output_type -> user.UserResponse
+	5,  // 25: user.UserService.UpdateUser:output_type -> user.UserResponse
+	19, // 26: user.UserService.DeleteUser:output_type -> user.Empty
+	6,  // 27: user.UserService.FindUsers:output_type -> user.UsersResponse
+	9,  // 28: user.UserService.SearchUsers:output_type -> user.SearchUsersResponse
+	12, // 29: user.UserService.ListUserEvents:output_type -> user.UserEventsResponse
+	14, // 30: user.UserService.WatchUsers:output_type -> user.UserChange
+	23, // [23:31] is the sub-list for method output_type
+	15, // [15:23] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_pkg_challenge_proto_user_user_proto_init() }
@@ -511,13 +1672,19 @@ func file_pkg_challenge_proto_user_user_proto_init() {
 	if File_pkg_challenge_proto_user_user_proto != nil {
 		return
 	}
+	file_pkg_challenge_proto_user_user_proto_msgTypes[6].OneofWrappers = []any{}
+	file_pkg_challenge_proto_user_user_proto_msgTypes[14].OneofWrappers = []any{
+		(*UserChange_Created)(nil),
+		(*UserChange_Updated)(nil),
+		(*UserChange_Deleted)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_challenge_proto_user_user_proto_rawDesc), len(file_pkg_challenge_proto_user_user_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -0,0 +1,35 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	eventIDKey   struct{}
+	eventTimeKey struct{}
+)
+
+// WithEventID returns a copy of ctx carrying the ID of the event being handled.
+// Publishers set it so handlers can tell events apart
+func WithEventID(ctx context.Context, eventID string) context.Context {
+	return context.WithValue(ctx, eventIDKey{}, eventID)
+}
+
+// EventIDFromContext returns the ID of the event being handled, if any
+func EventIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(eventIDKey{}).(string)
+	return id
+}
+
+// WithEventTime returns a copy of ctx carrying when the event being handled was stored.
+// Publishers set it so handlers don't mistake the delivery time for the event time
+func WithEventTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, eventTimeKey{}, t)
+}
+
+// EventTimeFromContext returns when the event being handled was stored, zero if unknown
+func EventTimeFromContext(ctx context.Context) time.Time {
+	t, _ := ctx.Value(eventTimeKey{}).(time.Time)
+	return t
+}
@@ -0,0 +1,26 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+)
+
+// Fanout publishes every event to all of its publishers
+type Fanout []Publisher
+
+// NewFanout returns a publisher that forwards every event to all the given publishers
+func NewFanout(pubs ...Publisher) Fanout {
+	return Fanout(pubs)
+}
+
+// Publish forwards the event to every publisher, even if some of them fail.
+// The returned error joins all the failures
+func (f Fanout) Publish(ctx context.Context, eventID string, eventType string, payload interface{}) error {
+	var errs []error
+	for _, p := range f {
+		if err := p.Publish(ctx, eventID, eventType, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
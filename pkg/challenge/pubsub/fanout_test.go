@@ -0,0 +1,37 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+)
+
+func TestFanout_Publish(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	first := mocks.NewMockPublisher(ctrl)
+	second := mocks.NewMockPublisher(ctrl)
+	fanout := pubsub.NewFanout(first, second)
+
+	t.Run("should publish to every publisher", func(t *testing.T) {
+		first.EXPECT().Publish(gomock.Any(), "id", "USER_CREATED", "payload").Return(nil)
+		second.EXPECT().Publish(gomock.Any(), "id", "USER_CREATED", "payload").Return(nil)
+
+		assert.NoError(t, fanout.Publish(context.Background(), "id", "USER_CREATED", "payload"))
+	})
+
+	t.Run("should keep publishing when one fails and return its error", func(t *testing.T) {
+		errDown := errors.New("down")
+		first.EXPECT().Publish(gomock.Any(), "id", "USER_CREATED", "payload").Return(errDown)
+		second.EXPECT().Publish(gomock.Any(), "id", "USER_CREATED", "payload").Return(nil)
+
+		assert.ErrorIs(t, fanout.Publish(context.Background(), "id", "USER_CREATED", "payload"), errDown)
+	})
+}
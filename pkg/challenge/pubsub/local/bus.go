@@ -2,60 +2,66 @@ package local
 
 import (
 	"context"
+	"fmt"
+	"runtime/debug"
 	"sync"
 
-	"gorm.io/gorm"
-
 	"github.com/rs/zerolog/log"
 
-	eventUser "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
 )
 
+// Bus is an in-process publisher/subscriber. Marking events as published
+// is up to the caller (aggregate or outbox relay), the bus only dispatches
 type Bus struct {
-	db          *gorm.DB
+	opts        Options
 	mu          sync.RWMutex
 	subscribers map[string][]pubsub.HandlerFunc
 }
 
-func NewBus(db *gorm.DB) *Bus {
+func NewBus(opts ...Option) *Bus {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
 	return &Bus{
-		db:          db,
+		opts:        options,
 		subscribers: make(map[string][]pubsub.HandlerFunc),
 	}
 }
 
 func (b *Bus) Publish(ctx context.Context, eventID string, eventType string, payload any) error {
-	tx := b.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-
-	err := tx.Model(&eventUser.User{}).
-		Where("id = ?", eventID).
-		Update("published", true).Error
-	if err != nil {
-		tx.Rollback()
-		log.Error().Err(err).Str("event_id", eventID).Msg("failed to update published event")
-		return err
-	}
-
 	b.mu.RLock()
 	handlers := b.subscribers[eventType]
 	b.mu.RUnlock()
 
-	if err := tx.Commit().Error; err != nil {
-		log.Error().Err(err).Str("event_id", eventID).Msg("failed to commit after event publish")
-		return err
-	}
+	ctx, span := tracing.Start(ctx, "publish "+eventType, tracing.WithKind(tracing.KindProducer), tracing.WithAttributes(map[string]any{
+		"event.id":   eventID,
+		"event.type": eventType,
+	}))
+	defer span.End()
 
-	log.Info().
+	log.Info().Ctx(ctx).
 		Str("event_type", eventType).
 		Str("event_id", eventID).
-		Msg("event published and marked as published in DB")
+		Msg("event published")
+	b.opts.Metrics.EventPublished(eventType)
 
+	// Handlers outlive the publisher call, they only get the event and trace IDs and the
+	// event time, not the request context, which is canceled and may be reused. Their
+	// spans are children of the publish one
+	handlerCtx := pubsub.WithEventID(context.Background(), eventID)
+	if t := pubsub.EventTimeFromContext(ctx); !t.IsZero() {
+		handlerCtx = pubsub.WithEventTime(handlerCtx, t)
+	}
+	handlerCtx = tracectx.WithTraceID(handlerCtx, tracectx.TraceIDFromContext(ctx))
+	ctx = tracing.ContextWithSpanContext(handlerCtx, span.SpanContext())
 	for _, handler := range handlers {
-		go handler(ctx, payload)
+		b.opts.Metrics.HandlerStarted(eventType)
+		go b.handle(ctx, eventType, handler, payload)
 	}
 
 	return nil
@@ -67,3 +73,29 @@ func (b *Bus) Subscribe(event string, handler pubsub.HandlerFunc) error {
 	b.subscribers[event] = append(b.subscribers[event], handler)
 	return nil
 }
+
+// handle runs a handler in its own span. A panicking handler is logged and counted
+// as failed instead of taking the whole process down
+func (b *Bus) handle(ctx context.Context, eventType string, handler pubsub.HandlerFunc, payload any) {
+	ctx, span := tracing.Start(ctx, "handle "+eventType, tracing.WithKind(tracing.KindConsumer), tracing.WithAttributes(map[string]any{
+		"event.id":   pubsub.EventIDFromContext(ctx),
+		"event.type": eventType,
+	}))
+	failed := false
+	defer func() {
+		if r := recover(); r != nil {
+			failed = true
+			span.RecordError(fmt.Errorf("handler panicked: %v", r))
+			log.Error().Ctx(ctx).
+				Str("event_type", eventType).
+				Str("event_id", pubsub.EventIDFromContext(ctx)).
+				Interface("panic", r).
+				Bytes("stack", debug.Stack()).
+				Msg("event handler panicked")
+		}
+		b.opts.Metrics.HandlerFinished(eventType, failed)
+		span.End()
+	}()
+
+	handler(ctx, payload)
+}
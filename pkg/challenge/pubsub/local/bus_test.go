@@ -0,0 +1,118 @@
+package local_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub/local"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+func TestBus_Metrics(t *testing.T) {
+	m := metrics.New()
+	bus := local.NewBus(local.WithMetrics(m))
+
+	var wg sync.WaitGroup
+	release := make(chan struct{})
+	assert.NoError(t, bus.Subscribe("USER_CREATED", func(ctx context.Context, _ interface{}) {
+		defer wg.Done()
+		<-release
+		assert.Equal(t, "event-1", pubsub.EventIDFromContext(ctx))
+	}))
+	assert.NoError(t, bus.Subscribe("USER_CREATED", func(context.Context, interface{}) {
+		defer wg.Done()
+		<-release
+		panic("boom")
+	}))
+
+	wg.Add(2)
+	assert.NoError(t, bus.Publish(context.Background(), "event-1", "USER_CREATED", nil))
+
+	t.Run("should count the handler runs in flight", func(t *testing.T) {
+		body, err := helpers.ScrapeMetrics(m)
+		assert.NoError(t, err)
+		assert.Contains(t, body, `challenge_bus_published_total{event_type="USER_CREATED"} 1`)
+		assert.Contains(t, body, `challenge_bus_queue_depth{event_type="USER_CREATED"} 2`)
+	})
+
+	close(release)
+	wg.Wait()
+
+	t.Run("should count the finished and failed handler runs", func(t *testing.T) {
+		assert.Eventually(t, func() bool {
+			body, err := helpers.ScrapeMetrics(m)
+			return err == nil &&
+				strings.Contains(body, `challenge_bus_queue_depth{event_type="USER_CREATED"} 0`) &&
+				strings.Contains(body, `challenge_bus_handled_total{event_type="USER_CREATED"} 2`) &&
+				strings.Contains(body, `challenge_bus_handler_failures_total{event_type="USER_CREATED"} 1`)
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestBus_EventTime(t *testing.T) {
+	bus := local.NewBus()
+	createdAt := time.Date(2025, 5, 12, 8, 30, 0, 0, time.UTC)
+
+	handled := make(chan time.Time, 2)
+	assert.NoError(t, bus.Subscribe("USER_CREATED", func(ctx context.Context, _ interface{}) {
+		handled <- pubsub.EventTimeFromContext(ctx)
+	}))
+
+	t.Run("should give handlers the time the event was stored", func(t *testing.T) {
+		assert.NoError(t, bus.Publish(pubsub.WithEventTime(context.Background(), createdAt), "event-1", "USER_CREATED", nil))
+		assert.Equal(t, createdAt, <-handled)
+	})
+
+	t.Run("should leave the time out when the publisher does not know it", func(t *testing.T) {
+		assert.NoError(t, bus.Publish(context.Background(), "event-2", "USER_CREATED", nil))
+		assert.True(t, (<-handled).IsZero())
+	})
+}
+
+func TestBus_Tracing(t *testing.T) {
+	exporter := tracing.NewMemoryExporter()
+	tracing.SetExporter(exporter)
+	defer tracing.SetExporter(nil)
+
+	bus := local.NewBus()
+	assert.NoError(t, bus.Subscribe("USER_CREATED", func(context.Context, interface{}) {}))
+	assert.NoError(t, bus.Subscribe("USER_CREATED", func(context.Context, interface{}) {
+		panic("boom")
+	}))
+
+	ctx, parent := tracing.Start(context.Background(), "userService.Create")
+	assert.NoError(t, bus.Publish(ctx, "event-1", "USER_CREATED", nil))
+	parent.End()
+
+	assert.Eventually(t, func() bool {
+		return len(exporter.Spans()) == 4
+	}, time.Second, 10*time.Millisecond)
+
+	publish, ok := exporter.Find("publish USER_CREATED")
+	assert.True(t, ok)
+	assert.Equal(t, tracing.KindProducer, publish.Kind)
+	assert.Equal(t, parent.SpanContext().SpanID, publish.ParentSpanID)
+
+	failed := 0
+	for _, s := range exporter.Spans() {
+		if s.Name != "handle USER_CREATED" {
+			continue
+		}
+		assert.Equal(t, tracing.KindConsumer, s.Kind)
+		assert.Equal(t, publish.SpanID, s.ParentSpanID)
+		assert.Equal(t, publish.TraceID, s.TraceID)
+		assert.Equal(t, "event-1", s.Attributes["event.id"])
+		if s.Status == tracing.StatusError {
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+}
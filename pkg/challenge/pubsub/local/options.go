@@ -0,0 +1,22 @@
+package local
+
+import "github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+
+// Retrieve the default options
+func defaultOptions() Options {
+	return Options{}
+}
+
+type Options struct {
+	// Metrics records the published events and the handler runs, nil disables them
+	Metrics *metrics.Metrics
+}
+
+// WithMetrics records the published events and the handler runs
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+type Option func(*Options)
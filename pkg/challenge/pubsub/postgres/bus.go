@@ -0,0 +1,275 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	dbInstance "github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+const (
+	// ErrMissingDB used when DB is nil
+	ErrMissingDB = "Bus is missing DB connection"
+	// ErrTransaction used when DB is a transaction. Notifications are only sent on commit
+	// and the listener needs its own connection from the pool
+	ErrTransaction = "Bus can not run on a DB transaction"
+	// ErrUnknownEventType used when subscribing to something that is not an user event
+	ErrUnknownEventType = "Bus only carries user events"
+)
+
+// Bus is a publisher/subscriber backed by Postgres LISTEN/NOTIFY, so every instance
+// sharing the database sees the events published by any of them.
+// Only the event ID travels in the notification, listeners load the full event
+// from challenge.user_event. Notifications sent while a listener is disconnected are lost
+type Bus struct {
+	db   *gorm.DB
+	opts Options
+
+	mu          sync.RWMutex
+	subscribers map[string][]pubsub.HandlerFunc
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	stopOnce  sync.Once
+	listening atomic.Bool
+}
+
+// New returns a new Postgres bus
+func New(db *gorm.DB, opts ...Option) (*Bus, error) {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
+	switch {
+	case db == nil:
+		return nil, errors.New(ErrMissingDB)
+	case dbInstance.IsTransaction(db):
+		return nil, errors.New(ErrTransaction)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Bus{
+		db:          db,
+		opts:        options,
+		subscribers: make(map[string][]pubsub.HandlerFunc),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}, nil
+}
+
+// Channel returns the NOTIFY channel used for the given event type
+func (b *Bus) Channel(eventType string) string {
+	return b.opts.ChannelPrefix + strings.ToLower(eventType)
+}
+
+// Publish notifies every listening instance about the event. The event must
+// already be committed in challenge.user_event, the payload is not sent
+func (b *Bus) Publish(ctx context.Context, eventID string, eventType string, _ any) error {
+	ctx, span := tracing.Start(ctx, "publish "+eventType, tracing.WithKind(tracing.KindProducer), tracing.WithAttributes(map[string]any{
+		"event.id":   eventID,
+		"event.type": eventType,
+	}))
+	defer span.End()
+
+	if err := b.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", b.Channel(eventType), eventID).Error; err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	log.Info().Ctx(ctx).
+		Str("event_type", eventType).
+		Str("event_id", eventID).
+		Msg("event published")
+	return nil
+}
+
+// Subscribe registers a handler for an user event type. Handlers are called
+// for the events published by any instance, including this one
+func (b *Bus) Subscribe(eventType string, handler pubsub.HandlerFunc) error {
+	if !event.IsValidType(eventType) {
+		return fmt.Errorf("%s: %s", ErrUnknownEventType, eventType)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[eventType] = append(b.subscribers[eventType], handler)
+	return nil
+}
+
+// Run listens for notifications until Stop is called, reconnecting on errors.
+// This method will block the calling go routine
+func (b *Bus) Run() error {
+	defer close(b.done)
+	log.Info().Msg("Postgres bus: listening...")
+
+	for {
+		err := b.listen()
+		if b.ctx.Err() != nil {
+			return nil
+		}
+		log.Error().Err(err).Msgf("Postgres bus: listener failed, reconnecting in %s", b.opts.ReconnectDelay)
+
+		select {
+		case <-b.ctx.Done():
+			return nil
+		case <-time.After(b.opts.ReconnectDelay):
+		}
+	}
+}
+
+// Stop stops listening and closes the listener connection
+func (b *Bus) Stop(ctx context.Context) error {
+	log.Info().Msg("Postgres bus: stopping...")
+	b.stopOnce.Do(b.cancel)
+
+	select {
+	case <-b.done:
+		log.Info().Msg("Postgres bus: stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Running reports whether the listener is connected and LISTENing, notifications
+// sent while it is not are lost
+func (b *Bus) Running() bool {
+	return b.listening.Load()
+}
+
+// listen takes a connection out of the pool, LISTENs on every event channel and
+// dispatches the notifications until the context is done or the connection breaks
+func (b *Bus) listen() error {
+	sqlDB, err := b.db.DB()
+	if err != nil {
+		return err
+	}
+
+	conn, err := sqlDB.Conn(b.ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	channels := make(map[string]string, len(event.Types))
+	for _, t := range event.Types {
+		channels[b.Channel(t)] = t
+	}
+
+	var listenErr error
+	// The connection is always discarded afterwards (driver.ErrBadConn) so
+	// it never goes back to the pool still subscribed to the channels
+	_ = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			listenErr = fmt.Errorf("unexpected driver connection %T", driverConn)
+			return driver.ErrBadConn
+		}
+		pgConn := c.Conn()
+
+		for channel := range channels {
+			if _, err := pgConn.Exec(b.ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+				listenErr = err
+				return driver.ErrBadConn
+			}
+		}
+		b.listening.Store(true)
+		defer b.listening.Store(false)
+
+		for {
+			n, err := pgConn.WaitForNotification(b.ctx)
+			if err != nil {
+				listenErr = err
+				return driver.ErrBadConn
+			}
+			if eventType, ok := channels[n.Channel]; ok {
+				b.dispatch(eventType, n.Payload)
+			}
+		}
+	})
+	return listenErr
+}
+
+// dispatch loads the notified event and calls the handlers subscribed to its type.
+// Each handler runs in a span child of the span that caused the event
+func (b *Bus) dispatch(eventType, eventID string) {
+	b.mu.RLock()
+	handlers := b.subscribers[eventType]
+	b.mu.RUnlock()
+	if len(handlers) == 0 {
+		return
+	}
+
+	id, err := uuid.Parse(eventID)
+	if err != nil {
+		log.Error().Err(err).Str("event_type", eventType).Msg("Postgres bus: invalid event ID in notification")
+		return
+	}
+
+	e, err := repo.GetEvent(id, b.db.WithContext(b.ctx))
+	if err != nil {
+		log.Error().Err(err).Str("event_id", eventID).Msg("Postgres bus: could not load event")
+		return
+	}
+
+	payload, err := e.DecodePayload()
+	if err != nil {
+		log.Error().Err(err).Str("event_id", eventID).Msg("Postgres bus: could not decode event")
+		return
+	}
+
+	ctx := pubsub.WithEventTime(pubsub.WithEventID(b.ctx, eventID), e.CreatedAt)
+	ctx = tracectx.WithTraceID(ctx, e.TraceID)
+	parent := tracing.SpanContext{TraceID: e.TraceID, SpanID: e.ParentSpanID()}
+	for _, handler := range handlers {
+		b.opts.Metrics.HandlerStarted(eventType)
+		go b.handle(ctx, parent, eventType, handler, payload)
+	}
+}
+
+// handle runs a handler in its own span. A panicking handler is logged and counted
+// as failed instead of taking the whole process down
+func (b *Bus) handle(ctx context.Context, parent tracing.SpanContext, eventType string, handler pubsub.HandlerFunc, payload any) {
+	ctx, span := tracing.Start(ctx, "handle "+eventType, tracing.WithKind(tracing.KindConsumer), tracing.WithParent(parent), tracing.WithAttributes(map[string]any{
+		"event.id":   pubsub.EventIDFromContext(ctx),
+		"event.type": eventType,
+	}))
+	failed := false
+	defer func() {
+		if r := recover(); r != nil {
+			failed = true
+			span.RecordError(fmt.Errorf("handler panicked: %v", r))
+			log.Error().Ctx(ctx).
+				Str("event_type", eventType).
+				Str("event_id", pubsub.EventIDFromContext(ctx)).
+				Interface("panic", r).
+				Bytes("stack", debug.Stack()).
+				Msg("event handler panicked")
+		}
+		b.opts.Metrics.HandlerFinished(eventType, failed)
+		span.End()
+	}()
+
+	handler(ctx, payload)
+}
@@ -0,0 +1,120 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub/postgres"
+)
+
+func TestBus(t *testing.T) {
+	db, teardown, err := helpers.NewTestConn()
+	assert.NoError(t, err)
+	defer teardown()
+
+	// Two buses on the same database act as two instances
+	publisherBus, err := postgres.New(db, postgres.WithChannelPrefix("test_"))
+	assert.NoError(t, err)
+	listenerBus, err := postgres.New(db, postgres.WithChannelPrefix("test_"))
+	assert.NoError(t, err)
+
+	type received struct {
+		eventID string
+		payload interface{}
+	}
+	got := make(chan received, 1)
+	assert.NoError(t, listenerBus.Subscribe(event.UserCreated, func(ctx context.Context, payload interface{}) {
+		select {
+		case got <- received{eventID: pubsub.EventIDFromContext(ctx), payload: payload}:
+		default:
+		}
+	}))
+
+	assert.False(t, listenerBus.Running())
+	go func() { _ = listenerBus.Run() }()
+	defer func() { _ = listenerBus.Stop(context.Background()) }()
+
+	t.Run("should be running once listening", func(t *testing.T) {
+		assert.Eventually(t, listenerBus.Running, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should deliver events published by another instance", func(t *testing.T) {
+		userID := uuid.New()
+		e := event.User{
+			ID:        uuid.New(),
+			UserID:    userID,
+			EventType: event.UserCreated,
+			Payload:   []byte(`{"user_id":"` + userID.String() + `","nickname":"pg-bus"}`),
+			Published: true,
+		}
+		assert.NoError(t, db.Create(&e).Error)
+		defer db.Delete(&event.User{}, "id = ?", e.ID)
+
+		// The listener may still be connecting, publish until it gets it
+		var r received
+		assert.Eventually(t, func() bool {
+			assert.NoError(t, publisherBus.Publish(context.Background(), e.ID.String(), event.UserCreated, nil))
+			select {
+			case r = <-got:
+				return true
+			case <-time.After(50 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+
+		assert.Equal(t, e.ID.String(), r.eventID)
+		payload, ok := r.payload.(event.CreatedPayload)
+		assert.True(t, ok)
+		assert.Equal(t, "pg-bus", payload.Nickname)
+	})
+
+	t.Run("should keep listening when a handler panics", func(t *testing.T) {
+		panicked := make(chan struct{}, 1)
+		assert.NoError(t, listenerBus.Subscribe(event.UserSoftDeleted, func(context.Context, interface{}) {
+			select {
+			case panicked <- struct{}{}:
+			default:
+			}
+			panic("boom")
+		}))
+
+		userID := uuid.New()
+		e := event.User{
+			ID:        uuid.New(),
+			UserID:    userID,
+			EventType: event.UserSoftDeleted,
+			Payload:   []byte(`{"user_id":"` + userID.String() + `"}`),
+			Published: true,
+		}
+		assert.NoError(t, db.Create(&e).Error)
+		defer db.Delete(&event.User{}, "id = ?", e.ID)
+
+		// The listener has to listen on the new channel first, publish until it gets it
+		assert.Eventually(t, func() bool {
+			assert.NoError(t, publisherBus.Publish(context.Background(), e.ID.String(), event.UserSoftDeleted, nil))
+			select {
+			case <-panicked:
+				return true
+			case <-time.After(50 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.True(t, listenerBus.Running())
+	})
+
+	t.Run("should refuse non user events and transactions", func(t *testing.T) {
+		assert.Error(t, listenerBus.Subscribe("SOMETHING_ELSE", func(context.Context, interface{}) {}))
+
+		tx := db.Begin()
+		defer tx.Rollback()
+		_, err := postgres.New(tx)
+		assert.Error(t, err)
+	})
+}
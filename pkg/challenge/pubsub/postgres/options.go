@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+)
+
+// Retrieve the default options
+func defaultOptions() Options {
+	return Options{
+		ChannelPrefix:  "challenge_",
+		ReconnectDelay: time.Second,
+	}
+}
+
+type Options struct {
+	// ChannelPrefix is prepended to the lower cased event type to build the channel name
+	ChannelPrefix string
+	// ReconnectDelay is how long the listener waits before connecting again after an error
+	ReconnectDelay time.Duration
+	// Metrics records the handler runs, nil disables them. Published events are counted
+	// by the local bus, which gets them too
+	Metrics *metrics.Metrics
+}
+
+// WithChannelPrefix sets the prefix of the NOTIFY channels
+func WithChannelPrefix(p string) Option {
+	return func(o *Options) {
+		o.ChannelPrefix = p
+	}
+}
+
+// WithReconnectDelay sets how long the listener waits before connecting again
+func WithReconnectDelay(d time.Duration) Option {
+	return func(o *Options) {
+		o.ReconnectDelay = d
+	}
+}
+
+// WithMetrics records the handler runs
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+type Option func(*Options)
@@ -0,0 +1,69 @@
+package relay
+
+import "time"
+
+// Retrieve the default options
+func defaultOptions() Options {
+	return Options{
+		PollInterval:   time.Second,
+		BatchSize:      100,
+		MinAge:         5 * time.Second,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Minute,
+		ClaimTimeout:   time.Minute,
+	}
+}
+
+type Options struct {
+	// PollInterval is how often the relay looks for unpublished events
+	PollInterval time.Duration
+	// BatchSize is the max number of events handled per poll
+	BatchSize int
+	// MinAge gives the aggregate time to publish an event itself before the relay takes over
+	MinAge time.Duration
+	// InitialBackoff is the delay after the first failed delivery, doubled on every retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+	// ClaimTimeout is how long the events of a batch are left to the relay publishing
+	// them before other relays take them over
+	ClaimTimeout time.Duration
+}
+
+// WithPollInterval sets how often the relay polls the outbox
+func WithPollInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.PollInterval = d
+	}
+}
+
+// WithBatchSize sets the max number of events handled per poll
+func WithBatchSize(n int) Option {
+	return func(o *Options) {
+		o.BatchSize = n
+	}
+}
+
+// WithMinAge sets how old an event must be before the relay picks it up
+func WithMinAge(d time.Duration) Option {
+	return func(o *Options) {
+		o.MinAge = d
+	}
+}
+
+// WithBackoff sets the initial and max delay between delivery retries
+func WithBackoff(initial, maxBackoff time.Duration) Option {
+	return func(o *Options) {
+		o.InitialBackoff = initial
+		o.MaxBackoff = maxBackoff
+	}
+}
+
+// WithClaimTimeout sets how long a batch is left to the relay publishing it
+func WithClaimTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.ClaimTimeout = d
+	}
+}
+
+type Option func(*Options)
@@ -0,0 +1,244 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	dbInstance "github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/env"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+const (
+	// ErrMissingDB used when DB is nil
+	ErrMissingDB = "Relay is missing DB connection"
+	// ErrMissingPublisher used when publisher is nil
+	ErrMissingPublisher = "Relay is missing publisher"
+	// ErrMissingTestEnv when test env is missing
+	ErrMissingTestEnv = "DB connection can only be a TX when ENV == env.Test"
+)
+
+// Relay is the outbox worker. It republishes every event of challenge.user_event
+// that is still unpublished, either because the process died right after the commit
+// or because the publisher failed
+type Relay struct {
+	db        *gorm.DB
+	testTx    bool
+	publisher pubsub.Publisher
+	opts      Options
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+	running  atomic.Bool
+}
+
+// New returns a new outbox relay
+func New(db *gorm.DB, e string, pub pubsub.Publisher, opts ...Option) (*Relay, error) {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Relay{
+		db:        db,
+		publisher: pub,
+		opts:      options,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	switch {
+	case db == nil:
+		return nil, errors.New(ErrMissingDB)
+	case pub == nil:
+		return nil, errors.New(ErrMissingPublisher)
+	case dbInstance.IsTransaction(db) && !env.IsTest(e):
+		return nil, errors.New(ErrMissingTestEnv)
+	case dbInstance.IsTransaction(db) && env.IsTest(e):
+		r.testTx = true
+	}
+
+	return r, nil
+}
+
+// Run polls the outbox until Stop is called.
+// This method will block the calling go routine
+func (r *Relay) Run() error {
+	r.running.Store(true)
+	defer close(r.done)
+	defer r.running.Store(false)
+	log.Info().Msgf("Outbox relay: polling every %s", r.opts.PollInterval)
+
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := r.Process(r.ctx); err != nil {
+				log.Error().Err(err).Msg("Outbox relay: could not process events")
+			}
+		}
+	}
+}
+
+// Stop stops polling and waits for the batch in flight to finish
+func (r *Relay) Stop(ctx context.Context) error {
+	log.Info().Msg("Outbox relay: stopping...")
+	r.stopOnce.Do(r.cancel)
+
+	select {
+	case <-r.done:
+		log.Info().Msg("Outbox relay: stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Running reports whether Run is polling the outbox
+func (r *Relay) Running() bool {
+	return r.running.Load()
+}
+
+// Process delivers one batch of unpublished events and returns how many were published.
+// The batch is claimed in a short transaction and published with no transaction open,
+// so a slow publisher does not keep the events locked. Failed deliveries are rescheduled
+// with exponential backoff
+func (r *Relay) Process(ctx context.Context) (int, error) {
+	events, err := r.claim()
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	type failure struct {
+		id       uuid.UUID
+		attempts int
+		reason   string
+	}
+	published := make([]uuid.UUID, 0, len(events))
+	var failures []failure
+	for _, e := range events {
+		// Subscribers log with the trace ID of the request that caused the event, and
+		// the relay span is a child of the span that caused it
+		eventCtx := pubsub.WithEventTime(tracectx.WithTraceID(ctx, e.TraceID), e.CreatedAt)
+		eventCtx, span := tracing.Start(eventCtx, "relay "+e.EventType,
+			tracing.WithParent(tracing.SpanContext{TraceID: e.TraceID, SpanID: e.ParentSpanID()}),
+			tracing.WithAttributes(map[string]any{
+				"event.id":       e.ID.String(),
+				"event.type":     e.EventType,
+				"event.attempts": e.Attempts,
+			}))
+		payload, err := e.DecodePayload()
+		if err == nil {
+			err = r.publisher.Publish(eventCtx, e.ID.String(), e.EventType, payload)
+		}
+		span.RecordError(err)
+		span.End()
+		if err != nil {
+			attempts := e.Attempts + 1
+			log.Error().Ctx(eventCtx).Err(err).
+				Str("event_id", e.ID.String()).
+				Int("attempts", attempts).
+				Msg("Outbox relay: could not publish event")
+			failures = append(failures, failure{id: e.ID, attempts: attempts, reason: err.Error()})
+			continue
+		}
+		published = append(published, e.ID)
+	}
+
+	tx := r.begin()
+	defer r.rollback(tx)
+
+	if err := repo.MarkEventsPublished(published, tx); err != nil {
+		return 0, err
+	}
+	for _, f := range failures {
+		if err := repo.MarkEventFailed(f.id, f.attempts, time.Now().Add(r.backoff(f.attempts)), f.reason, tx); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := r.commit(tx); err != nil {
+		return 0, err
+	}
+	return len(published), nil
+}
+
+// claim returns a batch of unpublished events, pushing their next attempt until the
+// claim expires. Events published when the claim expires are published again, which
+// subscribers have to bear with anyway
+func (r *Relay) claim() ([]event.User, error) {
+	tx := r.begin()
+	defer r.rollback(tx)
+
+	events, err := repo.FindUnpublishedEvents(tx, time.Now().Add(-r.opts.MinAge), r.opts.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	if err := repo.ClaimEvents(ids, time.Now().Add(r.opts.ClaimTimeout), tx); err != nil {
+		return nil, err
+	}
+
+	if err := r.commit(tx); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// backoff returns the delay before the given attempt is retried
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.opts.InitialBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= r.opts.MaxBackoff {
+			return r.opts.MaxBackoff
+		}
+	}
+	return d
+}
+
+func (r *Relay) begin() *gorm.DB {
+	if r.testTx {
+		return r.db
+	}
+	return r.db.Begin()
+}
+
+func (r *Relay) commit(tx *gorm.DB) error {
+	if !r.testTx {
+		return tx.Commit().Error
+	}
+	return nil
+}
+
+func (r *Relay) rollback(tx *gorm.DB) {
+	if !r.testTx {
+		tx.Rollback()
+	}
+}
@@ -0,0 +1,141 @@
+package relay_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	eventUser "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/mocks"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub/relay"
+)
+
+func TestRelay_Process(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPublisher := mocks.NewMockPublisher(ctrl)
+	r, err := relay.New(db, "test", mockPublisher, relay.WithMinAge(0))
+	assert.NoError(t, err)
+
+	t.Run("should publish pending events and mark them as published", func(t *testing.T) {
+		pending := insertPendingEvent(t, db)
+
+		mockPublisher.EXPECT().
+			Publish(gomock.Any(), pending.ID.String(), eventUser.UserCreated, gomock.AssignableToTypeOf(eventUser.CreatedPayload{})).
+			Return(nil)
+
+		published, err := r.Process(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, published)
+
+		var stored eventUser.User
+		err = db.First(&stored, "id = ?", pending.ID).Error
+		assert.NoError(t, err)
+		assert.True(t, stored.Published)
+	})
+
+	t.Run("should reschedule events that could not be published", func(t *testing.T) {
+		pending := insertPendingEvent(t, db)
+
+		mockPublisher.EXPECT().
+			Publish(gomock.Any(), pending.ID.String(), eventUser.UserCreated, gomock.Any()).
+			Return(errors.New("bus is down"))
+
+		published, err := r.Process(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, published)
+
+		var stored eventUser.User
+		err = db.First(&stored, "id = ?", pending.ID).Error
+		assert.NoError(t, err)
+		assert.False(t, stored.Published)
+		assert.Equal(t, 1, stored.Attempts)
+		assert.Equal(t, "bus is down", stored.LastError)
+		assert.NotNil(t, stored.NextAttemptAt)
+
+		// Not due yet, so the next poll must skip it
+		published, err = r.Process(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, published)
+	})
+
+	t.Run("should not hand the events being published to other relays", func(t *testing.T) {
+		pending := insertPendingEvent(t, db)
+		other, err := relay.New(db, "test", mockPublisher, relay.WithMinAge(0))
+		assert.NoError(t, err)
+
+		mockPublisher.EXPECT().
+			Publish(gomock.Any(), pending.ID.String(), eventUser.UserCreated, gomock.Any()).
+			DoAndReturn(func(context.Context, string, string, interface{}) error {
+				published, err := other.Process(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, 0, published)
+				return nil
+			})
+
+		published, err := r.Process(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, published)
+
+		var stored eventUser.User
+		err = db.First(&stored, "id = ?", pending.ID).Error
+		assert.NoError(t, err)
+		assert.True(t, stored.Published)
+		assert.Nil(t, stored.NextAttemptAt)
+	})
+}
+
+func TestRelay_RunAndStop(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	r, err := relay.New(db, "test", mocks.NewMockPublisher(ctrl), relay.WithPollInterval(time.Hour))
+	assert.NoError(t, err)
+
+	assert.False(t, r.Running())
+	errCh := make(chan error, 1)
+	go func() { errCh <- r.Run() }()
+	assert.Eventually(t, r.Running, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, r.Stop(ctx))
+	assert.NoError(t, <-errCh)
+	assert.False(t, r.Running())
+}
+
+func TestRelay_New(t *testing.T) {
+	t.Run("should fail without DB", func(t *testing.T) {
+		_, err := relay.New(nil, "test", nil)
+		assert.EqualError(t, err, relay.ErrMissingDB)
+	})
+}
+
+func insertPendingEvent(t *testing.T, db *gorm.DB) eventUser.User {
+	userID := uuid.New()
+	e := eventUser.User{
+		ID:        uuid.New(),
+		UserID:    userID,
+		EventType: eventUser.UserCreated,
+		Payload:   []byte(`{"user_id":"` + userID.String() + `","email":"relay@test.com","nickname":"relay"}`),
+		CreatedAt: time.Now().Add(-time.Minute),
+	}
+	assert.NoError(t, db.Create(&e).Error)
+	return e
+}
@@ -7,36 +7,105 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc/interceptor"
 )
 
 type Server struct {
-	port string
+	opts Options
 	srv  *grpc.Server
 }
 
-func New(port string) *Server {
-	return &Server{
-		port: port,
-		srv:  grpc.NewServer(),
+// New returns a gRPC server. Every call goes through the trace ID, request ID, tracing,
+// metrics (when enabled), access log, panic recovery and (unary only) default deadline
+// interceptors, then the configured ones
+func New(opts ...Option) (*Server, error) {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
+	// Validate the address
+	if _, _, err := net.SplitHostPort(options.Address); err != nil {
+		return nil, err
+	}
+
+	// The trace and request IDs go first so every other interceptor logs with them,
+	// recovery goes after tracing, logging and metrics so recovered panics are seen as Internal
+	unary := []grpc.UnaryServerInterceptor{
+		interceptor.UnaryTraceID(),
+		interceptor.UnaryRequestID(),
+		interceptor.UnaryTracing(),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		interceptor.StreamTraceID(),
+		interceptor.StreamRequestID(),
+		interceptor.StreamTracing(),
+	}
+	if options.Metrics != nil {
+		unary = append(unary, interceptor.UnaryMetrics(options.Metrics))
+		stream = append(stream, interceptor.StreamMetrics(options.Metrics))
 	}
+	unary = append(unary,
+		interceptor.UnaryLogging(),
+		interceptor.UnaryRecovery(),
+		interceptor.UnaryDeadline(options.DefaultTimeout),
+	)
+	unary = append(unary, options.UnaryInterceptors...)
+	stream = append(stream,
+		interceptor.StreamLogging(),
+		interceptor.StreamRecovery(),
+	)
+	stream = append(stream, options.StreamInterceptors...)
+
+	serverOptions := append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}, options.ServerOptions...)
+
+	return &Server{
+		opts: options,
+		srv:  grpc.NewServer(serverOptions...),
+	}, nil
 }
 
 func (s *Server) Server() *grpc.Server {
 	return s.srv
 }
 
+// Address Return address where the server is running
+func (s *Server) Address() string {
+	return s.opts.Address
+}
+
 func (s *Server) Run() error {
-	listener, err := net.Listen("tcp", ":"+s.port)
+	listener, err := net.Listen("tcp", s.opts.Address)
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
-	log.Info().Msgf("gRPC server listening on :%s", s.port)
+	log.Info().Msgf("gRPC server listening on %s", s.opts.Address)
 	return s.srv.Serve(listener)
 }
 
-func (s *Server) Stop(_ context.Context) error {
+// Stop stops the server gracefully, waiting for the calls in flight. The calls still
+// running when ctx is done, such as streams, are cancelled and ctx's error is returned
+func (s *Server) Stop(ctx context.Context) error {
 	log.Info().Msg("gRPC server stopping...")
-	s.srv.GracefulStop()
-	return nil
+	done := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info().Msg("gRPC server stopped")
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		<-done
+		log.Info().Msg("gRPC server stopped, open calls were cancelled")
+		return ctx.Err()
+	}
 }
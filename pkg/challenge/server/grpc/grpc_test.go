@@ -0,0 +1,166 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+	userProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/user"
+	grpcServer "github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc"
+)
+
+// fakeUserServer panics on GetUser and reports the deadline of DeleteUser
+type fakeUserServer struct {
+	userProto.UnimplementedUserServiceServer
+	deadline chan time.Duration
+}
+
+func (fakeUserServer) GetUser(context.Context, *userProto.GetUserRequest) (*userProto.UserResponse, error) {
+	panic("boom")
+}
+
+func (f fakeUserServer) DeleteUser(ctx context.Context, _ *userProto.DeleteUserRequest) (*userProto.Empty, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		f.deadline <- 0
+	} else {
+		f.deadline <- time.Until(deadline)
+	}
+	return &userProto.Empty{}, nil
+}
+
+func TestNew_InvalidAddress(t *testing.T) {
+	_, err := grpcServer.New(grpcServer.WithAddress("no-port"))
+	assert.Error(t, err)
+}
+
+func TestServer_Interceptors(t *testing.T) {
+	var calls []string
+	m := metrics.New()
+	srv, err := grpcServer.New(
+		grpcServer.WithAddress(":0"),
+		grpcServer.WithDefaultTimeout(time.Minute),
+		grpcServer.WithMetrics(m),
+		grpcServer.WithUnaryInterceptors(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, info.FullMethod)
+			return handler(ctx, req)
+		}),
+	)
+	assert.NoError(t, err)
+
+	fake := fakeUserServer{deadline: make(chan time.Duration, 1)}
+	userProto.RegisterUserServiceServer(srv.Server(), fake)
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Server().Serve(listener) }()
+	defer srv.Server().Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := userProto.NewUserServiceClient(conn)
+
+	t.Run("should turn panics into Internal errors and keep serving", func(t *testing.T) {
+		var header metadata.MD
+		_, err := client.GetUser(context.Background(), &userProto.GetUserRequest{Id: "id"}, grpc.Header(&header))
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.NotEmpty(t, header.Get("x-trace-id"))
+		assert.NotEmpty(t, header.Get("x-request-id"))
+
+		_, err = client.GetUser(context.Background(), &userProto.GetUserRequest{Id: "id"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+
+	t.Run("should give a default deadline to calls without one", func(t *testing.T) {
+		_, err := client.DeleteUser(context.Background(), &userProto.DeleteUserRequest{Id: "id"})
+		assert.NoError(t, err)
+		remaining := <-fake.deadline
+		assert.Greater(t, remaining, 50*time.Second)
+		assert.LessOrEqual(t, remaining, time.Minute)
+	})
+
+	t.Run("should keep the deadline of the client", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err := client.DeleteUser(ctx, &userProto.DeleteUserRequest{Id: "id"})
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, <-fake.deadline, 5*time.Second)
+	})
+
+	t.Run("should echo the trace and request IDs of the client", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-trace-id", "checkout-123", "x-request-id", "req-1")
+		var header metadata.MD
+		_, err := client.DeleteUser(ctx, &userProto.DeleteUserRequest{Id: "id"}, grpc.Header(&header))
+		assert.NoError(t, err)
+		<-fake.deadline
+		assert.Equal(t, []string{"checkout-123"}, header.Get("x-trace-id"))
+		assert.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+	})
+
+	t.Run("should run the configured interceptors", func(t *testing.T) {
+		assert.Contains(t, calls, userProto.UserService_DeleteUser_FullMethodName)
+	})
+
+	t.Run("should record the calls with the code sent to the client", func(t *testing.T) {
+		body, err := helpers.ScrapeMetrics(m)
+		assert.NoError(t, err)
+		assert.Contains(t, body, `challenge_grpc_requests_total{code="Internal",method="/user.UserService/GetUser"} 2`)
+		assert.Contains(t, body, `challenge_grpc_requests_total{code="OK",method="/user.UserService/DeleteUser"} 3`)
+	})
+}
+
+func TestRules_Health(t *testing.T) {
+	rules := grpcServer.Rules()
+	assert.True(t, rules[healthpb.Health_Check_FullMethodName].Public)
+	assert.True(t, rules[healthpb.Health_Watch_FullMethodName].Public)
+}
+
+func TestServer_Stop(t *testing.T) {
+	srv, err := grpcServer.New(grpcServer.WithAddress(":0"))
+	assert.NoError(t, err)
+	healthpb.RegisterHealthServer(srv.Server(), health.NewServer())
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Server().Serve(listener) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	watch, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	_, err = watch.Recv()
+	assert.NoError(t, err)
+
+	t.Run("should cancel the open streams when ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		assert.ErrorIs(t, srv.Stop(ctx), context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+
+		_, err := watch.Recv()
+		assert.Error(t, err)
+	})
+}
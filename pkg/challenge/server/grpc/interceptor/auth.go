@@ -0,0 +1,117 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+)
+
+// TokenVerifier checks access tokens, implemented by auth.TokenIssuer
+type TokenVerifier interface {
+	Verify(token string) (*auth.Claims, error)
+}
+
+// Rule is the access rule of a RPC
+type Rule struct {
+	// Public RPCs can be called without token
+	Public bool
+	// Policy applies to the non public RPCs
+	Policy auth.Policy
+}
+
+// Rules maps full method names (/package.Service/Method) to their access rule.
+// RPCs missing from the map can only be called by admins
+type Rules map[string]Rule
+
+// UnaryAuth validates the bearer token in the "authorization" metadata, puts its principal
+// into the context and checks the policy of the called RPC. The user the RPC acts on is
+// taken from the user_id or id field of the request
+func UnaryAuth(v TokenVerifier, rules Rules) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, v, rules, info.FullMethod, ownerOf(req))
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuth is UnaryAuth for streaming RPCs. The request is not read yet when the
+// policy is checked, so streams can not be restricted to the user itself
+func StreamAuth(v TokenVerifier, rules Rules) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), v, rules, info.FullMethod, "")
+		if err != nil {
+			return err
+		}
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authorize(ctx context.Context, v TokenVerifier, rules Rules, method, ownerID string) (context.Context, error) {
+	principal, err := principalFromMetadata(ctx, v)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("method", method).Msg("invalid access token")
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if principal != nil {
+		ctx = auth.WithPrincipal(ctx, principal)
+	}
+
+	rule, ok := rules[method]
+	if !ok {
+		rule = Rule{Policy: auth.AdminOnly}
+	}
+	if rule.Public {
+		return ctx, nil
+	}
+
+	err = rule.Policy.Authorize(principal, ownerID)
+	switch {
+	case err == nil:
+		return ctx, nil
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	default:
+		return ctx, status.Error(codes.PermissionDenied, err.Error())
+	}
+}
+
+// principalFromMetadata returns nil, without error, when the call has no token
+func principalFromMetadata(ctx context.Context, v TokenVerifier) (*auth.Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errors.New("invalid authorization metadata")
+	}
+
+	claims, err := v.Verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, errors.New("invalid access token")
+	}
+	return claims.Principal(), nil
+}
+
+// ownerOf returns the ID of the user a request acts on, if any
+func ownerOf(req interface{}) string {
+	switch r := req.(type) {
+	case interface{ GetUserId() string }:
+		return r.GetUserId()
+	case interface{ GetId() string }:
+		return r.GetId()
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package interceptor_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	userProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc/interceptor"
+)
+
+func TestUnaryAuth(t *testing.T) {
+	tokens, err := auth.New(auth.WithHMACSecret("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+
+	rules := interceptor.Rules{
+		userProto.UserService_CreateUser_FullMethodName: {Public: true},
+		userProto.UserService_UpdateUser_FullMethodName: {Policy: auth.Policy{Self: true, Scope: auth.ScopeUsersWrite}},
+	}
+	unary := interceptor.UnaryAuth(tokens, rules)
+
+	var called *auth.Principal
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		called, _ = auth.PrincipalFromContext(ctx)
+		return "ok", nil
+	}
+
+	userToken, _, err := tokens.Issue("user-id", "bandido")
+	assert.NoError(t, err)
+	adminToken, _, err := tokens.IssueFor(&auth.Principal{Subject: "admin-id", Role: auth.RoleAdmin})
+	assert.NoError(t, err)
+
+	call := func(method, token string, req interface{}) error {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+		called = nil
+		_, err := unary(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	t.Run("should let public RPCs through without token", func(t *testing.T) {
+		err := call(userProto.UserService_CreateUser_FullMethodName, "", &userProto.CreateUserRequest{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should be unauthenticated without a valid token", func(t *testing.T) {
+		err := call(userProto.UserService_UpdateUser_FullMethodName, "", &userProto.UpdateUserRequest{Id: "user-id"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		err = call(userProto.UserService_UpdateUser_FullMethodName, "nope", &userProto.UpdateUserRequest{Id: "user-id"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("should let users update themselves only", func(t *testing.T) {
+		err := call(userProto.UserService_UpdateUser_FullMethodName, userToken, &userProto.UpdateUserRequest{Id: "user-id"})
+		assert.NoError(t, err)
+		assert.Equal(t, "user-id", called.Subject)
+
+		err = call(userProto.UserService_UpdateUser_FullMethodName, userToken, &userProto.UpdateUserRequest{Id: "other-id"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("should only let admins call RPCs without rule", func(t *testing.T) {
+		err := call(userProto.UserService_DeleteUser_FullMethodName, userToken, &userProto.DeleteUserRequest{Id: "user-id"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		err = call(userProto.UserService_DeleteUser_FullMethodName, adminToken, &userProto.DeleteUserRequest{Id: "user-id"})
+		assert.NoError(t, err)
+	})
+}
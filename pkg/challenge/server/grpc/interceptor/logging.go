@@ -0,0 +1,57 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryLogging writes an access log line per call with the method, the status code and
+// the latency. Calls failing because of the server are logged as errors, the others as info
+func UnaryLogging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		logCall(ctx, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+// StreamLogging is UnaryLogging for streaming RPCs, the line is written when the stream ends
+func StreamLogging() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	var e *zerolog.Event
+	if isServerError(code) {
+		e = log.Error().Ctx(ctx).Err(err)
+	} else {
+		e = log.Info().Ctx(ctx)
+	}
+	e.Str("method", method).
+		Str("code", code.String()).
+		Dur("latency", time.Since(start)).
+		Msg("gRPC call")
+}
+
+// isServerError reports whether a status code means the server failed the call
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,60 @@
+package interceptor_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc/interceptor"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+)
+
+func TestUnaryLogging(t *testing.T) {
+	var buf bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buf).Hook(tracectx.LogHook{})
+	defer func() { log.Logger = previous }()
+
+	unary := interceptor.UnaryLogging()
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}
+	ctx := tracectx.WithTraceID(context.Background(), "checkout-123")
+
+	call := func(err error) map[string]interface{} {
+		buf.Reset()
+		_, _ = unary(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, err
+		})
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		return line
+	}
+
+	t.Run("should log successful calls as info", func(t *testing.T) {
+		line := call(nil)
+		assert.Equal(t, "info", line["level"])
+		assert.Equal(t, "/user.UserService/GetUser", line["method"])
+		assert.Equal(t, "OK", line["code"])
+		assert.Equal(t, "checkout-123", line[tracectx.LogField])
+		assert.Contains(t, line, "latency")
+	})
+
+	t.Run("should log client errors as info", func(t *testing.T) {
+		line := call(status.Error(codes.NotFound, "not found"))
+		assert.Equal(t, "info", line["level"])
+		assert.Equal(t, "NotFound", line["code"])
+	})
+
+	t.Run("should log server errors as errors", func(t *testing.T) {
+		line := call(status.Error(codes.Internal, "boom"))
+		assert.Equal(t, "error", line["level"])
+		assert.Equal(t, "Internal", line["code"])
+	})
+}
@@ -0,0 +1,31 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+)
+
+// UnaryMetrics records the count and latency of the calls per method and status code
+func UnaryMetrics(m *metrics.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		m.ObserveGRPCCall(info.FullMethod, status.Code(err).String(), time.Since(start))
+		return res, err
+	}
+}
+
+// StreamMetrics is UnaryMetrics for streaming RPCs, the call is recorded when the stream ends
+func StreamMetrics(m *metrics.Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.ObserveGRPCCall(info.FullMethod, status.Code(err).String(), time.Since(start))
+		return err
+	}
+}
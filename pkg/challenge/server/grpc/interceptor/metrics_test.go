@@ -0,0 +1,43 @@
+package interceptor_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc/interceptor"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	unary := interceptor.UnaryMetrics(m)
+	stream := interceptor.StreamMetrics(m)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}
+	_, err := unary(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+	_, err = unary(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	err = stream(nil, &fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/user.UserService/WatchUsers"}, func(interface{}, grpc.ServerStream) error {
+		return nil
+	})
+	assert.NoError(t, err)
+
+	body, err := helpers.ScrapeMetrics(m)
+	assert.NoError(t, err)
+	assert.Contains(t, body, `challenge_grpc_requests_total{code="OK",method="/user.UserService/GetUser"} 1`)
+	assert.Contains(t, body, `challenge_grpc_requests_total{code="NotFound",method="/user.UserService/GetUser"} 1`)
+	assert.Contains(t, body, `challenge_grpc_request_duration_seconds_count{method="/user.UserService/GetUser"} 2`)
+	assert.Contains(t, body, `challenge_grpc_requests_total{code="OK",method="/user.UserService/WatchUsers"} 1`)
+}
@@ -0,0 +1,23 @@
+package interceptor_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc/interceptor"
+)
+
+func TestUnaryRecovery(t *testing.T) {
+	unary := interceptor.UnaryRecovery()
+	res, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/m"}, func(context.Context, interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	assert.Nil(t, res)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.NotContains(t, err.Error(), "boom")
+}
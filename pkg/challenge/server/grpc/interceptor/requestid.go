@@ -0,0 +1,35 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+)
+
+// UnaryRequestID takes the ID of the call from a valid x-request-id metadata, or generates
+// one, puts it into the context and sends it back in the x-request-id header
+func UnaryRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// StreamRequestID is UnaryRequestID for streaming RPCs
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := tracectx.ResolveRequestID(first(md, tracectx.HeaderRequestID))
+
+	// Only fails outside of a RPC, e.g. when the interceptor is called directly
+	_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(tracectx.HeaderRequestID), requestID))
+	return tracectx.WithRequestID(ctx, requestID)
+}
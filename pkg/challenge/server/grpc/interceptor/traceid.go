@@ -0,0 +1,44 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+)
+
+// UnaryTraceID takes the trace ID of the call from a valid traceparent or x-trace-id
+// metadata, or generates one, puts it into the context and sends it back in the
+// x-trace-id header. It goes first so the other interceptors log with the trace ID
+func UnaryTraceID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withTraceID(ctx), req)
+	}
+}
+
+// StreamTraceID is UnaryTraceID for streaming RPCs
+func StreamTraceID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: withTraceID(ss.Context())})
+	}
+}
+
+func withTraceID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	traceID := tracectx.Resolve(first(md, tracectx.HeaderTraceparent), first(md, tracectx.HeaderTraceID))
+
+	// Only fails outside of a RPC, e.g. when the interceptor is called directly
+	_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(tracectx.HeaderTraceID), traceID))
+	return tracectx.WithTraceID(ctx, traceID)
+}
+
+// first returns the first value of a metadata key, keys are case insensitive
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
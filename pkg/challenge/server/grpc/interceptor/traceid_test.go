@@ -0,0 +1,72 @@
+package interceptor_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc/interceptor"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+)
+
+func TestUnaryTraceID(t *testing.T) {
+	unary := interceptor.UnaryTraceID()
+	call := func(md metadata.MD) string {
+		ctx := context.Background()
+		if md != nil {
+			ctx = metadata.NewIncomingContext(ctx, md)
+		}
+		res, err := unary(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return tracectx.TraceIDFromContext(ctx), nil
+		})
+		assert.NoError(t, err)
+		return res.(string)
+	}
+
+	t.Run("should use the traceparent trace ID", func(t *testing.T) {
+		traceID := call(metadata.Pairs(
+			"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"x-trace-id", "ignored",
+		))
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	})
+
+	t.Run("should use the x-trace-id", func(t *testing.T) {
+		assert.Equal(t, "checkout-123", call(metadata.Pairs("x-trace-id", "checkout-123")))
+	})
+
+	t.Run("should generate a trace ID", func(t *testing.T) {
+		assert.Len(t, call(nil), 32)
+		assert.Len(t, call(metadata.Pairs("x-trace-id", "not valid!")), 32)
+	})
+}
+
+func TestStreamTraceID(t *testing.T) {
+	stream := interceptor.StreamTraceID()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-trace-id", "checkout-123"))
+
+	var traceID string
+	err := stream(nil, &fakeStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(_ interface{}, ss grpc.ServerStream) error {
+		traceID = tracectx.TraceIDFromContext(ss.Context())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "checkout-123", traceID)
+}
+
+// fakeStream is a server stream only providing a context
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream) SetHeader(metadata.MD) error {
+	return nil
+}
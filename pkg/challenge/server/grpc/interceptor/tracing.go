@@ -0,0 +1,61 @@
+package interceptor
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+// UnaryTracing starts a server span per call, child of the caller span when it sent a
+// valid traceparent. It goes after UnaryTraceID so the span belongs to the call trace.
+// Calls failing because of the server mark the span as failed
+func UnaryTracing() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startCallSpan(ctx, info.FullMethod)
+		defer span.End()
+
+		res, err := handler(ctx, req)
+		endCallSpan(span, err)
+		return res, err
+	}
+}
+
+// StreamTracing is UnaryTracing for streaming RPCs, the span ends with the stream
+func StreamTracing() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startCallSpan(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		err := handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+		endCallSpan(span, err)
+		return err
+	}
+}
+
+func startCallSpan(ctx context.Context, method string) (context.Context, *tracing.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var parent tracing.SpanContext
+	if traceID, parentID, ok := tracectx.ParseTraceparentIDs(first(md, tracectx.HeaderTraceparent)); ok {
+		parent = tracing.SpanContext{TraceID: traceID, SpanID: parentID}
+	}
+
+	return tracing.Start(ctx, method,
+		tracing.WithKind(tracing.KindServer),
+		tracing.WithParent(parent),
+		tracing.WithAttributes(map[string]any{"rpc.method": method}),
+	)
+}
+
+func endCallSpan(span *tracing.Span, err error) {
+	code := status.Code(err)
+	span.SetAttribute("rpc.grpc.status_code", code.String())
+	if isServerError(code) {
+		span.RecordError(err)
+	}
+}
@@ -0,0 +1,55 @@
+package interceptor_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc/interceptor"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracing.NewMemoryExporter()
+	tracing.SetExporter(exporter)
+	defer tracing.SetExporter(nil)
+
+	unary := interceptor.UnaryTracing()
+	stream := interceptor.StreamTracing()
+
+	t.Run("should trace the call as a child of the caller span", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+		var handlerSpan tracing.SpanContext
+		_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			handlerSpan = tracing.SpanContextFromContext(ctx)
+			return nil, status.Error(codes.NotFound, "user not found")
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		span, ok := exporter.Find("/user.UserService/GetUser")
+		assert.True(t, ok)
+		assert.Equal(t, tracing.KindServer, span.Kind)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", span.ParentSpanID)
+		assert.Equal(t, "NotFound", span.Attributes["rpc.grpc.status_code"])
+		assert.Equal(t, tracing.StatusOK, span.Status)
+		assert.Equal(t, span.SpanID, handlerSpan.SpanID)
+	})
+
+	t.Run("should mark streams failing on the server as failed", func(t *testing.T) {
+		err := stream(nil, &fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/user.UserService/WatchUsers"}, func(_ interface{}, ss grpc.ServerStream) error {
+			assert.True(t, tracing.SpanContextFromContext(ss.Context()).Valid())
+			return status.Error(codes.Internal, "boom")
+		})
+		assert.Equal(t, codes.Internal, status.Code(err))
+
+		span, ok := exporter.Find("/user.UserService/WatchUsers")
+		assert.True(t, ok)
+		assert.Equal(t, tracing.StatusError, span.Status)
+	})
+}
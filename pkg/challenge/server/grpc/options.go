@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+)
+
+// Pass the address where we want the server to initialize
+func WithAddress(address string) Option {
+	return func(o *Options) {
+		o.Address = address
+	}
+}
+
+// WithDefaultTimeout sets the deadline of the unary calls arriving without one, 0 disables it
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.DefaultTimeout = d
+	}
+}
+
+// WithUnaryInterceptors appends interceptors run after the built in ones
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *Options) {
+		o.UnaryInterceptors = append(o.UnaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors appends stream interceptors run after the built in ones
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(o *Options) {
+		o.StreamInterceptors = append(o.StreamInterceptors, interceptors...)
+	}
+}
+
+// WithMetrics records the count and latency of the calls
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
+// WithServerOptions passes extra options to grpc.NewServer
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *Options) {
+		o.ServerOptions = append(o.ServerOptions, opts...)
+	}
+}
+
+type Options struct {
+	// Address where transport will be exposed
+	Address string
+	// DefaultTimeout is the deadline of the unary calls arriving without one.
+	// Streams are long lived (WatchUsers) and never get a default deadline
+	DefaultTimeout time.Duration
+	// UnaryInterceptors run after the trace ID, request ID, tracing, metrics, logging, recovery and deadline ones
+	UnaryInterceptors []grpc.UnaryServerInterceptor
+	// StreamInterceptors run after the trace ID, request ID, tracing, metrics, logging and recovery ones
+	StreamInterceptors []grpc.StreamServerInterceptor
+	// Metrics records the count and latency of the calls, nil disables them
+	Metrics *metrics.Metrics
+	// ServerOptions are passed as they are to grpc.NewServer
+	ServerOptions []grpc.ServerOption
+}
+
+type Option func(o *Options)
+
+func defaultOptions() Options {
+	return Options{
+		DefaultTimeout: 30 * time.Second,
+	}
+}
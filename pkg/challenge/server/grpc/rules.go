@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	authProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/auth"
+	userProto "github.com/nachoconques0/user_challenge_svc/pkg/challenge/proto/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/grpc/interceptor"
+)
+
+// Rules returns the access rule of every RPC, the same ones the HTTP routes apply.
+// Admins can always call them
+func Rules() interceptor.Rules {
+	return interceptor.Rules{
+		userProto.UserService_CreateUser_FullMethodName:     {Public: true},
+		userProto.UserService_GetUser_FullMethodName:        {Policy: auth.Policy{Self: true, Scope: auth.ScopeUsersRead}},
+		userProto.UserService_UpdateUser_FullMethodName:     {Policy: auth.Policy{Self: true, Scope: auth.ScopeUsersWrite}},
+		userProto.UserService_DeleteUser_FullMethodName:     {Policy: auth.Policy{Self: true, Scope: auth.ScopeUsersDelete}},
+		userProto.UserService_FindUsers_FullMethodName:      {Policy: auth.Policy{Scope: auth.ScopeUsersRead}},
+		userProto.UserService_SearchUsers_FullMethodName:    {Policy: auth.Policy{Scope: auth.ScopeUsersRead}},
+		userProto.UserService_ListUserEvents_FullMethodName: {Policy: auth.Policy{Self: true, Scope: auth.ScopeUsersRead}},
+		userProto.UserService_WatchUsers_FullMethodName:     {Policy: auth.Policy{Scope: auth.ScopeUsersRead}},
+
+		authProto.AuthService_Login_FullMethodName:          {Public: true},
+		authProto.AuthService_Token_FullMethodName:          {Public: true},
+		authProto.AuthService_Refresh_FullMethodName:        {Public: true},
+		authProto.AuthService_ListSessions_FullMethodName:   {Policy: auth.Policy{Self: true, Scope: auth.ScopeSessionsRead}},
+		authProto.AuthService_RevokeSession_FullMethodName:  {Policy: auth.Policy{Self: true, Scope: auth.ScopeSessionsWrite}},
+		authProto.AuthService_RevokeSessions_FullMethodName: {Policy: auth.Policy{Self: true, Scope: auth.ScopeSessionsWrite}},
+
+		// Load balancers and orchestrators probe without token
+		healthpb.Health_Check_FullMethodName: {Public: true},
+		healthpb.Health_Watch_FullMethodName: {Public: true},
+	}
+}
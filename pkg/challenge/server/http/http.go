@@ -43,9 +43,15 @@ func New(opts ...Option) (*server, error) { //nolint:revive // no need to return
 
 	// Initialize the server
 	router := gin.New()
+	// Handlers pass the gin context down, it has to expose the request context values
+	// such as the trace ID and the principal
+	router.ContextWithFallback = true
 
 	// Add middlewares
-	router.Use(middleware.TraceIDMiddleware())
+	router.Use(middleware.TraceIDMiddleware(), middleware.Tracing())
+	if options.Metrics != nil {
+		router.Use(middleware.Metrics(options.Metrics))
+	}
 
 	s := server{
 		opts:   options,
@@ -63,10 +69,14 @@ func New(opts ...Option) (*server, error) { //nolint:revive // no need to return
 func InitHTTPRouter(srv *server) *gin.Engine {
 	r := srv.router
 	r.Use(gin.Logger())
-	// Health endpoint
+	// Health endpoint, only tells the process answers. Probes should use /livez and /readyz
 	r.GET("/health", func(ctx *gin.Context) {
 		ctx.Status(netHTTP.StatusOK)
 	})
+	// Prometheus scrape endpoint
+	if srv.opts.Metrics != nil {
+		r.GET("/metrics", gin.WrapH(srv.opts.Metrics.Handler()))
+	}
 	return r
 }
 
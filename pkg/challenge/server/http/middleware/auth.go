@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/httperror"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/domainerr"
+)
+
+// TokenVerifier checks access tokens, implemented by auth.TokenIssuer
+type TokenVerifier interface {
+	Verify(token string) (*auth.Claims, error)
+}
+
+// Authenticate reads the bearer token of the request and puts its principal into the context.
+// Requests without token go through anonymously, Authorize decides if they can. Invalid tokens are rejected
+func Authenticate(v TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			abort(c, domainerr.Unauthenticated("invalid authorization header"))
+			return
+		}
+
+		claims, err := v.Verify(strings.TrimSpace(token))
+		if err != nil {
+			log.Error().Ctx(c.Request.Context()).Err(err).Str("middleware", "Authenticate").Msg("invalid access token")
+			abort(c, domainerr.Unauthenticated("invalid access token"))
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), claims.Principal()))
+		c.Next()
+	}
+}
+
+// Authorize only lets through the requests whose principal is allowed by the policy.
+// ownerParam is the path param holding the ID of the user the route acts on, empty when there is none
+func Authorize(policy auth.Policy, ownerParam string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, _ := auth.PrincipalFromContext(c.Request.Context())
+
+		var ownerID string
+		if ownerParam != "" {
+			ownerID = c.Param(ownerParam)
+		}
+
+		err := policy.Authorize(principal, ownerID)
+		switch {
+		case err == nil:
+			c.Next()
+		case errors.Is(err, auth.ErrUnauthenticated):
+			abort(c, domainerr.Unauthenticated(err.Error()))
+		default:
+			abort(c, domainerr.PermissionDenied(err.Error()))
+		}
+	}
+}
+
+// abort answers the request with the status and body of a domain error, like the controllers
+func abort(c *gin.Context, err error) {
+	if errors.Is(err, domainerr.ErrUnauthenticated) {
+		c.Header("WWW-Authenticate", `Bearer realm="user_challenge_svc"`)
+	}
+	c.AbortWithStatusJSON(httperror.Status(err), httperror.Response(err, "request not authorized"))
+}
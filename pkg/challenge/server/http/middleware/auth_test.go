@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/model"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/http/middleware"
+)
+
+func TestAuthenticateAndAuthorize(t *testing.T) {
+	tokens, err := auth.New(
+		auth.WithHMACSecret("0123456789abcdef0123456789abcdef"),
+		auth.WithServiceAccount("reporting", "reporting-secret-0123456789abcdef", auth.ScopeUsersRead),
+	)
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(middleware.Authenticate(tokens))
+	router.PATCH("/users/:id", middleware.Authorize(auth.Policy{Self: true, Scope: auth.ScopeUsersWrite}, "id"), func(c *gin.Context) {
+		principal, ok := auth.PrincipalFromContext(c.Request.Context())
+		assert.True(t, ok)
+		c.String(http.StatusOK, principal.Subject)
+	})
+	router.GET("/public", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	userToken, _, err := tokens.Issue("user-id", "bandido")
+	assert.NoError(t, err)
+	adminToken, _, err := tokens.IssueFor(&auth.Principal{Subject: "admin-id", Role: auth.RoleAdmin})
+	assert.NoError(t, err)
+	service, err := tokens.AuthenticateService("reporting", "reporting-secret-0123456789abcdef")
+	assert.NoError(t, err)
+	serviceToken, _, err := tokens.IssueFor(service)
+	assert.NoError(t, err)
+
+	call := func(method, path, authorization string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should let public routes through without token", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, call(http.MethodGet, "/public", "").Code)
+	})
+
+	t.Run("should return 401 without token", func(t *testing.T) {
+		w := call(http.MethodPatch, "/users/user-id", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+		var res model.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, model.ErrorResponse{Error: "request not authorized", Details: "authentication required"}, res)
+	})
+
+	t.Run("should return 401 with an invalid token, even on public routes", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, call(http.MethodPatch, "/users/user-id", "Bearer nope").Code)
+		assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/public", "Basic dXNlcjpwYXNz").Code)
+	})
+
+	t.Run("should let users modify themselves only", func(t *testing.T) {
+		w := call(http.MethodPatch, "/users/user-id", "Bearer "+userToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user-id", w.Body.String())
+
+		w = call(http.MethodPatch, "/users/other-id", "Bearer "+userToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+
+		var res model.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, model.ErrorResponse{Error: "request not authorized", Details: "permission denied"}, res)
+	})
+
+	t.Run("should let admins modify anyone", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, call(http.MethodPatch, "/users/other-id", "Bearer "+adminToken).Code)
+	})
+
+	t.Run("should return 403 for services without the scope", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, call(http.MethodPatch, "/users/other-id", "Bearer "+serviceToken).Code)
+	})
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+)
+
+// unmatchedRoute labels the requests not matching any route, so unknown paths do not
+// create new series
+const unmatchedRoute = "unmatched"
+
+// Metrics records the count and latency of the requests per route template
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
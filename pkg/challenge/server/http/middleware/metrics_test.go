@@ -0,0 +1,49 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/http/middleware"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	router := gin.New()
+	router.Use(middleware.Metrics(m))
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.DELETE("/users/:id", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusForbidden)
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/users/2", nil),
+		httptest.NewRequest(http.MethodDelete, "/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/unknown/path", nil),
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body, err := helpers.ScrapeMetrics(m)
+	assert.NoError(t, err)
+
+	t.Run("should count the requests per route template", func(t *testing.T) {
+		assert.Contains(t, body, `challenge_http_requests_total{code="200",method="GET",route="/users/:id"} 2`)
+		assert.Contains(t, body, `challenge_http_requests_total{code="403",method="DELETE",route="/users/:id"} 1`)
+		assert.Contains(t, body, `challenge_http_request_duration_seconds_count{method="GET",route="/users/:id"} 2`)
+	})
+
+	t.Run("should put unknown paths under a single route", func(t *testing.T) {
+		assert.Contains(t, body, `challenge_http_requests_total{code="404",method="GET",route="unmatched"} 1`)
+		assert.NotContains(t, body, "/unknown/path")
+	})
+}
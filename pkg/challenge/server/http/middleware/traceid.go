@@ -1,23 +1,18 @@
 package middleware
 
 import (
-	"context"
-
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-)
 
-type TraceIDKey string
-
-const traceIDKey TraceIDKey = "trace_id"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+)
 
-// TraceIDMiddleware generates a trace_id per request and injects it into Gin context and Go context
+// TraceIDMiddleware takes the trace ID of the request from a valid traceparent or X-Trace-ID
+// header, or generates one, puts it into the request context and sends it back in X-Trace-ID
 func TraceIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		traceID := uuid.New().String()
-		c.Set(string(traceIDKey), traceID)
-		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), traceIDKey, traceID))
-		c.Header("X-Trace-ID", traceID)
+		traceID := tracectx.Resolve(c.GetHeader(tracectx.HeaderTraceparent), c.GetHeader(tracectx.HeaderTraceID))
+		c.Request = c.Request.WithContext(tracectx.WithTraceID(c.Request.Context(), traceID))
+		c.Header(tracectx.HeaderTraceID, traceID)
 		c.Next()
 	}
 }
@@ -0,0 +1,54 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/http/middleware"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+)
+
+func TestTraceIDMiddleware(t *testing.T) {
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(middleware.TraceIDMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		// Handlers pass the gin context down to the services
+		c.String(http.StatusOK, tracectx.TraceIDFromContext(c))
+	})
+
+	do := func(headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should use the traceparent trace ID", func(t *testing.T) {
+		w := do(map[string]string{
+			tracectx.HeaderTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			tracectx.HeaderTraceID:     "ignored",
+		})
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", w.Body.String())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", w.Header().Get(tracectx.HeaderTraceID))
+	})
+
+	t.Run("should use the X-Trace-ID", func(t *testing.T) {
+		w := do(map[string]string{tracectx.HeaderTraceID: "checkout-123"})
+		assert.Equal(t, "checkout-123", w.Body.String())
+		assert.Equal(t, "checkout-123", w.Header().Get(tracectx.HeaderTraceID))
+	})
+
+	t.Run("should generate a trace ID", func(t *testing.T) {
+		w := do(nil)
+		assert.Len(t, w.Body.String(), 32)
+		assert.Equal(t, w.Body.String(), w.Header().Get(tracectx.HeaderTraceID))
+	})
+}
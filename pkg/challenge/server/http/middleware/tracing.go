@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+// Tracing starts a server span per request, child of the caller span when it sent a valid
+// traceparent. It goes after TraceIDMiddleware so the span belongs to the request trace
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		var parent tracing.SpanContext
+		if traceID, parentID, ok := tracectx.ParseTraceparentIDs(c.GetHeader(tracectx.HeaderTraceparent)); ok {
+			parent = tracing.SpanContext{TraceID: traceID, SpanID: parentID}
+		}
+
+		ctx, span := tracing.Start(c.Request.Context(), c.Request.Method+" "+route,
+			tracing.WithKind(tracing.KindServer),
+			tracing.WithParent(parent),
+			tracing.WithAttributes(map[string]any{
+				"http.method": c.Request.Method,
+				"http.route":  route,
+			}),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		if status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("%d %s", status, http.StatusText(status)))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/http/middleware"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracing.NewMemoryExporter()
+	tracing.SetExporter(exporter)
+	defer tracing.SetExporter(nil)
+
+	var handlerSpan tracing.SpanContext
+	router := gin.New()
+	// Controllers pass the gin context to the services, as the server router does
+	router.ContextWithFallback = true
+	router.Use(middleware.TraceIDMiddleware(), middleware.Tracing())
+	router.GET("/users/:id", func(c *gin.Context) {
+		handlerSpan = tracing.SpanContextFromContext(c)
+		c.Status(http.StatusOK)
+	})
+	router.DELETE("/users/:id", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+
+	t.Run("should trace the request as a child of the caller span", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set(tracectx.HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		span, ok := exporter.Find("GET /users/:id")
+		assert.True(t, ok)
+		assert.Equal(t, tracing.KindServer, span.Kind)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", span.ParentSpanID)
+		assert.Equal(t, http.StatusOK, span.Attributes["http.status_code"])
+		assert.Equal(t, span.SpanID, handlerSpan.SpanID)
+	})
+
+	t.Run("should mark server errors as failed", func(t *testing.T) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+
+		span, ok := exporter.Find("DELETE /users/:id")
+		assert.True(t, ok)
+		assert.Equal(t, tracing.StatusError, span.Status)
+		assert.Empty(t, span.ParentSpanID)
+	})
+}
@@ -1,6 +1,10 @@
 package server
 
-import "context"
+import (
+	"context"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/metrics"
+)
 
 // Pass the address where we want the server to initialize
 func WithAddress(address string) Option {
@@ -9,10 +13,19 @@ func WithAddress(address string) Option {
 	}
 }
 
+// WithMetrics records the count and latency of the requests and serves them on /metrics
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = m
+	}
+}
+
 type Options struct {
 	// Address where transport will be exposed
 	Address string
 	Context context.Context
+	// Metrics of the service, nil disables the metrics middleware and /metrics
+	Metrics *metrics.Metrics
 }
 
 type Option func(o *Options)
@@ -2,17 +2,77 @@ package server
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/auth"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/health"
+	httpAuth "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/auth"
 	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/user"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/controller/http/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/http/middleware"
 )
 
-// InitUserRoutes will set all the endpoints for an user
+// Route policies. Admins can always call them
+var (
+	readUsersPolicy     = auth.Policy{Self: true, Scope: auth.ScopeUsersRead}
+	writeUsersPolicy    = auth.Policy{Self: true, Scope: auth.ScopeUsersWrite}
+	deleteUsersPolicy   = auth.Policy{Self: true, Scope: auth.ScopeUsersDelete}
+	readSessionsPolicy  = auth.Policy{Self: true, Scope: auth.ScopeSessionsRead}
+	writeSessionsPolicy = auth.Policy{Self: true, Scope: auth.ScopeSessionsWrite}
+	listUsersPolicy     = auth.Policy{Scope: auth.ScopeUsersRead}
+	webhooksPolicy      = auth.Policy{Scope: auth.ScopeWebhooksManage}
+)
+
+// InitUserRoutes will set all the endpoints for an user.
+// Anyone can sign up, the rest needs a token allowed by the route policy
 func InitUserRoutes(
 	router *gin.Engine,
 	userCtrl *user.Controller,
 ) {
 	userGroup := router.Group("/users")
-	userGroup.GET("", userCtrl.Find)
+	userGroup.GET("", middleware.Authorize(listUsersPolicy, ""), userCtrl.Find)
 	userGroup.POST("", userCtrl.Create)
-	userGroup.PATCH("/:id", userCtrl.Update)
-	userGroup.DELETE("/:id", userCtrl.Delete)
+	// Static segments take precedence over /:id
+	userGroup.GET("/search", middleware.Authorize(listUsersPolicy, ""), userCtrl.Search)
+	userGroup.GET("/:id", middleware.Authorize(readUsersPolicy, "id"), userCtrl.Get)
+	userGroup.PATCH("/:id", middleware.Authorize(writeUsersPolicy, "id"), userCtrl.Update)
+	userGroup.DELETE("/:id", middleware.Authorize(deleteUsersPolicy, "id"), userCtrl.Delete)
+	userGroup.GET("/:id/events", middleware.Authorize(readUsersPolicy, "id"), userCtrl.ListEvents)
+	// Public as well, so nicknames can be checked before signing up
+	router.GET("/nicknames/:nick/availability", userCtrl.NicknameAvailability)
+}
+
+// InitWebhookRoutes will set all the endpoints for managing webhooks
+func InitWebhookRoutes(
+	router *gin.Engine,
+	webhookCtrl *webhook.Controller,
+) {
+	webhookGroup := router.Group("/webhooks", middleware.Authorize(webhooksPolicy, ""))
+	webhookGroup.GET("", webhookCtrl.Find)
+	webhookGroup.POST("", webhookCtrl.Create)
+	webhookGroup.GET("/:id", webhookCtrl.Get)
+	webhookGroup.PATCH("/:id", webhookCtrl.Update)
+	webhookGroup.DELETE("/:id", webhookCtrl.Delete)
+	webhookGroup.GET("/:id/deliveries", webhookCtrl.ListDeliveries)
+}
+
+// InitAuthRoutes will set the login, refresh, sessions and public keys endpoints
+func InitAuthRoutes(
+	router *gin.Engine,
+	authCtrl *httpAuth.Controller,
+) {
+	router.POST("/auth/login", authCtrl.Login)
+	router.POST("/auth/token", authCtrl.Token)
+	router.POST("/auth/refresh", authCtrl.Refresh)
+	router.GET("/users/:id/sessions", middleware.Authorize(readSessionsPolicy, "id"), authCtrl.ListSessions)
+	router.DELETE("/users/:id/sessions", middleware.Authorize(writeSessionsPolicy, "id"), authCtrl.RevokeSessions)
+	router.DELETE("/users/:id/sessions/:session_id", middleware.Authorize(writeSessionsPolicy, "id"), authCtrl.RevokeSession)
+	router.GET("/.well-known/jwks.json", authCtrl.JWKS)
+}
+
+// InitHealthRoutes sets the liveness and readiness probes. Both are public
+func InitHealthRoutes(
+	router *gin.Engine,
+	checker *health.Checker,
+) {
+	router.GET("/livez", gin.WrapH(checker.LiveHandler()))
+	router.GET("/readyz", gin.WrapH(checker.ReadyHandler()))
 }
@@ -0,0 +1,157 @@
+package mux
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	contentTypeGRPC        = "application/grpc"
+	contentTypeGRPCWeb     = "application/grpc-web"
+	contentTypeGRPCWebText = "application/grpc-web-text"
+
+	// trailerFrame flags the last frame of a gRPC-Web response, holding the trailers
+	trailerFrame byte = 0x80
+)
+
+// isGRPC reports whether the content type is application/grpc, optionally followed by a
+// codec (application/grpc+proto) or parameters
+func isGRPC(contentType string) bool {
+	if !strings.HasPrefix(contentType, contentTypeGRPC) {
+		return false
+	}
+	rest := contentType[len(contentTypeGRPC):]
+	return rest == "" || rest[0] == '+' || rest[0] == ';'
+}
+
+// serveGRPCWeb serves a gRPC-Web call with the gRPC server: the request is presented as an
+// HTTP/2 gRPC one, and the trailers of the response are sent in its body, as browsers and
+// HTTP/1.1 proxies can not read HTTP trailers. The -text variant is base64 encoded both ways
+func serveGRPCWeb(grpcSrv *grpc.Server, w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, contentTypeGRPCWebText)
+
+	webPrefix := contentTypeGRPCWeb
+	if text {
+		webPrefix = contentTypeGRPCWebText
+	}
+	codec := strings.TrimPrefix(contentType, webPrefix)
+
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2"
+	req.Header.Set("Content-Type", contentTypeGRPC+codec)
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	if text {
+		req.Body = io.NopCloser(base64.NewDecoder(base64.StdEncoding, r.Body))
+	}
+
+	res := newGRPCWebResponse(w, webPrefix, text)
+	grpcSrv.ServeHTTP(res, req)
+	res.finish()
+}
+
+// grpcWebResponse turns the response of the gRPC server into a gRPC-Web one
+type grpcWebResponse struct {
+	w          http.ResponseWriter
+	webPrefix  string
+	text       bool
+	body       io.Writer
+	encoder    io.WriteCloser
+	status     int
+	trailerKey []string
+}
+
+func newGRPCWebResponse(w http.ResponseWriter, webPrefix string, text bool) *grpcWebResponse {
+	res := &grpcWebResponse{w: w, webPrefix: webPrefix, text: text, body: w}
+	if text {
+		res.encoder = base64.NewEncoder(base64.StdEncoding, w)
+		res.body = res.encoder
+	}
+	return res
+}
+
+// Header implements http.ResponseWriter
+func (res *grpcWebResponse) Header() http.Header {
+	return res.w.Header()
+}
+
+// WriteHeader implements http.ResponseWriter. The declared trailers are kept to be
+// written in the body instead of being sent as HTTP trailers
+func (res *grpcWebResponse) WriteHeader(status int) {
+	if res.status != 0 {
+		return
+	}
+	res.status = status
+
+	h := res.w.Header()
+	for _, v := range h.Values("Trailer") {
+		for _, key := range strings.Split(v, ",") {
+			res.trailerKey = append(res.trailerKey, strings.TrimSpace(key))
+		}
+	}
+	h.Del("Trailer")
+	h.Del("Content-Length")
+	if ct := h.Get("Content-Type"); isGRPC(ct) {
+		h.Set("Content-Type", res.webPrefix+strings.TrimPrefix(ct, contentTypeGRPC))
+	}
+	res.w.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter
+func (res *grpcWebResponse) Write(b []byte) (int, error) {
+	res.WriteHeader(http.StatusOK)
+	return res.body.Write(b)
+}
+
+// Flush implements http.Flusher. In text mode each flush ends a base64 segment, with its
+// padding, so the client can decode what it got so far
+func (res *grpcWebResponse) Flush() {
+	res.WriteHeader(http.StatusOK)
+	if res.text {
+		_ = res.encoder.Close()
+		res.encoder = base64.NewEncoder(base64.StdEncoding, res.w)
+		res.body = res.encoder
+	}
+	if f, ok := res.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the trailers set by the gRPC server as the last frame of the body
+func (res *grpcWebResponse) finish() {
+	if res.status != http.StatusOK {
+		return
+	}
+
+	h := res.w.Header()
+	var trailers bytes.Buffer
+	writeTrailer := func(key string, values []string) {
+		for _, v := range values {
+			trailers.WriteString(strings.ToLower(key) + ": " + v + "\r\n")
+		}
+	}
+	for _, key := range res.trailerKey {
+		writeTrailer(key, h.Values(key))
+		h.Del(key)
+	}
+	for key, values := range h {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			writeTrailer(strings.TrimPrefix(key, http.TrailerPrefix), values)
+			delete(h, key)
+		}
+	}
+
+	frame := make([]byte, 5, 5+trailers.Len())
+	frame[0] = trailerFrame
+	binary.BigEndian.PutUint32(frame[1:], uint32(trailers.Len()))
+	frame = append(frame, trailers.Bytes()...)
+	_, _ = res.body.Write(frame)
+	res.Flush()
+}
@@ -0,0 +1,164 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// stopPollInterval is how often Stop checks whether the requests in flight are done
+const stopPollInterval = 50 * time.Millisecond
+
+// Server serves gRPC, gRPC-Web and the REST API on a single listener. Requests are
+// routed by content type: application/grpc over HTTP/2 (h2c, cleartext) goes to the
+// gRPC server, application/grpc-web(-text) is translated and goes to the gRPC server
+// as well, anything else goes to the REST handler
+type Server struct {
+	opts   Options
+	grpc   *grpc.Server
+	rest   http.Handler
+	server *http.Server
+	// cancel cancels the context of every request, ending the gRPC streams
+	cancel context.CancelFunc
+	// active counts the requests in flight but the gRPC streams, which only end when
+	// the client leaves. HTTP/2 connections are hijacked by h2c and not tracked by the http.Server
+	active atomic.Int64
+
+	streamsOnce sync.Once
+	streams     map[string]bool
+}
+
+// New returns a single port server in front of the given gRPC server and REST handler.
+// The gRPC server is only served through it, its own Run and Stop must not be used
+func New(grpcSrv *grpc.Server, rest http.Handler, opts ...Option) (*Server, error) {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
+	// Validate the address
+	if _, _, err := net.SplitHostPort(options.Address); err != nil {
+		return nil, err
+	}
+
+	baseCtx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		opts:   options,
+		grpc:   grpcSrv,
+		rest:   rest,
+		cancel: cancel,
+	}
+
+	h2s := &http2.Server{}
+	s.server = &http.Server{
+		Addr:              options.Address,
+		Handler:           h2c.NewHandler(s, h2s),
+		ReadHeaderTimeout: options.ReadHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+	// Shutdown sends GOAWAY to the HTTP/2 connections as well
+	if err := http2.ConfigureServer(s.server, h2s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Address Return address where the server is running
+func (s *Server) Address() string {
+	return s.opts.Address
+}
+
+// Run listens on the address of the server and serves until Stop is called.
+// This method will block the calling go routine
+func (s *Server) Run() error {
+	listener, err := net.Listen("tcp", s.opts.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	return s.Serve(listener)
+}
+
+// Serve serves the requests arriving on the given listener until Stop is called
+func (s *Server) Serve(listener net.Listener) error {
+	log.Info().Msgf("Single port server (gRPC, gRPC-Web and HTTP) listening on %s", listener.Addr())
+	return s.server.Serve(listener)
+}
+
+// Stop stops accepting requests and waits for the ones in flight, until ctx is done.
+// gRPC streams are not waited for, they never end on their own (e.g. WatchUsers):
+// their context is cancelled once the other requests are done
+func (s *Server) Stop(ctx context.Context) error {
+	log.Info().Msg("Single port server: graceful stop...")
+	err := s.server.Shutdown(ctx)
+	if err == nil {
+		err = s.wait(ctx)
+	}
+	s.cancel()
+	// gRPC calls served this way can not be drained, the ones left are closed
+	s.grpc.Stop()
+	log.Info().Msg("Single port server: stopped")
+	return err
+}
+
+// ServeHTTP routes a request to the gRPC server or to the REST handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	grpcWeb := strings.HasPrefix(contentType, contentTypeGRPCWeb)
+	grpcCall := grpcWeb || (r.ProtoMajor == 2 && isGRPC(contentType))
+
+	if !grpcCall || !s.isStream(r.URL.Path) {
+		s.active.Add(1)
+		defer s.active.Add(-1)
+	}
+
+	switch {
+	case grpcWeb:
+		serveGRPCWeb(s.grpc, w, r)
+	case grpcCall:
+		s.grpc.ServeHTTP(w, r)
+	default:
+		s.rest.ServeHTTP(w, r)
+	}
+}
+
+// isStream reports whether the full method is a streaming RPC. The services are read on
+// the first call, they are all registered before serving
+func (s *Server) isStream(method string) bool {
+	s.streamsOnce.Do(func() {
+		s.streams = make(map[string]bool)
+		for service, info := range s.grpc.GetServiceInfo() {
+			for _, m := range info.Methods {
+				if m.IsClientStream || m.IsServerStream {
+					s.streams["/"+service+"/"+m.Name] = true
+				}
+			}
+		}
+	})
+	return s.streams[method]
+}
+
+// wait waits until there are no requests in flight, or until ctx is done
+func (s *Server) wait(ctx context.Context) error {
+	ticker := time.NewTicker(stopPollInterval)
+	defer ticker.Stop()
+
+	for s.active.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
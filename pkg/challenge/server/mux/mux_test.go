@@ -0,0 +1,234 @@
+package mux_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/server/mux"
+)
+
+// startServer serves a gRPC health service and a REST /ping route on a single port
+func startServer(t *testing.T) (*mux.Server, string, chan error) {
+	grpcSrv := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcSrv, health.NewServer())
+
+	rest := http.NewServeMux()
+	rest.HandleFunc("GET /ping", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	})
+	rest.HandleFunc("GET /slow", func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	srv, err := mux.New(grpcSrv, rest, mux.WithAddress(":0"))
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(listener) }()
+	return srv, listener.Addr().String(), done
+}
+
+// grpcWebFrames splits a gRPC-Web body in its messages and its trailers
+func grpcWebFrames(t *testing.T, body []byte) ([][]byte, map[string]string) {
+	var messages [][]byte
+	trailers := map[string]string{}
+	for len(body) >= 5 {
+		flag, size := body[0], binary.BigEndian.Uint32(body[1:5])
+		frame := body[5 : 5+size]
+		body = body[5+size:]
+		if flag&0x80 == 0 {
+			messages = append(messages, frame)
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(frame)), "\r\n") {
+			key, value, _ := strings.Cut(line, ": ")
+			trailers[key] = value
+		}
+	}
+	assert.Empty(t, body)
+	return messages, trailers
+}
+
+// grpcWebCall POSTs a single message to the given method with the given content type
+func grpcWebCall(t *testing.T, addr, method, contentType string, msg proto.Message) (*http.Response, []byte) {
+	data, err := proto.Marshal(msg)
+	assert.NoError(t, err)
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	frame = append(frame, data...)
+
+	var body io.Reader = bytes.NewReader(frame)
+	text := strings.HasPrefix(contentType, "application/grpc-web-text")
+	if text {
+		body = strings.NewReader(base64.StdEncoding.EncodeToString(frame))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+method, body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Grpc-Web", "1")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	if text {
+		resBody = decodeSegments(t, string(resBody))
+	}
+	return res, resBody
+}
+
+// decodeSegments decodes a gRPC-Web text body, made of padded base64 segments
+func decodeSegments(t *testing.T, s string) []byte {
+	var out []byte
+	for s != "" {
+		end := len(s)
+		if i := strings.IndexByte(s, '='); i >= 0 {
+			end = i
+			for end < len(s) && s[end] == '=' {
+				end++
+			}
+		}
+		segment, err := base64.StdEncoding.DecodeString(s[:end])
+		assert.NoError(t, err)
+		out = append(out, segment...)
+		s = s[end:]
+	}
+	return out
+}
+
+func TestNew_InvalidAddress(t *testing.T) {
+	_, err := mux.New(grpc.NewServer(), http.NewServeMux(), mux.WithAddress("no-port"))
+	assert.Error(t, err)
+}
+
+func TestServer(t *testing.T) {
+	srv, addr, done := startServer(t)
+	defer srv.Stop(context.Background())
+
+	t.Run("should serve gRPC over h2c", func(t *testing.T) {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		res, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+
+		_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	for _, contentType := range []string{"application/grpc-web+proto", "application/grpc-web-text"} {
+		t.Run("should serve gRPC-Web calls with "+contentType, func(t *testing.T) {
+			res, body := grpcWebCall(t, addr, healthpb.Health_Check_FullMethodName, contentType, &healthpb.HealthCheckRequest{})
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, 1, res.ProtoMajor)
+			assert.True(t, strings.HasPrefix(res.Header.Get("Content-Type"), strings.TrimSuffix(contentType, "+proto")))
+			assert.Empty(t, res.Trailer)
+
+			messages, trailers := grpcWebFrames(t, body)
+			assert.Len(t, messages, 1)
+			var out healthpb.HealthCheckResponse
+			assert.NoError(t, proto.Unmarshal(messages[0], &out))
+			assert.Equal(t, healthpb.HealthCheckResponse_SERVING, out.GetStatus())
+			assert.Equal(t, "0", trailers["grpc-status"])
+		})
+	}
+
+	t.Run("should send gRPC-Web errors in the trailers", func(t *testing.T) {
+		res, body := grpcWebCall(t, addr, healthpb.Health_Check_FullMethodName, "application/grpc-web+proto", &healthpb.HealthCheckRequest{Service: "unknown"})
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		messages, trailers := grpcWebFrames(t, body)
+		assert.Empty(t, messages)
+		assert.Equal(t, "5", trailers["grpc-status"])
+		assert.Equal(t, "unknown service", trailers["grpc-message"])
+	})
+
+	t.Run("should serve the REST API", func(t *testing.T) {
+		res, err := http.Get("http://" + addr + "/ping")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "pong", string(body))
+	})
+
+	t.Run("should send gRPC calls over HTTP/1.1 to the REST API", func(t *testing.T) {
+		res, err := http.Post("http://"+addr+healthpb.Health_Check_FullMethodName, "application/grpc", bytes.NewReader(make([]byte, 5)))
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("should stop serving on Stop", func(t *testing.T) {
+		assert.NoError(t, srv.Stop(context.Background()))
+		assert.ErrorIs(t, <-done, http.ErrServerClosed)
+
+		_, err := http.Get("http://" + addr + "/ping")
+		assert.Error(t, err)
+	})
+}
+
+func TestServer_Stop(t *testing.T) {
+	srv, addr, done := startServer(t)
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	watch, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	res, err := watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+
+	slow := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		slow <- string(body)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	t.Run("should wait for the requests in flight but not for the open streams", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		assert.NoError(t, srv.Stop(ctx))
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.ErrorIs(t, <-done, http.ErrServerClosed)
+
+		assert.Equal(t, "done", <-slow)
+		_, err = watch.Recv()
+		assert.Error(t, err)
+	})
+}
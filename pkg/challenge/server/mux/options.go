@@ -0,0 +1,27 @@
+package mux
+
+import "time"
+
+// Options of the single port server
+type Options struct {
+	// Address where the server will listen
+	Address string
+	// ReadHeaderTimeout bounds how long reading the headers of a request can take
+	ReadHeaderTimeout time.Duration
+}
+
+// Option type to configure the server
+type Option func(o *Options)
+
+func defaultOptions() Options {
+	return Options{
+		ReadHeaderTimeout: time.Second * 60,
+	}
+}
+
+// WithAddress sets the address where the server will listen
+func WithAddress(address string) Option {
+	return func(o *Options) {
+		o.Address = address
+	}
+}
@@ -0,0 +1,18 @@
+package tracectx
+
+import "github.com/rs/zerolog"
+
+// LogHook adds the trace and request IDs of the event context to the log lines, so
+// every line logged with .Ctx(ctx) while handling a request can be correlated
+type LogHook struct{}
+
+// Run implements zerolog.Hook
+func (LogHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	ctx := e.GetCtx()
+	if id := TraceIDFromContext(ctx); id != "" {
+		e.Str(LogField, id)
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		e.Str(RequestIDLogField, id)
+	}
+}
@@ -0,0 +1,148 @@
+package tracectx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// HeaderTraceparent is the W3C Trace Context header, its trace ID is reused when valid
+	HeaderTraceparent = "traceparent"
+	// HeaderTraceID carries the trace ID of a request and is sent back in every response
+	HeaderTraceID = "X-Trace-ID"
+	// HeaderRequestID carries the ID of a single call, unlike the trace ID it is not
+	// shared by the calls made while handling it
+	HeaderRequestID = "X-Request-ID"
+	// LogField is the field the trace ID is logged and stored under
+	LogField = "trace_id"
+	// RequestIDLogField is the field the request ID is logged under
+	RequestIDLogField = "request_id"
+	// MaxTraceIDLength is the longest X-Trace-ID or X-Request-ID accepted from a caller
+	MaxTraceIDLength = 64
+)
+
+type (
+	traceIDKey   struct{}
+	requestIDKey struct{}
+)
+
+// WithTraceID returns a copy of ctx carrying the trace ID of the request being handled
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID of the request being handled, if any
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// WithRequestID returns a copy of ctx carrying the ID of the call being handled
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the ID of the call being handled, if any
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// New returns a random trace ID, 32 lowercase hex characters like the W3C trace IDs
+func New() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Resolve returns the trace ID of an incoming request: the one in a valid traceparent,
+// else a valid X-Trace-ID, else a new one
+func Resolve(traceparent, traceID string) string {
+	if id, ok := ParseTraceparent(traceparent); ok {
+		return id
+	}
+	if ValidID(traceID) {
+		return traceID
+	}
+	return New()
+}
+
+// ResolveRequestID returns the request ID sent by the caller when valid, else a new one
+func ResolveRequestID(requestID string) string {
+	if ValidID(requestID) {
+		return requestID
+	}
+	return New()
+}
+
+// ParseTraceparent returns the trace ID of a version 00 W3C traceparent header
+// (00-<trace id>-<parent id>-<flags>). Future versions may append fields
+func ParseTraceparent(header string) (string, bool) {
+	traceID, _, ok := ParseTraceparentIDs(header)
+	return traceID, ok
+}
+
+// ParseTraceparentIDs is ParseTraceparent also returning the ID of the caller span
+func ParseTraceparentIDs(header string) (traceID, parentID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return "", "", false
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	switch {
+	case !isLowerHex(version, 2) || version == "ff":
+		return "", "", false
+	case version == "00" && len(parts) != 4:
+		return "", "", false
+	case !ValidW3CTraceID(traceID):
+		return "", "", false
+	case !isLowerHex(parentID, 16) || parentID == strings.Repeat("0", 16):
+		return "", "", false
+	case !isLowerHex(flags, 2):
+		return "", "", false
+	}
+	return traceID, parentID, true
+}
+
+// ValidW3CTraceID reports whether a trace ID can be sent in a traceparent header:
+// 32 lowercase hex characters, not all zeros
+func ValidW3CTraceID(traceID string) bool {
+	return isLowerHex(traceID, 32) && traceID != strings.Repeat("0", 32)
+}
+
+// ValidID reports whether a caller provided trace or request ID can be trusted in logs
+// and headers: up to MaxTraceIDLength letters, digits, '-', '_', '.' or ':'
+func ValidID(id string) bool {
+	if id == "" || len(id) > MaxTraceIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package tracectx_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+)
+
+const (
+	traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	w3cTraceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+func TestContext(t *testing.T) {
+	assert.Empty(t, tracectx.TraceIDFromContext(context.Background()))
+
+	ctx := tracectx.WithTraceID(context.Background(), "abc")
+	assert.Equal(t, "abc", tracectx.TraceIDFromContext(ctx))
+	// The key can not be collided with plain strings
+	assert.Nil(t, ctx.Value(tracectx.LogField))
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Empty(t, tracectx.RequestIDFromContext(context.Background()))
+
+	ctx := tracectx.WithRequestID(context.Background(), "req-1")
+	assert.Equal(t, "req-1", tracectx.RequestIDFromContext(ctx))
+	assert.Empty(t, tracectx.TraceIDFromContext(ctx))
+
+	assert.Equal(t, "req-1", tracectx.ResolveRequestID("req-1"))
+	assert.Len(t, tracectx.ResolveRequestID("bad id\n"), 32)
+}
+
+func TestNew(t *testing.T) {
+	id := tracectx.New()
+	assert.Len(t, id, 32)
+	assert.NotEqual(t, id, tracectx.New())
+
+	_, ok := tracectx.ParseTraceparent("00-" + id + "-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := map[string]struct {
+		header string
+		ok     bool
+	}{
+		"valid":                      {header: traceparent, ok: true},
+		"future version with fields": {header: "01-" + w3cTraceID + "-00f067aa0ba902b7-01-extra", ok: true},
+		"empty":                      {header: ""},
+		"invalid version":            {header: "ff-" + w3cTraceID + "-00f067aa0ba902b7-01"},
+		"version 00 with fields":     {header: traceparent + "-extra"},
+		"uppercase trace ID":         {header: "00-" + strings.ToUpper(w3cTraceID) + "-00f067aa0ba902b7-01"},
+		"zero trace ID":              {header: "00-" + strings.Repeat("0", 32) + "-00f067aa0ba902b7-01"},
+		"zero parent ID":             {header: "00-" + w3cTraceID + "-0000000000000000-01"},
+		"short trace ID":             {header: "00-4bf92f35-00f067aa0ba902b7-01"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			id, ok := tracectx.ParseTraceparent(tt.header)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, w3cTraceID, id)
+			}
+		})
+	}
+}
+
+func TestParseTraceparentIDs(t *testing.T) {
+	traceID, parentID, ok := tracectx.ParseTraceparentIDs(traceparent)
+	assert.True(t, ok)
+	assert.Equal(t, w3cTraceID, traceID)
+	assert.Equal(t, "00f067aa0ba902b7", parentID)
+
+	_, _, ok = tracectx.ParseTraceparentIDs("00-" + w3cTraceID + "-0000000000000000-01")
+	assert.False(t, ok)
+
+	assert.True(t, tracectx.ValidW3CTraceID(w3cTraceID))
+	assert.False(t, tracectx.ValidW3CTraceID("checkout-123"))
+}
+
+func TestResolve(t *testing.T) {
+	t.Run("should prefer the traceparent", func(t *testing.T) {
+		assert.Equal(t, w3cTraceID, tracectx.Resolve(traceparent, "from-header"))
+	})
+
+	t.Run("should use a valid X-Trace-ID", func(t *testing.T) {
+		assert.Equal(t, "from-header", tracectx.Resolve("broken", "from-header"))
+	})
+
+	t.Run("should generate one for invalid X-Trace-ID", func(t *testing.T) {
+		id := tracectx.Resolve("", "bad id\n")
+		assert.Len(t, id, 32)
+
+		id = tracectx.Resolve("", strings.Repeat("a", tracectx.MaxTraceIDLength+1))
+		assert.Len(t, id, 32)
+	})
+}
+
+func TestLogHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(tracectx.LogHook{})
+
+	ctx := tracectx.WithRequestID(tracectx.WithTraceID(context.Background(), "abc"), "req-1")
+	logger.Info().Ctx(ctx).Msg("with trace")
+	logger.Info().Msg("without trace")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var withTrace, withoutTrace map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &withTrace))
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &withoutTrace))
+	assert.Equal(t, "abc", withTrace[tracectx.LogField])
+	assert.Equal(t, "req-1", withTrace[tracectx.RequestIDLogField])
+	assert.NotContains(t, withoutTrace, tracectx.LogField)
+	assert.NotContains(t, withoutTrace, tracectx.RequestIDLogField)
+}
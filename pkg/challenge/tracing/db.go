@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// spanKey is the statement setting holding the span of the statement
+const spanKey = "tracing:span"
+
+// InstrumentDB starts a span per statement run through db, child of the span of the
+// statement context (db.WithContext). Statements outside any span, such as the polls of
+// the background workers, are not traced. The SQL is recorded without its values
+func InstrumentDB(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", startStatement("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", endStatement),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", startStatement("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", endStatement),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", startStatement("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", endStatement),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", startStatement("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", endStatement),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", startStatement("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", endStatement),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", startStatement("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", endStatement),
+	)
+}
+
+func startStatement(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if !SpanContextFromContext(db.Statement.Context).Valid() {
+			return
+		}
+
+		name := "db." + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := Start(db.Statement.Context, name, WithKind(KindClient), WithAttributes(map[string]any{
+			"db.system":    "postgresql",
+			"db.operation": operation,
+		}))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endStatement(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(*Span)
+	if !ok {
+		return
+	}
+
+	if db.Statement.Table != "" {
+		span.SetAttribute("db.table", db.Statement.Table)
+	}
+	span.SetAttribute("db.statement", db.Statement.SQL.String())
+	span.SetAttribute("db.rows_affected", db.RowsAffected)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+	}
+	span.End()
+}
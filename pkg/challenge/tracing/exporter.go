@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Exporter sends the finished spans somewhere. Export is called once per span, when
+// it ends, from the goroutine ending it
+type Exporter interface {
+	Export(span SpanData) error
+}
+
+// JSONExporter writes every span as a JSON line
+type JSONExporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONExporter returns an exporter writing to w
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{enc: json.NewEncoder(w)}
+}
+
+// NewStdoutExporter returns an exporter writing to the standard output
+func NewStdoutExporter() *JSONExporter {
+	return NewJSONExporter(os.Stdout)
+}
+
+// Export implements Exporter
+func (e *JSONExporter) Export(span SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(span)
+}
+
+// MemoryExporter keeps the spans in memory, for tests
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewMemoryExporter returns an empty in-memory exporter
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+// Export implements Exporter
+func (e *MemoryExporter) Export(span SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+// Spans returns the exported spans in the order they ended
+func (e *MemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Find returns the first exported span with the given name
+func (e *MemoryExporter) Find(name string) (SpanData, bool) {
+	for _, s := range e.Spans() {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return SpanData{}, false
+}
+
+// Reset drops the exported spans
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
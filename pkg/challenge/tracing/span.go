@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+)
+
+// Kind tells the role of a span in the trace, as in OpenTelemetry
+type Kind string
+
+const (
+	// KindInternal is an operation inside the service, such as a service call
+	KindInternal Kind = "internal"
+	// KindServer is an incoming HTTP request or gRPC call
+	KindServer Kind = "server"
+	// KindClient is an outgoing call, such as a DB statement or a webhook delivery
+	KindClient Kind = "client"
+	// KindProducer is an event publish
+	KindProducer Kind = "producer"
+	// KindConsumer is an event handler run
+	KindConsumer Kind = "consumer"
+)
+
+// Status of a finished span
+type Status string
+
+const (
+	// StatusOK means the operation succeeded
+	StatusOK Status = "ok"
+	// StatusError means the operation failed, see SpanData.Error
+	StatusError Status = "error"
+)
+
+// SpanContext identifies a span across process boundaries
+type SpanContext struct {
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+}
+
+// Valid reports whether the span context identifies a span
+func (sc SpanContext) Valid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// Traceparent returns the W3C traceparent header of the span. It is empty when the trace ID
+// is not a W3C one, such as an X-Trace-ID sent by a caller
+func (sc SpanContext) Traceparent() string {
+	if !tracectx.ValidW3CTraceID(sc.TraceID) || sc.SpanID == "" {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", sc.TraceID, sc.SpanID)
+}
+
+// SpanData is a finished span, as exporters get it
+type SpanData struct {
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Name         string         `json:"name"`
+	Kind         Kind           `json:"kind"`
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Status       Status         `json:"status"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// Duration returns how long the span lasted
+func (d SpanData) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// Span is an operation being traced. Its methods can be called on a nil *Span
+// and from several goroutines
+type Span struct {
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the IDs of the span, to be carried to its children
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID}
+}
+
+// SetAttribute records a key/value describing the operation
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]any)
+	}
+	s.data.Attributes[key] = value
+}
+
+// RecordError marks the span as failed. A nil error does nothing
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = StatusError
+	s.data.Error = err.Error()
+}
+
+// End finishes the span and exports it. Only the first call counts
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	if s.data.Status == "" {
+		s.data.Status = StatusOK
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	export(data)
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+)
+
+type spanContextKey struct{}
+
+// exporterHolder lets atomic.Value store a nil exporter
+type exporterHolder struct {
+	exporter Exporter
+}
+
+var current atomic.Value
+
+// SetExporter sets where the finished spans of the process go, nil drops them.
+// Spans are still created without exporter so their IDs keep linking the events
+func SetExporter(e Exporter) {
+	current.Store(exporterHolder{exporter: e})
+}
+
+func export(data SpanData) {
+	h, _ := current.Load().(exporterHolder)
+	if h.exporter == nil {
+		return
+	}
+	if err := h.exporter.Export(data); err != nil {
+		log.Error().Err(err).Str("tracing", "export").Str("span", data.Name).Msg("could not export span")
+	}
+}
+
+type startConfig struct {
+	kind       Kind
+	parent     SpanContext
+	attributes map[string]any
+}
+
+// StartOption configures a new span
+type StartOption func(*startConfig)
+
+// WithKind sets the kind of the span, KindInternal by default
+func WithKind(k Kind) StartOption {
+	return func(c *startConfig) {
+		c.kind = k
+	}
+}
+
+// WithParent makes the span a child of a span of another process or of an event,
+// instead of the span of the context
+func WithParent(sc SpanContext) StartOption {
+	return func(c *startConfig) {
+		if sc.Valid() {
+			c.parent = sc
+		}
+	}
+}
+
+// WithAttributes sets attributes of the span from its start
+func WithAttributes(attributes map[string]any) StartOption {
+	return func(c *startConfig) {
+		c.attributes = attributes
+	}
+}
+
+// Start starts a span, child of the span of ctx, and returns a copy of ctx carrying it.
+// The span belongs to the trace of the request (tracectx), a new trace is started when
+// there is none, so the log lines of the operation carry its trace ID as well
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	cfg := startConfig{kind: KindInternal, parent: SpanContextFromContext(ctx)}
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	traceID := tracectx.TraceIDFromContext(ctx)
+	if traceID == "" {
+		traceID = cfg.parent.TraceID
+		if traceID == "" {
+			traceID = tracectx.New()
+		}
+		ctx = tracectx.WithTraceID(ctx, traceID)
+	}
+
+	// A parent of another trace, e.g. an event replayed by a request, is not a parent
+	parentID := cfg.parent.SpanID
+	if cfg.parent.TraceID != traceID {
+		parentID = ""
+	}
+
+	span := &Span{data: SpanData{
+		TraceID:      traceID,
+		SpanID:       newSpanID(),
+		ParentSpanID: parentID,
+		Name:         name,
+		Kind:         cfg.kind,
+		Start:        time.Now(),
+	}}
+	for k, v := range cfg.attributes {
+		span.SetAttribute(k, v)
+	}
+	return ContextWithSpanContext(ctx, span.SpanContext()), span
+}
+
+// ContextWithSpanContext returns a copy of ctx whose spans are children of the given one
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span the context is in, if any
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// SpanIDFromContext returns the ID of the span the context is in, if any.
+// It is stored in the event payloads so their handlers are linked to it
+func SpanIDFromContext(ctx context.Context) string {
+	return SpanContextFromContext(ctx).SpanID
+}
+
+// newSpanID returns a random span ID, 16 lowercase hex characters like the W3C span IDs
+func newSpanID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
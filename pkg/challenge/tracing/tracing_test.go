@@ -0,0 +1,196 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracectx"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+type row struct {
+	ID int
+}
+
+func (row) TableName() string {
+	return "challenge.row"
+}
+
+// newUnreachableDB returns a gorm DB whose statements fail to connect, unless run in dry run mode
+func newUnreachableDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.Open("host=127.0.0.1 port=1 connect_timeout=1"), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	assert.NoError(t, err)
+	return db
+}
+
+// useMemoryExporter sends the spans of the test to a memory exporter
+func useMemoryExporter(t *testing.T) *tracing.MemoryExporter {
+	exporter := tracing.NewMemoryExporter()
+	tracing.SetExporter(exporter)
+	t.Cleanup(func() { tracing.SetExporter(nil) })
+	return exporter
+}
+
+func TestStart(t *testing.T) {
+	exporter := useMemoryExporter(t)
+
+	t.Run("should start a trace when the context has none", func(t *testing.T) {
+		ctx, span := tracing.Start(context.Background(), "root")
+		span.End()
+
+		sc := span.SpanContext()
+		assert.True(t, sc.Valid())
+		assert.Equal(t, sc.TraceID, tracectx.TraceIDFromContext(ctx))
+		assert.Equal(t, sc, tracing.SpanContextFromContext(ctx))
+		assert.Len(t, sc.SpanID, 16)
+
+		data, ok := exporter.Find("root")
+		assert.True(t, ok)
+		assert.Empty(t, data.ParentSpanID)
+		assert.Equal(t, tracing.KindInternal, data.Kind)
+		assert.Equal(t, tracing.StatusOK, data.Status)
+		assert.GreaterOrEqual(t, data.Duration().Nanoseconds(), int64(0))
+	})
+
+	t.Run("should keep the trace ID of the request", func(t *testing.T) {
+		ctx := tracectx.WithTraceID(context.Background(), "checkout-123")
+		_, span := tracing.Start(ctx, "request")
+		assert.Equal(t, "checkout-123", span.SpanContext().TraceID)
+		assert.Empty(t, span.SpanContext().Traceparent())
+	})
+
+	t.Run("should make spans children of the span of the context", func(t *testing.T) {
+		exporter.Reset()
+		ctx, parent := tracing.Start(context.Background(), "parent")
+		_, child := tracing.Start(ctx, "child", tracing.WithKind(tracing.KindClient))
+		child.End()
+		parent.End()
+
+		spans := exporter.Spans()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, "child", spans[0].Name)
+		assert.Equal(t, parent.SpanContext().SpanID, spans[0].ParentSpanID)
+		assert.Equal(t, parent.SpanContext().TraceID, spans[0].TraceID)
+		assert.Equal(t, tracing.KindClient, spans[0].Kind)
+	})
+
+	t.Run("should make spans children of a remote parent", func(t *testing.T) {
+		parent := tracing.SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+		ctx, span := tracing.Start(context.Background(), "handle", tracing.WithParent(parent))
+		span.End()
+
+		data, _ := exporter.Find("handle")
+		assert.Equal(t, parent.TraceID, data.TraceID)
+		assert.Equal(t, parent.SpanID, data.ParentSpanID)
+		assert.Equal(t, parent.TraceID, tracectx.TraceIDFromContext(ctx))
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+data.SpanID+"-01", span.SpanContext().Traceparent())
+	})
+
+	t.Run("should drop a parent of another trace", func(t *testing.T) {
+		ctx := tracectx.WithTraceID(context.Background(), "checkout-123")
+		_, span := tracing.Start(ctx, "other", tracing.WithParent(tracing.SpanContext{TraceID: "replayed", SpanID: "00f067aa0ba902b7"}))
+		span.End()
+
+		data, _ := exporter.Find("other")
+		assert.Equal(t, "checkout-123", data.TraceID)
+		assert.Empty(t, data.ParentSpanID)
+	})
+}
+
+func TestSpan(t *testing.T) {
+	exporter := useMemoryExporter(t)
+
+	_, span := tracing.Start(context.Background(), "failing", tracing.WithAttributes(map[string]any{"user.id": "1"}))
+	span.SetAttribute("attempt", 2)
+	span.RecordError(nil)
+	span.RecordError(errors.New("boom"))
+	span.End()
+	span.End()
+
+	spans := exporter.Spans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, tracing.StatusError, spans[0].Status)
+	assert.Equal(t, "boom", spans[0].Error)
+	assert.Equal(t, map[string]any{"user.id": "1", "attempt": 2}, spans[0].Attributes)
+
+	t.Run("should do nothing on a nil span", func(t *testing.T) {
+		var span *tracing.Span
+		assert.NotPanics(t, func() {
+			span.SetAttribute("k", "v")
+			span.RecordError(errors.New("boom"))
+			span.End()
+		})
+		assert.False(t, span.SpanContext().Valid())
+	})
+}
+
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracing.SetExporter(tracing.NewJSONExporter(&buf))
+	defer tracing.SetExporter(nil)
+
+	_, span := tracing.Start(context.Background(), "userService.Create")
+	span.End()
+
+	var data tracing.SpanData
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal(t, "userService.Create", data.Name)
+	assert.Equal(t, span.SpanContext().SpanID, data.SpanID)
+	assert.Equal(t, tracing.StatusOK, data.Status)
+}
+
+func TestInstrumentDB(t *testing.T) {
+	exporter := useMemoryExporter(t)
+	db := newUnreachableDB(t)
+	assert.NoError(t, tracing.InstrumentDB(db))
+
+	t.Run("should not trace statements outside a span", func(t *testing.T) {
+		dryRun := db.Session(&gorm.Session{DryRun: true})
+		assert.NoError(t, dryRun.Find(&[]row{}).Error)
+		assert.Empty(t, exporter.Spans())
+	})
+
+	t.Run("should trace statements as children of the span of their context", func(t *testing.T) {
+		ctx, parent := tracing.Start(context.Background(), "userService.Get")
+		dryRun := db.WithContext(ctx).Session(&gorm.Session{DryRun: true})
+		assert.NoError(t, dryRun.Create(&row{ID: 1}).Error)
+		assert.NoError(t, dryRun.Where("id = ?", 1).Find(&[]row{}).Error)
+		parent.End()
+
+		data, ok := exporter.Find("db.query row")
+		assert.True(t, ok)
+		assert.Equal(t, parent.SpanContext().SpanID, data.ParentSpanID)
+		assert.Equal(t, tracing.KindClient, data.Kind)
+		assert.Equal(t, "query", data.Attributes["db.operation"])
+		assert.Equal(t, "row", data.Attributes["db.table"])
+		assert.Contains(t, data.Attributes["db.statement"], "WHERE id = $1")
+		assert.NotContains(t, data.Attributes["db.statement"], "id = 1")
+
+		_, ok = exporter.Find("db.create row")
+		assert.True(t, ok)
+	})
+
+	t.Run("should record failed statements", func(t *testing.T) {
+		exporter.Reset()
+		ctx, parent := tracing.Start(context.Background(), "userService.Get")
+		assert.Error(t, db.WithContext(ctx).First(&row{}).Error)
+		parent.End()
+
+		data, ok := exporter.Find("db.query row")
+		assert.True(t, ok)
+		assert.Equal(t, tracing.StatusError, data.Status)
+		assert.NotEmpty(t, data.Error)
+	})
+}
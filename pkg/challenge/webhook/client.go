@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+)
+
+// ErrPrivateAddress is returned when a delivery would reach a non public address
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// NewClient returns the HTTP client used by default to send the deliveries. The
+// addresses are checked once resolved, right before connecting, so host names pointing
+// to the internal network and redirects to it are refused as well. Proxies from the
+// environment are not used, the check must see the partner address
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicOnly,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// publicOnly refuses to connect to the addresses webhooks can not be delivered to
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+	if !webhook.IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package webhook_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/webhook"
+)
+
+func TestNewClient(t *testing.T) {
+	var called atomic.Bool
+	sink := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called.Store(true)
+	}))
+	defer sink.Close()
+
+	t.Run("should not connect to loopback addresses", func(t *testing.T) {
+		_, err := webhook.NewClient(time.Second).Post(sink.URL, "application/json", nil)
+		assert.ErrorIs(t, err, webhook.ErrPrivateAddress)
+		assert.False(t, called.Load())
+	})
+
+	t.Run("should not connect to host names resolving to loopback addresses", func(t *testing.T) {
+		_, err := webhook.NewClient(time.Second).Post("http://localhost:1/hooks", "application/json", nil)
+		assert.ErrorIs(t, err, webhook.ErrPrivateAddress)
+	})
+}
@@ -0,0 +1,482 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+
+	dbInstance "github.com/nachoconques0/user_challenge_svc/pkg/challenge/db"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/env"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/tracing"
+)
+
+const (
+	// ErrMissingDB used when DB is nil
+	ErrMissingDB = "Dispatcher is missing DB connection"
+	// ErrMissingTestEnv when test env is missing
+	ErrMissingTestEnv = "DB connection can only be a TX when ENV == env.Test"
+	// ErrWebhookInactive recorded on the attempts dropped because their webhook was deleted or disabled
+	ErrWebhookInactive = "webhook is deleted or disabled"
+)
+
+// Payload is the body POSTed to the partners
+type Payload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// job is a single delivery of an event to a webhook
+type job struct {
+	webhookID uuid.UUID
+	url       string
+	secret    string
+	eventID   uuid.UUID
+	eventType string
+	body      []byte
+	attempt   int
+	// deliveryID is the row of the attempt in challenge.webhook_delivery
+	deliveryID uuid.UUID
+	// span is the handler span of the event, parent of the delivery spans
+	span tracing.SpanContext
+}
+
+// Dispatcher subscribes to the user events and delivers them to the webhooks
+// registered for their type. Every attempt is stored in challenge.webhook_delivery
+// before it is sent, failed attempts are retried with exponential backoff, and
+// webhooks failing too many deliveries in a row are disabled
+type Dispatcher struct {
+	db     *gorm.DB
+	testTx bool
+	client *http.Client
+	opts   Options
+	jobs   chan job
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns a new webhook dispatcher
+func New(db *gorm.DB, e string, opts ...Option) (*Dispatcher, error) {
+	options := defaultOptions()
+	for _, o := range opts {
+		o(&options)
+	}
+
+	switch {
+	case db == nil:
+		return nil, errors.New(ErrMissingDB)
+	case dbInstance.IsTransaction(db) && !env.IsTest(e):
+		return nil, errors.New(ErrMissingTestEnv)
+	}
+	testTx := dbInstance.IsTransaction(db)
+
+	client := options.Client
+	if client == nil {
+		client = NewClient(options.Timeout)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		db:     db,
+		testTx: testTx,
+		client: client,
+		opts:   options,
+		jobs:   make(chan job, options.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Register subscribes the dispatcher to every user event type
+func (d *Dispatcher) Register(sub pubsub.Subscriber) error {
+	for _, t := range event.Types {
+		eventType := t
+		if err := sub.Subscribe(eventType, func(ctx context.Context, payload interface{}) {
+			d.enqueueEvent(ctx, eventType, payload)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run starts the delivery workers until Stop is called. Besides the new events, the
+// workers poll challenge.webhook_delivery for the attempts that are due.
+// This method will block the calling go routine
+func (d *Dispatcher) Run() error {
+	defer close(d.done)
+	log.Info().Msgf("Webhook dispatcher: running %d workers", d.opts.Workers)
+
+	for i := 0; i < d.opts.Workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			ticker := time.NewTicker(d.opts.PollInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-d.ctx.Done():
+					return
+				case j := <-d.jobs:
+					d.deliver(j)
+				case <-ticker.C:
+					if err := d.poll(); err != nil {
+						log.Error().Err(err).Msg("Webhook dispatcher: could not send due deliveries")
+					}
+				}
+			}
+		}()
+	}
+
+	<-d.ctx.Done()
+	d.wg.Wait()
+	return nil
+}
+
+// Stop stops the workers and waits for the deliveries in flight to finish.
+// The attempts not sent yet are stored, they are sent once running again
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	log.Info().Msg("Webhook dispatcher: stopping...")
+	d.stopOnce.Do(d.cancel)
+
+	select {
+	case <-d.done:
+		log.Info().Msg("Webhook dispatcher: stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) enqueueEvent(ctx context.Context, eventType string, payload interface{}) {
+	eventID, err := uuid.Parse(pubsub.EventIDFromContext(ctx))
+	if err != nil {
+		log.Error().Err(err).Str("event_type", eventType).Msg("Webhook dispatcher: event without a valid ID")
+		return
+	}
+
+	webhooks, err := repo.FindActiveWebhooksForEvent(d.db, eventType)
+	if err != nil {
+		log.Error().Err(err).Str("event_id", eventID.String()).Msg("Webhook dispatcher: could not find webhooks")
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	// Publishers that don't know when the event was stored leave the time out
+	createdAt := pubsub.EventTimeFromContext(ctx)
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	body, err := json.Marshal(Payload{
+		ID:        eventID.String(),
+		Type:      eventType,
+		CreatedAt: createdAt.UTC(),
+		Data:      payload,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("event_id", eventID.String()).Msg("Webhook dispatcher: could not encode payload")
+		return
+	}
+
+	// The attempts are stored before they are sent, so the ones the workers don't get
+	// to send are found by the poll
+	now := time.Now()
+	deliveries := make([]*webhook.Delivery, 0, len(webhooks))
+	tx := d.begin()
+	defer d.rollback(tx)
+	for _, w := range webhooks {
+		delivery := &webhook.Delivery{
+			WebhookID:     w.ID,
+			EventID:       eventID,
+			EventType:     eventType,
+			Attempt:       1,
+			Payload:       body,
+			NextAttemptAt: &now,
+		}
+		if err := repo.CreateWebhookDelivery(delivery, tx); err != nil {
+			log.Error().Err(err).Str("event_id", eventID.String()).Msg("Webhook dispatcher: could not store deliveries")
+			return
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := d.commit(tx); err != nil {
+		log.Error().Err(err).Str("event_id", eventID.String()).Msg("Webhook dispatcher: could not store deliveries")
+		return
+	}
+
+	for i, w := range webhooks {
+		d.enqueue(job{
+			webhookID:  w.ID,
+			url:        w.URL,
+			secret:     w.Secret,
+			eventID:    eventID,
+			eventType:  eventType,
+			body:       body,
+			attempt:    1,
+			deliveryID: deliveries[i].ID,
+			span:       tracing.SpanContextFromContext(ctx),
+		})
+	}
+}
+
+// enqueue hands a stored attempt to the workers. When the queue is full it is left to
+// the poll
+func (d *Dispatcher) enqueue(j job) {
+	select {
+	case d.jobs <- j:
+	default:
+	}
+}
+
+// deliver sends the first attempt of an event, unless a poll claimed it already
+func (d *Dispatcher) deliver(j job) {
+	now := time.Now()
+	claimed, err := repo.ClaimWebhookDelivery(j.deliveryID, now, now.Add(d.claimFor()), d.db)
+	if err != nil {
+		log.Error().Err(err).Str("webhook_id", j.webhookID.String()).Msg("Webhook dispatcher: could not claim delivery")
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if err := d.attempt(j); err != nil {
+		log.Error().Err(err).Str("webhook_id", j.webhookID.String()).Msg("Webhook dispatcher: could not record delivery")
+	}
+}
+
+// poll sends one batch of the attempts that are due. They are claimed in a short
+// transaction first, so no transaction is open while the partners answer
+func (d *Dispatcher) poll() error {
+	jobs, err := d.claim()
+	if err != nil {
+		return err
+	}
+
+	for _, j := range jobs {
+		if err := d.attempt(j); err != nil {
+			log.Error().Err(err).Str("webhook_id", j.webhookID.String()).Msg("Webhook dispatcher: could not record delivery")
+		}
+	}
+	return nil
+}
+
+// claim returns the attempts that are due, pushing them until the claim expires so
+// concurrent workers don't send them too
+func (d *Dispatcher) claim() ([]job, error) {
+	tx := d.begin()
+	defer d.rollback(tx)
+
+	now := time.Now()
+	deliveries, err := repo.FindDueWebhookDeliveries(tx, now, d.opts.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]job, 0, len(deliveries))
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		// Deleted or disabled webhooks don't get deliveries
+		w, err := repo.GetWebhook(delivery.WebhookID, tx)
+		if errors.Is(err, repo.ErrRecordNotFound) || (err == nil && !w.Active) {
+			delivery.Error = ErrWebhookInactive
+			if err := repo.CompleteWebhookDelivery(delivery, tx); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := repo.ClaimWebhookDelivery(delivery.ID, now, now.Add(d.claimFor()), tx); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job{
+			webhookID:  w.ID,
+			url:        w.URL,
+			secret:     w.Secret,
+			eventID:    delivery.EventID,
+			eventType:  delivery.EventType,
+			body:       delivery.Payload,
+			attempt:    delivery.Attempt,
+			deliveryID: delivery.ID,
+		})
+	}
+
+	return jobs, d.commit(tx)
+}
+
+// claimFor is how long a claimed attempt is left to its worker before others send it
+func (d *Dispatcher) claimFor() time.Duration {
+	return 2 * d.opts.Timeout
+}
+
+// attempt sends one attempt and then records its outcome. A failed attempt schedules
+// the next one after a backoff, the last one counts as a failure of the webhook
+func (d *Dispatcher) attempt(j job) error {
+	start := time.Now()
+	statusCode, err := d.send(j)
+
+	delivery := &webhook.Delivery{
+		ID:         j.deliveryID,
+		Success:    err == nil,
+		StatusCode: statusCode,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	var next *webhook.Delivery
+	if err != nil {
+		delivery.Error = err.Error()
+		log.Error().Err(err).
+			Str("webhook_id", j.webhookID.String()).
+			Str("event_id", j.eventID.String()).
+			Int("attempt", j.attempt).
+			Msg("Webhook dispatcher: delivery failed")
+
+		if j.attempt < d.opts.MaxAttempts {
+			nextAttemptAt := time.Now().Add(d.backoff(j.attempt))
+			next = &webhook.Delivery{
+				WebhookID:     j.webhookID,
+				EventID:       j.eventID,
+				EventType:     j.eventType,
+				Attempt:       j.attempt + 1,
+				Payload:       j.body,
+				NextAttemptAt: &nextAttemptAt,
+			}
+		}
+	}
+
+	tx := d.begin()
+	defer d.rollback(tx)
+
+	if err := repo.CompleteWebhookDelivery(delivery, tx); err != nil {
+		return err
+	}
+
+	switch {
+	case err == nil:
+		if err := repo.MarkWebhookSucceeded(j.webhookID, tx); err != nil {
+			return err
+		}
+		return d.commit(tx)
+	case next != nil:
+		if err := repo.CreateWebhookDelivery(next, tx); err != nil {
+			return err
+		}
+		return d.commit(tx)
+	}
+
+	disabled, err := repo.MarkWebhookFailed(j.webhookID, d.opts.DisableAfter, tx)
+	if err != nil {
+		return err
+	}
+	if err := d.commit(tx); err != nil {
+		return err
+	}
+	if disabled {
+		log.Warn().Str("webhook_id", j.webhookID.String()).Msg("Webhook dispatcher: webhook disabled after too many failures")
+	}
+	return nil
+}
+
+// send POSTs the signed payload. Any non 2xx answer is an error
+func (d *Dispatcher) send(j job) (statusCode int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.opts.Timeout)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "webhook POST", tracing.WithKind(tracing.KindClient), tracing.WithParent(j.span), tracing.WithAttributes(map[string]any{
+		"webhook.id":      j.webhookID.String(),
+		"webhook.attempt": j.attempt,
+		"event.id":        j.eventID.String(),
+		"event.type":      j.eventType,
+	}))
+	defer func() {
+		span.SetAttribute("http.status_code", statusCode)
+		span.RecordError(err)
+		span.End()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.url, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, j.eventID.String())
+	req.Header.Set(HeaderEvent, j.eventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(j.secret, now, j.body))
+	if traceparent := span.SpanContext().Traceparent(); traceparent != "" {
+		req.Header.Set("traceparent", traceparent)
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff returns the delay before the given attempt is retried
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.opts.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= d.opts.MaxBackoff {
+			return d.opts.MaxBackoff
+		}
+	}
+	return delay
+}
+
+func (d *Dispatcher) begin() *gorm.DB {
+	if d.testTx {
+		return d.db
+	}
+	return d.db.Begin()
+}
+
+func (d *Dispatcher) commit(tx *gorm.DB) error {
+	if !d.testTx {
+		return tx.Commit().Error
+	}
+	return nil
+}
+
+func (d *Dispatcher) rollback(tx *gorm.DB) {
+	if !d.testTx {
+		tx.Rollback()
+	}
+}
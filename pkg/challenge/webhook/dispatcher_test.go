@@ -0,0 +1,187 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/helpers"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/user/event"
+	entity "github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/entity/webhook"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/internal/repo"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/pubsub/local"
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/webhook"
+)
+
+func TestDispatcher(t *testing.T) {
+	db, teardown, err := helpers.NewTestDB()
+	assert.NoError(t, err)
+	defer teardown()
+
+	t.Run("should deliver signed events and retry failed attempts", func(t *testing.T) {
+		secret := "0123456789abcdef"
+		var calls atomic.Int32
+		var received webhook.Payload
+		sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if err := webhook.Verify(secret, r.Header, body, time.Minute); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_ = json.Unmarshal(body, &received)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer sink.Close()
+
+		hook := createWebhook(t, db, sink.URL, secret)
+		bus := startDispatcher(t, db, webhook.WithMaxAttempts(3))
+
+		eventID := uuid.New()
+		createdAt := time.Date(2025, 5, 12, 8, 30, 0, 0, time.UTC)
+		ctx := pubsub.WithEventTime(context.Background(), createdAt)
+		_ = bus.Publish(ctx, eventID.String(), event.UserCreated, event.CreatedPayload{UserID: uuid.NewString()})
+
+		assert.Eventually(t, func() bool {
+			return calls.Load() == 2
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, eventID.String(), received.ID)
+		assert.Equal(t, event.UserCreated, received.Type)
+		assert.Equal(t, createdAt, received.CreatedAt)
+
+		assert.Eventually(t, func() bool {
+			deliveries, err := repo.FindWebhookDeliveries(db, hook.ID, 1, 10)
+			return err == nil && len(deliveries) == 2
+		}, time.Second, 10*time.Millisecond)
+
+		deliveries, err := repo.FindWebhookDeliveries(db, hook.ID, 1, 10)
+		assert.NoError(t, err)
+		assert.True(t, deliveries[0].Success)
+		assert.Equal(t, 2, deliveries[0].Attempt)
+		assert.False(t, deliveries[1].Success)
+		assert.Equal(t, http.StatusInternalServerError, deliveries[1].StatusCode)
+		assert.Nil(t, deliveries[1].NextAttemptAt)
+	})
+
+	t.Run("should send the attempts stored before a restart", func(t *testing.T) {
+		var received atomic.Value
+		sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received.Store(string(body))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer sink.Close()
+
+		hook := createWebhook(t, db, sink.URL, "0123456789abcdef")
+		body := `{"id":"` + uuid.NewString() + `","type":"` + event.UserCreated + `"}`
+		due := time.Now().Add(-time.Second)
+		assert.NoError(t, repo.CreateWebhookDelivery(&entity.Delivery{
+			WebhookID:     hook.ID,
+			EventID:       uuid.New(),
+			EventType:     event.UserCreated,
+			Attempt:       2,
+			Payload:       []byte(body),
+			NextAttemptAt: &due,
+		}, db))
+
+		deliveries, err := repo.FindWebhookDeliveries(db, hook.ID, 1, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, deliveries)
+
+		startDispatcher(t, db, webhook.WithMaxAttempts(3))
+
+		assert.Eventually(t, func() bool {
+			return received.Load() != nil
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.JSONEq(t, body, received.Load().(string))
+
+		assert.Eventually(t, func() bool {
+			deliveries, err := repo.FindWebhookDeliveries(db, hook.ID, 1, 10)
+			return err == nil && len(deliveries) == 1 && deliveries[0].Success && deliveries[0].Attempt == 2
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should send the first attempts the workers could not take", func(t *testing.T) {
+		var calls atomic.Int32
+		sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer sink.Close()
+
+		hook := createWebhook(t, db, sink.URL, "0123456789abcdef")
+		bus := startDispatcher(t, db, webhook.WithQueueSize(0))
+
+		for i := 0; i < 3; i++ {
+			_ = bus.Publish(context.Background(), uuid.NewString(), event.UserCreated, event.CreatedPayload{UserID: uuid.NewString()})
+		}
+
+		assert.Eventually(t, func() bool {
+			deliveries, err := repo.FindWebhookDeliveries(db, hook.ID, 1, 10)
+			return err == nil && len(deliveries) == 3
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("should disable webhooks that keep failing", func(t *testing.T) {
+		sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer sink.Close()
+
+		hook := createWebhook(t, db, sink.URL, "0123456789abcdef")
+		bus := startDispatcher(t, db, webhook.WithMaxAttempts(2), webhook.WithDisableAfter(1))
+
+		_ = bus.Publish(context.Background(), uuid.NewString(), event.UserUpdated, event.UpdatedPayload{UserID: uuid.NewString()})
+
+		assert.Eventually(t, func() bool {
+			w, err := repo.GetWebhook(hook.ID, db)
+			return err == nil && !w.Active && w.DisabledAt != nil
+		}, 2*time.Second, 10*time.Millisecond)
+
+		deliveries, err := repo.FindWebhookDeliveries(db, hook.ID, 1, 10)
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 2)
+	})
+}
+
+func createWebhook(t *testing.T, db *gorm.DB, url, secret string) *entity.Entity {
+	hook, err := repo.CreateWebhook(&entity.Entity{
+		URL:        url,
+		EventTypes: []string{event.UserCreated, event.UserUpdated},
+		Secret:     secret,
+	}, db)
+	assert.NoError(t, err)
+	return hook
+}
+
+func startDispatcher(t *testing.T, db *gorm.DB, opts ...webhook.Option) *local.Bus {
+	opts = append([]webhook.Option{
+		webhook.WithWorkers(1),
+		webhook.WithBackoff(10*time.Millisecond, 10*time.Millisecond),
+		webhook.WithPollInterval(10 * time.Millisecond),
+		// The sinks listen on the loopback, refused by the default client
+		webhook.WithClient(&http.Client{Timeout: time.Second}),
+	}, opts...)
+	d, err := webhook.New(db, "test", opts...)
+	assert.NoError(t, err)
+
+	bus := local.NewBus()
+	assert.NoError(t, d.Register(bus))
+	go func() { _ = d.Run() }()
+	t.Cleanup(func() { _ = d.Stop(context.Background()) })
+	return bus
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"net/http"
+	"time"
+)
+
+// Retrieve the default options
+func defaultOptions() Options {
+	return Options{
+		Workers:        4,
+		QueueSize:      1000,
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Timeout:        10 * time.Second,
+		DisableAfter:   5,
+		PollInterval:   time.Second,
+		BatchSize:      10,
+	}
+}
+
+type Options struct {
+	// Workers is the number of deliveries sent concurrently
+	Workers int
+	// QueueSize is the number of deliveries that can wait for a worker
+	QueueSize int
+	// MaxAttempts is the number of times a delivery is tried before giving up
+	MaxAttempts int
+	// InitialBackoff is the delay after the first failed attempt, doubled on every retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+	// Timeout is the max time a partner has to answer a delivery
+	Timeout time.Duration
+	// DisableAfter is the number of consecutive failed deliveries after which a webhook is disabled
+	DisableAfter int
+	// PollInterval is how often the workers look for retries that are due
+	PollInterval time.Duration
+	// BatchSize is the max number of retries sent per poll
+	BatchSize int
+	// Client used to send the deliveries, instead of the one of NewClient. It has to
+	// refuse the internal addresses itself
+	Client *http.Client
+}
+
+// WithWorkers sets the number of deliveries sent concurrently
+func WithWorkers(n int) Option {
+	return func(o *Options) {
+		o.Workers = n
+	}
+}
+
+// WithQueueSize sets the number of deliveries that can wait for a worker
+func WithQueueSize(n int) Option {
+	return func(o *Options) {
+		o.QueueSize = n
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is tried
+func WithMaxAttempts(n int) Option {
+	return func(o *Options) {
+		o.MaxAttempts = n
+	}
+}
+
+// WithBackoff sets the initial and max delay between delivery retries
+func WithBackoff(initial, maxBackoff time.Duration) Option {
+	return func(o *Options) {
+		o.InitialBackoff = initial
+		o.MaxBackoff = maxBackoff
+	}
+}
+
+// WithTimeout sets the max time a partner has to answer a delivery
+func WithTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// WithDisableAfter sets after how many consecutive failed deliveries a webhook is disabled
+func WithDisableAfter(n int) Option {
+	return func(o *Options) {
+		o.DisableAfter = n
+	}
+}
+
+// WithPollInterval sets how often the workers look for retries that are due
+func WithPollInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.PollInterval = d
+	}
+}
+
+// WithBatchSize sets the max number of retries sent per poll
+func WithBatchSize(n int) Option {
+	return func(o *Options) {
+		o.BatchSize = n
+	}
+}
+
+// WithClient sets the HTTP client used to send the deliveries
+func WithClient(c *http.Client) Option {
+	return func(o *Options) {
+		o.Client = c
+	}
+}
+
+type Option func(*Options)
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderID carries the ID of the event, partners can use it to drop duplicated deliveries
+	HeaderID = "X-Webhook-ID"
+	// HeaderEvent carries the event type
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderTimestamp carries the unix time the delivery was signed at
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature carries the HMAC-SHA256 signature of the delivery
+	HeaderSignature = "X-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	ErrMissingSignature = errors.New("webhook signature or timestamp is missing")
+	ErrInvalidSignature = errors.New("webhook signature is not valid")
+	ErrExpiredSignature = errors.New("webhook timestamp is outside the tolerance")
+)
+
+// Sign returns the signature of a delivery: sha256=hex(HMAC-SHA256(secret, "<timestamp>.<body>")).
+// The timestamp is part of the signed content so captured deliveries cannot be replayed later
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of a received delivery. It is meant for partners
+// written in Go and for tests. Deliveries signed more than tolerance ago are rejected
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	signature := header.Get(HeaderSignature)
+	tsStr := header.Get(HeaderTimestamp)
+	if signature == "" || tsStr == "" || !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrMissingSignature
+	}
+
+	ts, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	timestamp := time.Unix(ts, 0)
+
+	if tolerance > 0 {
+		if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+			return ErrExpiredSignature
+		}
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package webhook_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nachoconques0/user_challenge_svc/pkg/challenge/webhook"
+)
+
+func TestSignature(t *testing.T) {
+	secret := "0123456789abcdef"
+	body := []byte(`{"id":"1","type":"USER_CREATED"}`)
+
+	signed := func(ts time.Time, signature string) http.Header {
+		h := http.Header{}
+		h.Set(webhook.HeaderTimestamp, strconv.FormatInt(ts.Unix(), 10))
+		h.Set(webhook.HeaderSignature, signature)
+		return h
+	}
+
+	t.Run("should sign with the documented format", func(t *testing.T) {
+		// echo -n '1700000000.{"id":"1","type":"USER_CREATED"}' | openssl dgst -sha256 -hmac 0123456789abcdef
+		assert.Equal(t,
+			"sha256=50c9c1fbd3f26debcb3ed29d3c4539ae433d7e71e573a86cb3b57744e7a2fe3e",
+			webhook.Sign(secret, time.Unix(1700000000, 0), body),
+		)
+	})
+
+	t.Run("should verify a valid signature", func(t *testing.T) {
+		now := time.Now()
+		err := webhook.Verify(secret, signed(now, webhook.Sign(secret, now, body)), body, time.Minute)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject a tampered body", func(t *testing.T) {
+		now := time.Now()
+		err := webhook.Verify(secret, signed(now, webhook.Sign(secret, now, body)), []byte(`{}`), time.Minute)
+		assert.ErrorIs(t, err, webhook.ErrInvalidSignature)
+	})
+
+	t.Run("should reject a different secret", func(t *testing.T) {
+		now := time.Now()
+		err := webhook.Verify("another-secret-value", signed(now, webhook.Sign(secret, now, body)), body, time.Minute)
+		assert.ErrorIs(t, err, webhook.ErrInvalidSignature)
+	})
+
+	t.Run("should reject an old delivery", func(t *testing.T) {
+		old := time.Now().Add(-time.Hour)
+		err := webhook.Verify(secret, signed(old, webhook.Sign(secret, old, body)), body, 5*time.Minute)
+		assert.ErrorIs(t, err, webhook.ErrExpiredSignature)
+	})
+
+	t.Run("should reject missing headers", func(t *testing.T) {
+		err := webhook.Verify(secret, http.Header{}, body, time.Minute)
+		assert.ErrorIs(t, err, webhook.ErrMissingSignature)
+	})
+}